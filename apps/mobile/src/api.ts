@@ -8,6 +8,20 @@ async function authHeaders() {
   return t ? { Authorization: `Bearer ${t}` } : {};
 }
 
+// deviceId identifies this install to notification-service, so re-registering
+// refreshes its push token instead of adding a device. It only has to be
+// unique among one user's installs; it is not a secret.
+export async function deviceId(): Promise<string> {
+  let id = await SecureStore.getItemAsync('device_id');
+  if (!id) {
+    const bytes = new Uint8Array(8);
+    (globalThis as any).crypto?.getRandomValues?.(bytes);
+    id = Date.now().toString(36) + '-' + Array.from(bytes, (b) => b.toString(16).padStart(2, '0')).join('');
+    await SecureStore.setItemAsync('device_id', id);
+  }
+  return id;
+}
+
 export async function api(path: string, init: RequestInit = {}) {
   const headers = { 'Content-Type': 'application/json', ...(await authHeaders()), ...(init.headers || {}) } as any;
   const res = await fetch(`${BFF_URL}${path}`, { ...init, headers });
@@ -17,9 +31,120 @@ export async function api(path: string, init: RequestInit = {}) {
 
 export type Venue = { id: string; title: string; subtitle?: string };
 
+export type Suggestion = { kind: 'venue' | 'neighborhood' | 'tag'; text: string; id?: string; count?: number };
+
+export type HeatCell = {
+  geohash: string;
+  lat: number;
+  lon: number;
+  bounds: [number, number, number, number];
+  intensity: number;
+  vibe: number | null;
+  venues: number;
+  devices: number;
+};
+
+export type Checkin = {
+  id: number;
+  venueId: string;
+  distance: number;
+  sharedWith: string[];
+  createdAt: string;
+  venue?: Venue;
+};
+
+export type Review = {
+  id: number;
+  venueId: string;
+  kind: 'review' | 'tip';
+  rating?: number;
+  body: string;
+  authorName: string;
+  helpfulCount: number;
+  status?: 'pending' | 'published' | 'rejected'; // own content only
+  createdAt: string;
+  updatedAt: string;
+};
+
+export type Achievement = {
+  id: string;
+  title: string;
+  description: string;
+  type: 'milestone' | 'discovery' | 'social' | 'streak' | 'special';
+  rarity: 'common' | 'rare' | 'epic' | 'legendary';
+  target: number;
+  progress?: number;
+  earnedAt?: string;
+};
+
+export type EventOccurrence = {
+  eventId: number;
+  venueId: string;
+  kind: 'event' | 'special';
+  title: string;
+  description: string;
+  startsAt: string;
+  endsAt: string;
+  timezone: string;
+  recurrence?: string;
+  ticketUrl?: string;
+  reservationUrl?: string;
+  tags: string[];
+  venue?: Venue; // GET /api/events only
+};
+
+export const events = {
+  list: async (
+    opts: { from?: string; to?: string; bbox?: [number, number, number, number]; kind?: 'event' | 'special'; tag?: string } = {}
+  ): Promise<{ items: EventOccurrence[] }> => {
+    const { bbox, ...rest } = opts;
+    const q = new URLSearchParams(rest as Record<string, string>);
+    if (bbox) q.set('bbox', bbox.join(','));
+    return api(`/api/events?${q}`);
+  },
+  forVenue: async (id: string, opts: { from?: string; to?: string } = {}): Promise<{ items: EventOccurrence[] }> =>
+    api(`/api/venues/${id}/events?${new URLSearchParams(opts as Record<string, string>)}`),
+  calendarUrl: (id: string) => `${BFF_URL}/api/venues/${id}/calendar.ics`
+};
+
+export const achievements = {
+  catalog: async (): Promise<{ items: Achievement[] }> => api('/api/achievements'),
+  mine: async (): Promise<{ earned: Achievement[]; inProgress: Achievement[] }> => api('/api/users/me/achievements')
+};
+
 export const venues = {
   discover: async (): Promise<{ items: Venue[] }> => api('/api/venues/discover'),
+  search: async (q: string): Promise<{ items: Venue[] }> => api(`/api/venues/search?q=${encodeURIComponent(q)}`),
+  autocomplete: async (q: string): Promise<{ suggestions: Suggestion[] }> =>
+    api(`/api/venues/autocomplete?q=${encodeURIComponent(q)}`),
+  trending: async (): Promise<{ items: (Venue & { trend: { score: number } })[] }> => api('/api/venues/trending'),
+  heatmap: async (bbox: [number, number, number, number], precision = 6): Promise<{ precision: number; cells: HeatCell[] }> =>
+    api(`/api/vibe/heatmap?bbox=${bbox.join(',')}&precision=${precision}`),
+  recommendations: async (): Promise<{ items: (Venue & { reason: string })[] }> => api('/api/users/recommendations'),
   like: async (id: string): Promise<void> => api(`/api/venues/${id}/like`, { method: 'POST' }),
+  checkin: async (
+    id: string,
+    at: { lat: number; lon: number; accuracy?: number },
+    share?: { friendIds: string[] }
+  ): Promise<Checkin> => api(`/api/venues/${id}/checkin`, { method: 'POST', body: JSON.stringify({ ...at, share }) }),
+  checkins: async (): Promise<{ items: Checkin[] }> => api('/api/users/me/checkins'),
+  reviews: async (
+    id: string,
+    opts: { kind?: 'review' | 'tip'; sort?: 'recent' | 'helpful' } = {}
+  ): Promise<{ items: Review[]; reviewCount: number; reviewRating: number | null }> =>
+    api(`/api/venues/${id}/reviews?${new URLSearchParams(opts as Record<string, string>)}`),
+  review: async (
+    id: string,
+    review: { kind: 'review' | 'tip'; rating?: number; body: string; authorName?: string }
+  ): Promise<Review> => api(`/api/venues/${id}/reviews`, { method: 'POST', body: JSON.stringify(review) }),
+  editReview: async (reviewId: number, patch: { rating?: number; body?: string; authorName?: string }): Promise<Review> =>
+    api(`/api/reviews/${reviewId}`, { method: 'PATCH', body: JSON.stringify(patch) }),
+  deleteReview: async (reviewId: number): Promise<void> => api(`/api/reviews/${reviewId}`, { method: 'DELETE' }),
+  helpful: async (reviewId: number, on = true): Promise<void> =>
+    api(`/api/reviews/${reviewId}/helpful`, { method: on ? 'POST' : 'DELETE' }),
+  report: async (reviewId: number, reason: 'spam' | 'offensive' | 'misleading' | 'off_topic' | 'other', details?: string): Promise<void> =>
+    api(`/api/reviews/${reviewId}/report`, { method: 'POST', body: JSON.stringify({ reason, details }) }),
+  myReviews: async (): Promise<{ items: Review[] }> => api('/api/users/me/reviews'),
   get: async (id: string): Promise<Venue> => api(`/api/venues/${id}`)
 };
 
@@ -1,9 +1,9 @@
 import React from 'react';
-import { SafeAreaView, Text, View, Button } from 'react-native';
+import { SafeAreaView, Text, View, Button, Platform } from 'react-native';
 import * as Location from 'expo-location';
 import * as Notifications from 'expo-notifications';
 import { Camera } from 'expo-camera';
-import { api } from '../api';
+import { api, deviceId } from '../api';
 
 export default function Permissions({ navigation }: any) {
   const [status, setStatus] = React.useState<string>('');
@@ -15,9 +15,10 @@ export default function Permissions({ navigation }: any) {
   const askPush = async () => {
     const { status } = await Notifications.requestPermissionsAsync();
     try {
-      const tokenData = await Notifications.getExpoPushTokenAsync();
-      const token = (tokenData as any)?.data || '';
-      if (token) await api('/api/devices/register', { method: 'POST', body: JSON.stringify({ token, role: 'user' }) });
+      // notification-service sends through APNs/FCM directly, so it needs the native token
+      const tokenData = await Notifications.getDevicePushTokenAsync();
+      const token = typeof tokenData?.data === 'string' ? tokenData.data : '';
+      if (token) await api('/api/devices/register', { method: 'POST', body: JSON.stringify({ deviceId: await deviceId(), platform: Platform.OS, token }) });
     } catch {}
     setStatus(`Push: ${status}`);
   };
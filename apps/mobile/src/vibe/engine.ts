@@ -14,20 +14,39 @@ function fuse(a:number, m:number) {
 
 let timer: any = null;
 
-async function sha256Hex(str: string): Promise<string> {
-  const enc = new TextEncoder().encode(str);
+function toHex(buf: ArrayBuffer): string {
+  return Array.from(new Uint8Array(buf)).map(b => b.toString(16).padStart(2,'0')).join('');
+}
+
+// Nonces and idempotency keys must be unpredictable; without a secure
+// source the report is not sent rather than signed with a guessable nonce.
+function randomNonce(): string {
+  const bytes = new Uint8Array(16);
   const cryptoAny: any = (globalThis as any).crypto;
-  if (!cryptoAny || !cryptoAny.subtle) return '';
-  const digest = await cryptoAny.subtle.digest('SHA-256', enc);
-  const bytes = Array.from(new Uint8Array(digest));
-  return bytes.map(b => b.toString(16).padStart(2,'0')).join('');
+  if (!cryptoAny?.getRandomValues) throw new Error('crypto.getRandomValues unavailable');
+  cryptoAny.getRandomValues(bytes);
+  return toHex(bytes.buffer);
 }
 
-async function signIfEnabled(body: string): Promise<Record<string,string>> {
-  const secret = (Constants.expoConfig?.extra as any)?.VIBE_HMAC_SECRET;
+// HMAC-SHA256 over `${method}\n${path}\n${timestamp}\n${nonce}\n${rawBody}`,
+// matching venue-service; path is venue-service's (no /api prefix).
+async function signIfEnabled(method: string, path: string, body: string): Promise<Record<string,string>> {
+  const extra = (Constants.expoConfig?.extra as any) || {};
+  const secret = extra.VIBE_HMAC_SECRET;
   if (!secret) return {};
-  const sig = await sha256Hex(secret + body);
-  return sig ? { 'x-signature': sig } : {};
+  const cryptoAny: any = (globalThis as any).crypto;
+  if (!cryptoAny || !cryptoAny.subtle) return {};
+  const enc = new TextEncoder();
+  const key = await cryptoAny.subtle.importKey('raw', enc.encode(secret), { name: 'HMAC', hash: 'SHA-256' }, false, ['sign']);
+  const ts = String(Math.floor(Date.now() / 1000));
+  const nonce = randomNonce();
+  const sig = await cryptoAny.subtle.sign('HMAC', key, enc.encode(`${method}\n${path}\n${ts}\n${nonce}\n${body}`));
+  return {
+    'X-Vibe-Key-Id': extra.VIBE_HMAC_KEY_ID || 'default',
+    'X-Vibe-Timestamp': ts,
+    'X-Vibe-Nonce': nonce,
+    'X-Vibe-Signature': toHex(sig),
+  };
 }
 
 export async function startVibeEngine(venueId: string) {
@@ -44,8 +63,9 @@ export async function startVibeEngine(venueId: string) {
         features: { audio_loudness_norm: a, motion_energy_norm: m, sample_duration_sec: 5 }
       };
       const body = JSON.stringify(payload);
-      const sigHeaders = await signIfEnabled(body);
-      const headers = { 'Content-Type': 'application/json', ...sigHeaders };
+      const sigHeaders = await signIfEnabled('POST', `/venues/${venueId}/vibe`, body);
+      // a retried submission replays the stored response instead of double counting
+      const headers = { 'Content-Type': 'application/json', 'Idempotency-Key': randomNonce(), ...sigHeaders };
       await api(`/api/venues/${venueId}/vibe`, { method: 'POST', body, headers });
     } catch (e) {
       // TODO: queue offline failures and retry later
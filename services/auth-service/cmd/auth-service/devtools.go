@@ -19,7 +19,7 @@ func devRoutes(mux *http.ServeMux, impl *server.ServerImpl) {
 		var req struct{ Email string `json:"email"` }
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil { w.WriteHeader(http.StatusBadRequest); return }
 		if req.Email == "" { w.WriteHeader(http.StatusBadRequest); return }
-		if err := impl.Store().PromoteAdminByEmail(r.Context(), req.Email); err != nil {
+		if err := impl.Users().PromoteAdminByEmail(r.Context(), req.Email); err != nil {
 			log.Printf("promote-admin error: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
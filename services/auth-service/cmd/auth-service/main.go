@@ -22,12 +22,12 @@ func main() {
 	}
 	addr := fmt.Sprintf(":%s", port)
 
-	r := server.NewRouter()
-	// Initialize DB-backed serverImpl for dev tools
+	// One serverImpl backs both the API router and the dev tools
 	impl, err := server.NewServerImpl(context.Background())
 	if err != nil {
 		log.Fatalf("failed to init server: %v", err)
 	}
+	r := server.NewRouterWithServer(impl)
 	mux := http.NewServeMux()
 	mux.Handle("/", r)
 	devRoutes(mux, impl)
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// FriendLink is another user the caller has added, been added by, or both.
+type FriendLink struct {
+	UserID   string
+	Name     *string
+	Outgoing bool // the caller added them
+	Incoming bool // they added the caller
+	Since    time.Time
+}
+
+// Mutual reports whether the two users are friends.
+func (l FriendLink) Mutual() bool { return l.Outgoing && l.Incoming }
+
+// AddFriendLink records that userID added friendID; adding twice is a no-op.
+func (s *Store) AddFriendLink(ctx context.Context, userID, friendID string) error {
+	_, err := s.Pool.Exec(ctx, `INSERT INTO friend_links (user_id, friend_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, userID, friendID)
+	return err
+}
+
+// RemoveFriendLinks unfriends the pair, or withdraws or declines a request:
+// both directions go.
+func (s *Store) RemoveFriendLinks(ctx context.Context, userID, friendID string) error {
+	_, err := s.Pool.Exec(ctx, `DELETE FROM friend_links WHERE (user_id=$1 AND friend_id=$2) OR (user_id=$2 AND friend_id=$1)`, userID, friendID)
+	return err
+}
+
+// ListFriendLinks returns everyone linked to userID either way, by name.
+func (s *Store) ListFriendLinks(ctx context.Context, userID string) ([]FriendLink, error) {
+	q := `SELECT u.id, u.name, bool_or(l.user_id = $1), bool_or(l.friend_id = $1), max(l.created_at)
+		FROM friend_links l
+		JOIN users u ON u.id = CASE WHEN l.user_id = $1 THEN l.friend_id ELSE l.user_id END
+		WHERE l.user_id = $1 OR l.friend_id = $1
+		GROUP BY u.id, u.name
+		ORDER BY lower(u.name) NULLS LAST, u.id`
+	rows, err := s.Pool.Query(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []FriendLink
+	for rows.Next() {
+		var l FriendLink
+		if err := rows.Scan(&l.UserID, &l.Name, &l.Outgoing, &l.Incoming, &l.Since); err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
+
+// FilterFriends returns the ids that are mutual friends of userID.
+func (s *Store) FilterFriends(ctx context.Context, userID string, ids []string) ([]string, error) {
+	q := `SELECT o.friend_id FROM friend_links o
+		JOIN friend_links i ON i.user_id = o.friend_id AND i.friend_id = o.user_id
+		WHERE o.user_id = $1 AND o.friend_id::text = ANY($2)`
+	rows, err := s.Pool.Query(ctx, q, userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
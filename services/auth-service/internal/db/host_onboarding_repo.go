@@ -2,6 +2,9 @@ package db
 
 import (
 	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
 )
 
 type HostOnboarding struct {
@@ -24,6 +27,9 @@ func (s *Store) GetHostOnboarding(ctx context.Context, userID string) (*HostOnbo
 	row := s.Pool.QueryRow(ctx, q, userID)
 	h := &HostOnboarding{}
 	if err := row.Scan(&h.UserID, &h.ServiceType, &h.Data, &h.Progress); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
 		return nil, err
 	}
 	return h, nil
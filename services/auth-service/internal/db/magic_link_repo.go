@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type MagicLink struct {
+	ID        string
+	Email     string
+	TokenHash string
+	NonceHash string
+	Attempts  int
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// UpsertMagicLink keeps one active link per email, like phone OTPs: a new
+// request invalidates the previous link.
+func (s *Store) UpsertMagicLink(ctx context.Context, email, tokenHash, nonceHash string, ttl time.Duration) error {
+	q := `INSERT INTO email_magic_links (email, token_hash, nonce_hash, expires_at)
+		VALUES (lower($1), $2, $3, $4)
+		ON CONFLICT ((lower(email))) DO UPDATE
+		SET token_hash = EXCLUDED.token_hash, nonce_hash = EXCLUDED.nonce_hash,
+			attempts = 0, expires_at = EXCLUDED.expires_at, created_at = NOW()`
+	_, err := s.Pool.Exec(ctx, q, email, tokenHash, nonceHash, time.Now().Add(ttl))
+	return err
+}
+
+func (s *Store) GetMagicLinkByEmail(ctx context.Context, email string) (*MagicLink, error) {
+	return s.getMagicLink(ctx, `WHERE lower(email) = lower($1)`, email)
+}
+
+func (s *Store) GetMagicLinkByToken(ctx context.Context, tokenHash string) (*MagicLink, error) {
+	return s.getMagicLink(ctx, `WHERE token_hash = $1`, tokenHash)
+}
+
+func (s *Store) getMagicLink(ctx context.Context, where string, arg string) (*MagicLink, error) {
+	q := `SELECT id, email, token_hash, nonce_hash, attempts, expires_at, created_at FROM email_magic_links ` + where
+	var l MagicLink
+	err := s.Pool.QueryRow(ctx, q, arg).Scan(&l.ID, &l.Email, &l.TokenHash, &l.NonceHash, &l.Attempts, &l.ExpiresAt, &l.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (s *Store) IncrementMagicLinkAttempts(ctx context.Context, id string) error {
+	_, err := s.Pool.Exec(ctx, `UPDATE email_magic_links SET attempts = attempts + 1 WHERE id=$1`, id)
+	return err
+}
+
+// ConsumeMagicLink deletes the link and reports whether this caller was the
+// one to do so, which makes concurrent verifies of the same link single-use.
+func (s *Store) ConsumeMagicLink(ctx context.Context, id string) (bool, error) {
+	tag, err := s.Pool.Exec(ctx, `DELETE FROM email_magic_links WHERE id=$1`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ClaimMagicLinkSend records a send to the address hashed as emailHash unless
+// one was recorded within every, reporting whether it did. Concurrent claims
+// for the same address get one winner. Claims older than a day are dropped
+// along the way.
+func (s *Store) ClaimMagicLinkSend(ctx context.Context, emailHash string, every time.Duration) (bool, error) {
+	if _, err := s.Pool.Exec(ctx, `DELETE FROM email_magic_link_sends WHERE sent_at < NOW() - INTERVAL '1 day'`); err != nil {
+		return false, err
+	}
+	q := `INSERT INTO email_magic_link_sends (email_hash) VALUES ($1)
+		ON CONFLICT (email_hash) DO UPDATE SET sent_at = NOW()
+		WHERE email_magic_link_sends.sent_at <= NOW() - make_interval(secs => $2)
+		RETURNING 1`
+	var one int
+	err := s.Pool.QueryRow(ctx, q, emailHash, every.Seconds()).Scan(&one)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
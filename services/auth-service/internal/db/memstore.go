@@ -0,0 +1,552 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemStore is an in-memory Repository for tests and DB-less local runs.
+// It mirrors the Postgres semantics the handlers rely on (case-insensitive
+// unique email, unique phone, nil results for missing rows).
+type MemStore struct {
+	mu            sync.RWMutex
+	users         map[string]*User
+	phoneHashes   map[string]string // user id -> phone hash
+	otps          map[string]*PhoneOTP
+	magicLinks    map[string]*MagicLink // lower(email) -> link
+	audit         []AdminAudit
+	onboarding    map[string]*HostOnboarding
+	sessions      map[string]*Session
+	refreshTokens map[string]*RefreshToken // token hash -> token
+	friendLinks   map[[2]string]time.Time  // {user, friend} -> added at
+	linkSends     map[string]time.Time     // email hash -> last magic link send
+}
+
+func NewMemStore() *MemStore {
+	return &MemStore{
+		users:         map[string]*User{},
+		phoneHashes:   map[string]string{},
+		otps:          map[string]*PhoneOTP{},
+		magicLinks:    map[string]*MagicLink{},
+		onboarding:    map[string]*HostOnboarding{},
+		sessions:      map[string]*Session{},
+		refreshTokens: map[string]*RefreshToken{},
+		friendLinks:   map[[2]string]time.Time{},
+		linkSends:     map[string]time.Time{},
+	}
+}
+
+// newID returns a random UUIDv4-formatted id like gen_random_uuid().
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+func copyUser(u *User) *User {
+	cp := *u
+	cp.Roles = append([]string(nil), u.Roles...)
+	return &cp
+}
+
+// Users
+
+func (m *MemStore) CreateUser(_ context.Context, u *User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	email := strings.ToLower(u.Email)
+	for _, cur := range m.users {
+		if email != "" && cur.Email == email {
+			return ErrDuplicateEmail
+		}
+		if u.Phone != nil && cur.Phone != nil && *cur.Phone == *u.Phone {
+			return ErrDuplicateEmail
+		}
+	}
+	now := time.Now()
+	u.ID, u.CreatedAt, u.UpdatedAt = newID(), now, now
+	stored := copyUser(u)
+	stored.Email = email
+	m.users[u.ID] = stored
+	return nil
+}
+
+func (m *MemStore) CreateUserPhoneOnly(ctx context.Context, phone string) (*User, error) {
+	u := &User{Phone: &phone, Roles: []string{"user"}, Provider: "phone", TokenVersion: 1}
+	if err := m.CreateUser(ctx, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (m *MemStore) findUser(match func(*User) bool) *User {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, u := range m.users {
+		if match(u) {
+			return copyUser(u)
+		}
+	}
+	return nil
+}
+
+func (m *MemStore) GetUserByID(_ context.Context, id string) (*User, error) {
+	return m.findUser(func(u *User) bool { return u.ID == id }), nil
+}
+
+func (m *MemStore) GetUserByEmail(_ context.Context, email string) (*User, error) {
+	email = strings.ToLower(email)
+	return m.findUser(func(u *User) bool { return email != "" && u.Email == email }), nil
+}
+
+func (m *MemStore) GetUserByPhone(_ context.Context, phone string) (*User, error) {
+	return m.findUser(func(u *User) bool { return u.Phone != nil && *u.Phone == phone }), nil
+}
+
+func (m *MemStore) UpdateLastLogin(_ context.Context, id string, t time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if u, ok := m.users[id]; ok {
+		u.LastLoginAt = &t
+	}
+	return nil
+}
+
+func (m *MemStore) UpdatePasswordHash(_ context.Context, id, passwordHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if u, ok := m.users[id]; ok {
+		u.PasswordHash = passwordHash
+		u.TokenVersion++
+		u.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+func (m *MemStore) updateRoles(email string, fn func([]string) []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	email = strings.ToLower(email)
+	for _, u := range m.users {
+		if u.Email == email {
+			u.Roles = fn(u.Roles)
+			u.UpdatedAt = time.Now()
+		}
+	}
+}
+
+func (m *MemStore) PromoteAdminByEmail(ctx context.Context, email string) error {
+	return m.GrantRoleByEmail(ctx, email, "admin")
+}
+
+func (m *MemStore) DemoteAdminByEmail(ctx context.Context, email string) error {
+	return m.RevokeRoleByEmail(ctx, email, "admin")
+}
+
+func (m *MemStore) GrantRoleByEmail(_ context.Context, email, role string) error {
+	m.updateRoles(email, func(roles []string) []string {
+		for _, r := range roles {
+			if r == role {
+				return roles
+			}
+		}
+		return append(roles, role)
+	})
+	return nil
+}
+
+func (m *MemStore) RevokeRoleByEmail(_ context.Context, email, role string) error {
+	m.updateRoles(email, func(roles []string) []string {
+		out := roles[:0]
+		for _, r := range roles {
+			if r != role {
+				out = append(out, r)
+			}
+		}
+		return out
+	})
+	return nil
+}
+
+func (m *MemStore) SetPhoneHash(_ context.Context, userID, phoneHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.phoneHashes[userID] = phoneHash
+	return nil
+}
+
+func (m *MemStore) MatchPhoneHashes(_ context.Context, hashes []string) ([]PhoneHashMatch, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	want := map[string]bool{}
+	for _, h := range hashes {
+		want[h] = true
+	}
+	var out []PhoneHashMatch
+	for id, h := range m.phoneHashes {
+		if want[h] {
+			out = append(out, PhoneHashMatch{ID: id, Hash: h})
+		}
+	}
+	return out, nil
+}
+
+// Phone OTP
+
+func (m *MemStore) UpsertPhoneOTP(_ context.Context, phone, codeHash string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	m.otps[phone] = &PhoneOTP{ID: newID(), Phone: phone, CodeHash: codeHash, ExpiresAt: now.Add(ttl), CreatedAt: now}
+	return nil
+}
+
+func (m *MemStore) GetPhoneOTP(_ context.Context, phone string) (*PhoneOTP, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if o, ok := m.otps[phone]; ok {
+		cp := *o
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (m *MemStore) IncrementOTPAttempts(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, o := range m.otps {
+		if o.ID == id {
+			o.Attempts++
+		}
+	}
+	return nil
+}
+
+func (m *MemStore) DeletePhoneOTP(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for phone, o := range m.otps {
+		if o.ID == id {
+			delete(m.otps, phone)
+		}
+	}
+	return nil
+}
+
+// Email magic links
+
+func (m *MemStore) UpsertMagicLink(_ context.Context, email, tokenHash, nonceHash string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	email = strings.ToLower(email)
+	m.magicLinks[email] = &MagicLink{ID: newID(), Email: email, TokenHash: tokenHash, NonceHash: nonceHash, ExpiresAt: now.Add(ttl), CreatedAt: now}
+	return nil
+}
+
+func (m *MemStore) findMagicLink(match func(*MagicLink) bool) *MagicLink {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, l := range m.magicLinks {
+		if match(l) {
+			cp := *l
+			return &cp
+		}
+	}
+	return nil
+}
+
+func (m *MemStore) GetMagicLinkByEmail(_ context.Context, email string) (*MagicLink, error) {
+	email = strings.ToLower(email)
+	return m.findMagicLink(func(l *MagicLink) bool { return l.Email == email }), nil
+}
+
+func (m *MemStore) GetMagicLinkByToken(_ context.Context, tokenHash string) (*MagicLink, error) {
+	return m.findMagicLink(func(l *MagicLink) bool { return l.TokenHash == tokenHash }), nil
+}
+
+func (m *MemStore) IncrementMagicLinkAttempts(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, l := range m.magicLinks {
+		if l.ID == id {
+			l.Attempts++
+		}
+	}
+	return nil
+}
+
+func (m *MemStore) ConsumeMagicLink(_ context.Context, id string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for email, l := range m.magicLinks {
+		if l.ID == id {
+			delete(m.magicLinks, email)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MemStore) ClaimMagicLinkSend(_ context.Context, emailHash string, every time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if last, ok := m.linkSends[emailHash]; ok && now.Sub(last) < every {
+		return false, nil
+	}
+	m.linkSends[emailHash] = now
+	return true, nil
+}
+
+// Admin audit
+
+func (m *MemStore) InsertAdminAudit(_ context.Context, actorID, targetEmail, action, reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a := AdminAudit{ID: newID(), ActorID: actorID, TargetEmail: strings.ToLower(targetEmail), Action: action, CreatedAt: time.Now()}
+	if reason != "" {
+		a.Reason = &reason
+	}
+	m.audit = append(m.audit, a)
+	return nil
+}
+
+func (m *MemStore) InsertImpersonationAudit(_ context.Context, actorID, targetUserID, targetEmail, reason, tokenID string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.audit = append(m.audit, AdminAudit{
+		ID: newID(), ActorID: actorID, TargetEmail: strings.ToLower(targetEmail), TargetUserID: &targetUserID,
+		Action: "impersonate", Reason: &reason, TokenID: &tokenID, ExpiresAt: &expiresAt, CreatedAt: time.Now(),
+	})
+	return nil
+}
+
+func (m *MemStore) ListAdminAudit(_ context.Context, limit int) ([]AdminAudit, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []AdminAudit
+	for i := len(m.audit) - 1; i >= 0 && len(out) < limit; i-- {
+		out = append(out, m.audit[i])
+	}
+	return out, nil
+}
+
+// Host onboarding
+
+func (m *MemStore) UpsertHostOnboarding(_ context.Context, userID string, serviceType *string, data map[string]any, progress int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onboarding[userID] = &HostOnboarding{UserID: userID, ServiceType: serviceType, Data: data, Progress: progress}
+	return nil
+}
+
+func (m *MemStore) GetHostOnboarding(_ context.Context, userID string) (*HostOnboarding, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if h, ok := m.onboarding[userID]; ok {
+		cp := *h
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+// Sessions
+
+func (m *MemStore) CreateSession(_ context.Context, sess *Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	sess.ID, sess.CreatedAt, sess.LastSeenAt = newID(), now, now
+	cp := *sess
+	m.sessions[sess.ID] = &cp
+	return nil
+}
+
+func (m *MemStore) GetSession(_ context.Context, id string) (*Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if s, ok := m.sessions[id]; ok {
+		cp := *s
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (m *MemStore) ListActiveSessions(_ context.Context, userID string) ([]Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []Session
+	for _, s := range m.sessions {
+		if s.UserID == userID && s.RevokedAt == nil {
+			out = append(out, *s)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].LastSeenAt.After(out[j].LastSeenAt) })
+	return out, nil
+}
+
+func (m *MemStore) TouchSession(_ context.Context, id string, t time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.sessions[id]; ok && s.LastSeenAt.Before(t.Add(-sessionTouchInterval)) {
+		s.LastSeenAt = t
+	}
+	return nil
+}
+
+func (m *MemStore) RevokeSession(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if s, ok := m.sessions[id]; ok && s.RevokedAt == nil {
+		s.RevokedAt = &now
+	}
+	for _, t := range m.refreshTokens {
+		if t.SessionID == id && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (m *MemStore) RevokeOtherSessions(ctx context.Context, userID, keepID string) error {
+	m.mu.RLock()
+	var ids []string
+	for id, s := range m.sessions {
+		if s.UserID == userID && id != keepID && s.RevokedAt == nil {
+			ids = append(ids, id)
+		}
+	}
+	m.mu.RUnlock()
+	for _, id := range ids {
+		_ = m.RevokeSession(ctx, id)
+	}
+	return nil
+}
+
+func (m *MemStore) InsertRefreshToken(_ context.Context, sessionID, tokenHash string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.sessions[sessionID]; !ok {
+		return errors.New("session not found")
+	}
+	m.refreshTokens[tokenHash] = &RefreshToken{ID: newID(), SessionID: sessionID, TokenHash: tokenHash, ExpiresAt: expiresAt, CreatedAt: time.Now()}
+	return nil
+}
+
+func (m *MemStore) GetRefreshToken(_ context.Context, tokenHash string) (*RefreshToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	t, ok := m.refreshTokens[tokenHash]
+	if !ok {
+		return nil, nil
+	}
+	cp := *t
+	if s, ok := m.sessions[t.SessionID]; ok {
+		cp.UserID = s.UserID
+		cp.SessionRevokedAt = s.RevokedAt
+	}
+	return &cp, nil
+}
+
+func (m *MemStore) RevokeRefreshToken(_ context.Context, id string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for _, t := range m.refreshTokens {
+		if t.ID == id && t.RevokedAt == nil {
+			t.RevokedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Friends
+
+func (m *MemStore) AddFriendLink(_ context.Context, userID, friendID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := [2]string{userID, friendID}
+	if _, ok := m.friendLinks[k]; !ok {
+		m.friendLinks[k] = time.Now()
+	}
+	return nil
+}
+
+func (m *MemStore) RemoveFriendLinks(_ context.Context, userID, friendID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.friendLinks, [2]string{userID, friendID})
+	delete(m.friendLinks, [2]string{friendID, userID})
+	return nil
+}
+
+func (m *MemStore) ListFriendLinks(_ context.Context, userID string) ([]FriendLink, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	byID := map[string]*FriendLink{}
+	for k, at := range m.friendLinks {
+		other := ""
+		switch userID {
+		case k[0]:
+			other = k[1]
+		case k[1]:
+			other = k[0]
+		default:
+			continue
+		}
+		l, ok := byID[other]
+		if !ok {
+			l = &FriendLink{UserID: other}
+			if u := m.users[other]; u != nil {
+				l.Name = u.Name
+			}
+			byID[other] = l
+		}
+		l.Outgoing = l.Outgoing || k[0] == userID
+		l.Incoming = l.Incoming || k[1] == userID
+		if at.After(l.Since) {
+			l.Since = at
+		}
+	}
+	out := make([]FriendLink, 0, len(byID))
+	for _, l := range byID {
+		out = append(out, *l)
+	}
+	name := func(l FriendLink) string {
+		if l.Name == nil {
+			return "\uffff" + l.UserID
+		}
+		return strings.ToLower(*l.Name)
+	}
+	sort.Slice(out, func(i, j int) bool { return name(out[i]) < name(out[j]) })
+	return out, nil
+}
+
+func (m *MemStore) FilterFriends(_ context.Context, userID string, ids []string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []string
+	for _, id := range ids {
+		_, added := m.friendLinks[[2]string{userID, id}]
+		_, back := m.friendLinks[[2]string{id, userID}]
+		if added && back {
+			out = append(out, id)
+		}
+	}
+	return out, nil
+}
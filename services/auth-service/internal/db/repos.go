@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// Repository interfaces used by the HTTP layer. *Store implements them on
+// Postgres; *MemStore implements them in memory for tests and local dev.
+
+type UserRepo interface {
+	CreateUser(ctx context.Context, u *User) error
+	CreateUserPhoneOnly(ctx context.Context, phone string) (*User, error)
+	GetUserByID(ctx context.Context, id string) (*User, error)
+	GetUserByEmail(ctx context.Context, email string) (*User, error)
+	GetUserByPhone(ctx context.Context, phone string) (*User, error)
+	UpdateLastLogin(ctx context.Context, id string, t time.Time) error
+	UpdatePasswordHash(ctx context.Context, id, passwordHash string) error
+	PromoteAdminByEmail(ctx context.Context, email string) error
+	DemoteAdminByEmail(ctx context.Context, email string) error
+	GrantRoleByEmail(ctx context.Context, email, role string) error
+	RevokeRoleByEmail(ctx context.Context, email, role string) error
+	SetPhoneHash(ctx context.Context, userID, phoneHash string) error
+	MatchPhoneHashes(ctx context.Context, hashes []string) ([]PhoneHashMatch, error)
+}
+
+type OTPRepo interface {
+	UpsertPhoneOTP(ctx context.Context, phone, codeHash string, ttl time.Duration) error
+	GetPhoneOTP(ctx context.Context, phone string) (*PhoneOTP, error)
+	IncrementOTPAttempts(ctx context.Context, id string) error
+	DeletePhoneOTP(ctx context.Context, id string) error
+}
+
+type MagicLinkRepo interface {
+	UpsertMagicLink(ctx context.Context, email, tokenHash, nonceHash string, ttl time.Duration) error
+	GetMagicLinkByEmail(ctx context.Context, email string) (*MagicLink, error)
+	GetMagicLinkByToken(ctx context.Context, tokenHash string) (*MagicLink, error)
+	IncrementMagicLinkAttempts(ctx context.Context, id string) error
+	ConsumeMagicLink(ctx context.Context, id string) (bool, error)
+	ClaimMagicLinkSend(ctx context.Context, emailHash string, every time.Duration) (bool, error)
+}
+
+type AuditRepo interface {
+	InsertAdminAudit(ctx context.Context, actorID, targetEmail, action, reason string) error
+	InsertImpersonationAudit(ctx context.Context, actorID, targetUserID, targetEmail, reason, tokenID string, expiresAt time.Time) error
+	ListAdminAudit(ctx context.Context, limit int) ([]AdminAudit, error)
+}
+
+type OnboardingRepo interface {
+	UpsertHostOnboarding(ctx context.Context, userID string, serviceType *string, data map[string]any, progress int) error
+	GetHostOnboarding(ctx context.Context, userID string) (*HostOnboarding, error)
+}
+
+type SessionRepo interface {
+	CreateSession(ctx context.Context, sess *Session) error
+	GetSession(ctx context.Context, id string) (*Session, error)
+	ListActiveSessions(ctx context.Context, userID string) ([]Session, error)
+	TouchSession(ctx context.Context, id string, t time.Time) error
+	RevokeSession(ctx context.Context, id string) error
+	RevokeOtherSessions(ctx context.Context, userID, keepID string) error
+	InsertRefreshToken(ctx context.Context, sessionID, tokenHash string, expiresAt time.Time) error
+	GetRefreshToken(ctx context.Context, tokenHash string) (*RefreshToken, error)
+	RevokeRefreshToken(ctx context.Context, id string) (bool, error)
+}
+
+type FriendRepo interface {
+	AddFriendLink(ctx context.Context, userID, friendID string) error
+	RemoveFriendLinks(ctx context.Context, userID, friendID string) error
+	ListFriendLinks(ctx context.Context, userID string) ([]FriendLink, error)
+	FilterFriends(ctx context.Context, userID string, ids []string) ([]string, error)
+}
+
+// Repository bundles every repo; both backends implement all of them.
+type Repository interface {
+	UserRepo
+	OTPRepo
+	MagicLinkRepo
+	AuditRepo
+	OnboardingRepo
+	SessionRepo
+	FriendRepo
+}
+
+var (
+	_ Repository = (*Store)(nil)
+	_ Repository = (*MemStore)(nil)
+)
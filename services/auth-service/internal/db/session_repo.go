@@ -0,0 +1,154 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type Session struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"userId"`
+	DeviceName *string    `json:"deviceName,omitempty"`
+	Platform   *string    `json:"platform,omitempty"`
+	IP         *string    `json:"ip,omitempty"`
+	UserAgent  *string    `json:"userAgent,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastSeenAt time.Time  `json:"lastSeenAt"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
+
+type RefreshToken struct {
+	ID        string
+	SessionID string
+	UserID    string
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	RevokedAt *time.Time
+	// SessionRevokedAt is set when the owning session has been revoked.
+	SessionRevokedAt *time.Time
+}
+
+// sessionTouchInterval throttles last_seen_at writes so authenticated
+// requests don't turn into one UPDATE each.
+const sessionTouchInterval = time.Minute
+
+func (s *Store) CreateSession(ctx context.Context, sess *Session) error {
+	q := `INSERT INTO user_sessions (user_id, device_name, platform, ip, user_agent)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, last_seen_at`
+	return s.Pool.QueryRow(ctx, q, sess.UserID, sess.DeviceName, sess.Platform, sess.IP, sess.UserAgent).
+		Scan(&sess.ID, &sess.CreatedAt, &sess.LastSeenAt)
+}
+
+// GetSession returns nil for an unknown or malformed id.
+func (s *Store) GetSession(ctx context.Context, id string) (*Session, error) {
+	q := `SELECT id, user_id, device_name, platform, ip, user_agent, created_at, last_seen_at, revoked_at
+		FROM user_sessions WHERE id = $1`
+	sess := &Session{}
+	err := s.Pool.QueryRow(ctx, q, id).Scan(&sess.ID, &sess.UserID, &sess.DeviceName, &sess.Platform, &sess.IP, &sess.UserAgent, &sess.CreatedAt, &sess.LastSeenAt, &sess.RevokedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.Is(err, pgx.ErrNoRows) || errors.As(err, &pgErr) && pgErr.Code == "22P02" { // not a uuid: no such session
+			return nil, nil
+		}
+		return nil, err
+	}
+	return sess, nil
+}
+
+// ListActiveSessions returns the user's non-revoked sessions, most recently used first.
+func (s *Store) ListActiveSessions(ctx context.Context, userID string) ([]Session, error) {
+	q := `SELECT id, user_id, device_name, platform, ip, user_agent, created_at, last_seen_at, revoked_at
+		FROM user_sessions WHERE user_id = $1 AND revoked_at IS NULL ORDER BY last_seen_at DESC`
+	rows, err := s.Pool.Query(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Session
+	for rows.Next() {
+		var sess Session
+		if err := rows.Scan(&sess.ID, &sess.UserID, &sess.DeviceName, &sess.Platform, &sess.IP, &sess.UserAgent, &sess.CreatedAt, &sess.LastSeenAt, &sess.RevokedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, sess)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) TouchSession(ctx context.Context, id string, t time.Time) error {
+	_, err := s.Pool.Exec(ctx, `UPDATE user_sessions SET last_seen_at=$2 WHERE id=$1 AND last_seen_at < $3`, id, t, t.Add(-sessionTouchInterval))
+	return err
+}
+
+// RevokeSession marks the session revoked together with all of its refresh tokens.
+func (s *Store) RevokeSession(ctx context.Context, id string) error {
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `UPDATE user_sessions SET revoked_at=NOW() WHERE id=$1 AND revoked_at IS NULL`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE refresh_tokens SET revoked_at=NOW() WHERE session_id=$1 AND revoked_at IS NULL`, id); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// RevokeOtherSessions revokes every active session of the user except keepID
+// (used after a password change).
+func (s *Store) RevokeOtherSessions(ctx context.Context, userID, keepID string) error {
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	q := `UPDATE user_sessions SET revoked_at=NOW() WHERE user_id=$1 AND id::text <> $2 AND revoked_at IS NULL`
+	if _, err := tx.Exec(ctx, q, userID, keepID); err != nil {
+		return err
+	}
+	q = `UPDATE refresh_tokens t SET revoked_at=NOW() FROM user_sessions us
+		WHERE us.id = t.session_id AND us.user_id=$1 AND us.id::text <> $2 AND t.revoked_at IS NULL`
+	if _, err := tx.Exec(ctx, q, userID, keepID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *Store) InsertRefreshToken(ctx context.Context, sessionID, tokenHash string, expiresAt time.Time) error {
+	_, err := s.Pool.Exec(ctx, `INSERT INTO refresh_tokens (session_id, token_hash, expires_at) VALUES ($1, $2, $3)`, sessionID, tokenHash, expiresAt)
+	return err
+}
+
+func (s *Store) GetRefreshToken(ctx context.Context, tokenHash string) (*RefreshToken, error) {
+	q := `SELECT t.id, t.session_id, us.user_id, t.token_hash, t.expires_at, t.created_at, t.revoked_at, us.revoked_at
+		FROM refresh_tokens t JOIN user_sessions us ON us.id = t.session_id
+		WHERE t.token_hash = $1`
+	t := &RefreshToken{}
+	err := s.Pool.QueryRow(ctx, q, tokenHash).Scan(&t.ID, &t.SessionID, &t.UserID, &t.TokenHash, &t.ExpiresAt, &t.CreatedAt, &t.RevokedAt, &t.SessionRevokedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+// RevokeRefreshToken marks the token used and reports whether this caller was
+// the one to do so, which makes concurrent refreshes of the same token
+// single-use.
+func (s *Store) RevokeRefreshToken(ctx context.Context, id string) (bool, error) {
+	tag, err := s.Pool.Exec(ctx, `UPDATE refresh_tokens SET revoked_at=NOW() WHERE id=$1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
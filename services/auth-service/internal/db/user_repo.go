@@ -26,12 +26,21 @@ type User struct {
 }
 
 type AdminAudit struct {
-	ID          string
-	ActorID     string
-	TargetEmail string
-	Action      string
-	Reason      *string
-	CreatedAt   time.Time
+	ID           string
+	ActorID      string
+	TargetEmail  string
+	TargetUserID *string
+	Action       string
+	Reason       *string
+	TokenID      *string
+	ExpiresAt    *time.Time
+	CreatedAt    time.Time
+}
+
+// PhoneHashMatch is a user whose hashed phone matched a contacts upload.
+type PhoneHashMatch struct {
+	ID   string `json:"id"`
+	Hash string `json:"hash"`
 }
 
 var ErrDuplicateEmail = errors.New("duplicate_email")
@@ -94,11 +103,54 @@ func (s *Store) GetUserByEmail(ctx context.Context, email string) (*User, error)
 	return u, nil
 }
 
+func (s *Store) GetUserByID(ctx context.Context, id string) (*User, error) {
+	// email/password_hash are NULL for phone-first accounts
+	q := `SELECT id, COALESCE(email, ''), COALESCE(password_hash, ''), name, phone, is_email_verified, roles, provider, token_version, metadata, last_login_at, created_at, updated_at
+		FROM users WHERE id = $1`
+	row := s.Pool.QueryRow(ctx, q, id)
+	u := &User{}
+	var metadataBytes []byte
+	if err := row.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Name, &u.Phone, &u.IsEmailVerified, &u.Roles, &u.Provider, &u.TokenVersion, &metadataBytes, &u.LastLoginAt, &u.CreatedAt, &u.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return u, nil
+}
+
 func (s *Store) UpdateLastLogin(ctx context.Context, id string, t time.Time) error {
 	_, err := s.Pool.Exec(ctx, `UPDATE users SET last_login_at=$1 WHERE id=$2`, t, id)
 	return err
 }
 
+func (s *Store) UpdatePasswordHash(ctx context.Context, id, passwordHash string) error {
+	_, err := s.Pool.Exec(ctx, `UPDATE users SET password_hash=$1, token_version=token_version+1 WHERE id=$2`, passwordHash, id)
+	return err
+}
+
+func (s *Store) SetPhoneHash(ctx context.Context, userID, phoneHash string) error {
+	_, err := s.Pool.Exec(ctx, `UPDATE users SET phone_hash=$1 WHERE id=$2`, phoneHash, userID)
+	return err
+}
+
+func (s *Store) MatchPhoneHashes(ctx context.Context, hashes []string) ([]PhoneHashMatch, error) {
+	rows, err := s.Pool.Query(ctx, `SELECT id, phone_hash FROM users WHERE phone_hash = ANY($1)`, hashes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []PhoneHashMatch
+	for rows.Next() {
+		var m PhoneHashMatch
+		if err := rows.Scan(&m.ID, &m.Hash); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
 func (s *Store) PromoteAdminByEmail(ctx context.Context, email string) error {
 	q := `UPDATE users SET roles = (SELECT ARRAY(SELECT DISTINCT UNNEST(roles || '{admin}'))) WHERE lower(email) = lower($1)`
 	_, err := s.Pool.Exec(ctx, q, email)
@@ -111,17 +163,40 @@ func (s *Store) DemoteAdminByEmail(ctx context.Context, email string) error {
 	return err
 }
 
+// GrantRoleByEmail adds role (e.g. "support") to the user's roles if missing.
+func (s *Store) GrantRoleByEmail(ctx context.Context, email, role string) error {
+	q := `UPDATE users SET roles = (SELECT ARRAY(SELECT DISTINCT UNNEST(roles || ARRAY[$2]::text[]))) WHERE lower(email) = lower($1)`
+	_, err := s.Pool.Exec(ctx, q, email, role)
+	return err
+}
+
+func (s *Store) RevokeRoleByEmail(ctx context.Context, email, role string) error {
+	q := `UPDATE users SET roles = ARRAY(SELECT UNNEST(roles) EXCEPT SELECT $2::text) WHERE lower(email) = lower($1)`
+	_, err := s.Pool.Exec(ctx, q, email, role)
+	return err
+}
+
 func (s *Store) InsertAdminAudit(ctx context.Context, actorID, targetEmail, action, reason string) error {
 	q := `INSERT INTO admin_audit(actor_id, target_email, action, reason) VALUES ($1, lower($2), $3, $4)`
 	_, err := s.Pool.Exec(ctx, q, actorID, targetEmail, action, reason)
 	return err
 }
 
+// InsertImpersonationAudit records an impersonation grant: who, as whom, why,
+// and which token (jti) was issued until when.
+func (s *Store) InsertImpersonationAudit(ctx context.Context, actorID, targetUserID, targetEmail, reason, tokenID string, expiresAt time.Time) error {
+	q := `INSERT INTO admin_audit(actor_id, target_email, target_user_id, action, reason, token_id, expires_at)
+		VALUES ($1, NULLIF(lower($2), ''), $3, 'impersonate', $4, $5, $6)`
+	_, err := s.Pool.Exec(ctx, q, actorID, targetEmail, targetUserID, reason, tokenID, expiresAt)
+	return err
+}
+
 func (s *Store) ListAdminAudit(ctx context.Context, limit int) ([]AdminAudit, error) {
 	if limit <= 0 {
 		limit = 50
 	}
-	rows, err := s.Pool.Query(ctx, `SELECT id, actor_id, target_email, action, reason, created_at FROM admin_audit ORDER BY created_at DESC LIMIT $1`, limit)
+	rows, err := s.Pool.Query(ctx, `SELECT id, actor_id, COALESCE(target_email, ''), target_user_id, action, reason, token_id, expires_at, created_at
+		FROM admin_audit ORDER BY created_at DESC LIMIT $1`, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -130,7 +205,7 @@ func (s *Store) ListAdminAudit(ctx context.Context, limit int) ([]AdminAudit, er
 	for rows.Next() {
 		var a AdminAudit
 		var reason *string
-		if err := rows.Scan(&a.ID, &a.ActorID, &a.TargetEmail, &a.Action, &reason, &a.CreatedAt); err != nil {
+		if err := rows.Scan(&a.ID, &a.ActorID, &a.TargetEmail, &a.TargetUserID, &a.Action, &reason, &a.TokenID, &a.ExpiresAt, &a.CreatedAt); err != nil {
 			return nil, err
 		}
 		a.Reason = reason
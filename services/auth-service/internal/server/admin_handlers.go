@@ -0,0 +1,104 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"bytspot/shared/middleware"
+)
+
+// requireAdmin authenticates the caller and checks the admin role, writing
+// the error response itself on failure.
+func (s *ServerImpl) requireAdmin(w http.ResponseWriter, r *http.Request) (*jwtCustomClaims, bool) {
+	claims, ok := s.requireFullAccess(w, r)
+	if !ok {
+		return nil, false
+	}
+	if !hasRole(claims, "admin") {
+		middleware.ErrorHandler(w, http.StatusForbidden, "admin role required", "FORBIDDEN")
+		return nil, false
+	}
+	return claims, true
+}
+
+// grantableRoles are the roles admins may hand out through promote/demote.
+// "support" unlocks impersonation; "host" lets venue hosts manage the venues
+// they own in venue-service.
+var grantableRoles = map[string]bool{"admin": true, "support": true, "host": true}
+
+// POST /auth/admin/promote { email, role? }
+func (s *ServerImpl) PostAdminPromote(w http.ResponseWriter, r *http.Request) {
+	s.changeAdminRole(w, r, "promote")
+}
+
+// POST /auth/admin/demote { email, role? }
+func (s *ServerImpl) PostAdminDemote(w http.ResponseWriter, r *http.Request) {
+	s.changeAdminRole(w, r, "demote")
+}
+
+func (s *ServerImpl) changeAdminRole(w http.ResponseWriter, r *http.Request, action string) {
+	claims, ok := s.requireAdmin(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "invalid JSON", "INVALID_JSON")
+		return
+	}
+	if req.Role == "" {
+		req.Role = "admin"
+	}
+	if !grantableRoles[req.Role] {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "unknown role", "VALIDATION_ERROR")
+		return
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	var err error
+	if action == "promote" {
+		err = s.users.GrantRoleByEmail(r.Context(), req.Email, req.Role)
+	} else {
+		err = s.users.RevokeRoleByEmail(r.Context(), req.Email, req.Role)
+	}
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	reason := ""
+	if req.Role != "admin" {
+		reason = "role=" + req.Role
+	}
+	_ = s.audit.InsertAdminAudit(r.Context(), claims.Sub, req.Email, action, reason)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /auth/admin/audit?limit=
+func (s *ServerImpl) GetAdminAudit(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.requireAdmin(w, r); !ok {
+		return
+	}
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if n, e := strconv.Atoi(v); e == nil {
+			limit = n
+		}
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	items, err := s.audit.ListAdminAudit(r.Context(), limit)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"items": items})
+}
@@ -13,18 +13,9 @@ func (s *ServerImpl) PostContactsMatch(w http.ResponseWriter, r *http.Request) {
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Hashes) == 0 {
         middleware.ErrorHandler(w, http.StatusBadRequest, "invalid payload", "VALIDATION_ERROR"); return
     }
-    if s.store == nil || s.store.Pool == nil { middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR"); return }
-    // Simple match query: SELECT id, phone_hash FROM users WHERE phone_hash IN (...)
-    rows, err := s.store.Pool.Query(r.Context(), `SELECT id, phone_hash FROM users WHERE phone_hash = ANY($1)`, req.Hashes)
+    if !s.ready() { middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR"); return }
+    matches, err := s.users.MatchPhoneHashes(r.Context(), req.Hashes)
     if err != nil { middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR"); return }
-    defer rows.Close()
-    type match struct { ID string `json:"id"`; Hash string `json:"hash"` }
-    var matches []match
-    for rows.Next() {
-        var id, h string
-        if err := rows.Scan(&id, &h); err == nil { matches = append(matches, match{ID: id, Hash: h}) }
-    }
     w.Header().Set("Content-Type", "application/json")
     json.NewEncoder(w).Encode(map[string]any{"matches": matches})
 }
-
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"bytspot/services/auth-service/internal/db"
+	"bytspot/shared/middleware"
+)
+
+// maxFriendCheck caps the ids one friend check may ask about.
+const maxFriendCheck = 100
+
+// friendView is one entry of GET /friends. status is friends, requested
+// (the caller added them) or incoming (they added the caller).
+type friendView struct {
+	ID     string    `json:"id"`
+	Name   *string   `json:"name"`
+	Status string    `json:"status"`
+	Since  time.Time `json:"since"`
+}
+
+func friendStatus(l db.FriendLink) string {
+	switch {
+	case l.Mutual():
+		return "friends"
+	case l.Outgoing:
+		return "requested"
+	default:
+		return "incoming"
+	}
+}
+
+// GET /friends
+func (s *ServerImpl) GetFriends(w http.ResponseWriter, r *http.Request) {
+	claims, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	links, err := s.friends.ListFriendLinks(r.Context(), claims.Sub)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	items := make([]friendView, 0, len(links))
+	for _, l := range links {
+		items = append(items, friendView{ID: l.UserID, Name: l.Name, Status: friendStatus(l), Since: l.Since})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"items": items})
+}
+
+// PUT /friends/{id}
+// Sends a friend request, or accepts theirs if they already added the caller.
+func (s *ServerImpl) PutFriendsId(w http.ResponseWriter, r *http.Request, id string) {
+	claims, ok := s.requireFullAccess(w, r)
+	if !ok {
+		return
+	}
+	if id == claims.Sub {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "cannot add yourself", "VALIDATION_ERROR")
+		return
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	if u, err := s.users.GetUserByID(r.Context(), id); err != nil || u == nil {
+		middleware.ErrorHandler(w, http.StatusNotFound, "user not found", "NOT_FOUND")
+		return
+	}
+	if err := s.friends.AddFriendLink(r.Context(), claims.Sub, id); err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	mutual, err := s.friends.FilterFriends(r.Context(), claims.Sub, []string{id})
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	status := "requested"
+	if len(mutual) == 1 {
+		status = "friends"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"id": id, "status": status})
+}
+
+// DELETE /friends/{id}
+// Unfriends, withdraws a request or declines one.
+func (s *ServerImpl) DeleteFriendsId(w http.ResponseWriter, r *http.Request, id string) {
+	claims, ok := s.requireFullAccess(w, r)
+	if !ok {
+		return
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	if err := s.friends.RemoveFriendLinks(r.Context(), claims.Sub, id); err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /internal/users/{id}/friends/check { ids } (service token)
+// Which of ids are the user's friends, and the user's name, for services that
+// tell friends about the user (venue-service check-in sharing).
+func (s *ServerImpl) PostInternalFriendsCheck(w http.ResponseWriter, r *http.Request, id string) {
+	if !s.services.Check(w, r) {
+		return
+	}
+	var req struct {
+		IDs []string `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "invalid JSON", "INVALID_JSON")
+		return
+	}
+	if len(req.IDs) > maxFriendCheck {
+		middleware.ErrorHandler(w, http.StatusBadRequest, fmt.Sprintf("at most %d ids", maxFriendCheck), "VALIDATION_ERROR")
+		return
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	u, err := s.users.GetUserByID(r.Context(), id)
+	if err != nil || u == nil {
+		middleware.ErrorHandler(w, http.StatusNotFound, "user not found", "NOT_FOUND")
+		return
+	}
+	friends := []string{}
+	if len(req.IDs) > 0 {
+		found, err := s.friends.FilterFriends(r.Context(), id, req.IDs)
+		if err != nil {
+			middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+			return
+		}
+		friends = append(friends, found...)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"name": u.Name, "friendIds": friends})
+}
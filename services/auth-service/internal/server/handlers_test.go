@@ -0,0 +1,509 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"bytspot/services/auth-service/internal/db"
+	"bytspot/shared/servicetoken"
+)
+
+type testEnv struct {
+	t    *testing.T
+	repo *db.MemStore
+	h    http.Handler
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	repo := db.NewMemStore()
+	return &testEnv{t: t, repo: repo, h: NewRouterWithServer(NewServerImplWithRepository(repo))}
+}
+
+func (e *testEnv) do(method, path, token string, body any) *httptest.ResponseRecorder {
+	e.t.Helper()
+	var rdr *bytes.Reader
+	if body != nil {
+		b, _ := json.Marshal(body)
+		rdr = bytes.NewReader(b)
+	} else {
+		rdr = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, path, rdr)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	e.h.ServeHTTP(w, req)
+	return w
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	var out map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decode %q: %v", w.Body.String(), err)
+	}
+	return out
+}
+
+// registerAndLogin returns the login response for a fresh email user.
+func (e *testEnv) registerAndLogin(email string) map[string]any {
+	e.t.Helper()
+	if w := e.do(http.MethodPost, "/auth/register", "", map[string]string{"email": email, "password": "S3cure!Password"}); w.Code != http.StatusCreated {
+		e.t.Fatalf("register: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	w := e.do(http.MethodPost, "/auth/login", "", map[string]string{"email": email, "password": "S3cure!Password"})
+	if w.Code != http.StatusOK {
+		e.t.Fatalf("login: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	return decode(e.t, w)
+}
+
+func TestRegisterLoginMe(t *testing.T) {
+	e := newTestEnv(t)
+	login := e.registerAndLogin("Alice@Example.com")
+	if login["refresh_token"] == "" || login["session_id"] == "" {
+		t.Fatalf("expected refresh token and session id, got %v", login)
+	}
+
+	if w := e.do(http.MethodPost, "/auth/register", "", map[string]string{"email": "alice@example.com", "password": "x"}); w.Code != http.StatusConflict {
+		t.Fatalf("duplicate register: expected 409, got %d", w.Code)
+	}
+	if w := e.do(http.MethodPost, "/auth/login", "", map[string]string{"email": "alice@example.com", "password": "wrong"}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("bad password: expected 401, got %d", w.Code)
+	}
+	if w := e.do(http.MethodGet, "/auth/me", "", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("me without token: expected 401, got %d", w.Code)
+	}
+	w := e.do(http.MethodGet, "/auth/me", login["access_token"].(string), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("me: expected 200, got %d", w.Code)
+	}
+}
+
+func TestPhoneOTPFlow(t *testing.T) {
+	e := newTestEnv(t)
+	phone := "+14155550100"
+	if w := e.do(http.MethodPost, "/auth/phone/start", "", map[string]string{"phone": "nope"}); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid phone: expected 400, got %d", w.Code)
+	}
+	if w := e.do(http.MethodPost, "/auth/phone/start", "", map[string]string{"phone": phone}); w.Code != http.StatusOK {
+		t.Fatalf("start: expected 200, got %d", w.Code)
+	}
+	// The real code is only logged; swap in a known one.
+	code := "123456"
+	salt := os.Getenv("PHONE_HASH_SALT")
+	_ = e.repo.UpsertPhoneOTP(context.Background(), phone, sha256Hex(salt+strings.ToLower(code+"|"+phone)), time.Minute)
+
+	if w := e.do(http.MethodPost, "/auth/phone/verify", "", map[string]string{"phone": phone, "code": "000000"}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong code: expected 401, got %d", w.Code)
+	}
+	w := e.do(http.MethodPost, "/auth/phone/verify", "", map[string]string{"phone": phone, "code": code})
+	if w.Code != http.StatusOK {
+		t.Fatalf("verify: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if decode(t, w)["access_token"] == nil {
+		t.Fatal("expected access token")
+	}
+	// OTP is single use
+	if w := e.do(http.MethodPost, "/auth/phone/verify", "", map[string]string{"phone": phone, "code": code}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("replayed code: expected 401, got %d", w.Code)
+	}
+	// The new user's phone hash is matchable by contacts
+	m := e.do(http.MethodPost, "/contacts/match", "", map[string][]string{"hashes": {sha256Hex(phone)}})
+	if !bytes.Contains(m.Body.Bytes(), []byte(sha256Hex(phone))) {
+		t.Fatalf("expected contacts match, got %s", m.Body.String())
+	}
+}
+
+func TestPhoneOTP_LocksAfterMaxAttempts(t *testing.T) {
+	e := newTestEnv(t)
+	phone := "+14155550101"
+	_ = e.repo.UpsertPhoneOTP(context.Background(), phone, "hash", time.Minute)
+	for i := 0; i < 5; i++ {
+		e.do(http.MethodPost, "/auth/phone/verify", "", map[string]string{"phone": phone, "code": "111111"})
+	}
+	if w := e.do(http.MethodPost, "/auth/phone/verify", "", map[string]string{"phone": phone, "code": "111111"}); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 after max attempts, got %d", w.Code)
+	}
+}
+
+// startMagicLink requests a link and swaps in a known token, like the OTP
+// test does, since the real one is only logged.
+func (e *testEnv) startMagicLink(email, token string) string {
+	e.t.Helper()
+	w := e.do(http.MethodPost, "/auth/email/magic-link/start", "", map[string]string{"email": email})
+	if w.Code != http.StatusOK {
+		e.t.Fatalf("magic link start: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	nonce := decode(e.t, w)["nonce"].(string)
+	_ = e.repo.UpsertMagicLink(context.Background(), email, sha256Hex(token), sha256Hex(nonce), time.Minute)
+	return nonce
+}
+
+func TestMagicLink_SingleUseAndDeviceBound(t *testing.T) {
+	e := newTestEnv(t)
+	e.registerAndLogin("host@example.com")
+	nonce := e.startMagicLink("host@example.com", "tok-1")
+
+	if w := e.do(http.MethodPost, "/auth/email/magic-link/start", "", map[string]string{"email": "host@example.com"}); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("immediate resend: expected 429, got %d", w.Code)
+	}
+	if w := e.do(http.MethodPost, "/auth/email/magic-link/verify", "", map[string]string{"token": "tok-1", "nonce": "other-device"}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong nonce: expected 401, got %d", w.Code)
+	}
+	w := e.do(http.MethodPost, "/auth/email/magic-link/verify", "", map[string]string{"token": "tok-1", "nonce": nonce})
+	if w.Code != http.StatusOK {
+		t.Fatalf("verify: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if resp := decode(t, w); resp["access_token"] == nil || resp["refresh_token"] == nil {
+		t.Fatalf("expected standard token response, got %v", resp)
+	}
+	if w := e.do(http.MethodPost, "/auth/email/magic-link/verify", "", map[string]string{"token": "tok-1", "nonce": nonce}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("replayed link: expected 401, got %d", w.Code)
+	}
+}
+
+func TestMagicLink_NonceCookieAndAttemptLimit(t *testing.T) {
+	e := newTestEnv(t)
+	e.registerAndLogin("cookie@example.com")
+	nonce := e.startMagicLink("cookie@example.com", "tok-2")
+	for i := 0; i < magicLinkMaxAttempts; i++ {
+		e.do(http.MethodPost, "/auth/email/magic-link/verify", "", map[string]string{"token": "tok-2", "nonce": "guess"})
+	}
+	req := httptest.NewRequest(http.MethodPost, "/auth/email/magic-link/verify", strings.NewReader(`{"token":"tok-2"}`))
+	req.AddCookie(&http.Cookie{Name: magicLinkNonceCookie, Value: nonce})
+	w := httptest.NewRecorder()
+	e.h.ServeHTTP(w, req)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("after max attempts: expected 429, got %d", w.Code)
+	}
+
+	e.registerAndLogin("cookie2@example.com")
+	nonce = e.startMagicLink("cookie2@example.com", "tok-3")
+	req = httptest.NewRequest(http.MethodPost, "/auth/email/magic-link/verify", strings.NewReader(`{"token":"tok-3"}`))
+	req.AddCookie(&http.Cookie{Name: magicLinkNonceCookie, Value: nonce})
+	w = httptest.NewRecorder()
+	e.h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("verify with cookie nonce: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestMagicLink_SignupIsConfigurable(t *testing.T) {
+	e := newTestEnv(t)
+	t.Setenv("MAGIC_LINK_ALLOW_SIGNUP", "false")
+	e.do(http.MethodPost, "/auth/email/magic-link/start", "", map[string]string{"email": "new@example.com"})
+	if l, _ := e.repo.GetMagicLinkByEmail(context.Background(), "new@example.com"); l != nil {
+		t.Fatal("expected no link for unknown email when signup is disabled")
+	}
+	// unknown addresses are throttled like known ones, so a 429 reveals nothing
+	if w := e.do(http.MethodPost, "/auth/email/magic-link/start", "", map[string]string{"email": "New@example.com"}); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("resend to an unknown email: expected 429, got %d", w.Code)
+	}
+
+	t.Setenv("MAGIC_LINK_ALLOW_SIGNUP", "true")
+	nonce := e.startMagicLink("fresh@example.com", "tok-4")
+	w := e.do(http.MethodPost, "/auth/email/magic-link/verify", "", map[string]string{"token": "tok-4", "nonce": nonce})
+	if w.Code != http.StatusOK {
+		t.Fatalf("signup via link: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if u, _ := e.repo.GetUserByEmail(context.Background(), "fresh@example.com"); u == nil || !u.IsEmailVerified {
+		t.Fatalf("expected verified passwordless account, got %+v", u)
+	}
+}
+
+func TestAdminPromoteDemoteAndAudit(t *testing.T) {
+	e := newTestEnv(t)
+	user := e.registerAndLogin("user@example.com")
+	if w := e.do(http.MethodPost, "/auth/admin/promote", user["access_token"].(string), map[string]string{"email": "user@example.com"}); w.Code != http.StatusForbidden {
+		t.Fatalf("non-admin promote: expected 403, got %d", w.Code)
+	}
+
+	e.registerAndLogin("root@example.com")
+	_ = e.repo.PromoteAdminByEmail(context.Background(), "root@example.com")
+	w := e.do(http.MethodPost, "/auth/login", "", map[string]string{"email": "root@example.com", "password": "S3cure!Password"})
+	admin := decode(t, w)["access_token"].(string)
+
+	if w := e.do(http.MethodPost, "/auth/admin/promote", admin, map[string]string{"email": "user@example.com"}); w.Code != http.StatusNoContent {
+		t.Fatalf("promote: expected 204, got %d", w.Code)
+	}
+	u, _ := e.repo.GetUserByEmail(context.Background(), "user@example.com")
+	if !contains(u.Roles, "admin") {
+		t.Fatalf("expected admin role, got %v", u.Roles)
+	}
+	if w := e.do(http.MethodPost, "/auth/admin/demote", admin, map[string]string{"email": "user@example.com"}); w.Code != http.StatusNoContent {
+		t.Fatalf("demote: expected 204, got %d", w.Code)
+	}
+	w = e.do(http.MethodGet, "/auth/admin/audit?limit=10", admin, nil)
+	items, _ := decode(t, w)["items"].([]any)
+	if len(items) != 2 {
+		t.Fatalf("expected 2 audit rows, got %d", len(items))
+	}
+}
+
+func TestHostOnboarding(t *testing.T) {
+	e := newTestEnv(t)
+	tok := e.registerAndLogin("host@example.com")["access_token"].(string)
+	if w := e.do(http.MethodGet, "/host/onboarding", tok, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("before upsert: expected 404, got %d", w.Code)
+	}
+	if w := e.do(http.MethodPost, "/host/onboarding", tok, map[string]any{"serviceType": "boat"}); w.Code != http.StatusBadRequest {
+		t.Fatalf("bad serviceType: expected 400, got %d", w.Code)
+	}
+	if w := e.do(http.MethodPost, "/host/onboarding", tok, map[string]any{"serviceType": "valet", "progress": 40, "data": map[string]any{"lots": 2}}); w.Code != http.StatusNoContent {
+		t.Fatalf("upsert: expected 204, got %d", w.Code)
+	}
+	w := e.do(http.MethodGet, "/host/onboarding", tok, nil)
+	if w.Code != http.StatusOK || decode(t, w)["Progress"].(float64) != 40 {
+		t.Fatalf("get: unexpected %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestSessions_RevokeInvalidatesRefresh(t *testing.T) {
+	e := newTestEnv(t)
+	first := e.registerAndLogin("multi@example.com")
+	w := e.do(http.MethodPost, "/auth/login", "", map[string]string{"email": "multi@example.com", "password": "S3cure!Password"})
+	second := decode(t, w)
+	tok := second["access_token"].(string)
+
+	w = e.do(http.MethodGet, "/auth/sessions", tok, nil)
+	items, _ := decode(t, w)["items"].([]any)
+	if len(items) != 2 {
+		t.Fatalf("expected 2 sessions, got %d", len(items))
+	}
+
+	// refresh rotates: the old refresh token stops working
+	w = e.do(http.MethodPost, "/auth/refresh", "", map[string]string{"refresh_token": first["refresh_token"].(string)})
+	if w.Code != http.StatusOK {
+		t.Fatalf("refresh: expected 200, got %d", w.Code)
+	}
+	rotated := decode(t, w)["refresh_token"].(string)
+
+	if w := e.do(http.MethodDelete, "/auth/sessions/"+first["session_id"].(string), tok, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("revoke: expected 204, got %d", w.Code)
+	}
+	if w := e.do(http.MethodPost, "/auth/refresh", "", map[string]string{"refresh_token": rotated}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("refresh after revoke: expected 401, got %d", w.Code)
+	}
+	if w := e.do(http.MethodGet, "/auth/me", first["access_token"].(string), nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("access token of revoked session: expected 401, got %d", w.Code)
+	}
+
+	other := e.registerAndLogin("other@example.com")
+	if w := e.do(http.MethodDelete, "/auth/sessions/"+other["session_id"].(string), tok, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("revoking someone else's session: expected 404, got %d", w.Code)
+	}
+}
+
+// flakySessions fails lookups of one session id, as a database outage would.
+type flakySessions struct {
+	db.SessionRepo
+	failID string
+}
+
+func (f flakySessions) GetSession(ctx context.Context, id string) (*db.Session, error) {
+	if id == f.failID {
+		return nil, errors.New("connection reset")
+	}
+	return f.SessionRepo.GetSession(ctx, id)
+}
+
+func TestSessions_RevokeTellsMissingFromErrors(t *testing.T) {
+	e := newTestEnv(t)
+	tok := e.registerAndLogin("carol@example.com")["access_token"].(string)
+	const unknown = "00000000-0000-4000-8000-000000000000"
+	for _, id := range []string{"not-a-uuid", unknown} {
+		if w := e.do(http.MethodDelete, "/auth/sessions/"+id, tok, nil); w.Code != http.StatusNotFound {
+			t.Fatalf("revoke %s: expected 404, got %d", id, w.Code)
+		}
+	}
+
+	impl := NewServerImplWithRepository(e.repo)
+	impl.sessions = flakySessions{SessionRepo: e.repo, failID: unknown}
+	e.h = NewRouterWithServer(impl)
+	if w := e.do(http.MethodDelete, "/auth/sessions/"+unknown, tok, nil); w.Code != http.StatusInternalServerError {
+		t.Fatalf("lookup failure: expected 500, got %d", w.Code)
+	}
+}
+
+func TestSessions_ConcurrentRefreshIsSingleUse(t *testing.T) {
+	e := newTestEnv(t)
+	login := e.registerAndLogin("race@example.com")
+	body := map[string]string{"refresh_token": login["refresh_token"].(string)}
+
+	const n = 8
+	codes := make([]int, n)
+	var wg sync.WaitGroup
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i] = e.do(http.MethodPost, "/auth/refresh", "", body).Code
+		}()
+	}
+	wg.Wait()
+	ok := 0
+	for _, c := range codes {
+		if c == http.StatusOK {
+			ok++
+		} else if c != http.StatusUnauthorized {
+			t.Fatalf("unexpected status %d", c)
+		}
+	}
+	if ok != 1 {
+		t.Fatalf("expected exactly one refresh to succeed, got %d (%v)", ok, codes)
+	}
+}
+
+func TestAdminImpersonation(t *testing.T) {
+	e := newTestEnv(t)
+	target := e.registerAndLogin("customer@example.com")
+	targetUser, _ := e.repo.GetUserByEmail(context.Background(), "customer@example.com")
+
+	e.registerAndLogin("agent@example.com")
+	_ = e.repo.PromoteAdminByEmail(context.Background(), "agent@example.com")
+	w := e.do(http.MethodPost, "/auth/login", "", map[string]string{"email": "agent@example.com", "password": "S3cure!Password"})
+	admin := decode(t, w)["access_token"].(string)
+
+	req := map[string]any{"userId": targetUser.ID, "reason": "ticket 4411: discovery feed empty"}
+	if w := e.do(http.MethodPost, "/auth/admin/impersonate", admin, req); w.Code != http.StatusForbidden {
+		t.Fatalf("admin without support: expected 403, got %d", w.Code)
+	}
+	if w := e.do(http.MethodPost, "/auth/admin/promote", admin, map[string]string{"email": "agent@example.com", "role": "support"}); w.Code != http.StatusNoContent {
+		t.Fatalf("grant support: expected 204, got %d", w.Code)
+	}
+	w = e.do(http.MethodPost, "/auth/login", "", map[string]string{"email": "agent@example.com", "password": "S3cure!Password"})
+	admin = decode(t, w)["access_token"].(string)
+
+	if w := e.do(http.MethodPost, "/auth/admin/impersonate", admin, map[string]any{"userId": targetUser.ID}); w.Code != http.StatusBadRequest {
+		t.Fatalf("missing reason: expected 400, got %d", w.Code)
+	}
+	req["ttlSeconds"] = 3600
+	w = e.do(http.MethodPost, "/auth/admin/impersonate", admin, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("impersonate: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	grant := decode(t, w)
+	if grant["expires_in"].(float64) > impersonationMaxTTL.Seconds() || grant["refresh_token"] != nil {
+		t.Fatalf("expected short-lived non-refreshable token, got %v", grant)
+	}
+	tok := grant["access_token"].(string)
+
+	w = e.do(http.MethodGet, "/auth/me", tok, nil)
+	me := decode(t, w)
+	if w.Code != http.StatusOK || me["impersonatedBy"] == nil {
+		t.Fatalf("me while impersonating: unexpected %d %v", w.Code, me)
+	}
+	if w := e.do(http.MethodPost, "/auth/password", tok, map[string]string{"currentPassword": "S3cure!Password", "newPassword": "An0ther!Password"}); w.Code != http.StatusForbidden {
+		t.Fatalf("password change while impersonating: expected 403, got %d", w.Code)
+	}
+	if w := e.do(http.MethodDelete, "/auth/sessions/"+target["session_id"].(string), tok, nil); w.Code != http.StatusForbidden {
+		t.Fatalf("session revoke while impersonating: expected 403, got %d", w.Code)
+	}
+
+	w = e.do(http.MethodGet, "/auth/admin/audit", admin, nil)
+	if !bytes.Contains(w.Body.Bytes(), []byte(`"impersonate"`)) || !bytes.Contains(w.Body.Bytes(), []byte("ticket 4411")) {
+		t.Fatalf("expected impersonation audit row, got %s", w.Body.String())
+	}
+}
+
+func TestChangePassword_RevokesOtherSessions(t *testing.T) {
+	e := newTestEnv(t)
+	first := e.registerAndLogin("pw@example.com")
+	w := e.do(http.MethodPost, "/auth/login", "", map[string]string{"email": "pw@example.com", "password": "S3cure!Password"})
+	second := decode(t, w)
+	tok := second["access_token"].(string)
+
+	if w := e.do(http.MethodPost, "/auth/password", tok, map[string]string{"currentPassword": "wrong", "newPassword": "An0ther!Password"}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong current password: expected 401, got %d", w.Code)
+	}
+	if w := e.do(http.MethodPost, "/auth/password", tok, map[string]string{"currentPassword": "S3cure!Password", "newPassword": "An0ther!Password"}); w.Code != http.StatusNoContent {
+		t.Fatalf("change password: expected 204, got %d", w.Code)
+	}
+	if w := e.do(http.MethodGet, "/auth/me", first["access_token"].(string), nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("other session after password change: expected 401, got %d", w.Code)
+	}
+	if w := e.do(http.MethodGet, "/auth/me", tok, nil); w.Code != http.StatusOK {
+		t.Fatalf("current session after password change: expected 200, got %d", w.Code)
+	}
+	if w := e.do(http.MethodPost, "/auth/login", "", map[string]string{"email": "pw@example.com", "password": "An0ther!Password"}); w.Code != http.StatusOK {
+		t.Fatalf("login with new password: expected 200, got %d", w.Code)
+	}
+}
+
+func contains(xs []string, v string) bool {
+	for _, x := range xs {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func TestFriends_MutualGraphAndServiceCheck(t *testing.T) {
+	repo := db.NewMemStore()
+	impl := NewServerImplWithRepository(repo)
+	impl.services = servicetoken.New("svc")
+	e := &testEnv{t: t, repo: repo, h: NewRouterWithServer(impl)}
+	login := func(email, name string) (id, token string) {
+		e.do(http.MethodPost, "/auth/register", "", map[string]any{"email": email, "password": "S3cure!Password", "name": name})
+		got := decode(t, e.do(http.MethodPost, "/auth/login", "", map[string]string{"email": email, "password": "S3cure!Password"}))
+		return got["user"].(map[string]any)["id"].(string), got["access_token"].(string)
+	}
+	sam, samTok := login("sam@example.com", "Sam")
+	ada, adaTok := login("ada@example.com", "Ada")
+	bob, _ := login("bob@example.com", "Bob")
+
+	if got := decode(t, e.do(http.MethodPut, "/friends/"+ada, samTok, nil)); got["status"] != "requested" {
+		t.Fatalf("request: %v", got)
+	}
+	e.do(http.MethodPut, "/friends/"+bob, samTok, nil) // bob never adds sam back
+	items := decode(t, e.do(http.MethodGet, "/friends", adaTok, nil))["items"].([]any)
+	if len(items) != 1 || items[0].(map[string]any)["status"] != "incoming" || items[0].(map[string]any)["name"] != "Sam" {
+		t.Fatalf("ada's incoming requests: %v", items)
+	}
+	if got := decode(t, e.do(http.MethodPut, "/friends/"+sam, adaTok, nil)); got["status"] != "friends" {
+		t.Fatalf("accept: %v", got)
+	}
+	if w := e.do(http.MethodPut, "/friends/"+sam, samTok, nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("adding yourself: expected 400, got %d", w.Code)
+	}
+	if w := e.do(http.MethodPut, "/friends/nobody", samTok, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown user: expected 404, got %d", w.Code)
+	}
+
+	check := func(token string) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(map[string]any{"ids": []string{ada, bob, "nobody"}})
+		req := httptest.NewRequest(http.MethodPost, "/internal/users/"+sam+"/friends/check", bytes.NewReader(b))
+		req.Header.Set(servicetoken.Header, token)
+		w := httptest.NewRecorder()
+		e.h.ServeHTTP(w, req)
+		return w
+	}
+	if w := check(""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("check without a service token: expected 401, got %d", w.Code)
+	}
+	got := decode(t, check("svc"))
+	if got["name"] != "Sam" || fmt.Sprint(got["friendIds"]) != "["+ada+"]" {
+		t.Fatalf("only mutual friends count: %v", got)
+	}
+
+	if w := e.do(http.MethodDelete, "/friends/"+sam, adaTok, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("unfriend: %d", w.Code)
+	}
+	if got := decode(t, check("svc")); len(got["friendIds"].([]any)) != 0 {
+		t.Fatalf("unfriended: %v", got)
+	}
+}
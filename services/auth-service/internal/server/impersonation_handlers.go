@@ -0,0 +1,165 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"bytspot/shared/middleware"
+)
+
+const (
+	impersonationDefaultTTL = 10 * time.Minute
+	impersonationMaxTTL     = 15 * time.Minute
+	impersonationMinReason  = 10
+)
+
+// requireFullAccess is authenticate for sensitive actions (password change,
+// session revocation, account and admin changes): impersonation tokens are
+// rejected even though they are otherwise valid for the subject.
+func (s *ServerImpl) requireFullAccess(w http.ResponseWriter, r *http.Request) (*jwtCustomClaims, bool) {
+	claims, ok := s.authenticate(w, r)
+	if !ok {
+		return nil, false
+	}
+	if claims.Impersonating() {
+		middleware.ErrorHandler(w, http.StatusForbidden, "not allowed while impersonating", "IMPERSONATION_FORBIDDEN")
+		return nil, false
+	}
+	return claims, true
+}
+
+// impersonationRoles drops privileged roles (admin, support, and host, which
+// manages venues) so support staff see exactly what the user sees, never more.
+func impersonationRoles(roles []string) []string {
+	out := make([]string, 0, len(roles))
+	for _, r := range roles {
+		if r != "admin" && r != "support" && r != "host" {
+			out = append(out, r)
+		}
+	}
+	if len(out) == 0 {
+		out = append(out, "user")
+	}
+	return out
+}
+
+// POST /auth/admin/impersonate { userId, reason, ttlSeconds? } (admin + support)
+func (s *ServerImpl) PostAdminImpersonate(w http.ResponseWriter, r *http.Request) {
+	claims, ok := s.requireAdmin(w, r)
+	if !ok {
+		return
+	}
+	if !hasRole(claims, "support") {
+		middleware.ErrorHandler(w, http.StatusForbidden, "support permission required", "FORBIDDEN")
+		return
+	}
+	var req struct {
+		UserID     string `json:"userId"`
+		Reason     string `json:"reason"`
+		TTLSeconds int    `json:"ttlSeconds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserID == "" {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "invalid payload", "VALIDATION_ERROR")
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if len(req.Reason) < impersonationMinReason {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "reason required", "VALIDATION_ERROR")
+		return
+	}
+	ttl := impersonationDefaultTTL
+	if req.TTLSeconds > 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+	}
+	if ttl > impersonationMaxTTL {
+		ttl = impersonationMaxTTL
+	}
+	if req.UserID == claims.Sub {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "cannot impersonate yourself", "VALIDATION_ERROR")
+		return
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	target, err := s.users.GetUserByID(r.Context(), req.UserID)
+	if err != nil || target == nil {
+		middleware.ErrorHandler(w, http.StatusNotFound, "user not found", "NOT_FOUND")
+		return
+	}
+	for _, role := range target.Roles {
+		if role == "admin" {
+			middleware.ErrorHandler(w, http.StatusForbidden, "cannot impersonate an admin", "FORBIDDEN")
+			return
+		}
+	}
+	tokenID, err := newRefreshToken()
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "token error", "INTERNAL_ERROR")
+		return
+	}
+	token, exp, err := signImpersonationToken(target.ID, impersonationRoles(target.Roles), claims.Sub, tokenID, ttl)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "token error", "INTERNAL_ERROR")
+		return
+	}
+	// No audit row, no token: a grant that can't be audited is refused.
+	if err := s.audit.InsertImpersonationAudit(r.Context(), claims.Sub, target.ID, target.Email, req.Reason, tokenID, exp); err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "audit error", "INTERNAL_ERROR")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   int64(time.Until(exp).Seconds()),
+		"scope":        scopeImpersonation,
+		"user":         map[string]any{"id": target.ID, "email": target.Email},
+	})
+}
+
+// POST /auth/password { currentPassword, newPassword }
+// Revokes every other session of the user.
+func (s *ServerImpl) PostAuthPassword(w http.ResponseWriter, r *http.Request) {
+	claims, ok := s.requireFullAccess(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		CurrentPassword string `json:"currentPassword"`
+		NewPassword     string `json:"newPassword"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.NewPassword) < 8 {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "new password must be at least 8 characters", "VALIDATION_ERROR")
+		return
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	u, err := s.users.GetUserByID(r.Context(), claims.Sub)
+	if err != nil || u == nil {
+		middleware.ErrorHandler(w, http.StatusUnauthorized, "invalid token", "UNAUTHORIZED")
+		return
+	}
+	// Phone-first accounts have no password yet and may set one directly.
+	if u.PasswordHash != "" {
+		if ok, err := VerifyPassword(req.CurrentPassword, u.PasswordHash); err != nil || !ok {
+			middleware.ErrorHandler(w, http.StatusUnauthorized, "invalid credentials", "UNAUTHORIZED")
+			return
+		}
+	}
+	hash, err := HashPassword(req.NewPassword)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "hashing failed", "INTERNAL_ERROR")
+		return
+	}
+	if err := s.users.UpdatePasswordHash(r.Context(), u.ID, hash); err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	_ = s.sessions.RevokeOtherSessions(r.Context(), u.ID, claims.Sid)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignSessionToken_CarriesSid(t *testing.T) {
+	tok, _, err := signSessionToken("u1", []string{"user"}, "s1", time.Minute)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	claims, err := verifyToken(tok)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if claims.Sub != "u1" || claims.Sid != "s1" {
+		t.Fatalf("unexpected claims: %+v", claims)
+	}
+
+	plain, _, _ := signToken("u2", nil, time.Minute)
+	c2, err := verifyToken(plain)
+	if err != nil || c2.Sid != "" {
+		t.Fatalf("expected sessionless token, got %+v (%v)", c2, err)
+	}
+}
+
+func TestImpersonationRoles_DropPrivileged(t *testing.T) {
+	got := impersonationRoles([]string{"user", "host", "support", "admin"})
+	if len(got) != 1 || got[0] != "user" {
+		t.Fatalf("expected only user, got %v", got)
+	}
+	if got := impersonationRoles([]string{"host"}); len(got) != 1 || got[0] != "user" {
+		t.Fatalf("expected user fallback, got %v", got)
+	}
+}
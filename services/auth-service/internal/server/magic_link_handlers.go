@@ -0,0 +1,181 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"bytspot/services/auth-service/internal/db"
+	"bytspot/shared/middleware"
+)
+
+const (
+	magicLinkTTL         = 15 * time.Minute
+	magicLinkResendAfter = 30 * time.Second
+	magicLinkMaxAttempts = 5
+	magicLinkNonceCookie = "bytspot_ml_nonce"
+	magicLinkCookiePath  = "/auth/email/magic-link"
+)
+
+// magicLinkSignupAllowed reports whether verifying a link for an unknown
+// email creates the account (MAGIC_LINK_ALLOW_SIGNUP, default false).
+func magicLinkSignupAllowed() bool {
+	ok, _ := strconv.ParseBool(os.Getenv("MAGIC_LINK_ALLOW_SIGNUP"))
+	return ok
+}
+
+func magicLinkURL(token string) string {
+	base := os.Getenv("MAGIC_LINK_BASE_URL")
+	if base == "" {
+		base = "http://localhost:3000/auth/magic-link"
+	}
+	return base + "?token=" + url.QueryEscape(token)
+}
+
+// POST /auth/email/magic-link/start { email }
+// Responds the same whether or not a link was sent so the endpoint can't be
+// used to probe for accounts; resends are throttled per address, known or
+// not, for the same reason. The device binding nonce is set as a cookie for
+// browsers and returned in the body for native apps.
+func (s *ServerImpl) PostAuthMagicLinkStart(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "invalid JSON", "INVALID_JSON")
+		return
+	}
+	addr, err := mail.ParseAddress(req.Email)
+	if err != nil || addr.Address != strings.TrimSpace(req.Email) {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "invalid email", "VALIDATION_ERROR")
+		return
+	}
+	email := strings.ToLower(addr.Address)
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	// Same shape as OTP: one live link per email, resends throttled.
+	sent, err := s.magicLinks.ClaimMagicLinkSend(r.Context(), sha256Hex(email), magicLinkResendAfter)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	if !sent {
+		middleware.ErrorHandler(w, http.StatusTooManyRequests, "link already sent, try later", "RATE_LIMITED")
+		return
+	}
+	nonce, err := newRefreshToken()
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "token error", "INTERNAL_ERROR")
+		return
+	}
+	u, err := s.users.GetUserByEmail(r.Context(), email)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	if u != nil || magicLinkSignupAllowed() {
+		token, err := newRefreshToken()
+		if err != nil {
+			middleware.ErrorHandler(w, http.StatusInternalServerError, "token error", "INTERNAL_ERROR")
+			return
+		}
+		if err := s.magicLinks.UpsertMagicLink(r.Context(), email, sha256Hex(token), sha256Hex(nonce), magicLinkTTL); err != nil {
+			middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+			return
+		}
+		// Dev provider: log link (replace with transactional email)
+		log.Printf("Magic link for %s: %s", email, magicLinkURL(token))
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     magicLinkNonceCookie,
+		Value:    nonce,
+		Path:     magicLinkCookiePath,
+		MaxAge:   int(magicLinkTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"status": "sent", "ttlSec": int(magicLinkTTL.Seconds()), "nonce": nonce})
+}
+
+// POST /auth/email/magic-link/verify { token, nonce? }
+// nonce falls back to the cookie set by start, so the link only works on the
+// device that asked for it.
+func (s *ServerImpl) PostAuthMagicLinkVerify(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token string `json:"token"`
+		Nonce string `json:"nonce"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "invalid payload", "VALIDATION_ERROR")
+		return
+	}
+	if req.Nonce == "" {
+		if c, err := r.Cookie(magicLinkNonceCookie); err == nil {
+			req.Nonce = c.Value
+		}
+	}
+	if req.Nonce == "" {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "nonce required", "VALIDATION_ERROR")
+		return
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	link, err := s.magicLinks.GetMagicLinkByToken(r.Context(), sha256Hex(req.Token))
+	if err != nil || link == nil || time.Now().After(link.ExpiresAt) {
+		middleware.ErrorHandler(w, http.StatusUnauthorized, "link expired", "UNAUTHORIZED")
+		return
+	}
+	// Too many attempts -> lock until expiry
+	if link.Attempts >= magicLinkMaxAttempts {
+		middleware.ErrorHandler(w, http.StatusTooManyRequests, "too many attempts, try later", "RATE_LIMITED")
+		return
+	}
+	if sha256Hex(req.Nonce) != link.NonceHash {
+		_ = s.magicLinks.IncrementMagicLinkAttempts(r.Context(), link.ID)
+		middleware.ErrorHandler(w, http.StatusUnauthorized, "link was requested from another device", "UNAUTHORIZED")
+		return
+	}
+	if ok, err := s.magicLinks.ConsumeMagicLink(r.Context(), link.ID); err != nil || !ok {
+		middleware.ErrorHandler(w, http.StatusUnauthorized, "link already used", "UNAUTHORIZED")
+		return
+	}
+	u, err := s.users.GetUserByEmail(r.Context(), link.Email)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	if u == nil {
+		if !magicLinkSignupAllowed() {
+			middleware.ErrorHandler(w, http.StatusUnauthorized, "no account for this email", "UNAUTHORIZED")
+			return
+		}
+		// Passwordless account; the address is verified by the link itself.
+		u = &db.User{Email: link.Email, IsEmailVerified: true, Provider: "magic_link", Roles: []string{"user"}, TokenVersion: 1}
+		if err := s.users.CreateUser(r.Context(), u); err != nil {
+			middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+			return
+		}
+	}
+	_ = s.users.UpdateLastLogin(r.Context(), u.ID, time.Now())
+	resp, err := s.issueSession(r, u.ID, u.Roles)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "session error", "INTERNAL_ERROR")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: magicLinkNonceCookie, Path: magicLinkCookiePath, MaxAge: -1, HttpOnly: true})
+	resp["user"] = map[string]any{"id": u.ID, "email": u.Email, "name": u.Name, "roles": u.Roles}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"bytspot/shared/middleware"
+)
+
+// POST /host/onboarding { serviceType, data, progress }
+func (s *ServerImpl) PostHostOnboarding(w http.ResponseWriter, r *http.Request) {
+	claims, ok := s.requireFullAccess(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		ServiceType *string        `json:"serviceType"`
+		Data        map[string]any `json:"data"`
+		Progress    *int           `json:"progress"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "invalid JSON", "INVALID_JSON")
+		return
+	}
+	if req.ServiceType != nil && !allowedServiceTypes[*req.ServiceType] {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "invalid serviceType", "VALIDATION_ERROR")
+		return
+	}
+	p := 0
+	if req.Progress != nil {
+		p = *req.Progress
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	if err := s.onboarding.UpsertHostOnboarding(r.Context(), claims.Sub, req.ServiceType, req.Data, p); err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /host/onboarding
+func (s *ServerImpl) GetHostOnboarding(w http.ResponseWriter, r *http.Request) {
+	claims, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	h, err := s.onboarding.GetHostOnboarding(r.Context(), claims.Sub)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	if h == nil {
+		middleware.ErrorHandler(w, http.StatusNotFound, "onboarding not started", "NOT_FOUND")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h)
+}
@@ -54,11 +54,11 @@ func (s *ServerImpl) PostAuthPhoneStart(w http.ResponseWriter, r *http.Request)
 	salt := os.Getenv("PHONE_HASH_SALT")
 	codeHash := sha256Hex(salt + strings.ToLower(code+"|"+req.Phone))
 	ttl := 5 * time.Minute
-	if s.store == nil || s.store.Pool == nil {
+	if !s.ready() {
 		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
 		return
 	}
-	if err := s.store.UpsertPhoneOTP(r.Context(), req.Phone, codeHash, ttl); err != nil {
+	if err := s.otps.UpsertPhoneOTP(r.Context(), req.Phone, codeHash, ttl); err != nil {
 		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
 		return
 	}
@@ -77,11 +77,11 @@ func (s *ServerImpl) PostAuthPhoneVerify(w http.ResponseWriter, r *http.Request)
 		middleware.ErrorHandler(w, http.StatusBadRequest, "invalid payload", "VALIDATION_ERROR")
 		return
 	}
-	if s.store == nil || s.store.Pool == nil {
+	if !s.ready() {
 		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
 		return
 	}
-	otp, err := s.store.GetPhoneOTP(r.Context(), req.Phone)
+	otp, err := s.otps.GetPhoneOTP(r.Context(), req.Phone)
 	if err != nil || otp == nil || time.Now().After(otp.ExpiresAt) {
 		middleware.ErrorHandler(w, http.StatusUnauthorized, "otp expired", "UNAUTHORIZED")
 		return
@@ -96,30 +96,34 @@ func (s *ServerImpl) PostAuthPhoneVerify(w http.ResponseWriter, r *http.Request)
 	salt := os.Getenv("PHONE_HASH_SALT")
 	expected := sha256Hex(salt + strings.ToLower(req.Code+"|"+req.Phone))
 	if expected != otp.CodeHash {
-		_ = s.store.IncrementOTPAttempts(r.Context(), otp.ID)
+		_ = s.otps.IncrementOTPAttempts(r.Context(), otp.ID)
 		middleware.ErrorHandler(w, http.StatusUnauthorized, "invalid code", "UNAUTHORIZED")
 		return
 	}
-	_ = s.store.DeletePhoneOTP(r.Context(), otp.ID)
+	_ = s.otps.DeletePhoneOTP(r.Context(), otp.ID)
 	// Create or fetch user by phone
-	user, err := s.store.GetUserByPhone(r.Context(), req.Phone)
+	user, err := s.users.GetUserByPhone(r.Context(), req.Phone)
 	if err != nil {
 		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
 		return
 	}
 	if user == nil {
-		user, err = s.store.CreateUserPhoneOnly(r.Context(), req.Phone)
+		user, err = s.users.CreateUserPhoneOnly(r.Context(), req.Phone)
 		if err != nil {
 			middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
 			return
 		}
 		// set phone_hash for contacts match (unsalted hash of normalized phone)
 		ph := sha256Hex(strings.ToLower(req.Phone))
-		_, _ = s.store.Pool.Exec(r.Context(), `UPDATE users SET phone_hash=$1 WHERE id=$2`, ph, user.ID)
+		_ = s.users.SetPhoneHash(r.Context(), user.ID, ph)
 	}
-	// Issue token
-	token, exp, _ := signToken(user.ID, append(user.Roles, "user"), 15*time.Minute)
-	resp := map[string]any{"access_token": token, "token_type": "Bearer", "expires_in": exp - time.Now().Unix(), "user": map[string]any{"id": user.ID, "phone": req.Phone}}
+	// Issue token bound to a new device session
+	resp, err := s.issueSession(r, user.ID, append(user.Roles, "user"))
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "session error", "INTERNAL_ERROR")
+		return
+	}
+	resp["user"] = map[string]any{"id": user.ID, "phone": req.Phone}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
@@ -5,32 +5,58 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
-	"strconv"
-	"strings"
+	"os"
 	"time"
 
 	"bytspot/services/auth-service/internal/api"
 	"bytspot/services/auth-service/internal/db"
 	"bytspot/shared/middleware"
+	"bytspot/shared/servicetoken"
 
 	"github.com/go-chi/chi/v5"
 )
 
 var allowedServiceTypes = map[string]bool{"venue": true, "parking": true, "valet": true}
 
-type ServerImpl struct{ store *db.Store }
+type ServerImpl struct {
+	users      db.UserRepo
+	otps       db.OTPRepo
+	magicLinks db.MagicLinkRepo
+	audit      db.AuditRepo
+	onboarding db.OnboardingRepo
+	sessions   db.SessionRepo
+	friends    db.FriendRepo
+
+	// services authenticates other services on /internal routes
+	// (X-Service-Token from AUTH_SERVICE_TOKENS); unset, they reject everything.
+	services *servicetoken.Verifier
+}
 
-// ServerImpl exposes store for dev tools
-func (s *ServerImpl) Store() *db.Store { return s.store }
+// Users exposes the user repo for dev tools
+func (s *ServerImpl) Users() db.UserRepo { return s.users }
 
+// NewServerImpl connects to Postgres via DATABASE_URL. Set AUTH_STORE=memory
+// to run against the in-memory store instead (local dev only; data is lost
+// on restart).
 func NewServerImpl(ctx context.Context) (*ServerImpl, error) {
+	if os.Getenv("AUTH_STORE") == "memory" {
+		return NewServerImplWithRepository(db.NewMemStore()), nil
+	}
 	store, err := db.New(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return &ServerImpl{store: store}, nil
+	return NewServerImplWithRepository(store), nil
+}
+
+func NewServerImplWithRepository(repo db.Repository) *ServerImpl {
+	return &ServerImpl{users: repo, otps: repo, magicLinks: repo, audit: repo, onboarding: repo, sessions: repo, friends: repo,
+		services: servicetoken.FromEnv("AUTH_SERVICE_TOKENS")}
 }
 
+// ready reports whether repositories are wired; handlers answer 500 otherwise.
+func (s *ServerImpl) ready() bool { return s.users != nil }
+
 // Health
 func (s *ServerImpl) GetHealthz(w http.ResponseWriter, r *http.Request) {
 	middleware.HealthzHandler("auth-service", "0.1.0")(w, r)
@@ -56,6 +82,10 @@ func (s *ServerImpl) PostAuthRegister(w http.ResponseWriter, r *http.Request) {
 		middleware.ErrorHandler(w, http.StatusBadRequest, "email and password required", "VALIDATION_ERROR")
 		return
 	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
 	// Hash password and insert user in DB
 	hash, err := HashPassword(req.Password)
 	if err != nil {
@@ -63,7 +93,7 @@ func (s *ServerImpl) PostAuthRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	u := &db.User{Email: req.Email, PasswordHash: hash, Name: req.Name, Provider: "local", Roles: []string{"user"}, TokenVersion: 1}
-	if err := s.store.CreateUser(r.Context(), u); err != nil {
+	if err := s.users.CreateUser(r.Context(), u); err != nil {
 		if errors.Is(err, db.ErrDuplicateEmail) {
 			middleware.ErrorHandler(w, http.StatusConflict, "email already exists", "EMAIL_EXISTS")
 			return
@@ -83,8 +113,12 @@ func (s *ServerImpl) PostAuthLogin(w http.ResponseWriter, r *http.Request) {
 		middleware.ErrorHandler(w, http.StatusBadRequest, "invalid JSON", "INVALID_JSON")
 		return
 	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
 	// Verify against DB
-	u, err := s.store.GetUserByEmail(r.Context(), req.Email)
+	u, err := s.users.GetUserByEmail(r.Context(), req.Email)
 	if err != nil {
 		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
 		return
@@ -98,244 +132,93 @@ func (s *ServerImpl) PostAuthLogin(w http.ResponseWriter, r *http.Request) {
 		middleware.ErrorHandler(w, http.StatusUnauthorized, "invalid credentials", "UNAUTHORIZED")
 		return
 	}
-	_ = s.store.UpdateLastLogin(r.Context(), u.ID, time.Now())
-	// Issue JWT with subject=user id + roles
-	token, exp, _ := signToken(u.ID, u.Roles, 15*time.Minute)
-	resp := map[string]any{"access_token": token, "token_type": "Bearer", "expires_in": exp - time.Now().Unix(), "user": map[string]any{"id": u.ID, "email": u.Email, "name": u.Name, "roles": u.Roles}}
+	_ = s.users.UpdateLastLogin(r.Context(), u.ID, time.Now())
+	// Issue JWT with subject=user id + roles, bound to a new device session
+	resp, err := s.issueSession(r, u.ID, u.Roles)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "session error", "INTERNAL_ERROR")
+		return
+	}
+	resp["user"] = map[string]any{"id": u.ID, "email": u.Email, "name": u.Name, "roles": u.Roles}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
 
 func (s *ServerImpl) GetAuthMe(w http.ResponseWriter, r *http.Request) {
-	authz := r.Header.Get("Authorization")
-	if !strings.HasPrefix(authz, "Bearer ") {
-		middleware.ErrorHandler(w, http.StatusUnauthorized, "missing token", "UNAUTHORIZED")
-		return
-	}
-	tok := strings.TrimPrefix(authz, "Bearer ")
-	claims, err := verifyToken(tok)
-	if err != nil {
-		middleware.ErrorHandler(w, http.StatusUnauthorized, "invalid token", "UNAUTHORIZED")
+	claims, ok := s.authenticate(w, r)
+	if !ok {
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]any{"user": map[string]any{"id": claims.Sub, "email": claims.Sub}})
+	resp := map[string]any{"user": map[string]any{"id": claims.Sub, "email": claims.Sub}}
+	if claims.Impersonating() {
+		resp["impersonatedBy"] = claims.Act.Sub
+	}
+	json.NewEncoder(w).Encode(resp)
 }
 
 func NewRouter() http.Handler {
-	r := chi.NewRouter()
-	for _, m := range middleware.StandardMiddleware() {
-		r.Use(m)
-	}
-
 	// Initialize server with DB store
 	impl, err := NewServerImpl(context.Background())
 	if err != nil {
 		// If store fails, we still return handler but endpoints using store will 500
 		impl = &ServerImpl{}
 	}
+	return NewRouterWithServer(impl)
+}
+
+func NewRouterWithServer(impl *ServerImpl) http.Handler {
+	r := chi.NewRouter()
+	for _, m := range middleware.StandardMiddleware() {
+		r.Use(m)
+	}
 
 	// Register OpenAPI-driven routes with live impl
 	h := api.HandlerFromMux(impl, r)
 
-	// Non-spec admin management route (secured by admin role)
-	r.Post("/auth/admin/promote", func(w http.ResponseWriter, r *http.Request) {
-		// Require admin JWT
-		authz := r.Header.Get("Authorization")
-		if !strings.HasPrefix(authz, "Bearer ") {
-			middleware.ErrorHandler(w, http.StatusUnauthorized, "missing token", "UNAUTHORIZED")
-			return
-		}
-		claims, err := verifyToken(strings.TrimPrefix(authz, "Bearer "))
-		if err != nil {
-			middleware.ErrorHandler(w, http.StatusUnauthorized, "invalid token", "UNAUTHORIZED")
-			return
-		}
-		isAdmin := false
-		for _, role := range claims.Roles {
-			if role == "admin" {
-				isAdmin = true
-				break
-			}
-		}
-		if !isAdmin {
-			middleware.ErrorHandler(w, http.StatusForbidden, "admin role required", "FORBIDDEN")
-			return
-		}
-		var req struct {
-			Email string `json:"email"`
-		}
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
-			middleware.ErrorHandler(w, http.StatusBadRequest, "invalid JSON", "INVALID_JSON")
-			return
-		}
-		if impl.store == nil || impl.store.Pool == nil {
-			middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
-			return
-		}
-		if err := impl.store.PromoteAdminByEmail(r.Context(), req.Email); err != nil {
-			middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
-			return
-		}
-		_ = impl.store.InsertAdminAudit(r.Context(), claims.Sub, req.Email, "promote", "")
-		w.WriteHeader(http.StatusNoContent)
-	})
-
-	// Non-spec admin demote route (secured by admin role)
-	r.Post("/auth/admin/demote", func(w http.ResponseWriter, r *http.Request) {
-		authz := r.Header.Get("Authorization")
-		if !strings.HasPrefix(authz, "Bearer ") {
-			middleware.ErrorHandler(w, http.StatusUnauthorized, "missing token", "UNAUTHORIZED")
-			return
-		}
-		claims, err := verifyToken(strings.TrimPrefix(authz, "Bearer "))
-		if err != nil {
-			middleware.ErrorHandler(w, http.StatusUnauthorized, "invalid token", "UNAUTHORIZED")
-			return
-		}
-		isAdmin := false
-		for _, role := range claims.Roles {
-			if role == "admin" {
-				isAdmin = true
-				break
-			}
-		}
-		if !isAdmin {
-			middleware.ErrorHandler(w, http.StatusForbidden, "admin role required", "FORBIDDEN")
-			return
-		}
-		var req struct {
-			Email string `json:"email"`
-		}
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
-			middleware.ErrorHandler(w, http.StatusBadRequest, "invalid JSON", "INVALID_JSON")
-			return
-		}
-		if impl.store == nil || impl.store.Pool == nil {
-			middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
-			return
-		}
-		if err := impl.store.DemoteAdminByEmail(r.Context(), req.Email); err != nil {
-			middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
-			return
-		}
-		_ = impl.store.InsertAdminAudit(r.Context(), claims.Sub, req.Email, "demote", "")
-		w.WriteHeader(http.StatusNoContent)
-	})
-
-	// Admin audit read (admin-only)
-	r.Get("/auth/admin/audit", func(w http.ResponseWriter, r *http.Request) {
-		authz := r.Header.Get("Authorization")
-		if !strings.HasPrefix(authz, "Bearer ") {
-			middleware.ErrorHandler(w, http.StatusUnauthorized, "missing token", "UNAUTHORIZED")
-			return
-		}
-		claims, err := verifyToken(strings.TrimPrefix(authz, "Bearer "))
-		if err != nil {
-			middleware.ErrorHandler(w, http.StatusUnauthorized, "invalid token", "UNAUTHORIZED")
-			return
-		}
-		isAdmin := false
-		for _, role := range claims.Roles {
-			if role == "admin" {
-				isAdmin = true
-				break
-			}
-		}
-		if !isAdmin {
-			middleware.ErrorHandler(w, http.StatusForbidden, "admin role required", "FORBIDDEN")
-			return
-		}
-		limit := 50
-		if v := r.URL.Query().Get("limit"); v != "" {
-			if n, e := strconv.Atoi(v); e == nil {
-				limit = n
-			}
-		}
-		if impl.store == nil || impl.store.Pool == nil {
-			middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
-			return
-		}
-		items, err := impl.store.ListAdminAudit(r.Context(), limit)
-		if err != nil {
-			middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
-			return
-		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]any{"items": items})
+	// Non-spec admin management routes (secured by admin role)
+	r.Post("/auth/admin/promote", impl.PostAdminPromote)
+	r.Post("/auth/admin/demote", impl.PostAdminDemote)
+	r.Get("/auth/admin/audit", impl.GetAdminAudit)
+	r.Post("/auth/admin/impersonate", impl.PostAdminImpersonate)
+
+	// Host onboarding (user)
+	r.Post("/host/onboarding", impl.PostHostOnboarding)
+	r.Get("/host/onboarding", impl.GetHostOnboarding)
+
+	// Device sessions + refresh tokens
+	r.Post("/auth/refresh", impl.PostAuthRefresh)
+	r.Post("/auth/password", impl.PostAuthPassword)
+	r.Get("/auth/sessions", impl.GetAuthSessions)
+	r.Delete("/auth/sessions/{id}", func(w http.ResponseWriter, r *http.Request) {
+		impl.DeleteAuthSessionsId(w, r, chi.URLParam(r, "id"))
 	})
-
-	// Host onboarding upsert (user)
-	r.Post("/host/onboarding", func(w http.ResponseWriter, r *http.Request) {
-		authz := r.Header.Get("Authorization")
-		if !strings.HasPrefix(authz, "Bearer ") {
-			middleware.ErrorHandler(w, http.StatusUnauthorized, "missing token", "UNAUTHORIZED")
-			return
-		}
-		claims, err := verifyToken(strings.TrimPrefix(authz, "Bearer "))
-		if err != nil {
-			middleware.ErrorHandler(w, http.StatusUnauthorized, "invalid token", "UNAUTHORIZED")
-			return
-		}
-		var req struct {
-			ServiceType *string        `json:"serviceType"`
-			Data        map[string]any `json:"data"`
-			Progress    *int           `json:"progress"`
-		}
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			middleware.ErrorHandler(w, http.StatusBadRequest, "invalid JSON", "INVALID_JSON")
-			return
-		}
-		if req.ServiceType != nil && !allowedServiceTypes[*req.ServiceType] {
-			middleware.ErrorHandler(w, http.StatusBadRequest, "invalid serviceType", "VALIDATION_ERROR")
-			return
-		}
-		p := 0
-		if req.Progress != nil {
-			p = *req.Progress
-		}
-		if impl.store == nil || impl.store.Pool == nil {
-			middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
-			return
-		}
-		if err := impl.store.UpsertHostOnboarding(r.Context(), claims.Sub, req.ServiceType, req.Data, p); err != nil {
-			middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
-			return
-		}
-		w.WriteHeader(http.StatusNoContent)
-	})
-
-	// Host onboarding get (user)
-	r.Get("/host/onboarding", func(w http.ResponseWriter, r *http.Request) {
-		authz := r.Header.Get("Authorization")
-		if !strings.HasPrefix(authz, "Bearer ") {
-			middleware.ErrorHandler(w, http.StatusUnauthorized, "missing token", "UNAUTHORIZED")
-			return
-		}
-		claims, err := verifyToken(strings.TrimPrefix(authz, "Bearer "))
-		if err != nil {
-			middleware.ErrorHandler(w, http.StatusUnauthorized, "invalid token", "UNAUTHORIZED")
-			return
-		}
-		if impl.store == nil || impl.store.Pool == nil {
-			middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
-			return
-		}
-		h, err := impl.store.GetHostOnboarding(r.Context(), claims.Sub)
-		if err != nil {
-			middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
-			return
-		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(h)
+	r.Get("/auth/admin/users/{id}/sessions", func(w http.ResponseWriter, r *http.Request) {
+		impl.GetAdminUserSessions(w, r, chi.URLParam(r, "id"))
 	})
 
 	// Phone-first auth
 	r.Post("/auth/phone/start", impl.PostAuthPhoneStart)
 	r.Post("/auth/phone/verify", impl.PostAuthPhoneVerify)
 
+	// Passwordless email
+	r.Post("/auth/email/magic-link/start", impl.PostAuthMagicLinkStart)
+	r.Post("/auth/email/magic-link/verify", impl.PostAuthMagicLinkVerify)
+
 	// Contacts match (privacy-first)
 	r.Post("/contacts/match", impl.PostContactsMatch)
 
+	// Friend graph; /internal is for other services
+	r.Get("/friends", impl.GetFriends)
+	r.Put("/friends/{id}", func(w http.ResponseWriter, r *http.Request) {
+		impl.PutFriendsId(w, r, chi.URLParam(r, "id"))
+	})
+	r.Delete("/friends/{id}", func(w http.ResponseWriter, r *http.Request) {
+		impl.DeleteFriendsId(w, r, chi.URLParam(r, "id"))
+	})
+	r.Post("/internal/users/{id}/friends/check", func(w http.ResponseWriter, r *http.Request) {
+		impl.PostInternalFriendsCheck(w, r, chi.URLParam(r, "id"))
+	})
+
 	return h
 }
@@ -0,0 +1,248 @@
+package server
+
+import (
+	crand "crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"bytspot/services/auth-service/internal/db"
+	"bytspot/shared/middleware"
+)
+
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+func newRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// optionalHeader returns a trimmed, length-capped header value or nil when absent.
+func optionalHeader(r *http.Request, name string, max int) *string {
+	v := strings.TrimSpace(r.Header.Get(name))
+	if v == "" {
+		return nil
+	}
+	if len(v) > max {
+		v = v[:max]
+	}
+	return &v
+}
+
+func clientIP(r *http.Request) *string {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	if ip == "" {
+		return nil
+	}
+	return &ip
+}
+
+// issueSession records a new login session for the user and returns the
+// standard token response (access + refresh token). Device details come from
+// the X-Device-Name / X-Device-Platform headers sent by the apps.
+func (s *ServerImpl) issueSession(r *http.Request, userID string, roles []string) (map[string]any, error) {
+	sess := &db.Session{
+		UserID:     userID,
+		DeviceName: optionalHeader(r, "X-Device-Name", 128),
+		Platform:   optionalHeader(r, "X-Device-Platform", 32),
+		IP:         clientIP(r),
+		UserAgent:  optionalHeader(r, "User-Agent", 512),
+	}
+	if err := s.sessions.CreateSession(r.Context(), sess); err != nil {
+		return nil, err
+	}
+	return s.mintTokens(r, userID, roles, sess.ID)
+}
+
+func (s *ServerImpl) mintTokens(r *http.Request, userID string, roles []string, sessionID string) (map[string]any, error) {
+	refresh, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sessions.InsertRefreshToken(r.Context(), sessionID, sha256Hex(refresh), time.Now().Add(refreshTokenTTL)); err != nil {
+		return nil, err
+	}
+	token, exp, err := signSessionToken(userID, roles, sessionID, accessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"access_token":  token,
+		"token_type":    "Bearer",
+		"expires_in":    exp - time.Now().Unix(),
+		"refresh_token": refresh,
+		"session_id":    sessionID,
+	}, nil
+}
+
+// authenticate verifies the bearer token and, for session-bound tokens,
+// rejects revoked sessions and bumps last_seen_at. It writes the error
+// response itself and returns ok=false on failure.
+func (s *ServerImpl) authenticate(w http.ResponseWriter, r *http.Request) (*jwtCustomClaims, bool) {
+	authz := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authz, "Bearer ") {
+		middleware.ErrorHandler(w, http.StatusUnauthorized, "missing token", "UNAUTHORIZED")
+		return nil, false
+	}
+	claims, err := verifyToken(strings.TrimPrefix(authz, "Bearer "))
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusUnauthorized, "invalid token", "UNAUTHORIZED")
+		return nil, false
+	}
+	if claims.Sid != "" && s.ready() {
+		sess, err := s.sessions.GetSession(r.Context(), claims.Sid)
+		if err != nil {
+			middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+			return nil, false
+		}
+		if sess == nil || sess.RevokedAt != nil {
+			middleware.ErrorHandler(w, http.StatusUnauthorized, "session revoked", "UNAUTHORIZED")
+			return nil, false
+		}
+		_ = s.sessions.TouchSession(r.Context(), sess.ID, time.Now())
+	}
+	if claims.Impersonating() {
+		log.Printf("impersonation: actor=%s subject=%s jti=%s %s %s", claims.Act.Sub, claims.Sub, claims.ID, r.Method, r.URL.Path)
+	}
+	return claims, true
+}
+
+func hasRole(claims *jwtCustomClaims, role string) bool {
+	for _, r := range claims.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// POST /auth/refresh { refresh_token } -> rotates the refresh token
+func (s *ServerImpl) PostAuthRefresh(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "invalid payload", "VALIDATION_ERROR")
+		return
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	rt, err := s.sessions.GetRefreshToken(r.Context(), sha256Hex(req.RefreshToken))
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	if rt == nil || rt.SessionRevokedAt != nil || time.Now().After(rt.ExpiresAt) {
+		middleware.ErrorHandler(w, http.StatusUnauthorized, "invalid refresh token", "UNAUTHORIZED")
+		return
+	}
+	u, err := s.users.GetUserByID(r.Context(), rt.UserID)
+	if err != nil || u == nil {
+		middleware.ErrorHandler(w, http.StatusUnauthorized, "invalid refresh token", "UNAUTHORIZED")
+		return
+	}
+	// Rotate before minting: only the request that marks the token used gets
+	// new tokens, so two concurrent refreshes can't both succeed.
+	rotated := false
+	if rt.RevokedAt == nil {
+		if rotated, err = s.sessions.RevokeRefreshToken(r.Context(), rt.ID); err != nil {
+			middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+			return
+		}
+	}
+	if !rotated {
+		// A rotated token was presented again: assume it leaked and kill the session.
+		_ = s.sessions.RevokeSession(r.Context(), rt.SessionID)
+		middleware.ErrorHandler(w, http.StatusUnauthorized, "invalid refresh token", "UNAUTHORIZED")
+		return
+	}
+	resp, err := s.mintTokens(r, u.ID, u.Roles, rt.SessionID)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "token error", "INTERNAL_ERROR")
+		return
+	}
+	_ = s.sessions.TouchSession(r.Context(), rt.SessionID, time.Now())
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GET /auth/sessions -> the caller's active sessions
+func (s *ServerImpl) GetAuthSessions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	s.writeSessions(w, r, claims.Sub, claims.Sid)
+}
+
+// DELETE /auth/sessions/{id} -> revoke one of the caller's sessions
+func (s *ServerImpl) DeleteAuthSessionsId(w http.ResponseWriter, r *http.Request, id string) {
+	claims, ok := s.requireFullAccess(w, r)
+	if !ok {
+		return
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	sess, err := s.sessions.GetSession(r.Context(), id)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	// Don't leak other users' session ids: treat them as missing
+	if sess == nil || sess.UserID != claims.Sub {
+		middleware.ErrorHandler(w, http.StatusNotFound, "session not found", "NOT_FOUND")
+		return
+	}
+	if err := s.sessions.RevokeSession(r.Context(), id); err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /auth/admin/users/{id}/sessions -> support view of a user's sessions (admin-only)
+func (s *ServerImpl) GetAdminUserSessions(w http.ResponseWriter, r *http.Request, userID string) {
+	if _, ok := s.requireAdmin(w, r); !ok {
+		return
+	}
+	s.writeSessions(w, r, userID, "")
+}
+
+func (s *ServerImpl) writeSessions(w http.ResponseWriter, r *http.Request, userID, currentSid string) {
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	items, err := s.sessions.ListActiveSessions(r.Context(), userID)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	type sessionView struct {
+		db.Session
+		Current bool `json:"current"`
+	}
+	out := make([]sessionView, 0, len(items))
+	for _, sess := range items {
+		out = append(out, sessionView{Session: sess, Current: currentSid != "" && sess.ID == currentSid})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"items": out})
+}
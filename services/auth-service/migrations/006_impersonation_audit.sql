@@ -0,0 +1,20 @@
+-- +goose Up
+-- Admin audit now also records impersonation grants, whose target may be a
+-- phone-only user without an email.
+ALTER TABLE admin_audit DROP CONSTRAINT IF EXISTS admin_audit_action_check;
+ALTER TABLE admin_audit ADD CONSTRAINT admin_audit_action_check CHECK (action IN ('promote','demote','impersonate'));
+ALTER TABLE admin_audit ALTER COLUMN target_email DROP NOT NULL;
+ALTER TABLE admin_audit ADD COLUMN IF NOT EXISTS target_user_id UUID;
+ALTER TABLE admin_audit ADD COLUMN IF NOT EXISTS token_id TEXT;
+ALTER TABLE admin_audit ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_admin_audit_target_user_id ON admin_audit(target_user_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_admin_audit_target_user_id;
+ALTER TABLE admin_audit DROP COLUMN IF EXISTS expires_at;
+ALTER TABLE admin_audit DROP COLUMN IF EXISTS token_id;
+ALTER TABLE admin_audit DROP COLUMN IF EXISTS target_user_id;
+DELETE FROM admin_audit WHERE action = 'impersonate';
+ALTER TABLE admin_audit ALTER COLUMN target_email SET NOT NULL;
+ALTER TABLE admin_audit DROP CONSTRAINT IF EXISTS admin_audit_action_check;
+ALTER TABLE admin_audit ADD CONSTRAINT admin_audit_action_check CHECK (action IN ('promote','demote'));
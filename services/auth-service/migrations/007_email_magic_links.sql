@@ -0,0 +1,21 @@
+-- +goose Up
+-- Passwordless email login. Only hashes are stored: token_hash identifies the
+-- link, nonce_hash binds it to the device that requested it.
+CREATE TABLE IF NOT EXISTS email_magic_links (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    email TEXT NOT NULL,
+    token_hash TEXT NOT NULL,
+    nonce_hash TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_email_magic_links_email ON email_magic_links (lower(email));
+CREATE UNIQUE INDEX IF NOT EXISTS uq_email_magic_links_token_hash ON email_magic_links (token_hash);
+CREATE INDEX IF NOT EXISTS idx_email_magic_links_expires_at ON email_magic_links (expires_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_email_magic_links_expires_at;
+DROP INDEX IF EXISTS uq_email_magic_links_token_hash;
+DROP INDEX IF EXISTS uq_email_magic_links_email;
+DROP TABLE IF EXISTS email_magic_links;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Friend graph. A row means user_id added friend_id; two users are friends
+-- once each has added the other, so one row alone is a pending request.
+CREATE TABLE IF NOT EXISTS friend_links (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    friend_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, friend_id),
+    CHECK (user_id <> friend_id)
+);
+CREATE INDEX IF NOT EXISTS idx_friend_links_friend_id ON friend_links (friend_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_friend_links_friend_id;
+DROP TABLE IF EXISTS friend_links;
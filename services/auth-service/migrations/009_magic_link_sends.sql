@@ -0,0 +1,13 @@
+-- +goose Up
+-- Magic link send throttle, kept for every address asked about (account or
+-- not) so the throttle can't reveal which emails have accounts. Only the
+-- SHA-256 of the lowercased address is stored.
+CREATE TABLE IF NOT EXISTS email_magic_link_sends (
+    email_hash TEXT PRIMARY KEY,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_email_magic_link_sends_sent_at ON email_magic_link_sends (sent_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_email_magic_link_sends_sent_at;
+DROP TABLE IF EXISTS email_magic_link_sends;
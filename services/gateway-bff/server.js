@@ -133,6 +133,11 @@ const AUTH_SERVICE_URL = process.env.AUTH_SERVICE_URL || 'http://localhost:8090'
 const VENUE_SERVICE_URL = process.env.VENUE_SERVICE_URL || 'http://localhost:8092';
 const PARKING_SERVICE_URL = process.env.PARKING_SERVICE_URL || 'http://localhost:8094';
 const VALET_SERVICE_URL = process.env.VALET_SERVICE_URL || 'http://localhost:8096';
+const NOTIFY_SERVICE_URL = process.env.NOTIFY_SERVICE_URL || 'http://localhost:8097';
+// X-Service-Token for venue-service's internal catalog reads (one of its CATALOG_SERVICE_TOKENS)
+const VENUE_SERVICE_TOKEN = process.env.VENUE_SERVICE_TOKEN || '';
+// X-Service-Token for notification-service's /events (one of its NOTIFY_SERVICE_TOKENS)
+const NOTIFY_SERVICE_TOKEN = process.env.NOTIFY_SERVICE_TOKEN || '';
 const JWT_SECRET = process.env.JWT_SECRET || 'dev_secret_change_me';
 const ADMIN_REQUIRE_AUTH = process.env.ADMIN_REQUIRE_AUTH !== 'false'; // default true
 
@@ -168,11 +173,24 @@ function requireAdmin(req, reply) {
   return decoded;
 }
 
+// Venue management is shared with venue hosts; venue-service scopes hosts to their own venues.
+function requireVenueEditor(req, reply) {
+  const decoded = requireJWT(req, reply);
+  if (!decoded) return null;
+  const roles = Array.isArray(decoded.roles) ? decoded.roles : [];
+  if (!roles.includes('admin') && !roles.includes('host')) {
+    reply.code(403).send({ error: 'admin or host role required' });
+    return null;
+  }
+  return decoded;
+}
+
 // Minimal in-memory rate limiter, correlation id, and basic body/content checks
 const rateBuckets = new Map(); // key: ip|bucket -> { count, reset }
 const metrics = { requests: 0, rateLimited: 0 };
 const RATE_CFG = [
   { prefix: '/api/venues/', method: 'POST', limit: Number(process.env.RATE_VENUE_POST_LIMIT||30), windowMs: Number(process.env.RATE_WINDOW_MS||60000) },
+  { prefix: '/api/reviews/', method: 'POST', limit: Number(process.env.RATE_REVIEW_POST_LIMIT||30), windowMs: Number(process.env.RATE_WINDOW_MS||60000) },
   { prefix: '/api/auth/phone/start', method: 'POST', limit: Number(process.env.RATE_PHONE_START_LIMIT||3), windowMs: Number(process.env.RATE_PHONE_WINDOW_MS||60000) },
   { prefix: '/api/auth/phone/verify', method: 'POST', limit: Number(process.env.RATE_PHONE_VERIFY_LIMIT||6), windowMs: Number(process.env.RATE_PHONE_WINDOW_MS||60000) },
   { prefix: '/api/contacts/match', method: 'POST', limit: Number(process.env.RATE_CONTACTS_MATCH_LIMIT||5), windowMs: Number(process.env.RATE_CONTACTS_WINDOW_MS||60000) },
@@ -209,7 +227,9 @@ app.addHook('onRequest', async (req, reply) => {
     if (url.startsWith('/api/secure')) {
       if (!requireJWT(req, reply)) return;
     }
-    if (ADMIN_REQUIRE_AUTH && url.startsWith('/api/admin')) {
+    if (ADMIN_REQUIRE_AUTH && url.startsWith('/api/admin/venues')) {
+      if (!requireVenueEditor(req, reply)) return;
+    } else if (ADMIN_REQUIRE_AUTH && url.startsWith('/api/admin')) {
       if (!requireAdmin(req, reply)) return;
     }
     // Basic rate limit and content checks
@@ -251,16 +271,13 @@ app.get('/metrics', async (req, reply) => {
   reply.send(`bff_requests_total ${metrics.requests}\n` + `bff_rate_limited_total ${metrics.rateLimited}\n`);
 });
 
-// In-memory admin data (beta mock)
-let adminVenues = [
-  { id: 'v1', title: 'Energetic Bar', subtitle: 'Downtown', rating: 4.5, distance: '0.5km', price: '$$' },
-  { id: 'v2', title: 'Chill Lounge', subtitle: 'Riverside', rating: 4.2, distance: '1.2km', price: '$' },
-];
-// Periodically refresh venue coordinates from venue service (best-effort)
+// Periodically refresh venue coordinates from venue service (best-effort).
+// Reads the internal unpaged list, not the public feed, so no impressions.
 let venueCoords = new Map(); // id -> { lat, lng }
 async function refreshVenueCoords() {
+  if (!VENUE_SERVICE_TOKEN) return;
   try {
-    const r = await fetch(`${VENUE_SERVICE_URL}/venues/discover`);
+    const r = await fetch(`${VENUE_SERVICE_URL}/internal/venues/coords`, { headers: { 'X-Service-Token': VENUE_SERVICE_TOKEN } });
     if (!r.ok) return;
     const d = await r.json().catch(()=>({ items: [] }));
     const items = Array.isArray(d.items) ? d.items : [];
@@ -307,10 +324,6 @@ app.addHook('preValidation', async (req, reply) => {
       const ok = b && typeof b.vibeScore === 'number' && b.vibeScore >= 0 && b.vibeScore <= 10;
       if (!ok) return reply.code(400).send({ error: 'invalid vibe payload' });
     }
-    if (method === 'POST' && url === '/api/admin/venues') {
-      const b = req.body || {};
-      if (!b || typeof b.title !== 'string' || !b.title.trim()) return reply.code(400).send({ error: 'title required' });
-    }
   } catch (e) {
     req.log.error(e);
     reply.code(400).send({ error: 'invalid payload' });
@@ -322,7 +335,7 @@ app.addHook('preValidation', async (req, reply) => {
   }
   if (method === 'POST' && url === '/api/valet/intake') {
     const b = req.body || {};
-    const ok = b && typeof b.userId === 'string' && b.userId && typeof b.vehicle === 'object' && b.vehicle && typeof b.vehicle.make === 'string' && typeof b.vehicle.model === 'string';
+    const ok = b && (b.userId === undefined || (typeof b.userId === 'string' && b.userId)) && typeof b.vehicle === 'object' && b.vehicle && typeof b.vehicle.make === 'string' && typeof b.vehicle.model === 'string';
     if (!ok) return reply.code(400).send({ error: 'invalid intake payload' });
     if (Array.isArray(b.services)) {
       const allowed = new Set(['basic_wash','full_detail','ev_charging']);
@@ -341,16 +354,21 @@ app.addHook('preValidation', async (req, reply) => {
 
 });
 
-app.get('/api/admin/venues', async () => ({ items: adminVenues }));
-app.post('/api/admin/venues', async (req, reply) => {
-  const body = req.body || {};
-  const id = 'v' + (adminVenues.length + 1);
-  const venue = { id, title: body.title, subtitle: body.subtitle || '', rating: body.rating || 0, distance: body.distance || '0km', price: body.price || '$' };
-  adminVenues.push(venue);
-  reply.code(201).send(venue);
-});
 app.get('/api/admin/users', async () => ({ items: adminUsers }));
-app.get('/api/admin/analytics/summary', async () => ({ users: adminUsers.length, venues: adminVenues.length, likes: 0 }));
+app.get('/api/admin/analytics/summary', async (req) => {
+  // Venue and like totals come from venue-service; fall back to zero when it is down.
+  const fallback = { users: adminUsers.length, venues: 0, likes: 0 };
+  try {
+    const auth = req.headers['authorization'];
+    const r = await fetch(`${VENUE_SERVICE_URL}/admin/analytics/summary`, { headers: auth ? { authorization: auth } : {} });
+    if (!r.ok) return fallback;
+    const d = await r.json();
+    return { users: adminUsers.length, venues: d.venues ?? fallback.venues, likes: d.likes ?? 0 };
+  } catch (e) {
+    app.log.warn(e, 'analytics summary upstream failed');
+    return fallback;
+  }
+});
 
 // Helper: role-aware session for gating UI
 app.get('/api/auth/session', async (req, reply) => {
@@ -359,14 +377,23 @@ app.get('/api/auth/session', async (req, reply) => {
   reply.send({ sub: decoded.sub, roles: decoded.roles || [] });
 });
 
-// Device tokens (dev/in-memory)
-const deviceTokens = { user: new Set(), driver: new Set() };
+// Device tokens live in notification-service; it checks the caller's token
+// and registers the device for that user.
 app.post('/api/devices/register', async (req, reply) => {
   const b = req.body || {};
-  const role = b.role === 'driver' ? 'driver' : 'user';
-  if (!b || typeof b.token !== 'string') return reply.code(400).send({ error: 'invalid device payload' });
-  deviceTokens[role].add(b.token);
-  reply.send({ status: 'ok' });
+  if (typeof b.token !== 'string' || typeof b.deviceId !== 'string') return reply.code(400).send({ error: 'invalid device payload' });
+  try {
+    const r = await fetch(`${NOTIFY_SERVICE_URL}/devices/register`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json', ...(req.headers.authorization ? { authorization: req.headers.authorization } : {}) },
+      body: JSON.stringify({ deviceId: b.deviceId, platform: b.platform, provider: b.provider, token: b.token })
+    });
+    const d = await r.json().catch(() => ({}));
+    reply.code(r.status).send(d);
+  } catch (e) {
+    req.log.error(e);
+    reply.code(502).send({ error: 'device registration failed' });
+  }
 });
 
 // Simple presence endpoint for pre-arrival
@@ -382,21 +409,25 @@ app.post('/api/presence', async (req, reply) => {
   reply.send({ status: 'ok', expiresAt: expiry });
 });
 
-// Dev push sender (Expo push)
+// Manual valet status push (admin), sent as a valet.status_changed event
+// through notification-service, which applies the user's devices and
+// preferences. valet-service publishes the same event on every status change.
 app.post('/api/notify/valet', async (req, reply) => {
+  if (!requireAdmin(req, reply)) return;
   const b = req.body || {};
-  if (!b || typeof b.to !== 'string' || typeof b.title !== 'string' || typeof b.body !== 'string') return reply.code(400).send({ error: 'invalid notify payload' });
+  if (typeof b.userId !== 'string' || typeof b.status !== 'string') return reply.code(400).send({ error: 'invalid notify payload' });
   try {
-    const r = await fetch('https://exp.host/--/api/v2/push/send', {
+    const data = { status: b.status, ...(typeof b.vehicle === 'string' ? { vehicle: b.vehicle } : {}), ...(typeof b.ticketId === 'string' ? { ticketId: b.ticketId } : {}) };
+    const r = await fetch(`${NOTIFY_SERVICE_URL}/events`, {
       method: 'POST',
-      headers: { 'Content-Type': 'application/json' },
-      body: JSON.stringify({ to: b.to, title: b.title, body: b.body })
+      headers: { 'Content-Type': 'application/json', ...(NOTIFY_SERVICE_TOKEN ? { 'X-Service-Token': NOTIFY_SERVICE_TOKEN } : {}) },
+      body: JSON.stringify({ type: 'valet.status_changed', userId: b.userId, data })
     });
     const d = await r.json().catch(() => ({}));
-    reply.send({ status: 'queued', result: d });
+    reply.code(r.status).send(d);
   } catch (e) {
     req.log.error(e);
-    reply.code(500).send({ error: 'push failed' });
+    reply.code(502).send({ error: 'push failed' });
   }
 });
 
@@ -531,7 +562,7 @@ app.post('/api/valet/intake', async (req, reply) => {
   try {
     const b = typeof req.body === 'object' ? req.body : JSON.parse(Buffer.isBuffer(req.body) ? req.body.toString('utf8') : String(req.body));
     const r = await fetch(`${VALET_SERVICE_URL}/valet/intake`, {
-      method: 'POST', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify(b)
+      method: 'POST', headers: { 'Content-Type': 'application/json', ...(req.headers.authorization ? { authorization: req.headers.authorization } : {}) }, body: JSON.stringify(b)
     });
     const d = await r.json().catch(()=>({}));
     try { io.emit('valet:task', { id: d.ticket || d.id, status: 'intake', ...d }); } catch { /* ignore */ }
@@ -547,7 +578,7 @@ app.patch('/api/valet/vehicles/:id/status', async (req, reply) => {
     const id = req.params?.id;
     const b = typeof req.body === 'object' ? req.body : JSON.parse(Buffer.isBuffer(req.body) ? req.body.toString('utf8') : String(req.body));
     const r = await fetch(`${VALET_SERVICE_URL}/valet/vehicles/${encodeURIComponent(id)}/status`, {
-      method: 'PATCH', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify(b)
+      method: 'PATCH', headers: { 'Content-Type': 'application/json', ...(req.headers.authorization ? { authorization: req.headers.authorization } : {}) }, body: JSON.stringify(b)
     });
     const d = await r.json().catch(()=>({ id, ...b }));
     try { io.emit('valet:task', { id: d.id || id, status: d.status || b.status, ...d }); } catch { /* ignore */ }
@@ -569,23 +600,30 @@ app.get('/api/host/onboarding/types', async () => ({
   ]
 }));
 
-// Discovery aggregator: venues + parking + valet offers
+// Discovery aggregator: venues + parking + valet offers. Venues page through
+// venue-service's discover feed (cursor/limit pass through, nextCursor comes
+// back); parking and valet cards only come with the first page.
 app.get('/api/discover/cards', async (req, reply) => {
   try {
-    const interests = String((req.query || {}).interests || '').split(',').filter(Boolean);
+    const q = req.query || {};
+    const interests = String(q.interests || '').split(',').filter(Boolean);
+    const qs = new URLSearchParams();
+    for (const k of ['cursor', 'limit', 'lat', 'lon', 'radius']) if (q[k] != null) qs.set(k, String(q[k]));
+    const auth = req.headers['authorization'];
     const [vr, pr] = await Promise.all([
-      fetch(`${VENUE_SERVICE_URL}/venues/discover`),
-      fetch(`${PARKING_SERVICE_URL}/parking/search`)
+      fetch(`${VENUE_SERVICE_URL}/venues/discover?${qs}`, { headers: auth ? { authorization: auth } : {} }),
+      q.cursor ? null : fetch(`${PARKING_SERVICE_URL}/parking/search`)
     ]);
+    if (vr.status === 400) return reply.code(400).send(await vr.json().catch(() => ({ error: 'invalid query' })));
     const vd = vr.ok ? await vr.json() : { items: [] };
-    const pd = pr.ok ? await pr.json() : { items: [] };
+    const pd = pr && pr.ok ? await pr.json() : { items: [] };
     const venueCards = (vd.items || []).map((v) => ({
       id: v.id, type: 'venue', title: v.title, subtitle: v.subtitle, rating: v.rating, distance: v.distance, price: v.price, data: v,
     }));
     const parkingCards = (pd.items || []).map((p) => ({
       id: `p:${p.id}`, type: 'parking', title: p.name, subtitle: p.distance, tags: p.features, data: p,
     }));
-    const valetOffers = [{ id: 'valet-offer-1', type: 'valet', title: 'Valet Available', subtitle: 'Request drop-off & services', data: { offer: true } }];
+    const valetOffers = q.cursor ? [] : [{ id: 'valet-offer-1', type: 'valet', title: 'Valet Available', subtitle: 'Request drop-off & services', data: { offer: true } }];
     let cards = [...venueCards, ...parkingCards, ...valetOffers];
     if (interests.length) {
       const weight = (c) => {
@@ -603,7 +641,7 @@ app.get('/api/discover/cards', async (req, reply) => {
       for (const c of expanded) { if (!seen.has(c.id)) { seen.add(c.id); result.push(c); } }
       cards = result;
     }
-    reply.send({ items: cards });
+    reply.send({ items: cards, nextCursor: vd.nextCursor ?? null });
   } catch (e) {
     req.log.error(e);
     reply.code(500).send({ error: 'aggregator error' });
@@ -689,10 +727,29 @@ app.get('/docs/upstream-valet', async (_req, reply) => {
 // Parking and Valet proxies
 app.register(proxy, { upstream: PARKING_SERVICE_URL, prefix: '/api/parking', rewritePrefix: '/parking', proxyPayloads: false });
 app.register(proxy, { upstream: VALET_SERVICE_URL, prefix: '/api/valet', rewritePrefix: '/valet', proxyPayloads: false });
+// Delivered/opened acks from the app; notification-service checks the caller
+app.register(proxy, { upstream: NOTIFY_SERVICE_URL, prefix: '/api/notifications', rewritePrefix: '/notifications', proxyPayloads: false });
 
 // Proxy public endpoints
 app.register(proxy, { upstream: AUTH_SERVICE_URL, prefix: '/api/auth', rewritePrefix: '/auth', proxyPayloads: false });
+// Friend graph; auth-service's /internal routes are not exposed
+app.register(proxy, { upstream: AUTH_SERVICE_URL, prefix: '/api/friends', rewritePrefix: '/friends', proxyPayloads: false });
 app.register(proxy, { upstream: VENUE_SERVICE_URL, prefix: '/api/venues', rewritePrefix: '/venues', proxyPayloads: false });
+app.register(proxy, { upstream: VENUE_SERVICE_URL, prefix: '/api/users/me/likes', rewritePrefix: '/users/me/likes', proxyPayloads: false });
+app.register(proxy, { upstream: VENUE_SERVICE_URL, prefix: '/api/users/me/checkins', rewritePrefix: '/users/me/checkins', proxyPayloads: false });
+app.register(proxy, { upstream: VENUE_SERVICE_URL, prefix: '/api/users/me/reviews', rewritePrefix: '/users/me/reviews', proxyPayloads: false });
+app.register(proxy, { upstream: VENUE_SERVICE_URL, prefix: '/api/reviews', rewritePrefix: '/reviews', proxyPayloads: false });
+app.register(proxy, { upstream: VENUE_SERVICE_URL, prefix: '/api/users/me/achievements', rewritePrefix: '/users/me/achievements', proxyPayloads: false });
+// Catalog only: achievement events come from other services, not clients
+app.register(proxy, { upstream: VENUE_SERVICE_URL, prefix: '/api/achievements', rewritePrefix: '/achievements', httpMethods: ['GET'], proxyPayloads: false });
+app.register(proxy, { upstream: VENUE_SERVICE_URL, prefix: '/api/events', rewritePrefix: '/events', httpMethods: ['GET'], proxyPayloads: false });
+app.register(proxy, { upstream: VENUE_SERVICE_URL, prefix: '/api/users/recommendations', rewritePrefix: '/users/recommendations', proxyPayloads: false });
+app.register(proxy, { upstream: VENUE_SERVICE_URL, prefix: '/api/vibe/stream', rewritePrefix: '/vibe/stream', proxyPayloads: false });
+app.register(proxy, { upstream: VENUE_SERVICE_URL, prefix: '/api/vibe/heatmap', rewritePrefix: '/vibe/heatmap', proxyPayloads: false });
+// Venue management (create/update/archive/restore/bulk-status/audit) lives in venue-service
+app.register(proxy, { upstream: VENUE_SERVICE_URL, prefix: '/api/admin/venues', rewritePrefix: '/admin/venues', proxyPayloads: false });
+// Review and tip moderation queue (admin)
+app.register(proxy, { upstream: VENUE_SERVICE_URL, prefix: '/api/admin/reviews', rewritePrefix: '/admin/reviews', proxyPayloads: false });
 // Proxy host onboarding to auth-service
 app.register(proxy, { upstream: AUTH_SERVICE_URL, prefix: '/api/host', rewritePrefix: '/host', proxyPayloads: false });
 
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	_ "time/tzdata" // quiet hours need IANA zones in distroless images
+
+	"bytspot/services/notification-service/internal/server"
+)
+
+func main() {
+	if err := runMigrations(); err != nil {
+		log.Printf("migrations failed: %v", err)
+	}
+
+	port := os.Getenv("PORT")
+	if port == "" { port = "8097" }
+	log.Printf("notification-service listening on :%s", port)
+	http.ListenAndServe(":"+port, server.NewRouter())
+}
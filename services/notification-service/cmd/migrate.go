@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/pressly/goose/v3"
+)
+
+// Runs at startup when DATABASE_URL is set. The database is shared with
+// auth-service and venue-service, so notification migrations track their
+// own version table.
+func runMigrations() error {
+	conn := os.Getenv("DATABASE_URL")
+	if conn == "" {
+		return nil
+	}
+	if err := goose.SetDialect("postgres"); err != nil {
+		return err
+	}
+	goose.SetTableName("notification_goose_db_version")
+	db, err := goose.OpenDBWithDriver("postgres", conn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	// Determine migrations directory (local dev vs container image)
+	dir := os.Getenv("MIGRATIONS_DIR")
+	if dir == "" {
+		if _, statErr := os.Stat("migrations"); statErr == nil {
+			dir = "migrations"
+		} else {
+			dir = "/migrations"
+		}
+	}
+	return goose.UpContext(ctx, db, dir)
+}
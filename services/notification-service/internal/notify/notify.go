@@ -0,0 +1,160 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"bytspot/services/notification-service/internal/provider"
+	"bytspot/services/notification-service/internal/store"
+	"bytspot/services/notification-service/internal/templates"
+)
+
+// Request asks for template Template to be rendered with Data and pushed to
+// every registered device of UserID.
+type Request struct {
+	UserID   string         `json:"userId"`
+	Template string         `json:"template"`
+	Data     map[string]any `json:"data"`
+}
+
+type Outcome struct {
+	NotificationID string          `json:"notificationId"`
+	Receipts       []store.Receipt `json:"receipts"`
+}
+
+// Dispatcher applies user preferences and fans a notification out to the
+// provider adapter of each device, recording a receipt per attempt.
+type Dispatcher struct {
+	Store     store.Repository
+	Templates *templates.Registry
+	Senders   map[store.Provider]provider.Sender
+	Now       func() time.Time
+}
+
+func (d *Dispatcher) now() time.Time {
+	if d.Now != nil {
+		return d.Now()
+	}
+	return time.Now()
+}
+
+// RenderError is a template that could not be rendered with the request's
+// data (unknown template, missing variables): the caller's mistake.
+type RenderError struct{ Err error }
+
+func (e *RenderError) Error() string { return e.Err.Error() }
+func (e *RenderError) Unwrap() error { return e.Err }
+
+func (d *Dispatcher) Notify(ctx context.Context, req Request) (Outcome, error) {
+	msg, err := d.Templates.Render(req.Template, req.Data)
+	if err != nil {
+		return Outcome{}, &RenderError{Err: err}
+	}
+	id, err := d.Store.NextNotificationID(ctx)
+	if err != nil {
+		return Outcome{}, err
+	}
+	out := Outcome{NotificationID: id}
+	record := func(rc *store.Receipt) error {
+		saved, err := d.Store.AddReceipt(ctx, rc)
+		if err != nil {
+			return err
+		}
+		out.Receipts = append(out.Receipts, *saved)
+		return nil
+	}
+	suppress := func(reason string) (Outcome, error) {
+		err := record(&store.Receipt{NotificationID: out.NotificationID, UserID: req.UserID, Status: store.ReceiptSuppressed, Reason: reason})
+		return out, err
+	}
+
+	prefs, err := d.Store.GetPreferences(ctx, req.UserID)
+	if err != nil {
+		return Outcome{}, err
+	}
+	if prefs.OptOuts[msg.Category] {
+		return suppress("opted_out")
+	}
+	if !msg.Urgent && inQuietHours(prefs.QuietHours, d.now()) {
+		return suppress("quiet_hours")
+	}
+	devices, err := d.Store.DevicesForUser(ctx, req.UserID)
+	if err != nil {
+		return Outcome{}, err
+	}
+	if len(devices) == 0 {
+		return suppress("no_devices")
+	}
+
+	data := map[string]string{"notificationId": out.NotificationID, "template": req.Template}
+	for k, v := range req.Data {
+		data[k] = fmt.Sprint(v)
+	}
+	for _, dev := range devices {
+		rc := &store.Receipt{NotificationID: out.NotificationID, UserID: req.UserID, DeviceID: dev.DeviceID, Provider: dev.Provider}
+		sender := d.Senders[dev.Provider]
+		if sender == nil {
+			rc.Status, rc.Reason = store.ReceiptFailed, "provider_not_configured"
+			if err := record(rc); err != nil {
+				return out, err
+			}
+			continue
+		}
+		res, err := sender.Send(ctx, provider.Message{Token: dev.Token, Title: msg.Title, Body: msg.Body, Category: msg.Category, Data: data})
+		switch {
+		case errors.Is(err, provider.ErrUnregistered):
+			if err := d.Store.DeleteToken(ctx, dev.Provider, dev.Token); err != nil {
+				log.Printf("forget unregistered token: %v", err)
+			}
+			rc.Status, rc.Reason = store.ReceiptFailed, "unregistered"
+		case err != nil:
+			rc.Status, rc.Reason = store.ReceiptFailed, err.Error()
+		default:
+			rc.Status, rc.ProviderRef = store.ReceiptSent, res.ProviderRef
+		}
+		if err := record(rc); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+// inQuietHours reports whether now falls inside the user's local quiet
+// window. Windows may wrap midnight ("22:00"-"07:00").
+func inQuietHours(qh *store.QuietHours, now time.Time) bool {
+	if qh == nil {
+		return false
+	}
+	start, ok1 := ParseClock(qh.Start)
+	end, ok2 := ParseClock(qh.End)
+	if !ok1 || !ok2 || start == end {
+		return false
+	}
+	loc := time.UTC
+	if qh.Timezone != "" {
+		if l, err := time.LoadLocation(qh.Timezone); err == nil {
+			loc = l
+		}
+	}
+	local := now.In(loc)
+	m := local.Hour()*60 + local.Minute()
+	if start < end {
+		return m >= start && m < end
+	}
+	return m >= start || m < end
+}
+
+// ParseClock parses "HH:MM" into minutes after midnight.
+func ParseClock(s string) (int, bool) {
+	var h, m int
+	if len(s) != 5 || s[2] != ':' {
+		return 0, false
+	}
+	if _, err := fmt.Sscanf(s, "%02d:%02d", &h, &m); err != nil || h > 23 || m > 59 || h < 0 || m < 0 {
+		return 0, false
+	}
+	return h*60 + m, true
+}
@@ -0,0 +1,145 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	apnsProductionHost = "https://api.push.apple.com"
+	apnsSandboxHost    = "https://api.sandbox.push.apple.com"
+	// Apple rejects provider tokens older than an hour; refresh well before.
+	apnsTokenTTL = 50 * time.Minute
+)
+
+// APNs sends through Apple's HTTP/2 provider API using token (.p8) auth.
+type APNs struct {
+	Host   string
+	Topic  string
+	KeyID  string
+	TeamID string
+	Key    *ecdsa.PrivateKey
+	Client *http.Client
+
+	mu       sync.Mutex
+	token    string
+	issuedAt time.Time
+}
+
+// NewAPNsFromEnv builds the adapter from APNS_KEY_FILE, APNS_KEY_ID,
+// APNS_TEAM_ID, APNS_TOPIC and APNS_SANDBOX. It returns nil, nil when APNs is
+// not configured.
+func NewAPNsFromEnv() (*APNs, error) {
+	keyFile := os.Getenv("APNS_KEY_FILE")
+	if keyFile == "" {
+		return nil, nil
+	}
+	raw, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parseP8(raw)
+	if err != nil {
+		return nil, err
+	}
+	host := apnsProductionHost
+	if os.Getenv("APNS_SANDBOX") == "true" {
+		host = apnsSandboxHost
+	}
+	return &APNs{
+		Host:   host,
+		Topic:  os.Getenv("APNS_TOPIC"),
+		KeyID:  os.Getenv("APNS_KEY_ID"),
+		TeamID: os.Getenv("APNS_TEAM_ID"),
+		Key:    key,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func parseP8(raw []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("apns: key is not PEM encoded")
+	}
+	k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	ec, ok := k.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("apns: key is not ECDSA")
+	}
+	return ec, nil
+}
+
+func (a *APNs) providerToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != "" && time.Since(a.issuedAt) < apnsTokenTTL {
+		return a.token, nil
+	}
+	now := time.Now()
+	tok := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{"iss": a.TeamID, "iat": now.Unix()})
+	tok.Header["kid"] = a.KeyID
+	signed, err := tok.SignedString(a.Key)
+	if err != nil {
+		return "", err
+	}
+	a.token, a.issuedAt = signed, now
+	return signed, nil
+}
+
+func (a *APNs) Send(ctx context.Context, msg Message) (Result, error) {
+	payload := map[string]any{
+		"aps": map[string]any{
+			"alert":    map[string]string{"title": msg.Title, "body": msg.Body},
+			"sound":    "default",
+			"category": msg.Category,
+		},
+	}
+	for k, v := range msg.Data {
+		if k != "aps" {
+			payload[k] = v
+		}
+	}
+	body, _ := json.Marshal(payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.Host+"/3/device/"+msg.Token, bytes.NewReader(body))
+	if err != nil {
+		return Result{}, err
+	}
+	bearer, err := a.providerToken()
+	if err != nil {
+		return Result{}, err
+	}
+	req.Header.Set("authorization", "bearer "+bearer)
+	req.Header.Set("apns-topic", a.Topic)
+	req.Header.Set("apns-push-type", "alert")
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return Result{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return Result{ProviderRef: resp.Header.Get("apns-id")}, nil
+	}
+	var apnsErr struct {
+		Reason string `json:"reason"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&apnsErr)
+	if resp.StatusCode == http.StatusGone || apnsErr.Reason == "BadDeviceToken" || apnsErr.Reason == "Unregistered" {
+		return Result{}, ErrUnregistered
+	}
+	return Result{}, fmt.Errorf("apns: %d %s", resp.StatusCode, apnsErr.Reason)
+}
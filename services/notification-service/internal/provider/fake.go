@@ -0,0 +1,42 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+)
+
+// Fake is an in-memory Sender for local development and tests. Tokens listed
+// in Unregistered fail with ErrUnregistered; tokens in Failing fail with a
+// transient error.
+type Fake struct {
+	mu           sync.Mutex
+	Sent         []Message
+	Unregistered map[string]bool
+	Failing      map[string]bool
+}
+
+func NewFake() *Fake {
+	return &Fake{Unregistered: map[string]bool{}, Failing: map[string]bool{}}
+}
+
+func (f *Fake) Send(_ context.Context, msg Message) (Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Unregistered[msg.Token] {
+		return Result{}, ErrUnregistered
+	}
+	if f.Failing[msg.Token] {
+		return Result{}, errors.New("fake provider failure")
+	}
+	f.Sent = append(f.Sent, msg)
+	return Result{ProviderRef: "fake-" + strconv.Itoa(len(f.Sent))}, nil
+}
+
+// Messages returns a copy of everything sent so far.
+func (f *Fake) Messages() []Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Message(nil), f.Sent...)
+}
@@ -0,0 +1,88 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+const fcmEndpoint = "https://fcm.googleapis.com/v1/projects/%s/messages:send"
+
+// TokenSource returns an OAuth2 access token for the FCM HTTP v1 API.
+type TokenSource func(ctx context.Context) (string, error)
+
+// FCM sends through Firebase Cloud Messaging's HTTP v1 API.
+type FCM struct {
+	ProjectID string
+	Token     TokenSource
+	Client    *http.Client
+}
+
+// NewFCMFromEnv builds the adapter from FCM_PROJECT_ID and FCM_ACCESS_TOKEN.
+// Deployments that mint short-lived tokens should construct FCM directly
+// with their own TokenSource. It returns nil when FCM is not configured.
+func NewFCMFromEnv() *FCM {
+	project := os.Getenv("FCM_PROJECT_ID")
+	if project == "" {
+		return nil
+	}
+	return &FCM{
+		ProjectID: project,
+		Token: func(context.Context) (string, error) {
+			if t := os.Getenv("FCM_ACCESS_TOKEN"); t != "" {
+				return t, nil
+			}
+			return "", errors.New("fcm: FCM_ACCESS_TOKEN not set")
+		},
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (f *FCM) Send(ctx context.Context, msg Message) (Result, error) {
+	payload := map[string]any{
+		"message": map[string]any{
+			"token":        msg.Token,
+			"notification": map[string]string{"title": msg.Title, "body": msg.Body},
+			"data":         msg.Data,
+		},
+	}
+	body, _ := json.Marshal(payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf(fcmEndpoint, f.ProjectID), bytes.NewReader(body))
+	if err != nil {
+		return Result{}, err
+	}
+	bearer, err := f.Token(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+bearer)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return Result{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		var ok struct {
+			Name string `json:"name"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&ok)
+		return Result{ProviderRef: ok.Name}, nil
+	}
+	var fcmErr struct {
+		Error struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&fcmErr)
+	if resp.StatusCode == http.StatusNotFound || fcmErr.Error.Status == "UNREGISTERED" {
+		return Result{}, ErrUnregistered
+	}
+	return Result{}, fmt.Errorf("fcm: %d %s", resp.StatusCode, fcmErr.Error.Message)
+}
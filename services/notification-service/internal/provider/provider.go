@@ -0,0 +1,29 @@
+package provider
+
+import (
+	"context"
+	"errors"
+)
+
+// Message is a rendered notification addressed to a single device token.
+type Message struct {
+	Token    string            `json:"token"`
+	Title    string            `json:"title"`
+	Body     string            `json:"body"`
+	Category string            `json:"category,omitempty"`
+	Data     map[string]string `json:"data,omitempty"`
+}
+
+// Result carries the provider's id for the accepted message, if any.
+type Result struct {
+	ProviderRef string
+}
+
+// ErrUnregistered means the provider rejected the token permanently; the
+// caller should forget the device.
+var ErrUnregistered = errors.New("push token unregistered")
+
+// Sender delivers messages through one push provider (APNs, FCM, or a fake).
+type Sender interface {
+	Send(ctx context.Context, msg Message) (Result, error)
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"bytspot/shared/jwtauth"
+)
+
+// authenticate requires a valid bearer token, writing 401 otherwise. Writes
+// also refuse impersonation tokens (403): support staff may look, not act.
+func authenticate(w http.ResponseWriter, r *http.Request, write bool) (*jwtauth.Claims, bool) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		writeError(w, http.StatusUnauthorized, "missing bearer token")
+		return nil, false
+	}
+	claims, err := jwtauth.Verify(strings.TrimPrefix(auth, "Bearer "))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "invalid token")
+		return nil, false
+	}
+	if write && claims.Act != nil {
+		writeError(w, http.StatusForbidden, "not allowed while impersonating")
+		return nil, false
+	}
+	return claims, true
+}
+
+// requireUser authenticates the user named by the {id} route parameter;
+// other users get 403.
+func requireUser(w http.ResponseWriter, r *http.Request, userID string, write bool) bool {
+	claims, ok := authenticate(w, r, write)
+	if !ok {
+		return false
+	}
+	if claims.Sub != userID {
+		writeError(w, http.StatusForbidden, "not your account")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"bytspot/services/notification-service/internal/store"
+	"bytspot/services/notification-service/internal/templates"
+)
+
+const (
+	// templateRefreshInterval is how long a template changed on another
+	// instance takes to apply here.
+	templateRefreshInterval = time.Minute
+	receiptPurgeInterval    = time.Hour
+	defaultReceiptRetention = 30 * 24 * time.Hour
+)
+
+// receiptRetentionFromEnv reads NOTIFY_RECEIPT_RETENTION (a Go duration,
+// e.g. 720h); receipts older than that are purged.
+func receiptRetentionFromEnv() time.Duration {
+	if v := os.Getenv("NOTIFY_RECEIPT_RETENTION"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("ignoring invalid NOTIFY_RECEIPT_RETENTION %q", v)
+	}
+	return defaultReceiptRetention
+}
+
+// loadTemplates applies the stored template overrides on top of reg.
+func loadTemplates(ctx context.Context, st store.Repository, reg *templates.Registry) error {
+	stored, err := st.ListTemplates(ctx)
+	if err != nil {
+		return err
+	}
+	for _, t := range stored {
+		if err := reg.Put(t); err != nil {
+			log.Printf("stored template %s: %v", t.Key, err)
+		}
+	}
+	return nil
+}
+
+// runJobs keeps templates in sync with the store and purges receipts older
+// than retention until ctx is done.
+func runJobs(ctx context.Context, st store.Repository, reg *templates.Registry, retention time.Duration) {
+	t := time.NewTicker(templateRefreshInterval)
+	defer t.Stop()
+	var lastPurge time.Time
+	for {
+		if now := time.Now(); now.Sub(lastPurge) >= receiptPurgeInterval {
+			if n, err := st.PurgeReceipts(ctx, now.Add(-retention)); err != nil {
+				log.Printf("receipt purge failed: %v", err)
+			} else {
+				lastPurge = now
+				if n > 0 {
+					log.Printf("receipt purge removed %d receipts older than %s", n, retention)
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if err := loadTemplates(ctx, st, reg); err != nil {
+			log.Printf("template refresh failed: %v", err)
+		}
+	}
+}
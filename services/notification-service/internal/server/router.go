@@ -0,0 +1,429 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"bytspot/services/notification-service/internal/notify"
+	"bytspot/services/notification-service/internal/provider"
+	"bytspot/services/notification-service/internal/store"
+	"bytspot/services/notification-service/internal/templates"
+	"bytspot/shared/servicetoken"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type serverImpl struct {
+	st       store.Repository
+	d        *notify.Dispatcher
+	services *servicetoken.Verifier // producers of /notify, /events; template admins
+}
+
+type registerReq struct {
+	UserID   string         `json:"userId"`
+	DeviceID string         `json:"deviceId"`
+	Platform store.Platform `json:"platform"`
+	Provider store.Provider `json:"provider"`
+	Token    string         `json:"token"`
+}
+
+// eventReq is a domain event from another service. Recipients and UserID
+// are merged, so single-user events (valet) and fan-out events (plans,
+// presence) share one shape.
+type eventReq struct {
+	Type       string         `json:"type"`
+	UserID     string         `json:"userId"`
+	Recipients []string       `json:"recipients"`
+	Data       map[string]any `json:"data"`
+}
+
+type ackReq struct {
+	DeviceID string              `json:"deviceId"`
+	Status   store.ReceiptStatus `json:"status"`
+}
+
+// Event types other services may publish; each maps to the template of the same key.
+var eventTemplates = map[string]string{
+	"valet.status_changed": "valet.status_changed",
+	"plan.result":          "plan.result",
+	"friend.presence":      "friend.presence",
+	"achievement.earned":   "achievement.earned",
+}
+
+var valetStatusLabels = map[string]string{
+	"intake":              "checked in with valet",
+	"parked":              "parked",
+	"service_in_progress": "service in progress",
+	"ready":               "ready for pickup",
+	"retrieved":           "retrieved, enjoy your night",
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]any{"error": msg})
+}
+
+func storeError(w http.ResponseWriter, err error) {
+	log.Printf("store: %v", err)
+	writeError(w, http.StatusInternalServerError, "store error")
+}
+
+func (s *serverImpl) GetHealthz(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+func (s *serverImpl) GetReadyz(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ready"))
+}
+
+// Device registration: one token per (user, device); provider defaults from platform.
+// Users register their own devices; userId defaults to the caller.
+func (s *serverImpl) PostDevicesRegister(w http.ResponseWriter, r *http.Request) {
+	claims, ok := authenticate(w, r, true)
+	if !ok {
+		return
+	}
+	var req registerReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.DeviceID == "" || req.Token == "" {
+		writeError(w, http.StatusBadRequest, "invalid device payload")
+		return
+	}
+	if req.UserID == "" {
+		req.UserID = claims.Sub
+	}
+	if req.UserID != claims.Sub {
+		writeError(w, http.StatusForbidden, "not your account")
+		return
+	}
+	if req.Provider == "" {
+		switch req.Platform {
+		case store.PlatformIOS:
+			req.Provider = store.ProviderAPNs
+		case store.PlatformAndroid:
+			req.Provider = store.ProviderFCM
+		}
+	}
+	if req.Provider != store.ProviderAPNs && req.Provider != store.ProviderFCM {
+		writeError(w, http.StatusBadRequest, "invalid provider")
+		return
+	}
+	d, err := s.st.UpsertDevice(r.Context(), &store.Device{UserID: req.UserID, DeviceID: req.DeviceID, Platform: req.Platform, Provider: req.Provider, Token: req.Token})
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, d)
+}
+
+func (s *serverImpl) GetUsersIdDevices(w http.ResponseWriter, r *http.Request, userID string) {
+	items, err := s.st.DevicesForUser(r.Context(), userID)
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	if items == nil {
+		items = []store.Device{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+func (s *serverImpl) DeleteUsersIdDevicesId(w http.ResponseWriter, r *http.Request, userID, deviceID string) {
+	found, err := s.st.DeleteDevice(r.Context(), userID, deviceID)
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Preferences: quiet hours + per-category opt-outs
+func (s *serverImpl) GetUsersIdPreferences(w http.ResponseWriter, r *http.Request, userID string) {
+	p, err := s.st.GetPreferences(r.Context(), userID)
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (s *serverImpl) PutUsersIdPreferences(w http.ResponseWriter, r *http.Request, userID string) {
+	var req store.Preferences
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	if qh := req.QuietHours; qh != nil {
+		_, ok1 := notify.ParseClock(qh.Start)
+		_, ok2 := notify.ParseClock(qh.End)
+		if !ok1 || !ok2 {
+			writeError(w, http.StatusBadRequest, "quiet hours must be HH:MM")
+			return
+		}
+	}
+	req.UserID = userID
+	p, err := s.st.PutPreferences(r.Context(), req)
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+// Templates
+func (s *serverImpl) GetTemplates(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"items": s.d.Templates.List()})
+}
+
+func (s *serverImpl) PutTemplatesKey(w http.ResponseWriter, r *http.Request, key string) {
+	var t templates.Template
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	t.Key = key
+	if err := templates.Validate(t); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.st.PutTemplate(r.Context(), t); err != nil {
+		storeError(w, err)
+		return
+	}
+	s.d.Templates.Put(t)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Direct send of a template to one user
+func (s *serverImpl) PostNotify(w http.ResponseWriter, r *http.Request) {
+	var req notify.Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserID == "" || req.Template == "" {
+		writeError(w, http.StatusBadRequest, "invalid notify payload")
+		return
+	}
+	out, err := s.d.Notify(r.Context(), req)
+	if err != nil {
+		s.renderError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, out)
+}
+
+// Domain events (valet status, plan results, friend presence, achievements) -> notifications
+func (s *serverImpl) PostEvents(w http.ResponseWriter, r *http.Request) {
+	var ev eventReq
+	if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid event payload")
+		return
+	}
+	tmpl, ok := eventTemplates[ev.Type]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "unknown event type")
+		return
+	}
+	recipients := ev.Recipients
+	if ev.UserID != "" {
+		recipients = append([]string{ev.UserID}, recipients...)
+	}
+	if len(recipients) == 0 {
+		writeError(w, http.StatusBadRequest, "no recipients")
+		return
+	}
+	data := ev.Data
+	if data == nil {
+		data = map[string]any{}
+	}
+	if ev.Type == "valet.status_changed" {
+		st, _ := data["status"].(string)
+		if _, ok := data["statusLabel"]; !ok {
+			label := valetStatusLabels[st]
+			if label == "" {
+				label = strings.ReplaceAll(st, "_", " ")
+			}
+			data["statusLabel"] = label
+		}
+		if _, ok := data["vehicle"]; !ok {
+			data["vehicle"] = "Your vehicle"
+		}
+	}
+	seen := map[string]bool{}
+	var outcomes []notify.Outcome
+	for _, uid := range recipients {
+		if uid == "" || seen[uid] {
+			continue
+		}
+		seen[uid] = true
+		out, err := s.d.Notify(r.Context(), notify.Request{UserID: uid, Template: tmpl, Data: data})
+		if err != nil {
+			s.renderError(w, err)
+			return
+		}
+		outcomes = append(outcomes, out)
+	}
+	writeJSON(w, http.StatusAccepted, map[string]any{"items": outcomes})
+}
+
+func (s *serverImpl) renderError(w http.ResponseWriter, err error) {
+	if errors.Is(err, templates.ErrUnknownTemplate) {
+		writeError(w, http.StatusBadRequest, "unknown template")
+		return
+	}
+	// template execution errors are caller data problems (missing variables)
+	var re *notify.RenderError
+	if errors.As(err, &re) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	storeError(w, err)
+}
+
+// Delivery receipts
+func (s *serverImpl) GetNotificationsIdReceipts(w http.ResponseWriter, r *http.Request, id string) {
+	items, err := s.st.Receipts(r.Context(), id)
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	if len(items) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+// Client acknowledgement: the app reports delivered/opened for its device
+func (s *serverImpl) PostNotificationsIdReceipts(w http.ResponseWriter, r *http.Request, id string) {
+	claims, ok := authenticate(w, r, true)
+	if !ok {
+		return
+	}
+	var req ackReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.DeviceID == "" ||
+		(req.Status != store.ReceiptDelivered && req.Status != store.ReceiptOpened) {
+		writeError(w, http.StatusBadRequest, "invalid receipt payload")
+		return
+	}
+	found, err := s.st.AckReceipt(r.Context(), id, claims.Sub, req.DeviceID, req.Status)
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sendersFromEnv wires real adapters when configured and falls back to the
+// logging fake so local stacks work without Apple/Google credentials.
+func sendersFromEnv() map[store.Provider]provider.Sender {
+	senders := map[store.Provider]provider.Sender{}
+	if apns, err := provider.NewAPNsFromEnv(); err != nil {
+		log.Printf("apns disabled: %v", err)
+	} else if apns != nil {
+		senders[store.ProviderAPNs] = apns
+	}
+	if fcm := provider.NewFCMFromEnv(); fcm != nil {
+		senders[store.ProviderFCM] = fcm
+	}
+	fake := provider.NewFake()
+	for _, p := range []store.Provider{store.ProviderAPNs, store.ProviderFCM} {
+		if senders[p] == nil {
+			log.Printf("%s not configured; using fake sender", p)
+			senders[p] = fake
+		}
+	}
+	return senders
+}
+
+// newStore connects to Postgres via DATABASE_URL. Without it, or with
+// NOTIFY_STORE=memory, devices and receipts live in memory (local dev only;
+// lost on restart).
+func newStore(ctx context.Context) (store.Repository, error) {
+	if os.Getenv("NOTIFY_STORE") == "memory" || os.Getenv("DATABASE_URL") == "" {
+		log.Printf("notification store in memory; set DATABASE_URL to persist")
+		return store.NewMemStore(), nil
+	}
+	return store.New(ctx)
+}
+
+func NewRouter() http.Handler {
+	ctx := context.Background()
+	st, err := newStore(ctx)
+	if err != nil {
+		log.Fatalf("notification store unavailable: %v", err)
+	}
+	reg := templates.Defaults()
+	if err := loadTemplates(ctx, st, reg); err != nil {
+		log.Printf("load templates: %v", err)
+	}
+	go runJobs(ctx, st, reg, receiptRetentionFromEnv())
+	// NOTIFY_SERVICE_TOKENS (comma-separated) lists the credentials producers
+	// send as X-Service-Token; unset, the service routes reject everything.
+	services := servicetoken.FromEnv("NOTIFY_SERVICE_TOKENS")
+	if !services.Configured() {
+		log.Printf("NOTIFY_SERVICE_TOKENS not set; /notify, /events and /templates are closed")
+	}
+	return newRouter(&notify.Dispatcher{Store: st, Templates: reg, Senders: sendersFromEnv()}, services)
+}
+
+func newRouter(d *notify.Dispatcher, services *servicetoken.Verifier) http.Handler {
+	impl := &serverImpl{st: d.Store, d: d, services: services}
+	r := chi.NewRouter()
+	r.Get("/healthz", impl.GetHealthz)
+	r.Get("/readyz", impl.GetReadyz)
+	// devices + preferences (the user's own, by bearer token)
+	r.Post("/devices/register", impl.PostDevicesRegister)
+	r.Get("/users/{id}/devices", func(w http.ResponseWriter, r *http.Request) {
+		if id := chi.URLParam(r, "id"); requireUser(w, r, id, false) {
+			impl.GetUsersIdDevices(w, r, id)
+		}
+	})
+	r.Delete("/users/{id}/devices/{deviceId}", func(w http.ResponseWriter, r *http.Request) {
+		if id := chi.URLParam(r, "id"); requireUser(w, r, id, true) {
+			impl.DeleteUsersIdDevicesId(w, r, id, chi.URLParam(r, "deviceId"))
+		}
+	})
+	r.Get("/users/{id}/preferences", func(w http.ResponseWriter, r *http.Request) {
+		if id := chi.URLParam(r, "id"); requireUser(w, r, id, false) {
+			impl.GetUsersIdPreferences(w, r, id)
+		}
+	})
+	r.Put("/users/{id}/preferences", func(w http.ResponseWriter, r *http.Request) {
+		if id := chi.URLParam(r, "id"); requireUser(w, r, id, true) {
+			impl.PutUsersIdPreferences(w, r, id)
+		}
+	})
+	r.Post("/notifications/{id}/receipts", func(w http.ResponseWriter, r *http.Request) {
+		impl.PostNotificationsIdReceipts(w, r, chi.URLParam(r, "id"))
+	})
+	// templates and sending (other services, by service token)
+	r.Group(func(r chi.Router) {
+		r.Use(services.Require)
+		r.Get("/templates", impl.GetTemplates)
+		r.Put("/templates/{key}", func(w http.ResponseWriter, r *http.Request) {
+			impl.PutTemplatesKey(w, r, chi.URLParam(r, "key"))
+		})
+		r.Post("/notify", impl.PostNotify)
+		r.Post("/events", impl.PostEvents)
+		r.Get("/notifications/{id}/receipts", func(w http.ResponseWriter, r *http.Request) {
+			impl.GetNotificationsIdReceipts(w, r, chi.URLParam(r, "id"))
+		})
+	})
+	return r
+}
@@ -0,0 +1,226 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bytspot/services/notification-service/internal/notify"
+	"bytspot/services/notification-service/internal/provider"
+	"bytspot/services/notification-service/internal/store"
+	"bytspot/services/notification-service/internal/templates"
+	"bytspot/shared/jwtauth"
+	"bytspot/shared/servicetoken"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testServiceToken = "svc-test-token"
+
+func newTestRouter(now time.Time) (http.Handler, *provider.Fake) {
+	h, fake, _ := newTestRouterWithStore(now)
+	return h, fake
+}
+
+func newTestRouterWithStore(now time.Time) (http.Handler, *provider.Fake, *store.MemStore) {
+	fake := provider.NewFake()
+	st := store.NewMemStore()
+	d := &notify.Dispatcher{
+		Store:     st,
+		Templates: templates.Defaults(),
+		Senders:   map[store.Provider]provider.Sender{store.ProviderAPNs: fake, store.ProviderFCM: fake},
+		Now:       func() time.Time { return now },
+	}
+	return newRouter(d, servicetoken.New(testServiceToken)), fake, st
+}
+
+// credential authenticates a test request.
+type credential func(t *testing.T, req *http.Request)
+
+func asService(_ *testing.T, req *http.Request) {
+	req.Header.Set(servicetoken.Header, testServiceToken)
+}
+
+func asUser(sub string) credential {
+	return func(t *testing.T, req *http.Request) {
+		claims := jwtauth.Claims{Sub: sub, RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))}}
+		tok, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtauth.Secret())
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+tok)
+	}
+}
+
+func anonymous(*testing.T, *http.Request) {}
+
+func do(t *testing.T, h http.Handler, method, path string, cred credential, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	b, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	cred(t, req)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func register(t *testing.T, h http.Handler, user, device, platform, token string) {
+	t.Helper()
+	w := do(t, h, http.MethodPost, "/devices/register", asUser(user), map[string]string{"userId": user, "deviceId": device, "platform": platform, "token": token})
+	if w.Code != http.StatusOK {
+		t.Fatalf("register: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestValetEvent_FansOutToAllDevices(t *testing.T) {
+	h, fake := newTestRouter(time.Date(2026, 1, 10, 20, 0, 0, 0, time.UTC))
+	register(t, h, "u1", "phone", "ios", "apns-tok")
+	register(t, h, "u1", "tablet", "android", "fcm-tok")
+
+	w := do(t, h, http.MethodPost, "/events", asService, map[string]any{"type": "valet.status_changed", "userId": "u1", "data": map[string]any{"status": "ready"}})
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body.String())
+	}
+	msgs := fake.Messages()
+	if len(msgs) != 2 {
+		t.Fatalf("expected 2 pushes, got %d", len(msgs))
+	}
+	if msgs[0].Body != "Your vehicle: ready for pickup" {
+		t.Fatalf("unexpected body %q", msgs[0].Body)
+	}
+
+	var resp struct {
+		Items []notify.Outcome `json:"items"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	id := resp.Items[0].NotificationID
+	ack := do(t, h, http.MethodPost, "/notifications/"+id+"/receipts", asUser("u1"), map[string]string{"deviceId": "phone", "status": "opened"})
+	if ack.Code != http.StatusNoContent {
+		t.Fatalf("ack: expected 204, got %d", ack.Code)
+	}
+	rc := do(t, h, http.MethodGet, "/notifications/"+id+"/receipts", asService, nil)
+	if !bytes.Contains(rc.Body.Bytes(), []byte(`"status":"opened"`)) {
+		t.Fatalf("expected opened receipt, got %s", rc.Body.String())
+	}
+}
+
+func TestQuietHoursAndOptOut_Suppress(t *testing.T) {
+	// 23:30 UTC falls inside a 22:00-07:00 window
+	h, fake := newTestRouter(time.Date(2026, 1, 10, 23, 30, 0, 0, time.UTC))
+	register(t, h, "u2", "phone", "ios", "tok2")
+	w := do(t, h, http.MethodPut, "/users/u2/preferences", asUser("u2"), map[string]any{
+		"quietHours": map[string]string{"start": "22:00", "end": "07:00", "timezone": "UTC"},
+		"optOuts":    map[string]bool{"plans": true},
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("prefs: expected 200, got %d", w.Code)
+	}
+
+	presence := do(t, h, http.MethodPost, "/events", asService, map[string]any{"type": "friend.presence", "recipients": []string{"u2"}, "data": map[string]any{"friendName": "Sam", "venueName": "Neon"}})
+	if !bytes.Contains(presence.Body.Bytes(), []byte("quiet_hours")) {
+		t.Fatalf("expected quiet_hours suppression, got %s", presence.Body.String())
+	}
+	plan := do(t, h, http.MethodPost, "/events", asService, map[string]any{"type": "plan.result", "recipients": []string{"u2"}, "data": map[string]any{"planName": "Fri", "venueName": "Neon"}})
+	if !bytes.Contains(plan.Body.Bytes(), []byte("opted_out")) {
+		t.Fatalf("expected opted_out suppression, got %s", plan.Body.String())
+	}
+	// valet updates are urgent and bypass quiet hours
+	do(t, h, http.MethodPost, "/events", asService, map[string]any{"type": "valet.status_changed", "userId": "u2", "data": map[string]any{"status": "parked"}})
+	if len(fake.Messages()) != 1 {
+		t.Fatalf("expected only the urgent valet push, got %d", len(fake.Messages()))
+	}
+}
+
+func TestUnregisteredToken_IsForgotten(t *testing.T) {
+	h, fake := newTestRouter(time.Now())
+	register(t, h, "u3", "old", "android", "dead")
+	fake.Unregistered["dead"] = true
+	do(t, h, http.MethodPost, "/notify", asService, map[string]any{"userId": "u3", "template": "plan.result", "data": map[string]any{"planName": "Sat", "venueName": "Loft"}})
+	w := do(t, h, http.MethodGet, "/users/u3/devices", asUser("u3"), nil)
+	if bytes.Contains(w.Body.Bytes(), []byte("dead")) {
+		t.Fatalf("expected unregistered token to be removed, got %s", w.Body.String())
+	}
+}
+
+func TestAccess_UsersOwnDataAndServiceCredential(t *testing.T) {
+	h, fake := newTestRouter(time.Now())
+	register(t, h, "u4", "phone", "ios", "tok4")
+
+	for _, c := range []struct {
+		name         string
+		method, path string
+		cred         credential
+		body         any
+		want         int
+	}{
+		{"anonymous device list", http.MethodGet, "/users/u4/devices", anonymous, nil, http.StatusUnauthorized},
+		{"another user's devices", http.MethodGet, "/users/u4/devices", asUser("u5"), nil, http.StatusForbidden},
+		{"another user's preferences", http.MethodPut, "/users/u4/preferences", asUser("u5"), map[string]any{}, http.StatusForbidden},
+		{"register for someone else", http.MethodPost, "/devices/register", asUser("u5"), map[string]string{"userId": "u4", "deviceId": "x", "platform": "ios", "token": "t"}, http.StatusForbidden},
+		{"user sending pushes", http.MethodPost, "/notify", asUser("u4"), map[string]any{"userId": "u4", "template": "plan.result"}, http.StatusUnauthorized},
+		{"anonymous events", http.MethodPost, "/events", anonymous, map[string]any{"type": "friend.presence", "recipients": []string{"u4"}}, http.StatusUnauthorized},
+		{"rewrite template", http.MethodPut, "/templates/plan.result", asUser("u4"), map[string]any{"title": "x", "body": "y"}, http.StatusUnauthorized},
+		{"own devices", http.MethodGet, "/users/u4/devices", asUser("u4"), nil, http.StatusOK},
+		{"service templates", http.MethodGet, "/templates", asService, nil, http.StatusOK},
+	} {
+		if w := do(t, h, c.method, c.path, c.cred, c.body); w.Code != c.want {
+			t.Fatalf("%s: expected %d, got %d: %s", c.name, c.want, w.Code, w.Body.String())
+		}
+	}
+	if len(fake.Messages()) != 0 {
+		t.Fatalf("expected no pushes, got %d", len(fake.Messages()))
+	}
+
+	// With no tokens configured the service routes are closed
+	d := &notify.Dispatcher{Store: store.NewMemStore(), Templates: templates.Defaults()}
+	closed := newRouter(d, servicetoken.New())
+	if w := do(t, closed, http.MethodPost, "/notify", asService, map[string]any{"userId": "u4", "template": "plan.result"}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("unconfigured: expected 401, got %d", w.Code)
+	}
+
+	// Receipts are acknowledged only by their recipient
+	w := do(t, h, http.MethodPost, "/events", asService, map[string]any{"type": "valet.status_changed", "userId": "u4", "data": map[string]any{"status": "ready"}})
+	var resp struct {
+		Items []notify.Outcome `json:"items"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	path := "/notifications/" + resp.Items[0].NotificationID + "/receipts"
+	if w := do(t, h, http.MethodPost, path, asUser("u5"), map[string]string{"deviceId": "phone", "status": "opened"}); w.Code != http.StatusNotFound {
+		t.Fatalf("ack by another user: expected 404, got %d", w.Code)
+	}
+}
+
+func TestTemplatesAndReceipts_Persisted(t *testing.T) {
+	h, _, st := newTestRouterWithStore(time.Now())
+	ctx := context.Background()
+	tmpl := map[string]any{"category": "plans", "title": "{{.planName}} is on", "body": "See you at {{.venueName}}"}
+	if w := do(t, h, http.MethodPut, "/templates/plan.result", asService, tmpl); w.Code != http.StatusNoContent {
+		t.Fatalf("put template: %d %s", w.Code, w.Body.String())
+	}
+	// another instance starting from the defaults picks the override up
+	reg := templates.Defaults()
+	if err := loadTemplates(ctx, st, reg); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := reg.Get("plan.result"); got.Title != "{{.planName}} is on" {
+		t.Fatalf("stored template not loaded: %+v", got)
+	}
+
+	w := do(t, h, http.MethodPost, "/notify", asService, map[string]any{"userId": "u6", "template": "plan.result", "data": map[string]any{"planName": "Fri", "venueName": "Neon"}})
+	var out notify.Outcome
+	_ = json.Unmarshal(w.Body.Bytes(), &out)
+	if n, _ := st.PurgeReceipts(ctx, time.Now().Add(-time.Hour)); n != 0 {
+		t.Fatalf("purged %d recent receipts", n)
+	}
+	if n, _ := st.PurgeReceipts(ctx, time.Now().Add(time.Second)); n != 1 {
+		t.Fatalf("expected the receipt purged, got %d", n)
+	}
+	if w := do(t, h, http.MethodGet, "/notifications/"+out.NotificationID+"/receipts", asService, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("purged receipts: expected 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,218 @@
+package store
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"bytspot/services/notification-service/internal/templates"
+)
+
+// MemStore is an in-memory Repository for tests and DB-less local runs.
+type MemStore struct {
+	mu        sync.RWMutex
+	seq       int
+	devices   map[string]*Device // key: userID|deviceID
+	prefs     map[string]*Preferences
+	receipts  map[string]*Receipt
+	byNotif   map[string][]string
+	templates map[string]templates.Template
+}
+
+func NewMemStore() *MemStore {
+	return &MemStore{
+		devices:   map[string]*Device{},
+		prefs:     map[string]*Preferences{},
+		receipts:  map[string]*Receipt{},
+		byNotif:   map[string][]string{},
+		templates: map[string]templates.Template{},
+	}
+}
+
+func (s *MemStore) nextID(prefix string) string {
+	s.seq++
+	return prefix + time.Now().Format("20060102150405") + "-" + strconv.Itoa(s.seq)
+}
+
+// NextNotificationID allocates an id shared by all receipts of one send.
+func (s *MemStore) NextNotificationID(context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.nextID("n"), nil
+}
+
+// UpsertDevice registers or refreshes the token for (userID, deviceID).
+func (s *MemStore) UpsertDevice(_ context.Context, d *Device) (*Device, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := d.UserID + "|" + d.DeviceID
+	// A token moving to another account (re-login on a shared device) must
+	// stop receiving the previous user's notifications.
+	for k, cur := range s.devices {
+		if k != key && cur.Token == d.Token && cur.Provider == d.Provider {
+			delete(s.devices, k)
+		}
+	}
+	now := time.Now()
+	if cur, ok := s.devices[key]; ok {
+		cur.Platform = d.Platform
+		cur.Provider = d.Provider
+		cur.Token = d.Token
+		cur.UpdatedAt = now
+		cp := *cur
+		return &cp, nil
+	}
+	d.ID = s.nextID("d")
+	d.CreatedAt = now
+	d.UpdatedAt = now
+	cp := *d
+	s.devices[key] = &cp
+	return d, nil
+}
+
+func (s *MemStore) DeleteDevice(_ context.Context, userID, deviceID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := userID + "|" + deviceID
+	if _, ok := s.devices[key]; !ok {
+		return false, nil
+	}
+	delete(s.devices, key)
+	return true, nil
+}
+
+// DeleteToken drops a token the provider reported as no longer valid.
+func (s *MemStore) DeleteToken(_ context.Context, provider Provider, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, d := range s.devices {
+		if d.Provider == provider && d.Token == token {
+			delete(s.devices, k)
+		}
+	}
+	return nil
+}
+
+func (s *MemStore) DevicesForUser(_ context.Context, userID string) ([]Device, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []Device
+	for _, d := range s.devices {
+		if d.UserID == userID {
+			out = append(out, *d)
+		}
+	}
+	return out, nil
+}
+
+func (s *MemStore) GetPreferences(_ context.Context, userID string) (Preferences, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if p, ok := s.prefs[userID]; ok {
+		cp := *p
+		cp.OptOuts = make(map[string]bool, len(p.OptOuts))
+		for k, v := range p.OptOuts {
+			cp.OptOuts[k] = v
+		}
+		return cp, nil
+	}
+	return Preferences{UserID: userID, OptOuts: map[string]bool{}}, nil
+}
+
+func (s *MemStore) PutPreferences(_ context.Context, p Preferences) (Preferences, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p.OptOuts == nil {
+		p.OptOuts = map[string]bool{}
+	}
+	p.UpdatedAt = time.Now()
+	s.prefs[p.UserID] = &p
+	return p, nil
+}
+
+func (s *MemStore) AddReceipt(_ context.Context, rc *Receipt) (*Receipt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rc.ID = s.nextID("rc")
+	now := time.Now()
+	rc.CreatedAt = now
+	rc.UpdatedAt = now
+	cp := *rc
+	s.receipts[rc.ID] = &cp
+	s.byNotif[rc.NotificationID] = append(s.byNotif[rc.NotificationID], rc.ID)
+	return rc, nil
+}
+
+// AckReceipt records a client-side delivery/open acknowledgement for the
+// receipt of notificationID on userID's deviceID.
+func (s *MemStore) AckReceipt(_ context.Context, notificationID, userID, deviceID string, status ReceiptStatus) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range s.byNotif[notificationID] {
+		rc := s.receipts[id]
+		if rc.UserID != userID || rc.DeviceID != deviceID {
+			continue
+		}
+		// never downgrade: opened implies delivered
+		if rc.Status == ReceiptOpened && status == ReceiptDelivered {
+			return true, nil
+		}
+		rc.Status = status
+		rc.UpdatedAt = time.Now()
+		return true, nil
+	}
+	return false, nil
+}
+
+func (s *MemStore) Receipts(_ context.Context, notificationID string) ([]Receipt, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ids := s.byNotif[notificationID]
+	out := make([]Receipt, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, *s.receipts[id])
+	}
+	return out, nil
+}
+
+// PurgeReceipts deletes receipts created before before.
+func (s *MemStore) PurgeReceipts(_ context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for nid, ids := range s.byNotif {
+		kept := ids[:0]
+		for _, id := range ids {
+			if s.receipts[id].CreatedAt.Before(before) {
+				delete(s.receipts, id)
+				n++
+				continue
+			}
+			kept = append(kept, id)
+		}
+		if len(kept) == 0 {
+			delete(s.byNotif, nid)
+		} else {
+			s.byNotif[nid] = kept
+		}
+	}
+	return n, nil
+}
+
+func (s *MemStore) ListTemplates(context.Context) ([]templates.Template, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]templates.Template, 0, len(s.templates))
+	for _, t := range s.templates {
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+func (s *MemStore) PutTemplate(_ context.Context, t templates.Template) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.templates[t.Key] = t
+	return nil
+}
@@ -0,0 +1,226 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"bytspot/services/notification-service/internal/templates"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Store is the Postgres Repository.
+type Store struct {
+	Pool *pgxpool.Pool
+}
+
+// New connects to DATABASE_URL.
+func New(ctx context.Context) (*Store, error) {
+	url := os.Getenv("DATABASE_URL")
+	if url == "" {
+		return nil, fmt.Errorf("DATABASE_URL not set")
+	}
+	cfg, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{Pool: pool}, nil
+}
+
+func (s *Store) Close() { s.Pool.Close() }
+
+// NextNotificationID needs no round trip: notification ids only group
+// receipts.
+func (s *Store) NextNotificationID(context.Context) (string, error) { return newID(), nil }
+
+const deviceColumns = `id, user_id, device_id, platform, provider, token, created_at, updated_at`
+
+func scanDevice(row pgx.Row) (*Device, error) {
+	var d Device
+	if err := row.Scan(&d.ID, &d.UserID, &d.DeviceID, &d.Platform, &d.Provider, &d.Token, &d.CreatedAt, &d.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// UpsertDevice registers or refreshes the token for (userID, deviceID). A
+// token moving to another account (re-login on a shared device) is taken
+// from its previous row in the same transaction.
+func (s *Store) UpsertDevice(ctx context.Context, d *Device) (*Device, error) {
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `DELETE FROM push_devices WHERE provider=$1 AND token=$2 AND NOT (user_id=$3 AND device_id=$4)`,
+		d.Provider, d.Token, d.UserID, d.DeviceID); err != nil {
+		return nil, err
+	}
+	q := `INSERT INTO push_devices (user_id, device_id, platform, provider, token) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, device_id) DO UPDATE
+		SET platform = EXCLUDED.platform, provider = EXCLUDED.provider, token = EXCLUDED.token, updated_at = NOW()
+		RETURNING ` + deviceColumns
+	out, err := scanDevice(tx.QueryRow(ctx, q, d.UserID, d.DeviceID, d.Platform, d.Provider, d.Token))
+	if err != nil {
+		return nil, err
+	}
+	return out, tx.Commit(ctx)
+}
+
+func (s *Store) DeleteDevice(ctx context.Context, userID, deviceID string) (bool, error) {
+	tag, err := s.Pool.Exec(ctx, `DELETE FROM push_devices WHERE user_id=$1 AND device_id=$2`, userID, deviceID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// DeleteToken drops a token the provider reported as no longer valid.
+func (s *Store) DeleteToken(ctx context.Context, provider Provider, token string) error {
+	_, err := s.Pool.Exec(ctx, `DELETE FROM push_devices WHERE provider=$1 AND token=$2`, provider, token)
+	return err
+}
+
+func (s *Store) DevicesForUser(ctx context.Context, userID string) ([]Device, error) {
+	rows, err := s.Pool.Query(ctx, `SELECT `+deviceColumns+` FROM push_devices WHERE user_id=$1 ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Device
+	for rows.Next() {
+		d, err := scanDevice(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *d)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) GetPreferences(ctx context.Context, userID string) (Preferences, error) {
+	p := Preferences{UserID: userID, OptOuts: map[string]bool{}}
+	var quiet, optOuts []byte
+	err := s.Pool.QueryRow(ctx, `SELECT quiet_hours, opt_outs, updated_at FROM notification_preferences WHERE user_id=$1`, userID).
+		Scan(&quiet, &optOuts, &p.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return p, nil
+	}
+	if err != nil {
+		return Preferences{}, err
+	}
+	if len(quiet) > 0 && string(quiet) != "null" {
+		p.QuietHours = &QuietHours{}
+		if err := json.Unmarshal(quiet, p.QuietHours); err != nil {
+			return Preferences{}, err
+		}
+	}
+	if err := json.Unmarshal(optOuts, &p.OptOuts); err != nil {
+		return Preferences{}, err
+	}
+	return p, nil
+}
+
+func (s *Store) PutPreferences(ctx context.Context, p Preferences) (Preferences, error) {
+	if p.OptOuts == nil {
+		p.OptOuts = map[string]bool{}
+	}
+	var quiet []byte
+	if p.QuietHours != nil {
+		quiet, _ = json.Marshal(p.QuietHours)
+	}
+	optOuts, _ := json.Marshal(p.OptOuts)
+	q := `INSERT INTO notification_preferences (user_id, quiet_hours, opt_outs) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET quiet_hours = EXCLUDED.quiet_hours, opt_outs = EXCLUDED.opt_outs, updated_at = NOW()
+		RETURNING updated_at`
+	if err := s.Pool.QueryRow(ctx, q, p.UserID, quiet, optOuts).Scan(&p.UpdatedAt); err != nil {
+		return Preferences{}, err
+	}
+	return p, nil
+}
+
+const receiptColumns = `id, notification_id, user_id, device_id, provider, status, reason, provider_ref, created_at, updated_at`
+
+func (s *Store) AddReceipt(ctx context.Context, rc *Receipt) (*Receipt, error) {
+	q := `INSERT INTO notification_receipts (notification_id, user_id, device_id, provider, status, reason, provider_ref)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at, updated_at`
+	if err := s.Pool.QueryRow(ctx, q, rc.NotificationID, rc.UserID, rc.DeviceID, rc.Provider, rc.Status, rc.Reason, rc.ProviderRef).
+		Scan(&rc.ID, &rc.CreatedAt, &rc.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return rc, nil
+}
+
+// AckReceipt records a client-side delivery/open acknowledgement for the
+// receipt of notificationID on userID's deviceID. opened is never
+// downgraded to delivered.
+func (s *Store) AckReceipt(ctx context.Context, notificationID, userID, deviceID string, status ReceiptStatus) (bool, error) {
+	q := `UPDATE notification_receipts
+		SET status = CASE WHEN status = 'opened' AND $4::text = 'delivered' THEN status ELSE $4::text END, updated_at = NOW()
+		WHERE notification_id=$1 AND user_id=$2 AND device_id=$3`
+	tag, err := s.Pool.Exec(ctx, q, notificationID, userID, deviceID, status)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (s *Store) Receipts(ctx context.Context, notificationID string) ([]Receipt, error) {
+	rows, err := s.Pool.Query(ctx, `SELECT `+receiptColumns+` FROM notification_receipts WHERE notification_id=$1 ORDER BY created_at, id`, notificationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Receipt
+	for rows.Next() {
+		var rc Receipt
+		if err := rows.Scan(&rc.ID, &rc.NotificationID, &rc.UserID, &rc.DeviceID, &rc.Provider, &rc.Status, &rc.Reason, &rc.ProviderRef, &rc.CreatedAt, &rc.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, rc)
+	}
+	return out, rows.Err()
+}
+
+// PurgeReceipts deletes receipts created before before.
+func (s *Store) PurgeReceipts(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := s.Pool.Exec(ctx, `DELETE FROM notification_receipts WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (s *Store) ListTemplates(ctx context.Context) ([]templates.Template, error) {
+	rows, err := s.Pool.Query(ctx, `SELECT key, category, title, body, urgent FROM notification_templates ORDER BY key`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []templates.Template
+	for rows.Next() {
+		var t templates.Template
+		if err := rows.Scan(&t.Key, &t.Category, &t.Title, &t.Body, &t.Urgent); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) PutTemplate(ctx context.Context, t templates.Template) error {
+	q := `INSERT INTO notification_templates (key, category, title, body, urgent) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (key) DO UPDATE
+		SET category = EXCLUDED.category, title = EXCLUDED.title, body = EXCLUDED.body, urgent = EXCLUDED.urgent, updated_at = NOW()`
+	_, err := s.Pool.Exec(ctx, q, t.Key, t.Category, t.Title, t.Body, t.Urgent)
+	return err
+}
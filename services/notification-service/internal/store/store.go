@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"bytspot/services/notification-service/internal/templates"
+)
+
+type Provider string
+
+const (
+	ProviderAPNs Provider = "apns"
+	ProviderFCM  Provider = "fcm"
+)
+
+type Platform string
+
+const (
+	PlatformIOS     Platform = "ios"
+	PlatformAndroid Platform = "android"
+)
+
+// Device is one push token registered by a user's app install.
+type Device struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"userId"`
+	DeviceID  string    `json:"deviceId"`
+	Platform  Platform  `json:"platform"`
+	Provider  Provider  `json:"provider"`
+	Token     string    `json:"token"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// QuietHours is a daily local-time window ("22:00"-"07:00") during which
+// non-urgent notifications are suppressed.
+type QuietHours struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Timezone string `json:"timezone"`
+}
+
+type Preferences struct {
+	UserID     string          `json:"userId"`
+	QuietHours *QuietHours     `json:"quietHours,omitempty"`
+	OptOuts    map[string]bool `json:"optOuts"`
+	UpdatedAt  time.Time       `json:"updatedAt"`
+}
+
+type ReceiptStatus string
+
+const (
+	ReceiptSent       ReceiptStatus = "sent"
+	ReceiptFailed     ReceiptStatus = "failed"
+	ReceiptSuppressed ReceiptStatus = "suppressed"
+	ReceiptDelivered  ReceiptStatus = "delivered"
+	ReceiptOpened     ReceiptStatus = "opened"
+)
+
+// Receipt tracks the outcome of one notification on one device (or the
+// reason it was never sent).
+type Receipt struct {
+	ID             string        `json:"id"`
+	NotificationID string        `json:"notificationId"`
+	UserID         string        `json:"userId"`
+	DeviceID       string        `json:"deviceId,omitempty"`
+	Provider       Provider      `json:"provider,omitempty"`
+	Status         ReceiptStatus `json:"status"`
+	Reason         string        `json:"reason,omitempty"`
+	ProviderRef    string        `json:"providerRef,omitempty"`
+	CreatedAt      time.Time     `json:"createdAt"`
+	UpdatedAt      time.Time     `json:"updatedAt"`
+}
+
+// Repository is what the dispatcher and handlers need from storage. *Store
+// implements it on Postgres; *MemStore in memory for tests and local dev.
+type Repository interface {
+	NextNotificationID(ctx context.Context) (string, error)
+
+	UpsertDevice(ctx context.Context, d *Device) (*Device, error)
+	DeleteDevice(ctx context.Context, userID, deviceID string) (bool, error)
+	DeleteToken(ctx context.Context, provider Provider, token string) error
+	DevicesForUser(ctx context.Context, userID string) ([]Device, error)
+
+	GetPreferences(ctx context.Context, userID string) (Preferences, error)
+	PutPreferences(ctx context.Context, p Preferences) (Preferences, error)
+
+	AddReceipt(ctx context.Context, rc *Receipt) (*Receipt, error)
+	AckReceipt(ctx context.Context, notificationID, userID, deviceID string, status ReceiptStatus) (bool, error)
+	Receipts(ctx context.Context, notificationID string) ([]Receipt, error)
+	PurgeReceipts(ctx context.Context, before time.Time) (int64, error)
+
+	ListTemplates(ctx context.Context) ([]templates.Template, error)
+	PutTemplate(ctx context.Context, t templates.Template) error
+}
+
+var (
+	_ Repository = (*Store)(nil)
+	_ Repository = (*MemStore)(nil)
+)
+
+// newID returns a random UUIDv4-formatted id like gen_random_uuid().
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
@@ -0,0 +1,135 @@
+package templates
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// Template is a named, parameterised notification. Category is what users
+// opt out of; Urgent notifications ignore quiet hours.
+type Template struct {
+	Key      string `json:"key"`
+	Category string `json:"category"`
+	Title    string `json:"title"`
+	Body     string `json:"body"`
+	Urgent   bool   `json:"urgent"`
+}
+
+type Rendered struct {
+	Title    string
+	Body     string
+	Category string
+	Urgent   bool
+}
+
+var ErrUnknownTemplate = errors.New("unknown template")
+
+type compiled struct {
+	def   Template
+	title *template.Template
+	body  *template.Template
+}
+
+type Registry struct {
+	mu    sync.RWMutex
+	items map[string]*compiled
+}
+
+func NewRegistry() *Registry { return &Registry{items: map[string]*compiled{}} }
+
+// Defaults returns a registry preloaded with the templates used by the
+// built-in event triggers.
+func Defaults() *Registry {
+	r := NewRegistry()
+	for _, t := range []Template{
+		{Key: "valet.status_changed", Category: "valet", Urgent: true,
+			Title: "Valet update", Body: "{{.vehicle}}: {{.statusLabel}}"},
+		{Key: "plan.result", Category: "plans",
+			Title: "{{.planName}} is decided", Body: "Your group picked {{.venueName}}"},
+		{Key: "friend.presence", Category: "friends",
+			Title: "{{.friendName}} is out", Body: "{{.friendName}} just checked in at {{.venueName}}"},
+		{Key: "achievement.earned", Category: "achievements",
+			Title: "Achievement unlocked", Body: "You earned {{.title}}: {{.description}}"},
+	} {
+		if err := r.Put(t); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+// Validate checks that t has the required fields and parses.
+func Validate(t Template) error {
+	_, err := compile(t)
+	return err
+}
+
+func compile(t Template) (*compiled, error) {
+	if t.Key == "" || t.Category == "" || t.Title == "" {
+		return nil, errors.New("template key, category and title are required")
+	}
+	title, err := template.New(t.Key + ".title").Option("missingkey=error").Parse(t.Title)
+	if err != nil {
+		return nil, err
+	}
+	body, err := template.New(t.Key + ".body").Option("missingkey=error").Parse(t.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &compiled{def: t, title: title, body: body}, nil
+}
+
+// Put adds or replaces a template after checking it parses.
+func (r *Registry) Put(t Template) error {
+	c, err := compile(t)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items[t.Key] = c
+	return nil
+}
+
+func (r *Registry) Get(key string) (Template, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.items[key]
+	if !ok {
+		return Template{}, false
+	}
+	return c.def, true
+}
+
+func (r *Registry) List() []Template {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]Template, 0, len(r.items))
+	for _, c := range r.items {
+		out = append(out, c.def)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// Render executes the template with data; missing variables are an error so
+// users never see "<no value>".
+func (r *Registry) Render(key string, data map[string]any) (Rendered, error) {
+	r.mu.RLock()
+	c, ok := r.items[key]
+	r.mu.RUnlock()
+	if !ok {
+		return Rendered{}, ErrUnknownTemplate
+	}
+	var title, body strings.Builder
+	if err := c.title.Execute(&title, data); err != nil {
+		return Rendered{}, err
+	}
+	if err := c.body.Execute(&body, data); err != nil {
+		return Rendered{}, err
+	}
+	return Rendered{Title: title.String(), Body: body.String(), Category: c.def.Category, Urgent: c.def.Urgent}, nil
+}
@@ -0,0 +1,61 @@
+-- +goose Up
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+-- Push tokens, one per (user, app install). A token belongs to one device
+-- row at a time: re-login on a shared phone moves it.
+CREATE TABLE IF NOT EXISTS push_devices (
+    id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::text,
+    user_id TEXT NOT NULL,
+    device_id TEXT NOT NULL,
+    platform TEXT NOT NULL DEFAULT '',
+    provider TEXT NOT NULL CHECK (provider IN ('apns','fcm')),
+    token TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, device_id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_push_devices_provider_token ON push_devices (provider, token);
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id TEXT PRIMARY KEY,
+    quiet_hours JSONB,
+    opt_outs JSONB NOT NULL DEFAULT '{}'::jsonb,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- One row per device a notification went to, or one suppressed row when it
+-- went nowhere. Purged after NOTIFY_RECEIPT_RETENTION.
+CREATE TABLE IF NOT EXISTS notification_receipts (
+    id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::text,
+    notification_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    device_id TEXT NOT NULL DEFAULT '',
+    provider TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL CHECK (status IN ('sent','failed','suppressed','delivered','opened')),
+    reason TEXT NOT NULL DEFAULT '',
+    provider_ref TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_notification_receipts_notification_id ON notification_receipts (notification_id);
+CREATE INDEX IF NOT EXISTS idx_notification_receipts_created_at ON notification_receipts (created_at);
+
+-- Templates changed through PUT /templates/{key}; built-in defaults are not
+-- stored until overridden.
+CREATE TABLE IF NOT EXISTS notification_templates (
+    key TEXT PRIMARY KEY,
+    category TEXT NOT NULL,
+    title TEXT NOT NULL,
+    body TEXT NOT NULL DEFAULT '',
+    urgent BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS notification_templates;
+DROP INDEX IF EXISTS idx_notification_receipts_created_at;
+DROP INDEX IF EXISTS idx_notification_receipts_notification_id;
+DROP TABLE IF EXISTS notification_receipts;
+DROP TABLE IF EXISTS notification_preferences;
+DROP INDEX IF EXISTS uq_push_devices_provider_token;
+DROP TABLE IF EXISTS push_devices;
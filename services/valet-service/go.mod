@@ -2,4 +2,10 @@ module bytspot/services/valet-service
 
 go 1.22.5
 
-require github.com/go-chi/chi/v5 v5.0.12
+require (
+	bytspot/shared v0.0.0
+	github.com/go-chi/chi/v5 v5.0.12
+	github.com/golang-jwt/jwt/v5 v5.2.1
+)
+
+replace bytspot/shared => ../../shared
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"bytspot/services/valet-service/internal/store"
+	"bytspot/shared/servicetoken"
+)
+
+// achievementPublisher reports completed valet uses to venue-service, which
+// awards the valet achievements.
+type achievementPublisher interface {
+	ValetUsed(t store.Ticket)
+}
+
+type noopPublisher struct{}
+
+func (noopPublisher) ValetUsed(store.Ticket) {}
+
+// httpPublisher posts valet_use events to venue-service's
+// /achievements/events. The ticket id is the event id, so a ticket marked
+// retrieved twice counts once.
+type httpPublisher struct {
+	url    string
+	token  string // X-Service-Token, one of venue-service's ACHIEVEMENT_SERVICE_TOKENS
+	client *http.Client
+}
+
+// achievementsFromEnv publishes when VENUE_SERVICE_URL is set, with
+// ACHIEVEMENT_SERVICE_TOKEN as the service credential.
+func achievementsFromEnv() achievementPublisher {
+	base := os.Getenv("VENUE_SERVICE_URL")
+	if base == "" {
+		return noopPublisher{}
+	}
+	token := os.Getenv("ACHIEVEMENT_SERVICE_TOKEN")
+	if token == "" {
+		log.Printf("ACHIEVEMENT_SERVICE_TOKEN not set; venue-service will reject valet achievements")
+	}
+	return &httpPublisher{url: strings.TrimRight(base, "/") + "/achievements/events", token: token, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// ValetUsed is fire-and-forget like StatusChanged.
+func (p *httpPublisher) ValetUsed(t store.Ticket) {
+	b, _ := json.Marshal(map[string]any{"events": []map[string]any{{
+		"id":         t.ID,
+		"userId":     t.UserID,
+		"type":       "valet_use",
+		"occurredAt": t.UpdatedAt.UTC().Format(time.RFC3339),
+	}}})
+	req, err := http.NewRequest(http.MethodPost, p.url, bytes.NewReader(b))
+	if err != nil {
+		log.Printf("publish valet use: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	servicetoken.Set(req, p.token)
+	go func() {
+		resp, err := p.client.Do(req)
+		if err != nil {
+			log.Printf("publish valet use: %v", err)
+			return
+		}
+		resp.Body.Close()
+	}()
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"bytspot/shared/jwtauth"
+)
+
+type claimsKey struct{}
+
+// fullAccess guards writes: they need a valid bearer token (the BFF forwards
+// the user's) that is not an impersonation token. Impersonation lets support
+// staff see what the user sees, not act for them.
+func fullAccess(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if auth == "" {
+			writeError(w, http.StatusUnauthorized, "missing token")
+			return
+		}
+		claims, err := jwtauth.Verify(strings.TrimPrefix(auth, "Bearer "))
+		switch {
+		case err != nil || !strings.HasPrefix(auth, "Bearer "):
+			writeError(w, http.StatusUnauthorized, "invalid token")
+		case claims.Act != nil:
+			writeError(w, http.StatusForbidden, "not allowed while impersonating")
+		default:
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, claims)))
+		}
+	})
+}
+
+// caller is the token fullAccess verified for r.
+func caller(r *http.Request) *jwtauth.Claims {
+	claims, _ := r.Context().Value(claimsKey{}).(*jwtauth.Claims)
+	return claims
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"error": msg})
+}
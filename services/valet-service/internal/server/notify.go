@@ -0,0 +1,66 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"bytspot/services/valet-service/internal/store"
+	"bytspot/shared/servicetoken"
+)
+
+// statusNotifier publishes ticket status changes so the owner gets a push.
+type statusNotifier interface {
+	StatusChanged(t store.Ticket)
+}
+
+type noopNotifier struct{}
+
+func (noopNotifier) StatusChanged(store.Ticket) {}
+
+// httpNotifier posts valet.status_changed events to notification-service.
+type httpNotifier struct {
+	url    string
+	token  string // X-Service-Token, one of notification-service's NOTIFY_SERVICE_TOKENS
+	client *http.Client
+}
+
+func notifierFromEnv() statusNotifier {
+	base := os.Getenv("NOTIFY_SERVICE_URL")
+	if base == "" {
+		return noopNotifier{}
+	}
+	return &httpNotifier{url: strings.TrimRight(base, "/") + "/events", token: os.Getenv("NOTIFY_SERVICE_TOKEN"), client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// StatusChanged is fire-and-forget: a slow push backend must not hold up the valet app.
+func (n *httpNotifier) StatusChanged(t store.Ticket) {
+	vehicle := strings.TrimSpace(t.Vehicle.Make + " " + t.Vehicle.Model)
+	if vehicle == "" {
+		vehicle = "Your vehicle"
+	}
+	b, _ := json.Marshal(map[string]any{
+		"type":   "valet.status_changed",
+		"userId": t.UserID,
+		"data":   map[string]any{"ticketId": t.ID, "status": string(t.Status), "vehicle": vehicle},
+	})
+	req, err := http.NewRequest(http.MethodPost, n.url, bytes.NewReader(b))
+	if err != nil {
+		log.Printf("notify valet status: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	servicetoken.Set(req, n.token)
+	go func() {
+		resp, err := n.client.Do(req)
+		if err != nil {
+			log.Printf("notify valet status: %v", err)
+			return
+		}
+		resp.Body.Close()
+	}()
+}
@@ -9,7 +9,11 @@ import (
 	"github.com/go-chi/chi/v5"
 )
 
-type serverImpl struct{ st *store.Store }
+type serverImpl struct {
+	st           *store.Store
+	notifier     statusNotifier
+	achievements achievementPublisher
+}
 
 type intakeReq struct {
 	UserID   string                `json:"userId"`
@@ -42,15 +46,21 @@ func (s *serverImpl) GetValetTasks(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
-// Intake: optional services, photos, vehicle info
+// Intake: optional services, photos, vehicle info. The ticket belongs to
+// the caller; userId, if sent, must name them.
 func (s *serverImpl) PostValetIntake(w http.ResponseWriter, r *http.Request) {
 	var req intakeReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserID == "" {
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Vehicle.Make == "" || req.Vehicle.Model == "" {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]any{"error": "invalid payload"})
 		return
 	}
-	t := &store.Ticket{UserID: req.UserID, Vehicle: req.Vehicle, Spot: req.Spot, Services: req.Services, Photos: req.Photos, Status: store.StatusIntake}
+	owner := caller(r).Sub
+	if req.UserID != "" && req.UserID != owner {
+		writeError(w, http.StatusForbidden, "userId must match the token")
+		return
+	}
+	t := &store.Ticket{UserID: owner, Vehicle: req.Vehicle, Spot: req.Spot, Services: req.Services, Photos: req.Photos, Status: store.StatusIntake}
 	s.st.CreateTicket(t)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{"status": "intake_started", "ticket": t.ID})
@@ -67,6 +77,12 @@ func (s *serverImpl) PatchValetVehiclesIdStatus(w http.ResponseWriter, r *http.R
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
+	if t, ok := s.st.Get(id); ok {
+		s.notifier.StatusChanged(*t)
+		if t.Status == store.StatusRetrieved {
+			s.achievements.ValetUsed(*t)
+		}
+	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -77,16 +93,19 @@ func (s *serverImpl) PostValetRequests(w http.ResponseWriter, r *http.Request) {
 }
 
 func NewRouter() http.Handler {
-	impl := &serverImpl{st: store.New()}
+	impl := &serverImpl{st: store.New(), notifier: notifierFromEnv(), achievements: achievementsFromEnv()}
 	r := chi.NewRouter()
 	r.Get("/healthz", impl.GetHealthz)
 	r.Get("/readyz", impl.GetReadyz)
 	// core valet endpoints
 	r.Get("/valet/tasks", impl.GetValetTasks)
-	r.Post("/valet/intake", impl.PostValetIntake)
-	r.Patch("/valet/vehicles/{id}/status", func(w http.ResponseWriter, r *http.Request) {
-		impl.PatchValetVehiclesIdStatus(w, r, chi.URLParam(r, "id"))
+	r.Group(func(r chi.Router) {
+		r.Use(fullAccess)
+		r.Post("/valet/intake", impl.PostValetIntake)
+		r.Patch("/valet/vehicles/{id}/status", func(w http.ResponseWriter, r *http.Request) {
+			impl.PatchValetVehiclesIdStatus(w, r, chi.URLParam(r, "id"))
+		})
+		r.Post("/valet/requests", impl.PostValetRequests)
 	})
-	r.Post("/valet/requests", impl.PostValetRequests)
 	return r
 }
@@ -6,8 +6,19 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+
+	"bytspot/shared/jwtauth"
+
+	"github.com/golang-jwt/jwt/v5"
 )
 
+func sign(claims jwtauth.Claims) string {
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+	tok, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtauth.Secret())
+	return tok
+}
+
 func TestValetIntake_Valid(t *testing.T) {
 	h := NewRouter()
 	body := map[string]any{
@@ -20,6 +31,7 @@ func TestValetIntake_Valid(t *testing.T) {
 	b, _ := json.Marshal(body)
 	req := httptest.NewRequest(http.MethodPost, "/valet/intake", bytes.NewReader(b))
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+sign(jwtauth.Claims{Sub: "u1"}))
 	w := httptest.NewRecorder()
 	h.ServeHTTP(w, req)
 	if w.Code != http.StatusOK {
@@ -34,10 +46,11 @@ func TestValetIntake_Valid(t *testing.T) {
 
 func TestValetIntake_Invalid(t *testing.T) {
 	h := NewRouter()
-	body := map[string]any{"vehicle": map[string]string{"make": "A", "model": "B"}}
+	body := map[string]any{"userId": "u1", "vehicle": map[string]string{"plate": "XYZ"}}
 	b, _ := json.Marshal(body)
 	req := httptest.NewRequest(http.MethodPost, "/valet/intake", bytes.NewReader(b))
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+sign(jwtauth.Claims{Sub: "u1"}))
 	w := httptest.NewRecorder()
 	h.ServeHTTP(w, req)
 	if w.Code != http.StatusBadRequest {
@@ -47,6 +60,7 @@ func TestValetIntake_Invalid(t *testing.T) {
 
 func TestValetStatus_UpdateFlow(t *testing.T) {
 	h := NewRouter()
+	auth := "Bearer " + sign(jwtauth.Claims{Sub: "u2"})
 	// create ticket
 	intake := map[string]any{
 		"userId": "u2",
@@ -56,6 +70,7 @@ func TestValetStatus_UpdateFlow(t *testing.T) {
 	w := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodPost, "/valet/intake", bytes.NewReader(b))
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", auth)
 	h.ServeHTTP(w, req)
 	if w.Code != http.StatusOK { t.Fatalf("intake failed: %d", w.Code) }
 	var resp map[string]any
@@ -67,13 +82,41 @@ func TestValetStatus_UpdateFlow(t *testing.T) {
 	w2 := httptest.NewRecorder()
 	req2 := httptest.NewRequest(http.MethodPatch, "/valet/vehicles/"+id+"/status", bytes.NewReader(sb))
 	req2.Header.Set("Content-Type", "application/json")
+	req2.Header.Set("Authorization", auth)
 	h.ServeHTTP(w2, req2)
 	if w2.Code != http.StatusNoContent { t.Fatalf("expected 204, got %d: %s", w2.Code, w2.Body.String()) }
 	// bad id
 	w3 := httptest.NewRecorder()
 	req3 := httptest.NewRequest(http.MethodPatch, "/valet/vehicles/bad/status", bytes.NewReader(sb))
 	req3.Header.Set("Content-Type", "application/json")
+	req3.Header.Set("Authorization", auth)
 	h.ServeHTTP(w3, req3)
 	if w3.Code != http.StatusNotFound { t.Fatalf("expected 404, got %d", w3.Code) }
 }
 
+
+func TestValetWrites_RequireOwnerToken(t *testing.T) {
+	h := NewRouter()
+	impersonated := jwtauth.Claims{Sub: "u3"}
+	impersonated.Act = &struct {
+		Sub string `json:"sub"`
+	}{Sub: "support-1"}
+	body, _ := json.Marshal(map[string]any{"userId": "u3", "vehicle": map[string]string{"make": "Audi", "model": "A4"}})
+	for token, want := range map[string]int{
+		"":                              http.StatusUnauthorized,
+		sign(impersonated):              http.StatusForbidden,
+		"garbage":                       http.StatusUnauthorized,
+		sign(jwtauth.Claims{Sub: "u4"}): http.StatusForbidden, // someone else's ticket
+		sign(jwtauth.Claims{Sub: "u3"}): http.StatusOK,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/valet/intake", bytes.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Fatalf("expected %d, got %d: %s", want, w.Code, w.Body.String())
+		}
+	}
+}
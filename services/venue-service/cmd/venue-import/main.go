@@ -0,0 +1,80 @@
+// Command venue-import loads venues from a CSV or GeoJSON file straight
+// into the venue database (DATABASE_URL) and prints the import report as
+// JSON. Use -dry-run to see what would change first.
+//
+//	venue-import -dry-run sf-launch.csv
+//	venue-import -radius 50 -actor ops@bytspot.ai sf-launch.geojson
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"bytspot/services/venue-service/internal/db"
+	"bytspot/services/venue-service/internal/importer"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report the changes without writing")
+	format := flag.String("format", "", "csv or geojson (default: from the file extension)")
+	radius := flag.Float64("radius", importer.DefaultMatchRadius, "meters within which a same-named venue is a duplicate")
+	actor := flag.String("actor", os.Getenv("USER"), "name recorded in the venue audit history")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: venue-import [flags] FILE\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	path := flag.Arg(0)
+
+	f := importer.Format(*format)
+	if f == "" {
+		var ok bool
+		if f, ok = importer.FormatFor(path); !ok {
+			log.Fatalf("cannot tell the format of %s; pass -format", path)
+		}
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rows, err := importer.Parse(file, f)
+	file.Close()
+	if err != nil {
+		log.Fatalf("%s: %v", path, err)
+	}
+
+	ctx := context.Background()
+	store, err := db.New(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
+	report, err := importer.Run(ctx, store, rows, importer.Options{
+		DryRun:      *dryRun,
+		MatchRadius: *radius,
+		Audit: func(ctx context.Context, changes map[string]db.FieldChange, venueID string) {
+			a := &db.VenueAudit{VenueID: venueID, ActorID: *actor, ActorRole: "cli", Action: "import", Changes: changes}
+			if err := store.InsertVenueAudit(ctx, a); err != nil {
+				log.Printf("audit %s: %v", venueID, err)
+			}
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(report)
+	log.Printf("created %d, updated %d, skipped %d, errors %d (dry run: %t)", report.Created, report.Updated, report.Skipped, report.Errors, report.DryRun)
+	if report.Errors > 0 {
+		os.Exit(1)
+	}
+}
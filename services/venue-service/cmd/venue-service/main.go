@@ -5,11 +5,17 @@ import (
 	"log"
 	"net/http"
 	"os"
+	_ "time/tzdata" // opening hours are evaluated in each venue's IANA timezone
 
 	"bytspot/services/venue-service/internal/server"
 )
 
 func main() {
+	// Optionally run migrations at startup if DATABASE_URL present
+	if err := runMigrations(); err != nil {
+		log.Printf("migrations failed: %v", err)
+	}
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8092"
@@ -0,0 +1,354 @@
+// Package achieve evaluates declarative achievement definitions against a
+// user's history of domain events (check-ins, likes, vibe reports, valet
+// uses, plans). Definitions are data: which events count, optional
+// attribute and hour-of-day filters, and a rule that turns the matching
+// events into progress toward a threshold. Evaluation is a pure function of
+// the history, so replaying or re-delivering events is harmless.
+package achieve
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event types the catalog can refer to.
+const (
+	EventCheckin     = "checkin"
+	EventLike        = "like"
+	EventVibeReport  = "vibe_report"
+	EventValetUse    = "valet_use"
+	EventPlanCreated = "plan_created"
+)
+
+var eventTypes = map[string]bool{EventCheckin: true, EventLike: true, EventVibeReport: true, EventValetUse: true, EventPlanCreated: true}
+
+// KnownEvent reports whether t is an event type definitions may use.
+func KnownEvent(t string) bool { return eventTypes[t] }
+
+// Event is one domain event in a user's history. At carries the location
+// that hour filters and streak days are judged in (the venue's timezone for
+// venue events).
+type Event struct {
+	Type  string
+	At    time.Time
+	Attrs map[string]string // e.g. venueId, category
+}
+
+// Rule kinds.
+const (
+	// KindCount counts matching events; with Per, only the best single
+	// value of that attribute counts (e.g. visits to one venue).
+	KindCount = "count"
+	// KindDistinct counts distinct values of Attr.
+	KindDistinct = "distinct"
+	// KindStreak counts consecutive days with at least one matching event.
+	KindStreak = "streak"
+)
+
+// Duration is a time.Duration that unmarshals from "6h" or "7d".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		*d = Duration(time.Duration(n) * 24 * time.Hour)
+		return nil
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil || v <= 0 {
+		return fmt.Errorf("invalid duration %q", s)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Hours is a local hour-of-day range [From, To); it wraps past midnight
+// when To <= From.
+type Hours struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+func (h Hours) contains(hour int) bool {
+	if h.From < h.To {
+		return hour >= h.From && hour < h.To
+	}
+	return hour >= h.From || hour < h.To
+}
+
+// Rule says which events count and how they turn into progress.
+type Rule struct {
+	Kind      string            `json:"kind"`
+	Events    []string          `json:"events"`
+	Where     map[string]string `json:"where,omitempty"` // attribute equality filters
+	Hours     *Hours            `json:"hours,omitempty"`
+	Attr      string            `json:"attr,omitempty"`   // KindDistinct
+	Per       string            `json:"per,omitempty"`    // KindCount
+	Window    Duration          `json:"window,omitempty"` // count/distinct inside any window this long
+	Threshold int               `json:"threshold"`
+}
+
+// Definition is one achievement. Type and Rarity are display categories the
+// app already uses.
+type Definition struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Type        string `json:"type"`   // milestone, discovery, social, streak, special
+	Rarity      string `json:"rarity"` // common, rare, epic, legendary
+	Rule        Rule   `json:"rule"`
+}
+
+var (
+	types    = map[string]bool{"milestone": true, "discovery": true, "social": true, "streak": true, "special": true}
+	rarities = map[string]bool{"common": true, "rare": true, "epic": true, "legendary": true}
+)
+
+func (d Definition) validate() error {
+	r := d.Rule
+	switch {
+	case d.ID == "" || d.Title == "":
+		return errors.New("id and title are required")
+	case !types[d.Type]:
+		return fmt.Errorf("unknown type %q", d.Type)
+	case !rarities[d.Rarity]:
+		return fmt.Errorf("unknown rarity %q", d.Rarity)
+	case len(r.Events) == 0:
+		return errors.New("rule.events is required")
+	case r.Threshold < 1:
+		return errors.New("rule.threshold must be at least 1")
+	case r.Hours != nil && (r.Hours.From < 0 || r.Hours.From > 23 || r.Hours.To < 0 || r.Hours.To > 24 || r.Hours.From == r.Hours.To):
+		return errors.New("rule.hours must be a range of hours 0-24")
+	}
+	for _, t := range r.Events {
+		if !eventTypes[t] {
+			return fmt.Errorf("unknown event type %q", t)
+		}
+	}
+	switch r.Kind {
+	case KindCount:
+		if r.Attr != "" {
+			return errors.New("rule.attr is only for distinct rules")
+		}
+	case KindDistinct:
+		if r.Attr == "" || r.Per != "" {
+			return errors.New("distinct rules need rule.attr and no rule.per")
+		}
+	case KindStreak:
+		if r.Attr != "" || r.Per != "" || r.Window != 0 {
+			return errors.New("streak rules take no attr, per or window")
+		}
+	default:
+		return fmt.Errorf("unknown rule kind %q", r.Kind)
+	}
+	return nil
+}
+
+//go:embed definitions.json
+var defaultDefinitions []byte
+
+// Load parses and validates a JSON array of definitions.
+func Load(r io.Reader) ([]Definition, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var defs []Definition
+	if err := dec.Decode(&defs); err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, d := range defs {
+		if err := d.validate(); err != nil {
+			return nil, fmt.Errorf("achievement %q: %w", d.ID, err)
+		}
+		if seen[d.ID] {
+			return nil, fmt.Errorf("achievement %q is defined twice", d.ID)
+		}
+		seen[d.ID] = true
+	}
+	return defs, nil
+}
+
+// Defaults is the built-in catalog.
+func Defaults() []Definition {
+	defs, err := Load(bytes.NewReader(defaultDefinitions))
+	if err != nil {
+		panic("achieve: built-in definitions: " + err.Error())
+	}
+	return defs
+}
+
+// Catalog is a validated set of definitions.
+type Catalog struct {
+	defs   []Definition
+	byID   map[string]int
+	byType map[string][]int
+}
+
+func NewCatalog(defs []Definition) *Catalog {
+	c := &Catalog{defs: defs, byID: map[string]int{}, byType: map[string][]int{}}
+	for i, d := range defs {
+		c.byID[d.ID] = i
+		for _, t := range d.Rule.Events {
+			c.byType[t] = append(c.byType[t], i)
+		}
+	}
+	return c
+}
+
+// All returns the definitions in catalog order.
+func (c *Catalog) All() []Definition { return c.defs }
+
+func (c *Catalog) Get(id string) (Definition, bool) {
+	i, ok := c.byID[id]
+	if !ok {
+		return Definition{}, false
+	}
+	return c.defs[i], true
+}
+
+// For returns the definitions an event of type t can advance.
+func (c *Catalog) For(t string) []Definition {
+	out := make([]Definition, 0, len(c.byType[t]))
+	for _, i := range c.byType[t] {
+		out = append(out, c.defs[i])
+	}
+	return out
+}
+
+// Progress is where a user stands on one definition. Progress is capped at
+// Target; Met is true once the threshold was reached at any point in the
+// history (a streak's Progress is the current run, which can drop after
+// Met).
+type Progress struct {
+	Progress int
+	Target   int
+	Met      bool
+}
+
+// Evaluate computes progress on d from the user's events, which need not be
+// sorted. now decides whether a streak is still running.
+func Evaluate(d Definition, history []Event, now time.Time) Progress {
+	r := d.Rule
+	var match []Event
+	for _, e := range history {
+		if r.matches(e) {
+			match = append(match, e)
+		}
+	}
+	sort.SliceStable(match, func(i, j int) bool { return match[i].At.Before(match[j].At) })
+
+	var cur, best int
+	switch r.Kind {
+	case KindStreak:
+		cur, best = streak(match, now)
+	default:
+		key := func(e Event) string { return "" }
+		if r.Kind == KindDistinct {
+			key = func(e Event) string { return e.Attrs[r.Attr] }
+		} else if r.Per != "" {
+			key = func(e Event) string { return e.Attrs[r.Per] }
+		}
+		best = windowed(match, r, key, time.Duration(r.Window))
+		cur = best
+	}
+	return Progress{Progress: min(cur, r.Threshold), Target: r.Threshold, Met: best >= r.Threshold}
+}
+
+func (r Rule) matches(e Event) bool {
+	if !slices.Contains(r.Events, e.Type) {
+		return false
+	}
+	for k, v := range r.Where {
+		if e.Attrs[k] != v {
+			return false
+		}
+	}
+	if r.Hours != nil && !r.Hours.contains(e.At.Hour()) {
+		return false
+	}
+	if r.Kind == KindDistinct && e.Attrs[r.Attr] == "" {
+		return false
+	}
+	if r.Per != "" && e.Attrs[r.Per] == "" {
+		return false
+	}
+	return true
+}
+
+// windowed returns the best score among events inside any window of length
+// w (the whole history when w is 0). For distinct rules the score is the
+// number of distinct keys; for count rules it is the largest number of
+// events sharing one key (all events share "" without Per).
+func windowed(events []Event, r Rule, key func(Event) string, w time.Duration) int {
+	counts := map[string]int{}
+	distinct, best, lo := 0, 0, 0
+	for _, e := range events {
+		for w > 0 && e.At.Sub(events[lo].At) >= w {
+			k := key(events[lo])
+			if counts[k]--; counts[k] == 0 {
+				distinct--
+			}
+			lo++
+		}
+		k := key(e)
+		if counts[k]++; counts[k] == 1 {
+			distinct++
+		}
+		score := counts[k]
+		if r.Kind == KindDistinct {
+			score = distinct
+		}
+		best = max(best, score)
+	}
+	return best
+}
+
+// streak returns the current run of consecutive local days with events
+// (zero unless the last one is today or yesterday in now's location) and the
+// longest run.
+func streak(events []Event, now time.Time) (cur, best int) {
+	days := map[int]bool{}
+	for _, e := range events {
+		days[dayNumber(e.At)] = true
+	}
+	sorted := make([]int, 0, len(days))
+	for d := range days {
+		sorted = append(sorted, d)
+	}
+	sort.Ints(sorted)
+	run := 0
+	for i, d := range sorted {
+		if i > 0 && d == sorted[i-1]+1 {
+			run++
+		} else {
+			run = 1
+		}
+		best = max(best, run)
+	}
+	if n := len(sorted); n > 0 && dayNumber(now)-sorted[n-1] <= 1 {
+		cur = run
+	}
+	return cur, best
+}
+
+// dayNumber numbers calendar days in t's own location.
+func dayNumber(t time.Time) int {
+	y, m, d := t.Date()
+	return int(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / 86400)
+}
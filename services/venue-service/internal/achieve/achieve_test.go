@@ -0,0 +1,118 @@
+package achieve
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func def(t *testing.T, id string) Definition {
+	t.Helper()
+	d, ok := NewCatalog(Defaults()).Get(id)
+	if !ok {
+		t.Fatalf("no built-in achievement %q", id)
+	}
+	return d
+}
+
+func checkin(at time.Time, venue, category string) Event {
+	return Event{Type: EventCheckin, At: at, Attrs: map[string]string{"venueId": venue, "category": category}}
+}
+
+func TestEvaluate_CountDistinctAndPer(t *testing.T) {
+	base := time.Date(2026, 5, 1, 20, 0, 0, 0, time.UTC)
+	var history []Event
+	for i := 0; i < 4; i++ {
+		history = append(history, checkin(base.AddDate(0, 0, i), "v1", "bar"))
+	}
+	history = append(history, checkin(base, "v2", "club"), Event{Type: EventLike, At: base})
+
+	if p := Evaluate(def(t, "first_checkin"), history, base); !p.Met || p.Progress != 1 {
+		t.Fatalf("first_checkin: %+v", p)
+	}
+	if p := Evaluate(def(t, "regular"), history, base); p.Met || p.Progress != 4 || p.Target != 5 {
+		t.Fatalf("regular after 4 visits to v1: %+v", p)
+	}
+	history = append(history, checkin(base.AddDate(0, 0, 9), "v1", "bar"))
+	if p := Evaluate(def(t, "regular"), history, base); !p.Met {
+		t.Fatalf("regular after 5 visits: %+v", p)
+	}
+	if p := Evaluate(def(t, "explorer"), history, base); p.Progress != 2 {
+		t.Fatalf("explorer counts distinct venues: %+v", p)
+	}
+	if p := Evaluate(def(t, "master_discoverer"), history, base); p.Progress != 2 {
+		t.Fatalf("master_discoverer counts distinct categories: %+v", p)
+	}
+}
+
+func TestEvaluate_WindowAndHours(t *testing.T) {
+	la, _ := time.LoadLocation("America/Los_Angeles")
+	night := time.Date(2026, 5, 2, 21, 0, 0, 0, la)
+	hop := []Event{checkin(night, "v1", "bar"), checkin(night.Add(3*time.Hour), "v2", "bar"), checkin(night.Add(7*time.Hour), "v3", "club")}
+	if p := Evaluate(def(t, "bar_hopper"), hop, night); p.Met || p.Progress != 2 {
+		t.Fatalf("third venue is outside the 6h window: %+v", p)
+	}
+	hop = append(hop, checkin(night.Add(5*time.Hour), "v1", "bar"))
+	if p := Evaluate(def(t, "bar_hopper"), hop, night); !p.Met {
+		t.Fatalf("v2, v1, v3 within 6h: %+v", p)
+	}
+
+	// 01:00 in LA is a night-owl check-in even though it is 08:00 UTC
+	owl := def(t, "night_owl")
+	if p := Evaluate(owl, []Event{checkin(night, "v1", "bar")}, night); p.Met {
+		t.Fatalf("21:00 is not night-owl hours: %+v", p)
+	}
+	late := night.Add(4 * time.Hour)
+	if p := Evaluate(owl, []Event{checkin(late, "v1", "bar")}, night); !p.Met {
+		t.Fatalf("01:00 local should count: %+v", p)
+	}
+	if p := Evaluate(owl, []Event{checkin(late.UTC(), "v1", "bar")}, night); p.Met {
+		t.Fatalf("hours are judged in the event's location")
+	}
+}
+
+func TestEvaluate_Streak(t *testing.T) {
+	d := def(t, "streak_7")
+	start := time.Date(2026, 5, 1, 23, 30, 0, 0, time.UTC)
+	var history []Event
+	for i := 0; i < 6; i++ {
+		typ := []string{EventCheckin, EventLike, EventValetUse}[i%3]
+		history = append(history, Event{Type: typ, At: start.AddDate(0, 0, i)})
+	}
+	last := start.AddDate(0, 0, 5)
+	if p := Evaluate(d, history, last); p.Met || p.Progress != 6 {
+		t.Fatalf("6 days in a row: %+v", p)
+	}
+	if p := Evaluate(d, history, last.AddDate(0, 0, 2)); p.Progress != 0 {
+		t.Fatalf("a broken streak has no current run: %+v", p)
+	}
+	history = append(history, Event{Type: EventPlanCreated, At: last.AddDate(0, 0, 1)})
+	if p := Evaluate(d, history, last.AddDate(0, 0, 1)); !p.Met || p.Progress != 7 {
+		t.Fatalf("7 days in a row: %+v", p)
+	}
+	// earned stays earned once the run breaks
+	if p := Evaluate(d, history, last.AddDate(0, 0, 10)); !p.Met || p.Progress != 0 {
+		t.Fatalf("after the run ends: %+v", p)
+	}
+}
+
+func TestLoad_Validates(t *testing.T) {
+	if len(Defaults()) == 0 {
+		t.Fatal("no built-in achievements")
+	}
+	bad := map[string]string{
+		"unknown event":  `[{"id":"a","title":"A","type":"special","rarity":"rare","rule":{"kind":"count","events":["dance"],"threshold":1}}]`,
+		"no threshold":   `[{"id":"a","title":"A","type":"special","rarity":"rare","rule":{"kind":"count","events":["like"]}}]`,
+		"distinct attr":  `[{"id":"a","title":"A","type":"special","rarity":"rare","rule":{"kind":"distinct","events":["like"],"threshold":2}}]`,
+		"streak window":  `[{"id":"a","title":"A","type":"streak","rarity":"rare","rule":{"kind":"streak","events":["like"],"window":"7d","threshold":2}}]`,
+		"bad window":     `[{"id":"a","title":"A","type":"special","rarity":"rare","rule":{"kind":"count","events":["like"],"window":"7w","threshold":2}}]`,
+		"unknown field":  `[{"id":"a","title":"A","type":"special","rarity":"rare","rule":{"kind":"count","events":["like"],"threshold":2,"points":5}}]`,
+		"duplicate id":   `[{"id":"a","title":"A","type":"special","rarity":"rare","rule":{"kind":"count","events":["like"],"threshold":1}},{"id":"a","title":"B","type":"special","rarity":"rare","rule":{"kind":"count","events":["like"],"threshold":2}}]`,
+		"unknown rarity": `[{"id":"a","title":"A","type":"special","rarity":"mythic","rule":{"kind":"count","events":["like"],"threshold":1}}]`,
+	}
+	for name, src := range bad {
+		if _, err := Load(strings.NewReader(src)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+// Package analytics builds the venue host dashboard from daily rollups:
+// each venue-day holds impressions, likes, check-ins and vibe reports, and
+// check-ins and vibe reports by local hour for peak hours. Days are local to
+// the venue, so "Friday" is the venue's Friday wherever the host is.
+package analytics
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DateLayout is how dates are written on the wire.
+const DateLayout = "2006-01-02"
+
+// Date returns t's calendar date in loc as midnight UTC, the form days are
+// keyed and stored by.
+func Date(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// Day is one venue's rollup for one local date.
+type Day struct {
+	VenueID      string
+	Date         time.Time // midnight UTC
+	Impressions  int
+	Likes        int
+	Checkins     int
+	VibeReports  int
+	VibeScoreSum float64
+	Hourly       [24]int // check-ins and vibe reports by local hour
+}
+
+// Kind is the source of an Activity.
+type Kind int
+
+const (
+	Like Kind = iota
+	Checkin
+	VibeReport
+)
+
+// Activity is one like, check-in or vibe report from the source stores.
+type Activity struct {
+	VenueID string
+	Kind    Kind
+	At      time.Time
+	Score   float64 // vibe reports only
+}
+
+// Rollup buckets activity into venue-days in each venue's zone, ordered by
+// venue and date. Impressions are counted separately (see Counter).
+func Rollup(acts []Activity, zone func(venueID string) *time.Location) []Day {
+	type key struct {
+		venue string
+		date  time.Time
+	}
+	days := map[key]*Day{}
+	zones := map[string]*time.Location{}
+	for _, a := range acts {
+		loc, ok := zones[a.VenueID]
+		if !ok {
+			loc = zone(a.VenueID)
+			zones[a.VenueID] = loc
+		}
+		local := a.At.In(loc)
+		k := key{a.VenueID, Date(local, loc)}
+		d := days[k]
+		if d == nil {
+			d = &Day{VenueID: a.VenueID, Date: k.date}
+			days[k] = d
+		}
+		switch a.Kind {
+		case Like:
+			d.Likes++
+		case Checkin:
+			d.Checkins++
+			d.Hourly[local.Hour()]++
+		case VibeReport:
+			d.VibeReports++
+			d.VibeScoreSum += a.Score
+			d.Hourly[local.Hour()]++
+		}
+	}
+	out := make([]Day, 0, len(days))
+	for _, d := range days {
+		out = append(out, *d)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].VenueID != out[j].VenueID {
+			return out[i].VenueID < out[j].VenueID
+		}
+		return out[i].Date.Before(out[j].Date)
+	})
+	return out
+}
+
+// Counter accumulates impressions in memory by venue and UTC hour until
+// they are flushed to the rollups; hours are resolved to local dates then.
+type Counter struct {
+	mu     sync.Mutex
+	counts map[HourKey]int
+}
+
+// HourKey is a venue and the UTC hour its impressions fell in.
+type HourKey struct {
+	VenueID string
+	Hour    int64 // unix seconds at the start of the UTC hour
+}
+
+func NewCounter() *Counter { return &Counter{counts: map[HourKey]int{}} }
+
+// Add counts one impression for each venue at t.
+func (c *Counter) Add(t time.Time, venueIDs ...string) {
+	hour := t.Truncate(time.Hour).Unix()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, id := range venueIDs {
+		c.counts[HourKey{id, hour}]++
+	}
+}
+
+// Drain returns and resets the counts.
+func (c *Counter) Drain() map[HourKey]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := c.counts
+	c.counts = map[HourKey]int{}
+	return out
+}
+
+// Restore adds counts back after a failed flush.
+func (c *Counter) Restore(counts map[HourKey]int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, n := range counts {
+		c.counts[k] += n
+	}
+}
+
+// Period is an inclusive range of local dates.
+type Period struct {
+	From time.Time
+	To   time.Time
+}
+
+// Days is the period's length in days.
+func (p Period) Days() int { return int(p.To.Sub(p.From).Hours()/24) + 1 }
+
+// Previous is the period of the same length just before p.
+func (p Period) Previous() Period {
+	n := p.Days()
+	return Period{From: p.From.AddDate(0, 0, -n), To: p.From.AddDate(0, 0, -1)}
+}
+
+func (p Period) contains(d time.Time) bool { return !d.Before(p.From) && !d.After(p.To) }
+
+func (p Period) MarshalJSON() ([]byte, error) {
+	return []byte(`{"from":"` + p.From.Format(DateLayout) + `","to":"` + p.To.Format(DateLayout) + `"}`), nil
+}
+
+// Count is a total with the previous period's; Change is relative
+// (0.25 = +25%) and nil when the previous period had none.
+type Count struct {
+	Value    int      `json:"value"`
+	Previous int      `json:"previous"`
+	Change   *float64 `json:"change"`
+}
+
+// Vibe is the average reported vibe (0-10); Change is in points. Values
+// are nil for periods without reports.
+type Vibe struct {
+	Value    *float64 `json:"value"`
+	Previous *float64 `json:"previous"`
+	Change   *float64 `json:"change"`
+}
+
+type Totals struct {
+	Impressions Count `json:"impressions"`
+	Likes       Count `json:"likes"`
+	Checkins    Count `json:"checkins"`
+	VibeReports Count `json:"vibeReports"`
+	AvgVibe     Vibe  `json:"avgVibe"`
+}
+
+// Point is one day of the daily series.
+type Point struct {
+	Date        string   `json:"date"`
+	Impressions int      `json:"impressions"`
+	Likes       int      `json:"likes"`
+	Checkins    int      `json:"checkins"`
+	VibeReports int      `json:"vibeReports"`
+	AvgVibe     *float64 `json:"avgVibe"`
+}
+
+type HourCount struct {
+	Hour     int `json:"hour"`
+	Activity int `json:"activity"`
+}
+
+// Report is a venue's dashboard for a period.
+type Report struct {
+	Period    Period      `json:"period"`
+	Previous  Period      `json:"previousPeriod"`
+	Totals    Totals      `json:"totals"`
+	Daily     []Point     `json:"daily"`
+	Hourly    [24]int     `json:"hourly"`
+	PeakHours []HourCount `json:"peakHours"` // busiest hours first, at most three
+}
+
+const peakHours = 3
+
+type sums struct {
+	impressions, likes, checkins, reports int
+	score                                 float64
+}
+
+func (s *sums) add(d Day) {
+	s.impressions += d.Impressions
+	s.likes += d.Likes
+	s.checkins += d.Checkins
+	s.reports += d.VibeReports
+	s.score += d.VibeScoreSum
+}
+
+func (s sums) avgVibe() *float64 {
+	if s.reports == 0 {
+		return nil
+	}
+	v := round2(s.score / float64(s.reports))
+	return &v
+}
+
+func count(cur, prev int) Count {
+	c := Count{Value: cur, Previous: prev}
+	if prev > 0 {
+		ch := round2(float64(cur-prev) / float64(prev))
+		c.Change = &ch
+	}
+	return c
+}
+
+func round2(f float64) float64 { return math.Round(f*100) / 100 }
+
+// Summarize totals the days in p and compares them with the period before;
+// days outside both are ignored.
+func Summarize(days []Day, p Period) Totals {
+	prevPeriod := p.Previous()
+	var cur, prev sums
+	for _, d := range days {
+		switch {
+		case p.contains(d.Date):
+			cur.add(d)
+		case prevPeriod.contains(d.Date):
+			prev.add(d)
+		}
+	}
+	t := Totals{
+		Impressions: count(cur.impressions, prev.impressions),
+		Likes:       count(cur.likes, prev.likes),
+		Checkins:    count(cur.checkins, prev.checkins),
+		VibeReports: count(cur.reports, prev.reports),
+		AvgVibe:     Vibe{Value: cur.avgVibe(), Previous: prev.avgVibe()},
+	}
+	if t.AvgVibe.Value != nil && t.AvgVibe.Previous != nil {
+		ch := round2(*t.AvgVibe.Value - *t.AvgVibe.Previous)
+		t.AvgVibe.Change = &ch
+	}
+	return t
+}
+
+// Build is the report for p: totals against the previous period, one point
+// per day (days without a rollup count as zero) and peak hours.
+func Build(days []Day, p Period) Report {
+	r := Report{Period: p, Previous: p.Previous(), Totals: Summarize(days, p), PeakHours: []HourCount{}}
+	byDate := map[time.Time]sums{}
+	for _, d := range days {
+		if !p.contains(d.Date) {
+			continue
+		}
+		s := byDate[d.Date]
+		s.add(d)
+		byDate[d.Date] = s
+		for h, n := range d.Hourly {
+			r.Hourly[h] += n
+		}
+	}
+	r.Daily = make([]Point, 0, p.Days())
+	for date := p.From; !date.After(p.To); date = date.AddDate(0, 0, 1) {
+		s := byDate[date]
+		r.Daily = append(r.Daily, Point{Date: date.Format(DateLayout), Impressions: s.impressions, Likes: s.likes,
+			Checkins: s.checkins, VibeReports: s.reports, AvgVibe: s.avgVibe()})
+	}
+	for h, n := range r.Hourly {
+		if n > 0 {
+			r.PeakHours = append(r.PeakHours, HourCount{Hour: h, Activity: n})
+		}
+	}
+	sort.SliceStable(r.PeakHours, func(i, j int) bool { return r.PeakHours[i].Activity > r.PeakHours[j].Activity })
+	if len(r.PeakHours) > peakHours {
+		r.PeakHours = r.PeakHours[:peakHours]
+	}
+	return r
+}
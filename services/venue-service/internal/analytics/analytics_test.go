@@ -0,0 +1,84 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	d, _ := time.Parse(DateLayout, s)
+	return d
+}
+
+func TestRollupUsesVenueLocalDays(t *testing.T) {
+	la, _ := time.LoadLocation("America/Los_Angeles")
+	zone := func(id string) *time.Location {
+		if id == "sf" {
+			return la
+		}
+		return time.UTC
+	}
+	at := time.Date(2026, 10, 24, 5, 30, 0, 0, time.UTC) // 22:30 the day before in LA
+	days := Rollup([]Activity{
+		{VenueID: "sf", Kind: Checkin, At: at},
+		{VenueID: "sf", Kind: VibeReport, At: at, Score: 7},
+		{VenueID: "sf", Kind: Like, At: at.Add(time.Hour)},
+		{VenueID: "ldn", Kind: Like, At: at},
+	}, zone)
+	if len(days) != 2 || days[0].VenueID != "ldn" || !days[0].Date.Equal(date("2026-10-24")) {
+		t.Fatalf("days: %+v", days)
+	}
+	sf := days[1]
+	if !sf.Date.Equal(date("2026-10-23")) || sf.Checkins != 1 || sf.Likes != 1 || sf.VibeReports != 1 || sf.VibeScoreSum != 7 || sf.Hourly[22] != 2 {
+		t.Fatalf("sf: %+v", sf)
+	}
+}
+
+func TestBuildComparesWithPreviousPeriod(t *testing.T) {
+	p := Period{From: date("2026-10-18"), To: date("2026-10-24")}
+	if prev := p.Previous(); !prev.From.Equal(date("2026-10-11")) || !prev.To.Equal(date("2026-10-17")) || p.Days() != 7 {
+		t.Fatalf("previous: %+v", prev)
+	}
+	var busy, quiet [24]int
+	busy[21], busy[22], busy[18], busy[12] = 5, 9, 2, 1
+	quiet[22] = 3
+	days := []Day{
+		{Date: date("2026-10-12"), Likes: 4, Checkins: 2, VibeReports: 1, VibeScoreSum: 6},
+		{Date: date("2026-10-20"), Impressions: 10, Likes: 5, Checkins: 3, VibeReports: 2, VibeScoreSum: 15, Hourly: busy},
+		{Date: date("2026-10-24"), Impressions: 5, Likes: 1, VibeReports: 1, VibeScoreSum: 8, Hourly: quiet},
+		{Date: date("2026-10-01"), Likes: 100}, // outside both
+	}
+	r := Build(days, p)
+	tt := r.Totals
+	if tt.Likes.Value != 6 || tt.Likes.Previous != 4 || *tt.Likes.Change != 0.5 {
+		t.Fatalf("likes: %+v", tt.Likes)
+	}
+	if tt.Impressions.Value != 15 || tt.Impressions.Change != nil {
+		t.Fatalf("impressions without a previous period: %+v", tt.Impressions)
+	}
+	if *tt.AvgVibe.Value != 7.67 || *tt.AvgVibe.Previous != 6 || *tt.AvgVibe.Change != 1.67 {
+		t.Fatalf("avgVibe: %v %v %v", *tt.AvgVibe.Value, *tt.AvgVibe.Previous, *tt.AvgVibe.Change)
+	}
+	if len(r.Daily) != 7 || r.Daily[0].Date != "2026-10-18" || r.Daily[0].AvgVibe != nil || r.Daily[2].Likes != 5 || *r.Daily[6].AvgVibe != 8 {
+		t.Fatalf("daily: %+v", r.Daily)
+	}
+	want := []HourCount{{22, 12}, {21, 5}, {18, 2}}
+	if len(r.PeakHours) != 3 || r.PeakHours[0] != want[0] || r.PeakHours[1] != want[1] || r.PeakHours[2] != want[2] {
+		t.Fatalf("peakHours: %+v", r.PeakHours)
+	}
+}
+
+func TestCounterRestore(t *testing.T) {
+	c := NewCounter()
+	at := time.Date(2026, 10, 24, 5, 30, 0, 0, time.UTC)
+	c.Add(at, "a", "b", "a")
+	got := c.Drain()
+	if len(c.Drain()) != 0 {
+		t.Fatal("drain should reset")
+	}
+	c.Restore(got)
+	c.Add(at.Add(10*time.Minute), "a")
+	if n := c.Drain()[HourKey{"a", at.Truncate(time.Hour).Unix()}]; n != 3 {
+		t.Fatalf("a: %d", n)
+	}
+}
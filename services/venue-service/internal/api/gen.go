@@ -2,29 +2,129 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type GetVenuesDiscoverParams struct {
-	Lat    float64  `json:"lat"`
-	Lon    float64  `json:"lon"`
-	Radius *float64 `json:"radius,omitempty"`
+	Lat      *float64   `json:"lat,omitempty"`
+	Lon      *float64   `json:"lon,omitempty"`
+	Radius   *float64   `json:"radius,omitempty"`
+	Category *string    `json:"category,omitempty"`
+	Price    *string    `json:"price,omitempty"`
+	OpenNow  *bool      `json:"openNow,omitempty"`
+	OpenAt   *time.Time `json:"openAt,omitempty"`
+	Vibe     *string    `json:"vibe,omitempty"`
+	Cursor   *string    `json:"cursor,omitempty"`
+	Limit    *int       `json:"limit,omitempty"`
+}
+
+type GetVenuesSearchParams struct {
+	Q        *string  `json:"q,omitempty"`
+	Lat      *float64 `json:"lat,omitempty"`
+	Lon      *float64 `json:"lon,omitempty"`
+	Category *string  `json:"category,omitempty"`
+	Limit    *int     `json:"limit,omitempty"`
+}
+
+type GetVenuesAutocompleteParams struct {
+	Q     *string `json:"q,omitempty"`
+	Limit *int    `json:"limit,omitempty"`
+}
+
+type GetUsersRecommendationsParams struct {
+	Lat   *float64 `json:"lat,omitempty"`
+	Lon   *float64 `json:"lon,omitempty"`
+	Vibe  *string  `json:"vibe,omitempty"`
+	Limit *int     `json:"limit,omitempty"`
+}
+
+type GetVenuesTrendingParams struct {
+	Lat   *float64 `json:"lat,omitempty"`
+	Lon   *float64 `json:"lon,omitempty"`
+	Limit *int     `json:"limit,omitempty"`
+}
+
+type GetVenuesIdReviewsParams struct {
+	Kind  *string `json:"kind,omitempty"`
+	Sort  *string `json:"sort,omitempty"`
+	Limit *int    `json:"limit,omitempty"`
+}
+
+type GetEventsParams struct {
+	From  *time.Time `json:"from,omitempty"`
+	To    *time.Time `json:"to,omitempty"`
+	Bbox  *string    `json:"bbox,omitempty"`
+	Kind  *string    `json:"kind,omitempty"`
+	Tag   *string    `json:"tag,omitempty"`
+	Limit *int       `json:"limit,omitempty"`
+}
+
+type GetVenuesIdEventsParams struct {
+	From  *time.Time `json:"from,omitempty"`
+	To    *time.Time `json:"to,omitempty"`
+	Limit *int       `json:"limit,omitempty"`
+}
+
+type GetVenuesIdVibeAggregateParams struct {
+	Window *string `json:"window,omitempty"`
+}
+
+type GetVibeStreamParams struct {
+	Bbox *string `json:"bbox,omitempty"`
+}
+
+type GetVibeHeatmapParams struct {
+	Bbox      *string `json:"bbox,omitempty"`
+	Precision *int    `json:"precision,omitempty"`
 }
 
 type ServerInterface interface {
 	GetHealthz(w http.ResponseWriter, r *http.Request)
 	GetReadyz(w http.ResponseWriter, r *http.Request)
 	GetVenuesDiscover(w http.ResponseWriter, r *http.Request, params GetVenuesDiscoverParams)
+	GetVenuesSearch(w http.ResponseWriter, r *http.Request, params GetVenuesSearchParams)
+	GetVenuesAutocomplete(w http.ResponseWriter, r *http.Request, params GetVenuesAutocompleteParams)
+	GetVenuesTrending(w http.ResponseWriter, r *http.Request, params GetVenuesTrendingParams)
 	GetVenuesId(w http.ResponseWriter, r *http.Request, id string)
 	PostVenuesIdLike(w http.ResponseWriter, r *http.Request, id string)
+	DeleteVenuesIdLike(w http.ResponseWriter, r *http.Request, id string)
+	PostVenuesIdSkip(w http.ResponseWriter, r *http.Request, id string)
+	PostVenuesIdCheckin(w http.ResponseWriter, r *http.Request, id string)
+	GetUsersMeCheckins(w http.ResponseWriter, r *http.Request)
+	GetVenuesIdReviews(w http.ResponseWriter, r *http.Request, id string, params GetVenuesIdReviewsParams)
+	PostVenuesIdReviews(w http.ResponseWriter, r *http.Request, id string)
+	PatchReviewsId(w http.ResponseWriter, r *http.Request, id string)
+	DeleteReviewsId(w http.ResponseWriter, r *http.Request, id string)
+	PostReviewsIdHelpful(w http.ResponseWriter, r *http.Request, id string)
+	DeleteReviewsIdHelpful(w http.ResponseWriter, r *http.Request, id string)
+	PostReviewsIdReport(w http.ResponseWriter, r *http.Request, id string)
+	GetUsersMeReviews(w http.ResponseWriter, r *http.Request)
+	GetAchievements(w http.ResponseWriter, r *http.Request)
+	GetUsersMeAchievements(w http.ResponseWriter, r *http.Request)
+	PostAchievementsEvents(w http.ResponseWriter, r *http.Request)
+	GetEvents(w http.ResponseWriter, r *http.Request, params GetEventsParams)
+	GetVenuesIdEvents(w http.ResponseWriter, r *http.Request, id string, params GetVenuesIdEventsParams)
+	GetVenuesIdCalendarIcs(w http.ResponseWriter, r *http.Request, id string)
+	GetUsersMeLikes(w http.ResponseWriter, r *http.Request)
+	GetUsersRecommendations(w http.ResponseWriter, r *http.Request, params GetUsersRecommendationsParams)
 	PostVenuesIdVibe(w http.ResponseWriter, r *http.Request, id string)
-	GetVenuesIdVibeAggregate(w http.ResponseWriter, r *http.Request, id string)
+	GetVenuesIdVibeAggregate(w http.ResponseWriter, r *http.Request, id string, params GetVenuesIdVibeAggregateParams)
+	GetVenuesIdVibeStream(w http.ResponseWriter, r *http.Request, id string)
+	GetVibeStream(w http.ResponseWriter, r *http.Request, params GetVibeStreamParams)
+	GetVibeHeatmap(w http.ResponseWriter, r *http.Request, params GetVibeHeatmapParams)
+	PostSignalsActivity(w http.ResponseWriter, r *http.Request)
 }
 
 type chiRouter interface {
 	Get(string, http.HandlerFunc)
 	Post(string, http.HandlerFunc)
+	Patch(string, http.HandlerFunc)
+	Delete(string, http.HandlerFunc)
 	ServeHTTP(http.ResponseWriter, *http.Request)
 }
 
@@ -39,25 +139,260 @@ func pathParam(path, prefix, suffix string) string {
 	return id
 }
 
+func invalidParam(w http.ResponseWriter, name string, err error) {
+	http.Error(w, fmt.Sprintf("Invalid format for parameter %s: %s", name, err), http.StatusBadRequest)
+}
+
+func bindFloat(q url.Values, name string, dst **float64) error {
+	if v := q.Get(name); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return err
+		}
+		*dst = &f
+	}
+	return nil
+}
+
+func bindString(q url.Values, name string, dst **string) {
+	if v := q.Get(name); v != "" {
+		*dst = &v
+	}
+}
+
+func bindInt(q url.Values, name string, dst **int) error {
+	if v := q.Get(name); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		*dst = &n
+	}
+	return nil
+}
+
+func bindBool(q url.Values, name string, dst **bool) error {
+	if v := q.Get(name); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		*dst = &b
+	}
+	return nil
+}
+
+func bindTime(q url.Values, name string, dst **time.Time) error {
+	if v := q.Get(name); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return err
+		}
+		*dst = &t
+	}
+	return nil
+}
+
 func HandlerFromMux(si ServerInterface, r chiRouter) http.Handler {
 	r.Get("/healthz", si.GetHealthz)
 	r.Get("/readyz", si.GetReadyz)
 	r.Get("/venues/discover", func(w http.ResponseWriter, req *http.Request) {
-		// Basic parsing; real codegen will generate robust parsing
 		params := GetVenuesDiscoverParams{}
+		q := req.URL.Query()
+		for name, dst := range map[string]**float64{"lat": &params.Lat, "lon": &params.Lon, "radius": &params.Radius} {
+			if err := bindFloat(q, name, dst); err != nil {
+				invalidParam(w, name, err)
+				return
+			}
+		}
+		bindString(q, "category", &params.Category)
+		bindString(q, "price", &params.Price)
+		bindString(q, "vibe", &params.Vibe)
+		bindString(q, "cursor", &params.Cursor)
+		if err := bindInt(q, "limit", &params.Limit); err != nil {
+			invalidParam(w, "limit", err)
+			return
+		}
+		if err := bindBool(q, "open_now", &params.OpenNow); err != nil {
+			invalidParam(w, "open_now", err)
+			return
+		}
+		if err := bindTime(q, "open_at", &params.OpenAt); err != nil {
+			invalidParam(w, "open_at", err)
+			return
+		}
 		si.GetVenuesDiscover(w, req, params)
 	})
+	r.Get("/venues/search", func(w http.ResponseWriter, req *http.Request) {
+		params := GetVenuesSearchParams{}
+		q := req.URL.Query()
+		for name, dst := range map[string]**float64{"lat": &params.Lat, "lon": &params.Lon} {
+			if err := bindFloat(q, name, dst); err != nil {
+				invalidParam(w, name, err)
+				return
+			}
+		}
+		bindString(q, "q", &params.Q)
+		bindString(q, "category", &params.Category)
+		if err := bindInt(q, "limit", &params.Limit); err != nil {
+			invalidParam(w, "limit", err)
+			return
+		}
+		si.GetVenuesSearch(w, req, params)
+	})
+	r.Get("/venues/autocomplete", func(w http.ResponseWriter, req *http.Request) {
+		params := GetVenuesAutocompleteParams{}
+		q := req.URL.Query()
+		bindString(q, "q", &params.Q)
+		if err := bindInt(q, "limit", &params.Limit); err != nil {
+			invalidParam(w, "limit", err)
+			return
+		}
+		si.GetVenuesAutocomplete(w, req, params)
+	})
+	r.Get("/venues/trending", func(w http.ResponseWriter, req *http.Request) {
+		params := GetVenuesTrendingParams{}
+		q := req.URL.Query()
+		for name, dst := range map[string]**float64{"lat": &params.Lat, "lon": &params.Lon} {
+			if err := bindFloat(q, name, dst); err != nil {
+				invalidParam(w, name, err)
+				return
+			}
+		}
+		if err := bindInt(q, "limit", &params.Limit); err != nil {
+			invalidParam(w, "limit", err)
+			return
+		}
+		si.GetVenuesTrending(w, req, params)
+	})
 	r.Get("/venues/{id}", func(w http.ResponseWriter, req *http.Request) {
 		si.GetVenuesId(w, req, pathParam(req.URL.Path, "/venues/", ""))
 	})
 	r.Post("/venues/{id}/like", func(w http.ResponseWriter, req *http.Request) {
 		si.PostVenuesIdLike(w, req, pathParam(req.URL.Path, "/venues/", "/like"))
 	})
+	r.Delete("/venues/{id}/like", func(w http.ResponseWriter, req *http.Request) {
+		si.DeleteVenuesIdLike(w, req, pathParam(req.URL.Path, "/venues/", "/like"))
+	})
+	r.Post("/venues/{id}/skip", func(w http.ResponseWriter, req *http.Request) {
+		si.PostVenuesIdSkip(w, req, pathParam(req.URL.Path, "/venues/", "/skip"))
+	})
+	r.Post("/venues/{id}/checkin", func(w http.ResponseWriter, req *http.Request) {
+		si.PostVenuesIdCheckin(w, req, pathParam(req.URL.Path, "/venues/", "/checkin"))
+	})
+	r.Get("/users/me/likes", si.GetUsersMeLikes)
+	r.Get("/users/me/checkins", si.GetUsersMeCheckins)
+	r.Get("/venues/{id}/reviews", func(w http.ResponseWriter, req *http.Request) {
+		params := GetVenuesIdReviewsParams{}
+		q := req.URL.Query()
+		bindString(q, "kind", &params.Kind)
+		bindString(q, "sort", &params.Sort)
+		if err := bindInt(q, "limit", &params.Limit); err != nil {
+			invalidParam(w, "limit", err)
+			return
+		}
+		si.GetVenuesIdReviews(w, req, pathParam(req.URL.Path, "/venues/", "/reviews"), params)
+	})
+	r.Post("/venues/{id}/reviews", func(w http.ResponseWriter, req *http.Request) {
+		si.PostVenuesIdReviews(w, req, pathParam(req.URL.Path, "/venues/", "/reviews"))
+	})
+	r.Patch("/reviews/{id}", func(w http.ResponseWriter, req *http.Request) {
+		si.PatchReviewsId(w, req, pathParam(req.URL.Path, "/reviews/", ""))
+	})
+	r.Delete("/reviews/{id}", func(w http.ResponseWriter, req *http.Request) {
+		si.DeleteReviewsId(w, req, pathParam(req.URL.Path, "/reviews/", ""))
+	})
+	r.Post("/reviews/{id}/helpful", func(w http.ResponseWriter, req *http.Request) {
+		si.PostReviewsIdHelpful(w, req, pathParam(req.URL.Path, "/reviews/", "/helpful"))
+	})
+	r.Delete("/reviews/{id}/helpful", func(w http.ResponseWriter, req *http.Request) {
+		si.DeleteReviewsIdHelpful(w, req, pathParam(req.URL.Path, "/reviews/", "/helpful"))
+	})
+	r.Post("/reviews/{id}/report", func(w http.ResponseWriter, req *http.Request) {
+		si.PostReviewsIdReport(w, req, pathParam(req.URL.Path, "/reviews/", "/report"))
+	})
+	r.Get("/users/me/reviews", si.GetUsersMeReviews)
+	r.Get("/achievements", si.GetAchievements)
+	r.Get("/users/me/achievements", si.GetUsersMeAchievements)
+	r.Post("/achievements/events", si.PostAchievementsEvents)
+	r.Get("/events", func(w http.ResponseWriter, req *http.Request) {
+		params := GetEventsParams{}
+		q := req.URL.Query()
+		for name, dst := range map[string]**time.Time{"from": &params.From, "to": &params.To} {
+			if err := bindTime(q, name, dst); err != nil {
+				invalidParam(w, name, err)
+				return
+			}
+		}
+		bindString(q, "bbox", &params.Bbox)
+		bindString(q, "kind", &params.Kind)
+		bindString(q, "tag", &params.Tag)
+		if err := bindInt(q, "limit", &params.Limit); err != nil {
+			invalidParam(w, "limit", err)
+			return
+		}
+		si.GetEvents(w, req, params)
+	})
+	r.Get("/venues/{id}/events", func(w http.ResponseWriter, req *http.Request) {
+		params := GetVenuesIdEventsParams{}
+		q := req.URL.Query()
+		for name, dst := range map[string]**time.Time{"from": &params.From, "to": &params.To} {
+			if err := bindTime(q, name, dst); err != nil {
+				invalidParam(w, name, err)
+				return
+			}
+		}
+		if err := bindInt(q, "limit", &params.Limit); err != nil {
+			invalidParam(w, "limit", err)
+			return
+		}
+		si.GetVenuesIdEvents(w, req, pathParam(req.URL.Path, "/venues/", "/events"), params)
+	})
+	r.Get("/venues/{id}/calendar.ics", func(w http.ResponseWriter, req *http.Request) {
+		si.GetVenuesIdCalendarIcs(w, req, pathParam(req.URL.Path, "/venues/", "/calendar.ics"))
+	})
+	r.Get("/users/recommendations", func(w http.ResponseWriter, req *http.Request) {
+		params := GetUsersRecommendationsParams{}
+		q := req.URL.Query()
+		for name, dst := range map[string]**float64{"lat": &params.Lat, "lon": &params.Lon} {
+			if err := bindFloat(q, name, dst); err != nil {
+				invalidParam(w, name, err)
+				return
+			}
+		}
+		bindString(q, "vibe", &params.Vibe)
+		if err := bindInt(q, "limit", &params.Limit); err != nil {
+			invalidParam(w, "limit", err)
+			return
+		}
+		si.GetUsersRecommendations(w, req, params)
+	})
 	r.Post("/venues/{id}/vibe", func(w http.ResponseWriter, req *http.Request) {
 		si.PostVenuesIdVibe(w, req, pathParam(req.URL.Path, "/venues/", "/vibe"))
 	})
 	r.Get("/venues/{id}/vibe-aggregate", func(w http.ResponseWriter, req *http.Request) {
-		si.GetVenuesIdVibeAggregate(w, req, pathParam(req.URL.Path, "/venues/", "/vibe-aggregate"))
+		params := GetVenuesIdVibeAggregateParams{}
+		bindString(req.URL.Query(), "window", &params.Window)
+		si.GetVenuesIdVibeAggregate(w, req, pathParam(req.URL.Path, "/venues/", "/vibe-aggregate"), params)
+	})
+	r.Get("/venues/{id}/vibe/stream", func(w http.ResponseWriter, req *http.Request) {
+		si.GetVenuesIdVibeStream(w, req, pathParam(req.URL.Path, "/venues/", "/vibe/stream"))
+	})
+	r.Get("/vibe/stream", func(w http.ResponseWriter, req *http.Request) {
+		params := GetVibeStreamParams{}
+		bindString(req.URL.Query(), "bbox", &params.Bbox)
+		si.GetVibeStream(w, req, params)
+	})
+	r.Get("/vibe/heatmap", func(w http.ResponseWriter, req *http.Request) {
+		params := GetVibeHeatmapParams{}
+		q := req.URL.Query()
+		bindString(q, "bbox", &params.Bbox)
+		if err := bindInt(q, "precision", &params.Precision); err != nil {
+			invalidParam(w, "precision", err)
+			return
+		}
+		si.GetVibeHeatmap(w, req, params)
 	})
+	r.Post("/signals/activity", si.PostSignalsActivity)
 	return r
 }
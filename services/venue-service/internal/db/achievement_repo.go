@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// AchievementEvent is a domain event kept to evaluate a user's
+// achievements. EventID is unique per user (e.g. "checkin:42"), so a
+// re-delivered event is stored once.
+type AchievementEvent struct {
+	UserID     string            `json:"userId"`
+	EventID    string            `json:"eventId"`
+	Type       string            `json:"type"`
+	Attrs      map[string]string `json:"attrs"`
+	Zone       string            `json:"zone,omitempty"` // IANA timezone; "" for the service default
+	OccurredAt time.Time         `json:"occurredAt"`
+}
+
+// AchievementProgress is a user's standing on one achievement. EarnedAt is
+// set the first time the target is reached and never changes after.
+type AchievementProgress struct {
+	AchievementID string     `json:"achievementId"`
+	Progress      int        `json:"progress"`
+	Target        int        `json:"target"`
+	EarnedAt      *time.Time `json:"earnedAt,omitempty"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+}
+
+// InsertAchievementEvent stores e; created is false for an event id the
+// user already has.
+func (s *Store) InsertAchievementEvent(ctx context.Context, e *AchievementEvent) (bool, error) {
+	attrs := e.Attrs
+	if attrs == nil {
+		attrs = map[string]string{}
+	}
+	tag, err := s.Pool.Exec(ctx, `INSERT INTO achievement_events (user_id, event_id, type, attrs, zone, occurred_at)
+		VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT DO NOTHING`, e.UserID, e.EventID, e.Type, attrs, e.Zone, e.OccurredAt)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ListAchievementEvents returns the user's events of the given types,
+// oldest first.
+func (s *Store) ListAchievementEvents(ctx context.Context, userID string, types []string) ([]AchievementEvent, error) {
+	rows, err := s.Pool.Query(ctx, `SELECT user_id, event_id, type, attrs, zone, occurred_at FROM achievement_events
+		WHERE user_id = $1 AND type = ANY($2) ORDER BY occurred_at, event_id`, userID, types)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []AchievementEvent
+	for rows.Next() {
+		var e AchievementEvent
+		if err := rows.Scan(&e.UserID, &e.EventID, &e.Type, &e.Attrs, &e.Zone, &e.OccurredAt); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// SaveAchievementProgress upserts the user's progress. A row with EarnedAt
+// set grants the achievement unless it was already earned; earned lists the
+// achievement ids granted by this call.
+func (s *Store) SaveAchievementProgress(ctx context.Context, userID string, progress []AchievementProgress) ([]string, error) {
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	var earned []string
+	for _, p := range progress {
+		q := `INSERT INTO user_achievements (user_id, achievement_id, progress, target) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id, achievement_id) DO UPDATE SET progress = EXCLUDED.progress, target = EXCLUDED.target, updated_at = NOW()`
+		if _, err := tx.Exec(ctx, q, userID, p.AchievementID, p.Progress, p.Target); err != nil {
+			return nil, err
+		}
+		if p.EarnedAt == nil {
+			continue
+		}
+		// the row lock taken above makes a concurrent grant wait and then
+		// find earned_at already set
+		tag, err := tx.Exec(ctx, `UPDATE user_achievements SET earned_at = $3
+			WHERE user_id = $1 AND achievement_id = $2 AND earned_at IS NULL`, userID, p.AchievementID, *p.EarnedAt)
+		if err != nil {
+			return nil, err
+		}
+		if tag.RowsAffected() == 1 {
+			earned = append(earned, p.AchievementID)
+		}
+	}
+	return earned, tx.Commit(ctx)
+}
+
+// ListAchievementProgress returns every achievement the user has progress
+// on, in no particular order.
+func (s *Store) ListAchievementProgress(ctx context.Context, userID string) ([]AchievementProgress, error) {
+	rows, err := s.Pool.Query(ctx, `SELECT achievement_id, progress, target, earned_at, updated_at FROM user_achievements
+		WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []AchievementProgress
+	for rows.Next() {
+		var p AchievementProgress
+		if err := rows.Scan(&p.AchievementID, &p.Progress, &p.Target, &p.EarnedAt, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ActivitySignal is an ingestion-derived activity aggregate for one geohash7
+// cell: how many devices were there and how lively they were (0-1, from
+// motion and loudness) over a window ending at ObservedAt.
+type ActivitySignal struct {
+	ID         int64     `json:"id"`
+	Geohash    string    `json:"geohash7"`
+	Devices    int       `json:"devices"`
+	Intensity  float64   `json:"intensity"`
+	ObservedAt time.Time `json:"observedAt"`
+	ReceivedAt time.Time `json:"receivedAt"`
+}
+
+// InsertActivitySignals stores a batch in one round trip.
+func (s *Store) InsertActivitySignals(ctx context.Context, signals []ActivitySignal) error {
+	batch := &pgx.Batch{}
+	for _, sig := range signals {
+		batch.Queue(`INSERT INTO activity_signals (geohash, devices, intensity, observed_at) VALUES ($1, $2, $3, $4)`,
+			sig.Geohash, sig.Devices, sig.Intensity, sig.ObservedAt)
+	}
+	return s.Pool.SendBatch(ctx, batch).Close()
+}
+
+// ListActivitySignals returns every signal observed since the cutoff, oldest
+// first.
+func (s *Store) ListActivitySignals(ctx context.Context, since time.Time) ([]ActivitySignal, error) {
+	rows, err := s.Pool.Query(ctx, `SELECT id, geohash, devices, intensity, observed_at, received_at
+		FROM activity_signals WHERE observed_at >= $1 ORDER BY observed_at, id`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ActivitySignal
+	for rows.Next() {
+		var a ActivitySignal
+		if err := rows.Scan(&a.ID, &a.Geohash, &a.Devices, &a.Intensity, &a.ObservedAt, &a.ReceivedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// PurgeActivitySignals deletes signals received before the cutoff.
+func (s *Store) PurgeActivitySignals(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := s.Pool.Exec(ctx, `DELETE FROM activity_signals WHERE received_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// FieldChange is the before/after JSON of one venue field.
+type FieldChange struct {
+	From json.RawMessage `json:"from,omitempty"`
+	To   json.RawMessage `json:"to,omitempty"`
+}
+
+// VenueAudit records one admin or host write to a venue.
+type VenueAudit struct {
+	ID        int64                  `json:"id"`
+	VenueID   string                 `json:"venueId"`
+	ActorID   string                 `json:"actorId"`
+	ActorRole string                 `json:"actorRole"`
+	Action    string                 `json:"action"` // create, update, archive, restore, status
+	Changes   map[string]FieldChange `json:"changes"`
+	CreatedAt time.Time              `json:"createdAt"`
+}
+
+func (s *Store) InsertVenueAudit(ctx context.Context, a *VenueAudit) error {
+	changes := a.Changes
+	if changes == nil {
+		changes = map[string]FieldChange{}
+	}
+	q := `INSERT INTO venue_audit (venue_id, actor_id, actor_role, action, changes)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`
+	return s.Pool.QueryRow(ctx, q, a.VenueID, a.ActorID, a.ActorRole, a.Action, changes).Scan(&a.ID, &a.CreatedAt)
+}
+
+// ListVenueAudit returns the venue's most recent audit entries, newest first.
+func (s *Store) ListVenueAudit(ctx context.Context, venueID string, limit int) ([]VenueAudit, error) {
+	q := `SELECT id, venue_id, actor_id, actor_role, action, changes, created_at
+		FROM venue_audit WHERE venue_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2`
+	rows, err := s.Pool.Query(ctx, q, venueID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []VenueAudit
+	for rows.Next() {
+		var a VenueAudit
+		if err := rows.Scan(&a.ID, &a.VenueID, &a.ActorID, &a.ActorRole, &a.Action, &a.Changes, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
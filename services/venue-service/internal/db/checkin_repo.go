@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Checkin records that a user was at a venue. Lat/Lon is the submitted
+// location and Distance its distance from the venue in meters. CreatedAt is
+// server time, set by the caller.
+type Checkin struct {
+	ID         int64     `json:"id"`
+	UserID     string    `json:"userId"`
+	VenueID    string    `json:"venueId"`
+	Lat        float64   `json:"lat"`
+	Lon        float64   `json:"lon"`
+	Accuracy   *float64  `json:"accuracy,omitempty"`
+	Distance   float64   `json:"distance"`
+	SharedWith []string  `json:"sharedWith"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+const checkinColumns = `id, user_id, venue_id, lat, lon, accuracy, distance, shared_with, created_at`
+
+func scanCheckin(row pgx.Row) (*Checkin, error) {
+	var c Checkin
+	if err := row.Scan(&c.ID, &c.UserID, &c.VenueID, &c.Lat, &c.Lon, &c.Accuracy, &c.Distance, &c.SharedWith, &c.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// InsertCheckin stores c unless the user already checked in at the venue
+// after since; that check-in is returned instead and c is left untouched.
+// Check-ins for the same user and venue are serialized, so two concurrent
+// requests can't both get past the cooldown.
+func (s *Store) InsertCheckin(ctx context.Context, c *Checkin, since time.Time) (*Checkin, error) {
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1 || '/' || $2))`, c.UserID, c.VenueID); err != nil {
+		return nil, err
+	}
+	prev, err := scanCheckin(tx.QueryRow(ctx, `SELECT `+checkinColumns+` FROM venue_checkins
+		WHERE user_id = $1 AND venue_id = $2 AND created_at > $3 ORDER BY created_at DESC LIMIT 1`, c.UserID, c.VenueID, since))
+	if err == nil {
+		return prev, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	shared := c.SharedWith
+	if shared == nil {
+		shared = []string{}
+	}
+	q := `INSERT INTO venue_checkins (user_id, venue_id, lat, lon, accuracy, distance, shared_with, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+	if err := tx.QueryRow(ctx, q, c.UserID, c.VenueID, c.Lat, c.Lon, c.Accuracy, c.Distance, shared, c.CreatedAt).Scan(&c.ID); err != nil {
+		return nil, err
+	}
+	return nil, tx.Commit(ctx)
+}
+
+// ListCheckinsBetween returns every venue's check-ins in [from, to), oldest
+// first.
+func (s *Store) ListCheckinsBetween(ctx context.Context, from, to time.Time) ([]Checkin, error) {
+	rows, err := s.Pool.Query(ctx, `SELECT `+checkinColumns+` FROM venue_checkins
+		WHERE created_at >= $1 AND created_at < $2 ORDER BY created_at, id`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Checkin
+	for rows.Next() {
+		c, err := scanCheckin(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *c)
+	}
+	return out, rows.Err()
+}
+
+// ListCheckins returns the user's check-ins, most recent first.
+func (s *Store) ListCheckins(ctx context.Context, userID string, limit int) ([]Checkin, error) {
+	rows, err := s.Pool.Query(ctx, `SELECT `+checkinColumns+` FROM venue_checkins
+		WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Checkin
+	for rows.Next() {
+		c, err := scanCheckin(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *c)
+	}
+	return out, rows.Err()
+}
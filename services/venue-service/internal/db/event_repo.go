@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type EventKind string
+
+const (
+	EventKindEvent   EventKind = "event"
+	EventKindSpecial EventKind = "special" // happy hours, drink deals
+)
+
+type EventStatus string
+
+const (
+	EventScheduled EventStatus = "scheduled"
+	EventCancelled EventStatus = "cancelled"
+)
+
+// VenueEvent is an event or special a venue promotes. StartsAt and EndsAt
+// are the first occurrence; Recurrence (an RRULE value, "" for a one-off)
+// repeats it in Timezone. SeriesEndsAt is when the last occurrence ends, nil
+// for open-ended series.
+type VenueEvent struct {
+	ID             int64       `json:"id"`
+	VenueID        string      `json:"venueId"`
+	Kind           EventKind   `json:"kind"`
+	Title          string      `json:"title"`
+	Description    string      `json:"description"`
+	StartsAt       time.Time   `json:"startsAt"`
+	EndsAt         time.Time   `json:"endsAt"`
+	Timezone       string      `json:"timezone"`
+	Recurrence     string      `json:"recurrence,omitempty"`
+	SeriesEndsAt   *time.Time  `json:"seriesEndsAt,omitempty"`
+	TicketURL      string      `json:"ticketUrl,omitempty"`
+	ReservationURL string      `json:"reservationUrl,omitempty"`
+	Tags           []string    `json:"tags"`
+	Status         EventStatus `json:"status"`
+	CreatedBy      string      `json:"createdBy"`
+	CreatedAt      time.Time   `json:"createdAt"`
+	UpdatedAt      time.Time   `json:"updatedAt"`
+}
+
+// EventFilter narrows ListVenueEvents to series that may have an occurrence
+// in [From, To); zero times leave that side open, nil VenueIDs matches every
+// venue.
+type EventFilter struct {
+	VenueIDs         []string
+	From, To         time.Time
+	IncludeCancelled bool
+}
+
+const eventColumns = `id, venue_id, kind, title, description, starts_at, ends_at, timezone, recurrence, series_ends_at,
+	ticket_url, reservation_url, tags, status, created_by, created_at, updated_at`
+
+func scanEvent(row pgx.Row) (*VenueEvent, error) {
+	var e VenueEvent
+	err := row.Scan(&e.ID, &e.VenueID, &e.Kind, &e.Title, &e.Description, &e.StartsAt, &e.EndsAt, &e.Timezone, &e.Recurrence,
+		&e.SeriesEndsAt, &e.TicketURL, &e.ReservationURL, &e.Tags, &e.Status, &e.CreatedBy, &e.CreatedAt, &e.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (s *Store) CreateVenueEvent(ctx context.Context, e *VenueEvent) error {
+	q := `INSERT INTO venue_events (venue_id, kind, title, description, starts_at, ends_at, timezone, recurrence, series_ends_at,
+			ticket_url, reservation_url, tags, status, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING id, created_at, updated_at`
+	return s.Pool.QueryRow(ctx, q, e.VenueID, e.Kind, e.Title, e.Description, e.StartsAt, e.EndsAt, e.Timezone, e.Recurrence,
+		e.SeriesEndsAt, e.TicketURL, e.ReservationURL, nonNil(e.Tags), e.Status, e.CreatedBy).Scan(&e.ID, &e.CreatedAt, &e.UpdatedAt)
+}
+
+func (s *Store) GetVenueEvent(ctx context.Context, id int64) (*VenueEvent, error) {
+	e, err := scanEvent(s.Pool.QueryRow(ctx, `SELECT `+eventColumns+` FROM venue_events WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return e, err
+}
+
+// UpdateVenueEvent writes every editable field of e; it returns ErrNotFound
+// for an unknown id.
+func (s *Store) UpdateVenueEvent(ctx context.Context, e *VenueEvent) error {
+	q := `UPDATE venue_events SET kind=$2, title=$3, description=$4, starts_at=$5, ends_at=$6, timezone=$7, recurrence=$8,
+			series_ends_at=$9, ticket_url=$10, reservation_url=$11, tags=$12, status=$13, updated_at=NOW()
+		WHERE id=$1 RETURNING updated_at`
+	err := s.Pool.QueryRow(ctx, q, e.ID, e.Kind, e.Title, e.Description, e.StartsAt, e.EndsAt, e.Timezone, e.Recurrence,
+		e.SeriesEndsAt, e.TicketURL, e.ReservationURL, nonNil(e.Tags), e.Status).Scan(&e.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Store) DeleteVenueEvent(ctx context.Context, id int64) (bool, error) {
+	tag, err := s.Pool.Exec(ctx, `DELETE FROM venue_events WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ListVenueEvents returns the series matching f by first start, then id.
+func (s *Store) ListVenueEvents(ctx context.Context, f EventFilter) ([]VenueEvent, error) {
+	var from, to *time.Time
+	if !f.From.IsZero() {
+		from = &f.From
+	}
+	if !f.To.IsZero() {
+		to = &f.To
+	}
+	q := `SELECT ` + eventColumns + ` FROM venue_events
+		WHERE ($1::text[] IS NULL OR venue_id = ANY($1))
+			AND ($2::timestamptz IS NULL OR series_ends_at IS NULL OR series_ends_at > $2)
+			AND ($3::timestamptz IS NULL OR starts_at < $3)
+			AND ($4 OR status = 'scheduled')
+		ORDER BY starts_at, id`
+	rows, err := s.Pool.Query(ctx, q, f.VenueIDs, from, to, f.IncludeCancelled)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []VenueEvent
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *e)
+	}
+	return out, rows.Err()
+}
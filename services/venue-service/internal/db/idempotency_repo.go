@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"bytspot/shared/idempotency"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// BeginIdempotentRequest claims key in one statement: a new key is inserted,
+// an expired or abandoned one is taken over, otherwise the existing record
+// is returned.
+func (s *Store) BeginIdempotentRequest(ctx context.Context, key, fingerprint string, ttl time.Duration) (*idempotency.Record, error) {
+	q := `INSERT INTO idempotency_keys (key, fingerprint, expires_at) VALUES ($1, $2, NOW() + make_interval(secs => $3))
+		ON CONFLICT (key) DO UPDATE SET fingerprint = EXCLUDED.fingerprint, status = NULL, content_type = '',
+			body = NULL, locked_at = NOW(), expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at < NOW()
+			OR (idempotency_keys.status IS NULL AND idempotency_keys.locked_at < NOW() - make_interval(secs => $4))
+		RETURNING key`
+	var claimed string
+	err := s.Pool.QueryRow(ctx, q, key, fingerprint, ttl.Seconds(), idempotency.LockTimeout.Seconds()).Scan(&claimed)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	rec := &idempotency.Record{}
+	var status *int
+	err = s.Pool.QueryRow(ctx, `SELECT fingerprint, status, content_type, body FROM idempotency_keys WHERE key=$1`, key).
+		Scan(&rec.Fingerprint, &status, &rec.ContentType, &rec.Body)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// released between the two statements; let the client retry
+		return &idempotency.Record{Fingerprint: fingerprint}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status != nil {
+		rec.Status = *status
+	}
+	return rec, nil
+}
+
+func (s *Store) CompleteIdempotentRequest(ctx context.Context, key string, status int, contentType string, body []byte) error {
+	_, err := s.Pool.Exec(ctx, `UPDATE idempotency_keys SET status=$2, content_type=$3, body=$4 WHERE key=$1`, key, status, contentType, body)
+	return err
+}
+
+func (s *Store) ReleaseIdempotentRequest(ctx context.Context, key string) error {
+	_, err := s.Pool.Exec(ctx, `DELETE FROM idempotency_keys WHERE key=$1 AND status IS NULL`, key)
+	return err
+}
+
+func (s *Store) PurgeIdempotentRequests(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := s.Pool.Exec(ctx, `DELETE FROM idempotency_keys WHERE expires_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,183 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type InteractionKind string
+
+const (
+	InteractionLike InteractionKind = "like"
+	InteractionSkip InteractionKind = "skip"
+	// InteractionUnlike only appears in the event log.
+	InteractionUnlike InteractionKind = "unlike"
+)
+
+type Interaction struct {
+	UserID    string          `json:"userId"`
+	VenueID   string          `json:"venueId"`
+	Kind      InteractionKind `json:"kind"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// UpsertInteraction records the user's swipe, replacing an earlier one on the
+// same venue, and keeps venues.like_count in step. changed is false when the
+// same kind was already recorded, so retries are no-ops. Swipes by the same
+// user on the same venue are serialized: with no row yet, FOR UPDATE locks
+// nothing and two first likes would both count.
+func (s *Store) UpsertInteraction(ctx context.Context, userID, venueID string, kind InteractionKind) (bool, error) {
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+	if err := lockInteraction(ctx, tx, userID, venueID); err != nil {
+		return false, err
+	}
+	var prev InteractionKind
+	err = tx.QueryRow(ctx, `SELECT kind FROM venue_interactions WHERE user_id=$1 AND venue_id=$2`, userID, venueID).Scan(&prev)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return false, err
+	}
+	if prev == kind {
+		return false, nil
+	}
+	q := `INSERT INTO venue_interactions (user_id, venue_id, kind) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, venue_id) DO UPDATE SET kind = EXCLUDED.kind, created_at = NOW()`
+	if _, err := tx.Exec(ctx, q, userID, venueID, kind); err != nil {
+		return false, err
+	}
+	delta := 0
+	if kind == InteractionLike {
+		delta = 1
+	} else if prev == InteractionLike {
+		delta = -1
+	}
+	if delta != 0 {
+		if _, err := tx.Exec(ctx, `UPDATE venues SET like_count = like_count + $2 WHERE id=$1`, venueID, delta); err != nil {
+			return false, err
+		}
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO venue_interaction_events (user_id, venue_id, kind) VALUES ($1, $2, $3)`, userID, venueID, kind); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// DeleteLike removes the user's like; changed is false if there was none.
+func (s *Store) DeleteLike(ctx context.Context, userID, venueID string) (bool, error) {
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+	if err := lockInteraction(ctx, tx, userID, venueID); err != nil {
+		return false, err
+	}
+	tag, err := tx.Exec(ctx, `DELETE FROM venue_interactions WHERE user_id=$1 AND venue_id=$2 AND kind='like'`, userID, venueID)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	if _, err := tx.Exec(ctx, `UPDATE venues SET like_count = GREATEST(like_count - 1, 0) WHERE id=$1`, venueID); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO venue_interaction_events (user_id, venue_id, kind) VALUES ($1, $2, 'unlike')`, userID, venueID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// lockInteraction holds the user's swipe on the venue until tx ends.
+func lockInteraction(ctx context.Context, tx pgx.Tx, userID, venueID string) error {
+	_, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('interaction/' || $1 || '/' || $2))`, userID, venueID)
+	return err
+}
+
+// InteractedVenueIDs returns every venue the user liked or skipped.
+func (s *Store) InteractedVenueIDs(ctx context.Context, userID string) ([]string, error) {
+	rows, err := s.Pool.Query(ctx, `SELECT venue_id FROM venue_interactions WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
+
+// ListLikes returns the user's likes, most recent first.
+func (s *Store) ListLikes(ctx context.Context, userID string, limit int) ([]Interaction, error) {
+	return s.listInteractions(ctx, `SELECT user_id, venue_id, kind, created_at FROM venue_interactions
+		WHERE user_id = $1 AND kind = 'like' ORDER BY created_at DESC, venue_id LIMIT $2`, userID, limit)
+}
+
+// ListInteractions returns the user's current likes and skips, most recent
+// first.
+func (s *Store) ListInteractions(ctx context.Context, userID string, limit int) ([]Interaction, error) {
+	return s.listInteractions(ctx, `SELECT user_id, venue_id, kind, created_at FROM venue_interactions
+		WHERE user_id = $1 ORDER BY created_at DESC, venue_id LIMIT $2`, userID, limit)
+}
+
+func (s *Store) listInteractions(ctx context.Context, q string, args ...any) ([]Interaction, error) {
+	rows, err := s.Pool.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Interaction
+	for rows.Next() {
+		var i Interaction
+		if err := rows.Scan(&i.UserID, &i.VenueID, &i.Kind, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, i)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) TotalLikes(ctx context.Context) (int, error) {
+	var n int
+	err := s.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM venue_interactions WHERE kind = 'like'`).Scan(&n)
+	return n, err
+}
+
+// ListLikeEvents returns the likes in the event log in [from, to), oldest
+// first.
+func (s *Store) ListLikeEvents(ctx context.Context, from, to time.Time) ([]Interaction, error) {
+	return s.listInteractions(ctx, `SELECT user_id, venue_id, kind, created_at FROM venue_interaction_events
+		WHERE kind = 'like' AND created_at >= $1 AND created_at < $2 ORDER BY created_at, id`, from, to)
+}
+
+// CountLikeEvents returns how many likes each venue received in [from, to),
+// counting every like in the event log (an unlike doesn't take one back).
+func (s *Store) CountLikeEvents(ctx context.Context, from, to time.Time) (map[string]int, error) {
+	rows, err := s.Pool.Query(ctx, `SELECT venue_id, COUNT(*) FROM venue_interaction_events
+		WHERE kind = 'like' AND created_at >= $1 AND created_at < $2 GROUP BY venue_id`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]int{}
+	for rows.Next() {
+		var id string
+		var n int
+		if err := rows.Scan(&id, &n); err != nil {
+			return nil, err
+		}
+		out[id] = n
+	}
+	return out, rows.Err()
+}
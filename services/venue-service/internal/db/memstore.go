@@ -0,0 +1,908 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"maps"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"bytspot/shared/idempotency"
+)
+
+var (
+	ErrDuplicateID = errors.New("duplicate_id")
+	ErrNotFound    = errors.New("not_found")
+)
+
+// MemStore is an in-memory Repository for tests and DB-less local runs.
+// Reads return copies so callers can't mutate stored rows.
+type MemStore struct {
+	*idempotency.MemoryStore
+
+	mu           sync.RWMutex
+	venues       map[string]*Venue
+	interactions map[interactionKey]*Interaction
+	events       []Interaction // append-only swipe log
+	vibes        []VibeReport  // in insertion order
+	vibeKeys     map[vibeKey]bool
+	nextVibeID   int64
+	checkins     []Checkin // in insertion order
+	reviews      map[int64]*Review
+	reviewVotes  map[reviewUserKey]bool
+	reports      map[reviewUserKey]string // reason
+	nextReviewID int64
+	activity     []ActivitySignal
+	nextSignalID int64
+	audit        []VenueAudit
+	nextAuditID  int64
+	venueEvents  map[int64]*VenueEvent
+	nextEventID  int64
+	achEvents    map[string][]AchievementEvent // by user, in insertion order
+	achProgress  map[achievementKey]*AchievementProgress
+	dailyStats   map[statsKey]*VenueDailyStats
+	nonces       map[string]time.Time // key -> expiry
+}
+
+type interactionKey struct{ userID, venueID string }
+
+type vibeKey struct{ venueID, idempotencyKey string }
+
+type reviewUserKey struct {
+	reviewID int64
+	userID   string
+}
+
+type achievementKey struct{ userID, achievementID string }
+
+type statsKey struct {
+	venueID string
+	day     time.Time
+}
+
+func NewMemStore() *MemStore {
+	return &MemStore{MemoryStore: idempotency.NewMemoryStore(), venues: map[string]*Venue{}, interactions: map[interactionKey]*Interaction{}, vibeKeys: map[vibeKey]bool{},
+		reviews: map[int64]*Review{}, reviewVotes: map[reviewUserKey]bool{}, reports: map[reviewUserKey]string{}, venueEvents: map[int64]*VenueEvent{},
+		achEvents: map[string][]AchievementEvent{}, achProgress: map[achievementKey]*AchievementProgress{},
+		dailyStats: map[statsKey]*VenueDailyStats{}, nonces: map[string]time.Time{}}
+}
+
+// newID returns a random UUIDv4-formatted id like gen_random_uuid().
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+func copyVenue(v *Venue) *Venue {
+	cp := *v
+	cp.Tags = append([]string{}, v.Tags...)
+	cp.Photos = append([]string{}, v.Photos...)
+	cp.Hours.Weekly = append([]DayRange(nil), v.Hours.Weekly...)
+	cp.Hours.Exceptions = nil
+	for _, ex := range v.Hours.Exceptions {
+		ex.Ranges = append([]TimeRange(nil), ex.Ranges...)
+		cp.Hours.Exceptions = append(cp.Hours.Exceptions, ex)
+	}
+	if v.OwnerID != nil {
+		owner := *v.OwnerID
+		cp.OwnerID = &owner
+	}
+	if v.ReviewRating != nil {
+		avg := *v.ReviewRating
+		cp.ReviewRating = &avg
+	}
+	return &cp
+}
+
+// Venues
+
+func (m *MemStore) CreateVenue(_ context.Context, v *Venue) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if v.ID == "" {
+		v.ID = newID()
+	}
+	if _, ok := m.venues[v.ID]; ok {
+		return ErrDuplicateID
+	}
+	if v.Status == "" {
+		v.Status = VenueActive
+	}
+	now := time.Now()
+	v.CreatedAt, v.UpdatedAt = now, now
+	m.venues[v.ID] = copyVenue(v)
+	return nil
+}
+
+func (m *MemStore) GetVenue(_ context.Context, id string) (*Venue, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if v, ok := m.venues[id]; ok {
+		return copyVenue(v), nil
+	}
+	return nil, nil
+}
+
+func (m *MemStore) CountVenues(_ context.Context) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	n := 0
+	for _, v := range m.venues {
+		if v.Status == VenueActive {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MemStore) ListVenues(_ context.Context) ([]Venue, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []Venue
+	for _, v := range m.venues {
+		if v.Status == VenueActive {
+			out = append(out, *copyVenue(v))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (m *MemStore) UpdateVenue(_ context.Context, v *Venue) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cur, ok := m.venues[v.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	v.LikeCount, v.Rating, v.CreatedAt = cur.LikeCount, cur.Rating, cur.CreatedAt
+	v.ReviewCount, v.ReviewRating = cur.ReviewCount, cur.ReviewRating
+	v.UpdatedAt = time.Now()
+	m.venues[v.ID] = copyVenue(v)
+	return nil
+}
+
+func (m *MemStore) ListAllVenues(_ context.Context, f VenueFilter) ([]Venue, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []Venue
+	for _, v := range m.venues {
+		if f.Status != "" && v.Status != f.Status {
+			continue
+		}
+		if f.OwnerID != "" && (v.OwnerID == nil || *v.OwnerID != f.OwnerID) {
+			continue
+		}
+		out = append(out, *copyVenue(v))
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.After(out[j].CreatedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+// Venue audit
+
+func (m *MemStore) InsertVenueAudit(_ context.Context, a *VenueAudit) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextAuditID++
+	a.ID = m.nextAuditID
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now()
+	}
+	m.audit = append(m.audit, *a)
+	return nil
+}
+
+func (m *MemStore) ListVenueAudit(_ context.Context, venueID string, limit int) ([]VenueAudit, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []VenueAudit
+	for i := len(m.audit) - 1; i >= 0 && len(out) < limit; i-- {
+		if m.audit[i].VenueID == venueID {
+			out = append(out, m.audit[i])
+		}
+	}
+	return out, nil
+}
+
+// Interactions
+
+func (m *MemStore) UpsertInteraction(_ context.Context, userID, venueID string, kind InteractionKind) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := interactionKey{userID, venueID}
+	prev := m.interactions[key]
+	if prev != nil && prev.Kind == kind {
+		return false, nil
+	}
+	if v := m.venues[venueID]; v != nil {
+		if kind == InteractionLike {
+			v.LikeCount++
+		} else if prev != nil && prev.Kind == InteractionLike {
+			v.LikeCount--
+		}
+	}
+	now := time.Now()
+	m.interactions[key] = &Interaction{UserID: userID, VenueID: venueID, Kind: kind, CreatedAt: now}
+	m.events = append(m.events, Interaction{UserID: userID, VenueID: venueID, Kind: kind, CreatedAt: now})
+	return true, nil
+}
+
+func (m *MemStore) DeleteLike(_ context.Context, userID, venueID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := interactionKey{userID, venueID}
+	if cur := m.interactions[key]; cur == nil || cur.Kind != InteractionLike {
+		return false, nil
+	}
+	delete(m.interactions, key)
+	if v := m.venues[venueID]; v != nil && v.LikeCount > 0 {
+		v.LikeCount--
+	}
+	m.events = append(m.events, Interaction{UserID: userID, VenueID: venueID, Kind: InteractionUnlike, CreatedAt: time.Now()})
+	return true, nil
+}
+
+func (m *MemStore) InteractedVenueIDs(_ context.Context, userID string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []string
+	for k := range m.interactions {
+		if k.userID == userID {
+			out = append(out, k.venueID)
+		}
+	}
+	return out, nil
+}
+
+func (m *MemStore) ListLikes(_ context.Context, userID string, limit int) ([]Interaction, error) {
+	return m.listInteractions(userID, InteractionLike, limit), nil
+}
+
+func (m *MemStore) ListInteractions(_ context.Context, userID string, limit int) ([]Interaction, error) {
+	return m.listInteractions(userID, "", limit), nil
+}
+
+// listInteractions returns the user's interactions of kind (any when
+// empty), most recent first.
+func (m *MemStore) listInteractions(userID string, kind InteractionKind, limit int) []Interaction {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []Interaction
+	for k, i := range m.interactions {
+		if k.userID == userID && (kind == "" || i.Kind == kind) {
+			out = append(out, *i)
+		}
+	}
+	sort.Slice(out, func(a, b int) bool {
+		if !out[a].CreatedAt.Equal(out[b].CreatedAt) {
+			return out[a].CreatedAt.After(out[b].CreatedAt)
+		}
+		return out[a].VenueID < out[b].VenueID
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+func (m *MemStore) TotalLikes(_ context.Context) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	n := 0
+	for _, i := range m.interactions {
+		if i.Kind == InteractionLike {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MemStore) CountLikeEvents(_ context.Context, from, to time.Time) (map[string]int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := map[string]int{}
+	for _, e := range m.events {
+		if e.Kind == InteractionLike && !e.CreatedAt.Before(from) && e.CreatedAt.Before(to) {
+			out[e.VenueID]++
+		}
+	}
+	return out, nil
+}
+
+func (m *MemStore) ListLikeEvents(_ context.Context, from, to time.Time) ([]Interaction, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []Interaction
+	for _, e := range m.events {
+		if e.Kind == InteractionLike && !e.CreatedAt.Before(from) && e.CreatedAt.Before(to) {
+			out = append(out, e)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+// Vibe reports
+
+func (m *MemStore) InsertVibeReport(_ context.Context, r *VibeReport) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if r.IdempotencyKey != nil {
+		k := vibeKey{r.VenueID, *r.IdempotencyKey}
+		if m.vibeKeys[k] {
+			return false, nil
+		}
+		m.vibeKeys[k] = true
+	}
+	m.nextVibeID++
+	r.ID = m.nextVibeID
+	r.ReceivedAt = time.Now()
+	m.vibes = append(m.vibes, copyVibe(*r))
+	return true, nil
+}
+
+func copyVibe(r VibeReport) VibeReport {
+	features := make(map[string]float64, len(r.Features))
+	for k, v := range r.Features {
+		features[k] = v
+	}
+	r.Features = features
+	return r
+}
+
+func (m *MemStore) ListVibeReports(_ context.Context, since time.Time) ([]VibeReport, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []VibeReport
+	for _, r := range m.vibes {
+		if !r.ReportedAt.Before(since) {
+			out = append(out, copyVibe(r))
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].ReportedAt.Before(out[j].ReportedAt) })
+	return out, nil
+}
+
+func (m *MemStore) PurgeVibeReports(_ context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kept := m.vibes[:0]
+	var n int64
+	for _, r := range m.vibes {
+		if r.ReceivedAt.Before(before) {
+			if r.IdempotencyKey != nil {
+				delete(m.vibeKeys, vibeKey{r.VenueID, *r.IdempotencyKey})
+			}
+			n++
+			continue
+		}
+		kept = append(kept, r)
+	}
+	m.vibes = kept
+	return n, nil
+}
+
+// Check-ins
+
+func copyCheckin(c Checkin) Checkin {
+	c.SharedWith = append([]string{}, c.SharedWith...)
+	return c
+}
+
+func (m *MemStore) InsertCheckin(_ context.Context, c *Checkin, since time.Time) (*Checkin, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.checkins) - 1; i >= 0; i-- {
+		if prev := m.checkins[i]; prev.UserID == c.UserID && prev.VenueID == c.VenueID && prev.CreatedAt.After(since) {
+			cp := copyCheckin(prev)
+			return &cp, nil
+		}
+	}
+	c.ID = int64(len(m.checkins) + 1)
+	m.checkins = append(m.checkins, copyCheckin(*c))
+	return nil, nil
+}
+
+func (m *MemStore) ListCheckins(_ context.Context, userID string, limit int) ([]Checkin, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []Checkin
+	for i := len(m.checkins) - 1; i >= 0; i-- {
+		if m.checkins[i].UserID == userID {
+			out = append(out, copyCheckin(m.checkins[i]))
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (m *MemStore) ListCheckinsBetween(_ context.Context, from, to time.Time) ([]Checkin, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []Checkin
+	for _, c := range m.checkins {
+		if !c.CreatedAt.Before(from) && c.CreatedAt.Before(to) {
+			out = append(out, copyCheckin(c))
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+// Reviews
+
+func copyReview(r *Review) Review {
+	cp := *r
+	cp.Flags = append([]string{}, r.Flags...)
+	if r.Rating != nil {
+		n := *r.Rating
+		cp.Rating = &n
+	}
+	if r.ModeratedBy != nil {
+		by := *r.ModeratedBy
+		cp.ModeratedBy = &by
+	}
+	return cp
+}
+
+// refreshReviewStats recomputes the venue's published review aggregates;
+// the caller holds the write lock.
+func (m *MemStore) refreshReviewStats(venueID string) {
+	v := m.venues[venueID]
+	if v == nil {
+		return
+	}
+	n, sum := 0, 0
+	for _, r := range m.reviews {
+		if r.VenueID == venueID && r.Kind == ReviewKindReview && r.Status == ReviewPublished {
+			n++
+			sum += *r.Rating
+		}
+	}
+	v.ReviewCount, v.ReviewRating = n, nil
+	if n > 0 {
+		avg := math.Round(float64(sum)/float64(n)*100) / 100
+		v.ReviewRating = &avg
+	}
+}
+
+func (m *MemStore) CreateReview(_ context.Context, r *Review) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if r.Kind == ReviewKindReview {
+		for _, cur := range m.reviews {
+			if cur.Kind == ReviewKindReview && cur.VenueID == r.VenueID && cur.UserID == r.UserID {
+				return ErrDuplicateReview
+			}
+		}
+	}
+	m.nextReviewID++
+	r.ID = m.nextReviewID
+	r.CreatedAt = time.Now()
+	r.UpdatedAt = r.CreatedAt
+	cp := copyReview(r)
+	m.reviews[r.ID] = &cp
+	m.refreshReviewStats(r.VenueID)
+	return nil
+}
+
+func (m *MemStore) GetReview(_ context.Context, id int64) (*Review, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	r, ok := m.reviews[id]
+	if !ok {
+		return nil, nil
+	}
+	cp := copyReview(r)
+	return &cp, nil
+}
+
+func (m *MemStore) UpdateReview(_ context.Context, r *Review) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cur, ok := m.reviews[r.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	cur.AuthorName, cur.Rating, cur.Body, cur.Status, cur.Flags = r.AuthorName, r.Rating, r.Body, r.Status, r.Flags
+	cur.UpdatedAt = time.Now()
+	*cur = copyReview(cur)
+	r.UpdatedAt = cur.UpdatedAt
+	m.refreshReviewStats(cur.VenueID)
+	return nil
+}
+
+func (m *MemStore) ModerateReview(_ context.Context, id int64, status ReviewStatus, moderator, note string) (*Review, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cur, ok := m.reviews[id]
+	if !ok {
+		return nil, nil
+	}
+	cur.Status, cur.ModeratedBy, cur.ModerationNote = status, &moderator, note
+	if status == ReviewPublished {
+		cur.ReportCount = 0
+	}
+	m.refreshReviewStats(cur.VenueID)
+	cp := copyReview(cur)
+	return &cp, nil
+}
+
+func (m *MemStore) DeleteReview(_ context.Context, id int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cur, ok := m.reviews[id]
+	if !ok {
+		return false, nil
+	}
+	delete(m.reviews, id)
+	for k := range m.reviewVotes {
+		if k.reviewID == id {
+			delete(m.reviewVotes, k)
+		}
+	}
+	for k := range m.reports {
+		if k.reviewID == id {
+			delete(m.reports, k)
+		}
+	}
+	m.refreshReviewStats(cur.VenueID)
+	return true, nil
+}
+
+func (m *MemStore) ListReviews(_ context.Context, f ReviewFilter) ([]Review, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []Review
+	for _, r := range m.reviews {
+		if (f.VenueID == "" || r.VenueID == f.VenueID) && (f.UserID == "" || r.UserID == f.UserID) &&
+			(f.Kind == "" || r.Kind == f.Kind) && (f.Status == "" || r.Status == f.Status) {
+			out = append(out, copyReview(r))
+		}
+	}
+	recent := func(a, b Review) bool {
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		switch f.Sort {
+		case ReviewsHelpful:
+			if a.HelpfulCount != b.HelpfulCount {
+				return a.HelpfulCount > b.HelpfulCount
+			}
+		case ReviewsReported:
+			if a.ReportCount != b.ReportCount {
+				return a.ReportCount > b.ReportCount
+			}
+			return recent(b, a)
+		}
+		return recent(a, b)
+	})
+	if len(out) > f.Limit {
+		out = out[:f.Limit]
+	}
+	return out, nil
+}
+
+func (m *MemStore) SetHelpful(_ context.Context, reviewID int64, userID string, helpful bool) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := reviewUserKey{reviewID, userID}
+	r, ok := m.reviews[reviewID]
+	if !ok || m.reviewVotes[key] == helpful {
+		return false, nil
+	}
+	if helpful {
+		m.reviewVotes[key] = true
+		r.HelpfulCount++
+	} else {
+		delete(m.reviewVotes, key)
+		r.HelpfulCount = max(r.HelpfulCount-1, 0)
+	}
+	return true, nil
+}
+
+func (m *MemStore) ReportReview(_ context.Context, reviewID int64, userID, reason string, holdAt int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := reviewUserKey{reviewID, userID}
+	r, ok := m.reviews[reviewID]
+	if _, dup := m.reports[key]; !ok || dup {
+		return false, nil
+	}
+	m.reports[key] = reason
+	r.ReportCount++
+	if r.Status == ReviewPublished && r.ReportCount >= holdAt {
+		r.Status = ReviewPending
+		if !slices.Contains(r.Flags, FlagReported) {
+			r.Flags = append(r.Flags, FlagReported)
+		}
+		m.refreshReviewStats(r.VenueID)
+	}
+	return true, nil
+}
+
+// Venue events
+
+func copyEvent(e *VenueEvent) VenueEvent {
+	cp := *e
+	cp.Tags = append([]string{}, e.Tags...)
+	if e.SeriesEndsAt != nil {
+		end := *e.SeriesEndsAt
+		cp.SeriesEndsAt = &end
+	}
+	return cp
+}
+
+func (m *MemStore) CreateVenueEvent(_ context.Context, e *VenueEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextEventID++
+	now := time.Now()
+	e.ID, e.CreatedAt, e.UpdatedAt = m.nextEventID, now, now
+	cp := copyEvent(e)
+	m.venueEvents[e.ID] = &cp
+	return nil
+}
+
+func (m *MemStore) GetVenueEvent(_ context.Context, id int64) (*VenueEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	e, ok := m.venueEvents[id]
+	if !ok {
+		return nil, nil
+	}
+	cp := copyEvent(e)
+	return &cp, nil
+}
+
+func (m *MemStore) UpdateVenueEvent(_ context.Context, e *VenueEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cur, ok := m.venueEvents[e.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	e.UpdatedAt = time.Now()
+	cp := copyEvent(e)
+	cp.VenueID, cp.CreatedBy, cp.CreatedAt = cur.VenueID, cur.CreatedBy, cur.CreatedAt
+	m.venueEvents[e.ID] = &cp
+	return nil
+}
+
+func (m *MemStore) DeleteVenueEvent(_ context.Context, id int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.venueEvents[id]; !ok {
+		return false, nil
+	}
+	delete(m.venueEvents, id)
+	return true, nil
+}
+
+func (m *MemStore) ListVenueEvents(_ context.Context, f EventFilter) ([]VenueEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []VenueEvent
+	for _, e := range m.venueEvents {
+		switch {
+		case f.VenueIDs != nil && !slices.Contains(f.VenueIDs, e.VenueID),
+			!f.From.IsZero() && e.SeriesEndsAt != nil && !e.SeriesEndsAt.After(f.From),
+			!f.To.IsZero() && !e.StartsAt.Before(f.To),
+			!f.IncludeCancelled && e.Status != EventScheduled:
+			continue
+		}
+		out = append(out, copyEvent(e))
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].StartsAt.Equal(out[j].StartsAt) {
+			return out[i].StartsAt.Before(out[j].StartsAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+// Daily stats
+
+func (m *MemStore) statsRow(venueID string, day time.Time) *VenueDailyStats {
+	k := statsKey{venueID, day}
+	d := m.dailyStats[k]
+	if d == nil {
+		d = &VenueDailyStats{VenueID: venueID, Day: day, Hourly: make([]int, 24)}
+		m.dailyStats[k] = d
+	}
+	return d
+}
+
+func (m *MemStore) SaveVenueDailyStats(_ context.Context, stats []VenueDailyStats) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range stats {
+		d := m.statsRow(s.VenueID, s.Day)
+		d.Likes, d.Checkins, d.VibeReports, d.VibeScoreSum = s.Likes, s.Checkins, s.VibeReports, s.VibeScoreSum
+		d.Hourly = append([]int{}, s.Hourly...)
+	}
+	return nil
+}
+
+func (m *MemStore) AddVenueImpressions(_ context.Context, counts []VenueImpressions) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range counts {
+		m.statsRow(c.VenueID, c.Day).Impressions += c.Count
+	}
+	return nil
+}
+
+func (m *MemStore) ListVenueDailyStats(_ context.Context, venueIDs []string, from, to time.Time) ([]VenueDailyStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []VenueDailyStats
+	for _, d := range m.dailyStats {
+		if slices.Contains(venueIDs, d.VenueID) && !d.Day.Before(from) && !d.Day.After(to) {
+			cp := *d
+			cp.Hourly = append([]int{}, d.Hourly...)
+			out = append(out, cp)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].VenueID != out[j].VenueID {
+			return out[i].VenueID < out[j].VenueID
+		}
+		return out[i].Day.Before(out[j].Day)
+	})
+	return out, nil
+}
+
+// Achievements
+
+func (m *MemStore) InsertAchievementEvent(_ context.Context, e *AchievementEvent) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, cur := range m.achEvents[e.UserID] {
+		if cur.EventID == e.EventID {
+			return false, nil
+		}
+	}
+	cp := *e
+	cp.Attrs = maps.Clone(e.Attrs)
+	m.achEvents[e.UserID] = append(m.achEvents[e.UserID], cp)
+	return true, nil
+}
+
+func (m *MemStore) ListAchievementEvents(_ context.Context, userID string, types []string) ([]AchievementEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []AchievementEvent
+	for _, e := range m.achEvents[userID] {
+		if slices.Contains(types, e.Type) {
+			cp := e
+			cp.Attrs = maps.Clone(e.Attrs)
+			out = append(out, cp)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].OccurredAt.Before(out[j].OccurredAt) })
+	return out, nil
+}
+
+func (m *MemStore) SaveAchievementProgress(_ context.Context, userID string, progress []AchievementProgress) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	var earned []string
+	for _, p := range progress {
+		key := achievementKey{userID, p.AchievementID}
+		cur, ok := m.achProgress[key]
+		if !ok {
+			cur = &AchievementProgress{AchievementID: p.AchievementID}
+			m.achProgress[key] = cur
+		}
+		cur.Progress, cur.Target, cur.UpdatedAt = p.Progress, p.Target, now
+		if p.EarnedAt != nil && cur.EarnedAt == nil {
+			at := *p.EarnedAt
+			cur.EarnedAt = &at
+			earned = append(earned, p.AchievementID)
+		}
+	}
+	return earned, nil
+}
+
+func (m *MemStore) ListAchievementProgress(_ context.Context, userID string) ([]AchievementProgress, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []AchievementProgress
+	for key, p := range m.achProgress {
+		if key.userID == userID {
+			out = append(out, *p)
+		}
+	}
+	return out, nil
+}
+
+// Activity signals
+
+func (m *MemStore) InsertActivitySignals(_ context.Context, signals []ActivitySignal) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for _, sig := range signals {
+		m.nextSignalID++
+		sig.ID, sig.ReceivedAt = m.nextSignalID, now
+		m.activity = append(m.activity, sig)
+	}
+	return nil
+}
+
+func (m *MemStore) ListActivitySignals(_ context.Context, since time.Time) ([]ActivitySignal, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []ActivitySignal
+	for _, a := range m.activity {
+		if !a.ObservedAt.Before(since) {
+			out = append(out, a)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].ObservedAt.Before(out[j].ObservedAt) })
+	return out, nil
+}
+
+func (m *MemStore) ClaimNonce(_ context.Context, key string, now, expires time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if exp, ok := m.nonces[key]; ok && !exp.Before(now) {
+		return false, nil
+	}
+	m.nonces[key] = expires
+	return true, nil
+}
+
+func (m *MemStore) PurgeNonces(_ context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for k, exp := range m.nonces {
+		if exp.Before(before) {
+			delete(m.nonces, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MemStore) PurgeActivitySignals(_ context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kept := m.activity[:0]
+	var n int64
+	for _, a := range m.activity {
+		if a.ReceivedAt.Before(before) {
+			n++
+			continue
+		}
+		kept = append(kept, a)
+	}
+	m.activity = kept
+	return n, nil
+}
@@ -0,0 +1,32 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ClaimNonce records key until expires and reports whether it was unused at
+// now. An expired record is taken over, so purging is only housekeeping.
+func (s *Store) ClaimNonce(ctx context.Context, key string, now, expires time.Time) (bool, error) {
+	q := `INSERT INTO signature_nonces (key, expires_at) VALUES ($1, $3)
+		ON CONFLICT (key) DO UPDATE SET expires_at = EXCLUDED.expires_at
+		WHERE signature_nonces.expires_at < $2
+		RETURNING key`
+	var claimed string
+	err := s.Pool.QueryRow(ctx, q, key, now, expires).Scan(&claimed)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *Store) PurgeNonces(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := s.Pool.Exec(ctx, `DELETE FROM signature_nonces WHERE expires_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
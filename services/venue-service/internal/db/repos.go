@@ -0,0 +1,120 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"bytspot/shared/idempotency"
+)
+
+// Repository interfaces used by the HTTP layer. *Store implements them on
+// Postgres; *MemStore implements them in memory for tests and local dev.
+
+type VenueRepo interface {
+	CreateVenue(ctx context.Context, v *Venue) error
+	GetVenue(ctx context.Context, id string) (*Venue, error)
+	ListVenues(ctx context.Context) ([]Venue, error)
+	CountVenues(ctx context.Context) (int, error)
+	// Admin: any status, and full-row updates.
+	ListAllVenues(ctx context.Context, f VenueFilter) ([]Venue, error)
+	UpdateVenue(ctx context.Context, v *Venue) error
+}
+
+type VenueAuditRepo interface {
+	InsertVenueAudit(ctx context.Context, a *VenueAudit) error
+	ListVenueAudit(ctx context.Context, venueID string, limit int) ([]VenueAudit, error)
+}
+
+type InteractionRepo interface {
+	UpsertInteraction(ctx context.Context, userID, venueID string, kind InteractionKind) (bool, error)
+	DeleteLike(ctx context.Context, userID, venueID string) (bool, error)
+	InteractedVenueIDs(ctx context.Context, userID string) ([]string, error)
+	ListLikes(ctx context.Context, userID string, limit int) ([]Interaction, error)
+	ListInteractions(ctx context.Context, userID string, limit int) ([]Interaction, error)
+	TotalLikes(ctx context.Context) (int, error)
+	CountLikeEvents(ctx context.Context, from, to time.Time) (map[string]int, error)
+	ListLikeEvents(ctx context.Context, from, to time.Time) ([]Interaction, error)
+}
+
+type VibeRepo interface {
+	InsertVibeReport(ctx context.Context, r *VibeReport) (bool, error)
+	ListVibeReports(ctx context.Context, since time.Time) ([]VibeReport, error)
+	PurgeVibeReports(ctx context.Context, before time.Time) (int64, error)
+}
+
+type CheckinRepo interface {
+	InsertCheckin(ctx context.Context, c *Checkin, since time.Time) (*Checkin, error)
+	ListCheckins(ctx context.Context, userID string, limit int) ([]Checkin, error)
+	ListCheckinsBetween(ctx context.Context, from, to time.Time) ([]Checkin, error)
+}
+
+type StatsRepo interface {
+	SaveVenueDailyStats(ctx context.Context, stats []VenueDailyStats) error
+	AddVenueImpressions(ctx context.Context, counts []VenueImpressions) error
+	ListVenueDailyStats(ctx context.Context, venueIDs []string, from, to time.Time) ([]VenueDailyStats, error)
+}
+
+type ReviewRepo interface {
+	CreateReview(ctx context.Context, r *Review) error
+	GetReview(ctx context.Context, id int64) (*Review, error)
+	UpdateReview(ctx context.Context, r *Review) error
+	ModerateReview(ctx context.Context, id int64, status ReviewStatus, moderator, note string) (*Review, error)
+	DeleteReview(ctx context.Context, id int64) (bool, error)
+	ListReviews(ctx context.Context, f ReviewFilter) ([]Review, error)
+	SetHelpful(ctx context.Context, reviewID int64, userID string, helpful bool) (bool, error)
+	ReportReview(ctx context.Context, reviewID int64, userID, reason string, holdAt int) (bool, error)
+}
+
+type EventRepo interface {
+	CreateVenueEvent(ctx context.Context, e *VenueEvent) error
+	GetVenueEvent(ctx context.Context, id int64) (*VenueEvent, error)
+	UpdateVenueEvent(ctx context.Context, e *VenueEvent) error
+	DeleteVenueEvent(ctx context.Context, id int64) (bool, error)
+	ListVenueEvents(ctx context.Context, f EventFilter) ([]VenueEvent, error)
+}
+
+type AchievementRepo interface {
+	InsertAchievementEvent(ctx context.Context, e *AchievementEvent) (bool, error)
+	ListAchievementEvents(ctx context.Context, userID string, types []string) ([]AchievementEvent, error)
+	SaveAchievementProgress(ctx context.Context, userID string, progress []AchievementProgress) ([]string, error)
+	ListAchievementProgress(ctx context.Context, userID string) ([]AchievementProgress, error)
+}
+
+type ActivityRepo interface {
+	InsertActivitySignals(ctx context.Context, signals []ActivitySignal) error
+	ListActivitySignals(ctx context.Context, since time.Time) ([]ActivitySignal, error)
+	PurgeActivitySignals(ctx context.Context, before time.Time) (int64, error)
+}
+
+// IdempotencyRepo backs the shared Idempotency-Key middleware.
+type IdempotencyRepo interface {
+	idempotency.Store
+	PurgeIdempotentRequests(ctx context.Context, before time.Time) (int64, error)
+}
+
+// NonceRepo remembers the nonces of signed requests across instances.
+type NonceRepo interface {
+	ClaimNonce(ctx context.Context, key string, now, expires time.Time) (bool, error)
+	PurgeNonces(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Repository bundles every repo; both backends implement all of them.
+type Repository interface {
+	VenueRepo
+	VenueAuditRepo
+	InteractionRepo
+	VibeRepo
+	CheckinRepo
+	ReviewRepo
+	EventRepo
+	AchievementRepo
+	StatsRepo
+	ActivityRepo
+	IdempotencyRepo
+	NonceRepo
+}
+
+var (
+	_ Repository = (*Store)(nil)
+	_ Repository = (*MemStore)(nil)
+)
@@ -0,0 +1,333 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type ReviewKind string
+
+const (
+	ReviewKindReview ReviewKind = "review"
+	ReviewKindTip    ReviewKind = "tip"
+)
+
+type ReviewStatus string
+
+const (
+	ReviewPending   ReviewStatus = "pending"
+	ReviewPublished ReviewStatus = "published"
+	ReviewRejected  ReviewStatus = "rejected"
+)
+
+// FlagReported marks content that went back to the queue on abuse reports.
+const FlagReported = "reported"
+
+// ErrDuplicateReview: the user already reviewed the venue (tips are not
+// limited).
+var ErrDuplicateReview = errors.New("duplicate_review")
+
+// Review is a user's review (Rating set) or insider tip (Rating nil) of a
+// venue. Only published ones are public and count toward the venue's
+// review aggregates.
+type Review struct {
+	ID             int64        `json:"id"`
+	VenueID        string       `json:"venueId"`
+	UserID         string       `json:"userId"`
+	AuthorName     string       `json:"authorName"`
+	Kind           ReviewKind   `json:"kind"`
+	Rating         *int         `json:"rating,omitempty"`
+	Body           string       `json:"body"`
+	Status         ReviewStatus `json:"status"`
+	Flags          []string     `json:"flags"` // keyword filter matches, FlagReported
+	HelpfulCount   int          `json:"helpfulCount"`
+	ReportCount    int          `json:"reportCount"`
+	ModeratedBy    *string      `json:"moderatedBy,omitempty"`
+	ModerationNote string       `json:"moderationNote,omitempty"`
+	CreatedAt      time.Time    `json:"createdAt"`
+	UpdatedAt      time.Time    `json:"updatedAt"`
+}
+
+type ReviewSort string
+
+const (
+	ReviewsRecent   ReviewSort = "recent"
+	ReviewsHelpful  ReviewSort = "helpful"
+	ReviewsReported ReviewSort = "reported" // most reported, then oldest: the admin queue
+)
+
+// ReviewFilter narrows ListReviews; zero values match everything.
+type ReviewFilter struct {
+	VenueID string
+	UserID  string
+	Kind    ReviewKind
+	Status  ReviewStatus
+	Sort    ReviewSort // default ReviewsRecent
+	Limit   int
+}
+
+const reviewColumns = `id, venue_id, user_id, author_name, kind, rating, body, status, flags, helpful_count, report_count,
+	moderated_by, moderation_note, created_at, updated_at`
+
+func scanReview(row pgx.Row) (*Review, error) {
+	var r Review
+	var rating *int16
+	err := row.Scan(&r.ID, &r.VenueID, &r.UserID, &r.AuthorName, &r.Kind, &rating, &r.Body, &r.Status, &r.Flags,
+		&r.HelpfulCount, &r.ReportCount, &r.ModeratedBy, &r.ModerationNote, &r.CreatedAt, &r.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if rating != nil {
+		n := int(*rating)
+		r.Rating = &n
+	}
+	return &r, nil
+}
+
+// lockReviewStats locks the venue row before a review write. Every writer
+// takes it first, so under READ COMMITTED two transactions can't each
+// recompute the aggregates from a snapshot missing the other's review.
+func lockReviewStats(ctx context.Context, tx pgx.Tx, venueID string) error {
+	_, err := tx.Exec(ctx, `SELECT 1 FROM venues WHERE id = $1 FOR UPDATE`, venueID)
+	return err
+}
+
+// lockReviewVenue is lockReviewStats for a review known by id. It returns the
+// review's venue, or pgx.ErrNoRows for an unknown review.
+func lockReviewVenue(ctx context.Context, tx pgx.Tx, reviewID int64) (string, error) {
+	var venueID string
+	q := `SELECT v.id FROM venue_reviews r JOIN venues v ON v.id = r.venue_id WHERE r.id = $1 FOR UPDATE OF v`
+	err := tx.QueryRow(ctx, q, reviewID).Scan(&venueID)
+	return venueID, err
+}
+
+// refreshReviewStats recomputes the venue's published review count and
+// average rating inside tx, which must hold lockReviewStats.
+func refreshReviewStats(ctx context.Context, tx pgx.Tx, venueID string) error {
+	q := `UPDATE venues SET review_count = s.n, review_rating = s.avg
+		FROM (SELECT COUNT(*) AS n, ROUND(AVG(rating)::numeric, 2)::float8 AS avg FROM venue_reviews
+			WHERE venue_id = $1 AND kind = 'review' AND status = 'published') s
+		WHERE venues.id = $1`
+	_, err := tx.Exec(ctx, q, venueID)
+	return err
+}
+
+// CreateReview inserts r and, when it is published, updates the venue's
+// aggregates. It returns ErrDuplicateReview for a second review by the same
+// user.
+func (s *Store) CreateReview(ctx context.Context, r *Review) error {
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if err := lockReviewStats(ctx, tx, r.VenueID); err != nil {
+		return err
+	}
+	q := `INSERT INTO venue_reviews (venue_id, user_id, author_name, kind, rating, body, status, flags)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at, updated_at`
+	err = tx.QueryRow(ctx, q, r.VenueID, r.UserID, r.AuthorName, r.Kind, r.Rating, r.Body, r.Status, nonNil(r.Flags)).
+		Scan(&r.ID, &r.CreatedAt, &r.UpdatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+		return ErrDuplicateReview
+	}
+	if err != nil {
+		return err
+	}
+	if r.Status == ReviewPublished {
+		if err := refreshReviewStats(ctx, tx, r.VenueID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *Store) GetReview(ctx context.Context, id int64) (*Review, error) {
+	r, err := scanReview(s.Pool.QueryRow(ctx, `SELECT `+reviewColumns+` FROM venue_reviews WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return r, err
+}
+
+// UpdateReview writes the author-editable fields of r (name, rating, body)
+// and the status and flags the filter gave the edit, then refreshes the
+// venue's aggregates. It returns ErrNotFound for an unknown id.
+func (s *Store) UpdateReview(ctx context.Context, r *Review) error {
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if err := lockReviewStats(ctx, tx, r.VenueID); err != nil {
+		return err
+	}
+	q := `UPDATE venue_reviews SET author_name=$2, rating=$3, body=$4, status=$5, flags=$6, updated_at=NOW()
+		WHERE id=$1 RETURNING updated_at`
+	err = tx.QueryRow(ctx, q, r.ID, r.AuthorName, r.Rating, r.Body, r.Status, nonNil(r.Flags)).Scan(&r.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := refreshReviewStats(ctx, tx, r.VenueID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ModerateReview records a moderator's decision: the new status, who made
+// it and why. Approving clears the report count so the review needs fresh
+// reports to return to the queue. It returns nil for an unknown id.
+func (s *Store) ModerateReview(ctx context.Context, id int64, status ReviewStatus, moderator, note string) (*Review, error) {
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := lockReviewVenue(ctx, tx, id); errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	q := `UPDATE venue_reviews SET status=$2, moderated_by=$3, moderation_note=$4,
+			report_count = CASE WHEN $2 = 'published' THEN 0 ELSE report_count END
+		WHERE id=$1 RETURNING ` + reviewColumns
+	r, err := scanReview(tx.QueryRow(ctx, q, id, status, moderator, note))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := refreshReviewStats(ctx, tx, r.VenueID); err != nil {
+		return nil, err
+	}
+	return r, tx.Commit(ctx)
+}
+
+// DeleteReview removes the review with its votes and reports; deleted is
+// false if there was none.
+func (s *Store) DeleteReview(ctx context.Context, id int64) (bool, error) {
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+	venueID, err := lockReviewVenue(ctx, tx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	tag, err := tx.Exec(ctx, `DELETE FROM venue_reviews WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 { // deleted while we waited for the lock
+		return false, nil
+	}
+	if err := refreshReviewStats(ctx, tx, venueID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// ListReviews returns reviews and tips matching f.
+func (s *Store) ListReviews(ctx context.Context, f ReviewFilter) ([]Review, error) {
+	order := `created_at DESC, id DESC`
+	switch f.Sort {
+	case ReviewsHelpful:
+		order = `helpful_count DESC, ` + order
+	case ReviewsReported:
+		order = `report_count DESC, created_at, id`
+	}
+	q := `SELECT ` + reviewColumns + ` FROM venue_reviews
+		WHERE ($1 = '' OR venue_id = $1) AND ($2 = '' OR user_id = $2) AND ($3 = '' OR kind = $3) AND ($4 = '' OR status = $4)
+		ORDER BY ` + order + ` LIMIT $5`
+	rows, err := s.Pool.Query(ctx, q, f.VenueID, f.UserID, string(f.Kind), string(f.Status), f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Review
+	for rows.Next() {
+		r, err := scanReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *r)
+	}
+	return out, rows.Err()
+}
+
+// SetHelpful adds or withdraws the user's helpful vote and keeps
+// helpful_count in step; changed is false when the vote was already in that
+// state.
+func (s *Store) SetHelpful(ctx context.Context, reviewID int64, userID string, helpful bool) (bool, error) {
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+	q, delta := `INSERT INTO venue_review_votes (review_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, 1
+	if !helpful {
+		q, delta = `DELETE FROM venue_review_votes WHERE review_id = $1 AND user_id = $2`, -1
+	}
+	tag, err := tx.Exec(ctx, q, reviewID, userID)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	if _, err := tx.Exec(ctx, `UPDATE venue_reviews SET helpful_count = GREATEST(helpful_count + $2, 0) WHERE id = $1`, reviewID, delta); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// ReportReview records the user's abuse report (once per user). When the
+// report count reaches holdAt, published content goes back to the moderation
+// queue flagged FlagReported. added is false for a repeat report.
+func (s *Store) ReportReview(ctx context.Context, reviewID int64, userID, reason string, holdAt int) (bool, error) {
+	tx, err := s.Pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+	tag, err := tx.Exec(ctx, `INSERT INTO venue_review_reports (review_id, user_id, reason) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`,
+		reviewID, userID, reason)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	if _, err := lockReviewVenue(ctx, tx, reviewID); err != nil {
+		return false, err
+	}
+	q := `UPDATE venue_reviews SET report_count = report_count + 1,
+			status = CASE WHEN status = 'published' AND report_count + 1 >= $2 THEN 'pending' ELSE status END,
+			flags = CASE WHEN status = 'published' AND report_count + 1 >= $2 AND NOT $3 = ANY(flags)
+				THEN array_append(flags, $3) ELSE flags END
+		WHERE id = $1 RETURNING venue_id, status`
+	var venueID string
+	var status ReviewStatus
+	if err := tx.QueryRow(ctx, q, reviewID, holdAt, FlagReported).Scan(&venueID, &status); err != nil {
+		return false, err
+	}
+	if status == ReviewPending {
+		if err := refreshReviewStats(ctx, tx, venueID); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit(ctx)
+}
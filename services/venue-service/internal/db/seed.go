@@ -0,0 +1,63 @@
+package db
+
+import "context"
+
+// lateNight is a typical bar week: 17:00-02:00, crossing midnight.
+func lateNight(tz string) OpeningHours {
+	h := OpeningHours{Timezone: tz}
+	for d := 0; d < 7; d++ {
+		h.Weekly = append(h.Weekly, DayRange{Day: d, Open: "17:00", Close: "02:00"})
+	}
+	return h
+}
+
+func daytime(tz string) OpeningHours {
+	h := OpeningHours{Timezone: tz}
+	for d := 0; d < 7; d++ {
+		h.Weekly = append(h.Weekly, DayRange{Day: d, Open: "07:00", Close: "18:00"})
+	}
+	return h
+}
+
+// DemoVenues is the beta catalog around downtown San Francisco. v1/v2 keep
+// the ids the old mock responses used so existing clients keep working.
+func DemoVenues() []Venue {
+	const tz = "America/Los_Angeles"
+	sf := func(line1 string) Address {
+		return Address{Line1: line1, City: "San Francisco", Region: "CA", Country: "US"}
+	}
+	return []Venue{
+		{ID: "v1", Name: "Energetic Bar", Subtitle: "Downtown", Category: "bar", Tags: []string{"cocktails", "dj", "dancing"}, PriceTier: 2,
+			Lat: 37.7897, Lon: -122.4011, Address: sf("55 2nd St"), Hours: lateNight(tz), Rating: 4.5},
+		{ID: "v2", Name: "Chill Lounge", Subtitle: "Riverside", Category: "lounge", Tags: []string{"wine", "quiet", "patio"}, PriceTier: 1,
+			Lat: 37.7955, Lon: -122.3937, Address: sf("1 Ferry Building"), Hours: lateNight(tz), Rating: 4.2},
+		{ID: "v3", Name: "Neon Warehouse", Subtitle: "SoMa", Category: "club", Tags: []string{"techno", "late night", "dancing"}, PriceTier: 3,
+			Lat: 37.7749, Lon: -122.4128, Address: sf("1015 Folsom St"), Hours: lateNight(tz), Rating: 4.6},
+		{ID: "v4", Name: "Mission Taqueria", Subtitle: "Mission", Category: "restaurant", Tags: []string{"mexican", "casual"}, PriceTier: 1,
+			Lat: 37.7599, Lon: -122.4148, Address: sf("2889 Mission St"), Hours: daytime(tz), Rating: 4.7},
+		{ID: "v5", Name: "Fog City Roasters", Subtitle: "North Beach", Category: "cafe", Tags: []string{"coffee", "wifi", "quiet"}, PriceTier: 1,
+			Lat: 37.8003, Lon: -122.4098, Address: sf("566 Columbus Ave"), Hours: daytime(tz), Rating: 4.4},
+		{ID: "v6", Name: "Blue Note Cellar", Subtitle: "Hayes Valley", Category: "live_music", Tags: []string{"jazz", "cocktails"}, PriceTier: 3,
+			Lat: 37.7764, Lon: -122.4242, Address: sf("401 Hayes St"), Hours: lateNight(tz), Rating: 4.8},
+		{ID: "v7", Name: "Rooftop 22", Subtitle: "Union Square", Category: "bar", Tags: []string{"rooftop", "views", "cocktails"}, PriceTier: 4,
+			Lat: 37.7880, Lon: -122.4075, Address: sf("333 Geary St"), Hours: lateNight(tz), Rating: 4.3},
+		{ID: "v8", Name: "Ocean Beach Bonfire Grill", Subtitle: "Outer Sunset", Category: "restaurant", Tags: []string{"seafood", "outdoor"}, PriceTier: 2,
+			Lat: 37.7596, Lon: -122.5107, Address: sf("4001 Judah St"), Hours: daytime(tz), Rating: 4.1},
+	}
+}
+
+// Seed inserts the demo catalog, skipping ids that already exist.
+func Seed(ctx context.Context, repo VenueRepo) error {
+	for _, v := range DemoVenues() {
+		if existing, err := repo.GetVenue(ctx, v.ID); err != nil {
+			return err
+		} else if existing != nil {
+			continue
+		}
+		v := v
+		if err := repo.CreateVenue(ctx, &v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
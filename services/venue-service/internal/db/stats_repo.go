@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// VenueDailyStats is a venue's rollup for one local date. Day is midnight
+// UTC of that date; Hourly has 24 entries.
+type VenueDailyStats struct {
+	VenueID      string
+	Day          time.Time
+	Impressions  int
+	Likes        int
+	Checkins     int
+	VibeReports  int
+	VibeScoreSum float64
+	Hourly       []int
+}
+
+// VenueImpressions is a number of impressions to add to a venue's day.
+type VenueImpressions struct {
+	VenueID string
+	Day     time.Time
+	Count   int
+}
+
+// SaveVenueDailyStats writes the activity columns of each rollup, keeping
+// the day's impressions.
+func (s *Store) SaveVenueDailyStats(ctx context.Context, stats []VenueDailyStats) error {
+	batch := &pgx.Batch{}
+	for _, d := range stats {
+		batch.Queue(`INSERT INTO venue_daily_stats (venue_id, day, likes, checkins, vibe_reports, vibe_score_sum, hourly)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (venue_id, day) DO UPDATE SET likes = EXCLUDED.likes, checkins = EXCLUDED.checkins,
+				vibe_reports = EXCLUDED.vibe_reports, vibe_score_sum = EXCLUDED.vibe_score_sum, hourly = EXCLUDED.hourly, updated_at = NOW()`,
+			d.VenueID, d.Day, d.Likes, d.Checkins, d.VibeReports, d.VibeScoreSum, d.Hourly)
+	}
+	return s.Pool.SendBatch(ctx, batch).Close()
+}
+
+// AddVenueImpressions adds to the days' impression counts; instances flush
+// their own counts, so this never overwrites.
+func (s *Store) AddVenueImpressions(ctx context.Context, counts []VenueImpressions) error {
+	batch := &pgx.Batch{}
+	for _, c := range counts {
+		batch.Queue(`INSERT INTO venue_daily_stats (venue_id, day, impressions) VALUES ($1, $2, $3)
+			ON CONFLICT (venue_id, day) DO UPDATE SET impressions = venue_daily_stats.impressions + EXCLUDED.impressions, updated_at = NOW()`,
+			c.VenueID, c.Day, c.Count)
+	}
+	return s.Pool.SendBatch(ctx, batch).Close()
+}
+
+// ListVenueDailyStats returns the venues' rollups for days from..to
+// (inclusive), by venue and day.
+func (s *Store) ListVenueDailyStats(ctx context.Context, venueIDs []string, from, to time.Time) ([]VenueDailyStats, error) {
+	rows, err := s.Pool.Query(ctx, `SELECT venue_id, day, impressions, likes, checkins, vibe_reports, vibe_score_sum, hourly
+		FROM venue_daily_stats WHERE venue_id = ANY($1) AND day BETWEEN $2 AND $3 ORDER BY venue_id, day`, venueIDs, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []VenueDailyStats
+	for rows.Next() {
+		var d VenueDailyStats
+		if err := rows.Scan(&d.VenueID, &d.Day, &d.Impressions, &d.Likes, &d.Checkins, &d.VibeReports, &d.VibeScoreSum, &d.Hourly); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,193 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type VenueStatus string
+
+const (
+	VenueDraft    VenueStatus = "draft"
+	VenueActive   VenueStatus = "active"
+	VenueArchived VenueStatus = "archived"
+)
+
+type Address struct {
+	Line1      string `json:"line1,omitempty"`
+	City       string `json:"city,omitempty"`
+	Region     string `json:"region,omitempty"`
+	PostalCode string `json:"postalCode,omitempty"`
+	Country    string `json:"country,omitempty"`
+}
+
+// DayRange is one opening interval. Day is 0=Sunday..6=Saturday; Open and
+// Close are local "HH:MM".
+type DayRange struct {
+	Day   int    `json:"day"`
+	Open  string `json:"open"`
+	Close string `json:"close"`
+}
+
+// TimeRange is an opening interval on an exception date; like DayRange it
+// runs past midnight when Close is at or before Open.
+type TimeRange struct {
+	Open  string `json:"open"`
+	Close string `json:"close"`
+}
+
+// HoursException replaces the weekly schedule on one local date (holiday,
+// private event, late opening). Closed shuts the venue for the day.
+type HoursException struct {
+	Date   string      `json:"date"` // YYYY-MM-DD in the venue timezone
+	Closed bool        `json:"closed,omitempty"`
+	Ranges []TimeRange `json:"ranges,omitempty"`
+	Note   string      `json:"note,omitempty"`
+}
+
+type OpeningHours struct {
+	Timezone   string           `json:"timezone,omitempty"`
+	Weekly     []DayRange       `json:"weekly,omitempty"`
+	Exceptions []HoursException `json:"exceptions,omitempty"`
+}
+
+type Venue struct {
+	ID        string       `json:"id"`
+	Name      string       `json:"name"`
+	Subtitle  string       `json:"subtitle"`
+	Category  string       `json:"category"`
+	Tags      []string     `json:"tags"`
+	PriceTier int          `json:"priceTier"`
+	Lat       float64      `json:"lat"`
+	Lon       float64      `json:"lng"`
+	Address   Address      `json:"address"`
+	Hours     OpeningHours `json:"hours"`
+	Photos    []string     `json:"photos"`
+	Status    VenueStatus  `json:"status"`
+	Rating    float64      `json:"rating"`
+	LikeCount int          `json:"likeCount"`
+	// Published user reviews: how many and their average rating (nil
+	// until the first one).
+	ReviewCount  int       `json:"reviewCount"`
+	ReviewRating *float64  `json:"reviewRating"`
+	OwnerID      *string   `json:"ownerId,omitempty"` // venue host allowed to edit it
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+const venueColumns = `id, name, subtitle, category, tags, price_tier, lat, lon, address, hours, photos, status, rating, like_count, review_count, review_rating, owner_id, created_at, updated_at`
+
+func scanVenue(row pgx.Row) (*Venue, error) {
+	v := &Venue{}
+	err := row.Scan(&v.ID, &v.Name, &v.Subtitle, &v.Category, &v.Tags, &v.PriceTier, &v.Lat, &v.Lon,
+		&v.Address, &v.Hours, &v.Photos, &v.Status, &v.Rating, &v.LikeCount, &v.ReviewCount, &v.ReviewRating, &v.OwnerID, &v.CreatedAt, &v.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// CreateVenue inserts v; an empty ID gets a generated one.
+func (s *Store) CreateVenue(ctx context.Context, v *Venue) error {
+	if v.Status == "" {
+		v.Status = VenueActive
+	}
+	q := `INSERT INTO venues (id, name, subtitle, category, tags, price_tier, lat, lon, address, hours, photos, status, rating, owner_id)
+		VALUES (COALESCE(NULLIF($1, ''), gen_random_uuid()::text), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING id, created_at, updated_at`
+	err := s.Pool.QueryRow(ctx, q, v.ID, v.Name, v.Subtitle, v.Category, nonNil(v.Tags), v.PriceTier, v.Lat, v.Lon,
+		v.Address, v.Hours, nonNil(v.Photos), v.Status, v.Rating, v.OwnerID).Scan(&v.ID, &v.CreatedAt, &v.UpdatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+		return ErrDuplicateID
+	}
+	return err
+}
+
+// UpdateVenue writes every editable field of v (not likes or ratings) and
+// refreshes UpdatedAt. It returns ErrNotFound for an unknown id.
+func (s *Store) UpdateVenue(ctx context.Context, v *Venue) error {
+	q := `UPDATE venues SET name=$2, subtitle=$3, category=$4, tags=$5, price_tier=$6, lat=$7, lon=$8, address=$9,
+			hours=$10, photos=$11, status=$12, owner_id=$13, updated_at=NOW()
+		WHERE id=$1 RETURNING updated_at`
+	err := s.Pool.QueryRow(ctx, q, v.ID, v.Name, v.Subtitle, v.Category, nonNil(v.Tags), v.PriceTier, v.Lat, v.Lon,
+		v.Address, v.Hours, nonNil(v.Photos), v.Status, v.OwnerID).Scan(&v.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Store) GetVenue(ctx context.Context, id string) (*Venue, error) {
+	v, err := scanVenue(s.Pool.QueryRow(ctx, `SELECT `+venueColumns+` FROM venues WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return v, nil
+}
+
+// ListVenues returns the active catalog ordered by id.
+func (s *Store) ListVenues(ctx context.Context) ([]Venue, error) {
+	rows, err := s.Pool.Query(ctx, `SELECT `+venueColumns+` FROM venues WHERE status = 'active' ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Venue
+	for rows.Next() {
+		v, err := scanVenue(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *v)
+	}
+	return out, rows.Err()
+}
+
+// VenueFilter narrows ListAllVenues; zero values match everything.
+type VenueFilter struct {
+	Status  VenueStatus
+	OwnerID string
+}
+
+// ListAllVenues returns venues in any status (for admin tools), newest first.
+func (s *Store) ListAllVenues(ctx context.Context, f VenueFilter) ([]Venue, error) {
+	q := `SELECT ` + venueColumns + ` FROM venues
+		WHERE ($1 = '' OR status = $1) AND ($2 = '' OR owner_id = $2) ORDER BY created_at DESC, id`
+	rows, err := s.Pool.Query(ctx, q, string(f.Status), f.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Venue
+	for rows.Next() {
+		v, err := scanVenue(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *v)
+	}
+	return out, rows.Err()
+}
+
+// CountVenues counts the active catalog.
+func (s *Store) CountVenues(ctx context.Context) (int, error) {
+	var n int
+	err := s.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM venues WHERE status = 'active'`).Scan(&n)
+	return n, err
+}
+
+// nonNil keeps NOT NULL array columns happy when callers leave slices unset.
+func nonNil(xs []string) []string {
+	if xs == nil {
+		return []string{}
+	}
+	return xs
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/pressly/goose/v3"
+)
+
+func TestVenueRepo_CreateAndGet(t *testing.T) {
+	conn := os.Getenv("DATABASE_URL")
+	if conn == "" {
+		t.Skip("DATABASE_URL not set; skipping DB test")
+	}
+
+	// Ensure migrations are up on the test DB
+	sqlDB, err := goose.OpenDBWithDriver("pgx", conn)
+	if err != nil {
+		t.Fatalf("goose open: %v", err)
+	}
+	defer sqlDB.Close()
+	if err := goose.SetDialect("postgres"); err != nil {
+		t.Fatalf("goose dialect: %v", err)
+	}
+	goose.SetTableName("venue_goose_db_version")
+	if err := goose.Up(sqlDB, "../../migrations"); err != nil {
+		t.Fatalf("goose up: %v", err)
+	}
+
+	ctx := context.Background()
+	store, err := New(ctx)
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	defer store.Close()
+
+	v := &Venue{Name: "Test Venue", Category: "bar", PriceTier: 2, Lat: 37.78, Lon: -122.41,
+		Hours: OpeningHours{Timezone: "America/Los_Angeles", Weekly: []DayRange{{Day: 5, Open: "18:00", Close: "02:00"}}}}
+	if err := store.CreateVenue(ctx, v); err != nil {
+		t.Fatalf("create venue: %v", err)
+	}
+	if v.ID == "" {
+		t.Fatalf("expected id set")
+	}
+	got, err := store.GetVenue(ctx, v.ID)
+	if err != nil {
+		t.Fatalf("get venue: %v", err)
+	}
+	if got == nil || got.Name != v.Name || len(got.Hours.Weekly) != 1 {
+		t.Fatalf("expected same venue, got %+v", got)
+	}
+	if missing, err := store.GetVenue(ctx, "does-not-exist"); err != nil || missing != nil {
+		t.Fatalf("expected nil for unknown id, got %+v, %v", missing, err)
+	}
+}
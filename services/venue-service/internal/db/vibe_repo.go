@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// VibeReport is one vibe sample (0-10) sent by a device at the venue.
+// ReportedAt is the client's sample time (clamped by the handler);
+// ReceivedAt is server time and drives retention.
+type VibeReport struct {
+	ID             int64              `json:"id"`
+	VenueID        string             `json:"venueId"`
+	UserID         *string            `json:"userId,omitempty"`
+	Score          float64            `json:"vibeScore"`
+	Confidence     float64            `json:"confidence"`
+	Features       map[string]float64 `json:"features,omitempty"`
+	IdempotencyKey *string            `json:"-"`
+	ReportedAt     time.Time          `json:"timestamp"`
+	ReceivedAt     time.Time          `json:"receivedAt"`
+}
+
+// InsertVibeReport stores r. created is false when the venue already has a
+// report with the same idempotency key; r is left untouched in that case.
+func (s *Store) InsertVibeReport(ctx context.Context, r *VibeReport) (bool, error) {
+	features := r.Features
+	if features == nil {
+		features = map[string]float64{}
+	}
+	q := `INSERT INTO vibe_reports (venue_id, user_id, score, confidence, features, idempotency_key, reported_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (venue_id, idempotency_key) WHERE idempotency_key IS NOT NULL DO NOTHING
+		RETURNING id, received_at`
+	err := s.Pool.QueryRow(ctx, q, r.VenueID, r.UserID, r.Score, r.Confidence, features, r.IdempotencyKey, r.ReportedAt).
+		Scan(&r.ID, &r.ReceivedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// ListVibeReports returns every report sampled since the cutoff, oldest first.
+func (s *Store) ListVibeReports(ctx context.Context, since time.Time) ([]VibeReport, error) {
+	q := `SELECT id, venue_id, user_id, score, confidence, features, idempotency_key, reported_at, received_at
+		FROM vibe_reports WHERE reported_at >= $1 ORDER BY reported_at, id`
+	rows, err := s.Pool.Query(ctx, q, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []VibeReport
+	for rows.Next() {
+		var r VibeReport
+		if err := rows.Scan(&r.ID, &r.VenueID, &r.UserID, &r.Score, &r.Confidence, &r.Features, &r.IdempotencyKey, &r.ReportedAt, &r.ReceivedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// PurgeVibeReports deletes reports received before the cutoff.
+func (s *Store) PurgeVibeReports(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := s.Pool.Exec(ctx, `DELETE FROM vibe_reports WHERE received_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,333 @@
+// Package events expands venue event schedules: a first occurrence with a
+// duration and an optional recurrence rule in the RFC 5545 RRULE syntax
+// (the DAILY, WEEKLY and MONTHLY subset venues need for DJ nights and happy
+// hours). Occurrences keep the first one's wall-clock time in its location,
+// so a 22:00 weekly event stays at 22:00 across daylight saving changes.
+package events
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Freq string
+
+const (
+	Daily   Freq = "DAILY"
+	Weekly  Freq = "WEEKLY"
+	Monthly Freq = "MONTHLY"
+)
+
+const (
+	maxInterval = 99
+	maxCount    = 1000
+	// maxPeriods bounds expansion: about 55 years of a daily rule.
+	maxPeriods = 20000
+)
+
+var dayCodes = map[string]time.Weekday{"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday}
+
+var dayNames = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// WeekdayNum is a BYDAY entry: a weekday, and for monthly rules optionally
+// the Nth (1..5) or Nth-from-last (-1..-5) one in the month; 0 means every.
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+func (w WeekdayNum) String() string {
+	if w.N == 0 {
+		return dayNames[w.Day]
+	}
+	return strconv.Itoa(w.N) + dayNames[w.Day]
+}
+
+// Rule is a parsed recurrence rule. Until is inclusive; Until and Count are
+// never both set.
+type Rule struct {
+	Freq     Freq
+	Interval int
+	ByDay    []WeekdayNum
+	Until    time.Time
+	Count    int
+}
+
+// ParseRule parses an RRULE value ("FREQ=WEEKLY;BYDAY=FR,SA", an "RRULE:"
+// prefix is allowed). A date-only UNTIL includes that whole day in loc.
+func ParseRule(s string, loc *time.Location) (*Rule, error) {
+	s = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "RRULE:")
+	if s == "" {
+		return nil, errors.New("empty rule")
+	}
+	r := &Rule{Interval: 1}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok || val == "" {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("%s is given twice", key)
+		}
+		seen[key] = true
+		switch key {
+		case "FREQ":
+			r.Freq = Freq(val)
+			if r.Freq != Daily && r.Freq != Weekly && r.Freq != Monthly {
+				return nil, fmt.Errorf("FREQ must be DAILY, WEEKLY or MONTHLY")
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 || n > maxInterval {
+				return nil, fmt.Errorf("INTERVAL must be 1-%d", maxInterval)
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 || n > maxCount {
+				return nil, fmt.Errorf("COUNT must be 1-%d", maxCount)
+			}
+			r.Count = n
+		case "UNTIL":
+			t, err := parseUntil(val, loc)
+			if err != nil {
+				return nil, err
+			}
+			r.Until = t
+		case "BYDAY":
+			for _, d := range strings.Split(val, ",") {
+				wd, err := parseWeekdayNum(d)
+				if err != nil {
+					return nil, err
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "WKST":
+			// weeks start on Monday, the RFC default
+			if val != "MO" {
+				return nil, errors.New("only WKST=MO is supported")
+			}
+		default:
+			return nil, fmt.Errorf("unsupported rule part %s", key)
+		}
+	}
+	if r.Freq == "" {
+		return nil, errors.New("FREQ is required")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return nil, errors.New("use either COUNT or UNTIL")
+	}
+	for _, d := range r.ByDay {
+		if d.N != 0 && r.Freq != Monthly {
+			return nil, errors.New("numbered BYDAY (like 1FR) needs FREQ=MONTHLY")
+		}
+	}
+	return r, nil
+}
+
+func parseUntil(val string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", val); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("20060102", val, loc); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("UNTIL must be YYYYMMDD or YYYYMMDDTHHMMSSZ")
+}
+
+func parseWeekdayNum(s string) (WeekdayNum, error) {
+	if len(s) < 2 {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", s)
+	}
+	day, ok := dayCodes[s[len(s)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", s)
+	}
+	w := WeekdayNum{Day: day}
+	if num := s[:len(s)-2]; num != "" {
+		n, err := strconv.Atoi(num)
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", s)
+		}
+		w.N = n
+	}
+	return w, nil
+}
+
+// String is the canonical RRULE value; UNTIL is written in UTC as RFC 5545
+// requires next to a DTSTART with a timezone.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = d.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Series is an event's schedule.
+type Series struct {
+	Start    time.Time // first occurrence, in the location the rule follows
+	Duration time.Duration
+	Rule     *Rule // nil for a one-off
+}
+
+type Occurrence struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Between returns up to max occurrences that overlap [from, to), in order.
+// An occurrence that started before from but is still running counts.
+func (s Series) Between(from, to time.Time, max int) []Occurrence {
+	var out []Occurrence
+	s.each(func(start time.Time) bool {
+		if !start.Before(to) || len(out) >= max {
+			return false
+		}
+		if end := start.Add(s.Duration); end.After(from) {
+			out = append(out, Occurrence{Start: start, End: end})
+		}
+		return true
+	})
+	return out
+}
+
+// At returns the occurrence running at t, if any.
+func (s Series) At(t time.Time) (Occurrence, bool) {
+	occ := s.Between(t, t.Add(time.Nanosecond), 1)
+	if len(occ) == 0 {
+		return Occurrence{}, false
+	}
+	return occ[0], true
+}
+
+// End returns when the last occurrence ends; ok is false for a series
+// without COUNT or UNTIL.
+func (s Series) End() (end time.Time, ok bool) {
+	if s.Rule != nil && s.Rule.Count == 0 && s.Rule.Until.IsZero() {
+		return time.Time{}, false
+	}
+	last := s.Start
+	s.each(func(start time.Time) bool {
+		last = start
+		return true
+	})
+	return last.Add(s.Duration), true
+}
+
+// each calls fn with every occurrence start in order until fn returns false
+// or the rule runs out. The first occurrence always counts, as in RFC 5545,
+// even when it doesn't match the rule.
+func (s Series) each(fn func(time.Time) bool) {
+	r := s.Rule
+	emitted := 0
+	emit := func(t time.Time) bool {
+		if r != nil && !r.Until.IsZero() && t.After(r.Until) {
+			return false
+		}
+		emitted++
+		if !fn(t) {
+			return false
+		}
+		return r == nil || r.Count == 0 || emitted < r.Count
+	}
+	if !emit(s.Start) || r == nil {
+		return
+	}
+	loc := s.Start.Location()
+	y, m, d := s.Start.Date()
+	hh, mm, ss := s.Start.Clock()
+	// dates are computed in UTC, where every day has 24 hours
+	first := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	byDay := r.ByDay
+	if len(byDay) == 0 && r.Freq == Weekly {
+		byDay = []WeekdayNum{{Day: s.Start.Weekday()}}
+	}
+	for period := 0; period < maxPeriods; period++ {
+		var dates []time.Time
+		switch r.Freq {
+		case Daily:
+			date := first.AddDate(0, 0, period*r.Interval)
+			if len(byDay) == 0 || hasWeekday(byDay, date.Weekday()) {
+				dates = append(dates, date)
+			}
+		case Weekly:
+			monday := first.AddDate(0, 0, -((int(first.Weekday())+6)%7)+period*r.Interval*7)
+			for _, wd := range byDay {
+				dates = append(dates, monday.AddDate(0, 0, (int(wd.Day)+6)%7))
+			}
+		case Monthly:
+			month := time.Date(y, m+time.Month(period*r.Interval), 1, 0, 0, 0, 0, time.UTC)
+			if len(byDay) == 0 {
+				// months without that day are skipped, as RFC 5545 does
+				if date := month.AddDate(0, 0, d-1); date.Month() == month.Month() {
+					dates = append(dates, date)
+				}
+			}
+			for _, wd := range byDay {
+				dates = append(dates, monthDays(month, wd)...)
+			}
+		}
+		sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+		for i, date := range dates {
+			if i > 0 && date.Equal(dates[i-1]) {
+				continue
+			}
+			t := time.Date(date.Year(), date.Month(), date.Day(), hh, mm, ss, 0, loc)
+			if !t.After(s.Start) {
+				continue
+			}
+			if !emit(t) {
+				return
+			}
+		}
+	}
+}
+
+func hasWeekday(days []WeekdayNum, wd time.Weekday) bool {
+	for _, d := range days {
+		if d.Day == wd {
+			return true
+		}
+	}
+	return false
+}
+
+// monthDays returns the dates in month (its first day) that wd selects.
+func monthDays(month time.Time, wd WeekdayNum) []time.Time {
+	var all []time.Time
+	for date := month; date.Month() == month.Month(); date = date.AddDate(0, 0, 1) {
+		if date.Weekday() == wd.Day {
+			all = append(all, date)
+		}
+	}
+	switch {
+	case wd.N == 0:
+		return all
+	case wd.N > 0 && wd.N <= len(all):
+		return all[wd.N-1 : wd.N]
+	case wd.N < 0 && -wd.N <= len(all):
+		i := len(all) + wd.N
+		return all[i : i+1]
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package events
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustRule(t *testing.T, s string, loc *time.Location) *Rule {
+	t.Helper()
+	r, err := ParseRule(s, loc)
+	if err != nil {
+		t.Fatalf("ParseRule(%q): %v", s, err)
+	}
+	return r
+}
+
+func starts(occ []Occurrence) string {
+	var out []string
+	for _, o := range occ {
+		out = append(out, o.Start.Format("Mon 01-02 15:04"))
+	}
+	return strings.Join(out, ", ")
+}
+
+func TestBetween_WeeklyKeepsWallClockAcrossDST(t *testing.T) {
+	la, _ := time.LoadLocation("America/Los_Angeles")
+	// Friday and Saturday nights, 22:00-02:00; DST ends on Sunday 2026-11-01
+	s := Series{Start: time.Date(2026, 10, 23, 22, 0, 0, 0, la), Duration: 4 * time.Hour, Rule: mustRule(t, "RRULE:FREQ=WEEKLY;BYDAY=FR,SA", la)}
+	got := s.Between(time.Date(2026, 10, 24, 0, 0, 0, 0, la), time.Date(2026, 11, 8, 0, 0, 0, 0, la), 10)
+	want := "Fri 10-23 22:00, Sat 10-24 22:00, Fri 10-30 22:00, Sat 10-31 22:00, Fri 11-06 22:00, Sat 11-07 22:00"
+	if starts(got) != want {
+		t.Fatalf("got %s\nwant %s", starts(got), want)
+	}
+	// still running at 01:00 after the clocks went back
+	if o, ok := s.At(time.Date(2026, 11, 1, 1, 30, 0, 0, la)); !ok || o.Start.Day() != 31 {
+		t.Fatalf("At during the Saturday night: %v %v", o, ok)
+	}
+	if _, ok := s.At(time.Date(2026, 10, 28, 23, 0, 0, 0, la)); ok {
+		t.Fatal("nothing runs on Wednesday")
+	}
+	if _, ok := s.End(); ok {
+		t.Fatal("an open-ended series has no end")
+	}
+}
+
+func TestBetween_MonthlyCountAndUntil(t *testing.T) {
+	utc := time.UTC
+	last := Series{Start: time.Date(2026, 1, 30, 18, 0, 0, 0, utc), Duration: 2 * time.Hour, Rule: mustRule(t, "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3", utc)}
+	if got := starts(last.Between(time.Date(2026, 1, 1, 0, 0, 0, 0, utc), time.Date(2027, 1, 1, 0, 0, 0, 0, utc), 10)); got != "Fri 01-30 18:00, Fri 02-27 18:00, Fri 03-27 18:00" {
+		t.Fatalf("last Friday x3: %s", got)
+	}
+	if end, ok := last.End(); !ok || !end.Equal(time.Date(2026, 3, 27, 20, 0, 0, 0, utc)) {
+		t.Fatalf("End = %v %v", end, ok)
+	}
+
+	// the 31st skips shorter months
+	s := Series{Start: time.Date(2026, 1, 31, 12, 0, 0, 0, utc), Duration: time.Hour, Rule: mustRule(t, "FREQ=MONTHLY;UNTIL=20260531", utc)}
+	if got := starts(s.Between(s.Start, time.Date(2027, 1, 1, 0, 0, 0, 0, utc), 10)); got != "Sat 01-31 12:00, Tue 03-31 12:00, Sun 05-31 12:00" {
+		t.Fatalf("monthly on the 31st: %s", got)
+	}
+
+	daily := Series{Start: time.Date(2026, 6, 1, 17, 0, 0, 0, utc), Duration: 2 * time.Hour, Rule: mustRule(t, "FREQ=DAILY;INTERVAL=2;BYDAY=MO,WE,FR", utc)}
+	// 06-07 onwards the every-other-day dates fall on other weekdays until 06-15
+	if got := starts(daily.Between(daily.Start, time.Date(2026, 6, 15, 0, 0, 0, 0, utc), 10)); got != "Mon 06-01 17:00, Wed 06-03 17:00, Fri 06-05 17:00" {
+		t.Fatalf("every other day on MO/WE/FR: %s", got)
+	}
+}
+
+func TestParseRule_RejectsUnsupported(t *testing.T) {
+	for _, s := range []string{
+		"", "FREQ=YEARLY", "FREQ=WEEKLY;BYDAY=1FR", "FREQ=MONTHLY;BYDAY=6FR", "FREQ=DAILY;COUNT=2;UNTIL=20260101",
+		"FREQ=DAILY;BYHOUR=3", "FREQ=DAILY;INTERVAL=0", "FREQ=DAILY;FREQ=WEEKLY", "INTERVAL=2", "FREQ=WEEKLY;WKST=SU",
+	} {
+		if _, err := ParseRule(s, time.UTC); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+	r := mustRule(t, "rrule:freq=monthly;byday=1fr,-1sa;until=20261231", time.UTC)
+	if got := r.String(); got != "FREQ=MONTHLY;BYDAY=1FR,-1SA;UNTIL=20261231T235959Z" {
+		t.Fatalf("String = %s", got)
+	}
+}
+
+func TestWriteCalendar(t *testing.T) {
+	la, _ := time.LoadLocation("America/Los_Angeles")
+	var b strings.Builder
+	err := WriteCalendar(&b, "Rooftop 22", []CalendarEvent{{
+		UID:         "event-1@bytspot",
+		Summary:     "Happy hour; $5 drinks, all night",
+		Description: strings.Repeat("long line ", 10) + "\nsecond",
+		Categories:  []string{"happy hour"},
+		Series:      Series{Start: time.Date(2026, 10, 23, 17, 0, 0, 0, la), Duration: 2 * time.Hour, Rule: mustRule(t, "FREQ=WEEKLY;BYDAY=FR", la)},
+	}}, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"DTSTART;TZID=America/Los_Angeles:20261023T170000\r\n",
+		"DTEND;TZID=America/Los_Angeles:20261023T190000\r\n",
+		"RRULE:FREQ=WEEKLY;BYDAY=FR\r\n",
+		`SUMMARY:Happy hour\; $5 drinks\, all night`,
+		"CATEGORIES:happy hour\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+	for _, l := range strings.Split(out, "\r\n") {
+		if len(l) > 75 {
+			t.Errorf("unfolded line %q", l)
+		}
+	}
+	if unfolded := strings.ReplaceAll(out, "\r\n ", ""); !strings.Contains(unfolded, "DESCRIPTION:"+strings.Repeat("long line ", 10)+`\nsecond`) {
+		t.Errorf("description not folded and escaped:\n%s", out)
+	}
+}
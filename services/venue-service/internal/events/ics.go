@@ -0,0 +1,104 @@
+package events
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// CalendarEvent is one VEVENT of an iCalendar feed.
+type CalendarEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	URL         string
+	Categories  []string
+	Series      Series
+	Cancelled   bool
+	Updated     time.Time
+}
+
+// WriteCalendar writes an RFC 5545 calendar. Times carry the IANA zone
+// name as TZID (without VTIMEZONE blocks, which the calendar apps that
+// subscribe to feeds resolve themselves); UTC series are written in UTC.
+func WriteCalendar(w io.Writer, name string, evs []CalendarEvent, now time.Time) error {
+	bw := bufio.NewWriter(w)
+	line := func(s string) { bw.WriteString(fold(s) + "\r\n") }
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//Bytspot//Venue Events//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:" + escapeText(name))
+	for _, e := range evs {
+		line("BEGIN:VEVENT")
+		line("UID:" + e.UID)
+		line("DTSTAMP:" + now.UTC().Format("20060102T150405Z"))
+		if !e.Updated.IsZero() {
+			line("LAST-MODIFIED:" + e.Updated.UTC().Format("20060102T150405Z"))
+		}
+		line("DTSTART" + icsTime(e.Series.Start))
+		line("DTEND" + icsTime(e.Series.Start.Add(e.Series.Duration)))
+		if e.Series.Rule != nil {
+			line("RRULE:" + e.Series.Rule.String())
+		}
+		line("SUMMARY:" + escapeText(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION:" + escapeText(e.Description))
+		}
+		if e.URL != "" {
+			line("URL:" + e.URL)
+		}
+		if len(e.Categories) > 0 {
+			cats := make([]string, len(e.Categories))
+			for i, c := range e.Categories {
+				cats[i] = escapeText(c)
+			}
+			line("CATEGORIES:" + strings.Join(cats, ","))
+		}
+		if e.Cancelled {
+			line("STATUS:CANCELLED")
+		} else {
+			line("STATUS:CONFIRMED")
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return bw.Flush()
+}
+
+// icsTime is the parameters and value of a DTSTART/DTEND property.
+func icsTime(t time.Time) string {
+	if name := t.Location().String(); name != "UTC" && name != "Local" {
+		return ";TZID=" + name + ":" + t.Format("20060102T150405")
+	}
+	return ":" + t.UTC().Format("20060102T150405Z")
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+func escapeText(s string) string { return textEscaper.Replace(s) }
+
+// fold splits content lines longer than 75 octets, never inside a UTF-8
+// sequence; continuation lines start with a space.
+func fold(s string) string {
+	if len(s) <= 75 {
+		return s
+	}
+	var b strings.Builder
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		limit = 74 // the leading space counts
+	}
+	b.WriteString(s)
+	return b.String()
+}
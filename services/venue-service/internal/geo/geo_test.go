@@ -0,0 +1,116 @@
+package geo
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestDistance_KnownPairs(t *testing.T) {
+	// SF Ferry Building -> Oakland City Hall is ~10.7km
+	d := Distance(37.7955, -122.3937, 37.8053, -122.2723)
+	if math.Abs(d-10740) > 150 {
+		t.Fatalf("unexpected distance %.0fm", d)
+	}
+	if Distance(10, 20, 10, 20) != 0 {
+		t.Fatal("expected zero distance for identical points")
+	}
+}
+
+func TestEncodeDecode_RoundTrip(t *testing.T) {
+	if got := Encode(57.64911, 10.40744, 11); got != "u4pruydqqvj" {
+		t.Fatalf("unexpected geohash %q", got)
+	}
+	b, ok := Decode("u4pruydqqvj")
+	if !ok || !b.Contains(57.64911, 10.40744) {
+		t.Fatalf("decoded box %+v does not contain the point", b)
+	}
+	if _, ok := Decode("u4pa"); ok {
+		t.Fatal("expected invalid character to fail")
+	}
+}
+
+// Within must agree with a brute-force scan, including near the antimeridian.
+func TestIndexWithin_MatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, c := range []struct{ lat, lon float64 }{{37.77, -122.42}, {-33.86, 151.2}, {0.1, 179.99}} {
+		ix := NewIndex()
+		type pt struct {
+			id       string
+			lat, lon float64
+		}
+		var pts []pt
+		for i := 0; i < 500; i++ {
+			p := pt{id: string(rune('a'+i%26)) + string(rune('0'+i/26)), lat: c.lat + (rng.Float64()-0.5)*0.2, lon: c.lon + (rng.Float64()-0.5)*0.2}
+			if p.lon > 180 {
+				p.lon -= 360
+			}
+			pts = append(pts, p)
+			ix.Upsert(p.id, p.lat, p.lon)
+		}
+		for _, radius := range []float64{250, 1500, 5000} {
+			var want []string
+			for _, p := range pts {
+				if Distance(c.lat, c.lon, p.lat, p.lon) <= radius {
+					want = append(want, p.id)
+				}
+			}
+			var got []string
+			prev := -1.0
+			for _, h := range ix.Within(c.lat, c.lon, radius) {
+				if h.Distance < prev {
+					t.Fatal("hits not sorted by distance")
+				}
+				prev = h.Distance
+				got = append(got, h.ID)
+			}
+			sort.Strings(want)
+			sort.Strings(got)
+			if len(want) != len(got) {
+				t.Fatalf("%v r=%v: expected %d hits, got %d", c, radius, len(want), len(got))
+			}
+		}
+	}
+}
+
+func TestIndexUpsertMovesPoint(t *testing.T) {
+	ix := NewIndex()
+	ix.Upsert("v", 37.77, -122.42)
+	ix.Upsert("v", 40.71, -74.0)
+	if ix.Len() != 1 || len(ix.Within(37.77, -122.42, 1000)) != 0 || len(ix.Within(40.71, -74.0, 10)) != 1 {
+		t.Fatal("expected the point to move")
+	}
+	ix.Remove("v")
+	if ix.Len() != 0 {
+		t.Fatal("expected empty index after remove")
+	}
+}
+
+func TestCellsInBox_CoversBoxExactly(t *testing.T) {
+	b := Box{MinLat: 37.76, MinLon: -122.43, MaxLat: 37.79, MaxLon: -122.40}
+	cells, ok := CellsInBox(b, 6, 1000)
+	if !ok || len(cells) == 0 {
+		t.Fatalf("expected cells, got %v %v", cells, ok)
+	}
+	seen := map[string]bool{}
+	for _, c := range cells {
+		cb, _ := Decode(c)
+		if seen[c] || cb.MaxLat < b.MinLat || cb.MinLat > b.MaxLat || cb.MaxLon < b.MinLon || cb.MinLon > b.MaxLon {
+			t.Fatalf("cell %s duplicated or outside the box", c)
+		}
+		seen[c] = true
+	}
+	// every point in the box falls in a returned cell
+	rng := rand.New(rand.NewSource(2))
+	for i := 0; i < 500; i++ {
+		lat := b.MinLat + rng.Float64()*(b.MaxLat-b.MinLat)
+		lon := b.MinLon + rng.Float64()*(b.MaxLon-b.MinLon)
+		if !seen[Encode(lat, lon, 6)] {
+			t.Fatalf("point %v,%v not covered", lat, lon)
+		}
+	}
+	if _, ok := CellsInBox(b, 7, 100); ok {
+		t.Fatal("expected the cell limit to be enforced")
+	}
+}
@@ -0,0 +1,172 @@
+package geo
+
+import (
+	"math"
+	"strings"
+)
+
+const base32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// MaxPrecision is the geohash length stored in the index (~5m cells).
+const MaxPrecision = 9
+
+// Encode returns the geohash of lat/lon with the given number of characters.
+func Encode(lat, lon float64, precision int) string {
+	latLo, latHi := -90.0, 90.0
+	lonLo, lonHi := -180.0, 180.0
+	var sb strings.Builder
+	bit, ch, even := 0, 0, true
+	for sb.Len() < precision {
+		if even {
+			mid := (lonLo + lonHi) / 2
+			if lon >= mid {
+				ch = ch<<1 | 1
+				lonLo = mid
+			} else {
+				ch <<= 1
+				lonHi = mid
+			}
+		} else {
+			mid := (latLo + latHi) / 2
+			if lat >= mid {
+				ch = ch<<1 | 1
+				latLo = mid
+			} else {
+				ch <<= 1
+				latHi = mid
+			}
+		}
+		even = !even
+		if bit++; bit == 5 {
+			sb.WriteByte(base32[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return sb.String()
+}
+
+// Box is a lat/lon rectangle.
+type Box struct {
+	MinLat, MinLon, MaxLat, MaxLon float64
+}
+
+// Contains reports whether the point lies inside the box (edges included).
+func (b Box) Contains(lat, lon float64) bool {
+	return lat >= b.MinLat && lat <= b.MaxLat && lon >= b.MinLon && lon <= b.MaxLon
+}
+
+// Decode returns the cell covered by hash. Invalid characters yield false.
+func Decode(hash string) (Box, bool) {
+	b := Box{MinLat: -90, MaxLat: 90, MinLon: -180, MaxLon: 180}
+	even := true
+	for i := 0; i < len(hash); i++ {
+		idx := strings.IndexByte(base32, hash[i])
+		if idx < 0 {
+			return Box{}, false
+		}
+		for n := 4; n >= 0; n-- {
+			on := idx>>n&1 == 1
+			if even {
+				mid := (b.MinLon + b.MaxLon) / 2
+				if on {
+					b.MinLon = mid
+				} else {
+					b.MaxLon = mid
+				}
+			} else {
+				mid := (b.MinLat + b.MaxLat) / 2
+				if on {
+					b.MinLat = mid
+				} else {
+					b.MaxLat = mid
+				}
+			}
+			even = !even
+		}
+	}
+	return b, true
+}
+
+// cellSize returns a cell's height and width in degrees at precision.
+func cellSize(precision int) (latDeg, lonDeg float64) {
+	bits := 5 * precision
+	lonBits := (bits + 1) / 2
+	latBits := bits / 2
+	return 180 / math.Exp2(float64(latBits)), 360 / math.Exp2(float64(lonBits))
+}
+
+const metersPerDegree = 111320.0
+
+// PrecisionFor returns the longest geohash whose cells are at least radius
+// meters on each side at lat, so a cell and its 8 neighbors cover any
+// circle of that radius centred in the middle cell.
+func PrecisionFor(radius, lat float64) int {
+	cosLat := math.Max(math.Cos(radians(lat)), 0.01)
+	for p := MaxPrecision; p > 1; p-- {
+		h, w := cellSize(p)
+		if h*metersPerDegree >= radius && w*metersPerDegree*cosLat >= radius {
+			return p
+		}
+	}
+	return 1
+}
+
+// Cover returns the cell containing lat/lon plus its 8 neighbors at precision,
+// deduplicated (near the poles several neighbors collapse into one cell).
+func Cover(lat, lon float64, precision int) []string {
+	h, w := cellSize(precision)
+	center, _ := Decode(Encode(lat, lon, precision))
+	cLat := (center.MinLat + center.MaxLat) / 2
+	cLon := (center.MinLon + center.MaxLon) / 2
+	seen := map[string]bool{}
+	var out []string
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			nLat := cLat + float64(dy)*h
+			if nLat > 90 || nLat < -90 {
+				continue
+			}
+			nLon := cLon + float64(dx)*w
+			// wrap across the antimeridian
+			if nLon > 180 {
+				nLon -= 360
+			} else if nLon < -180 {
+				nLon += 360
+			}
+			cell := Encode(nLat, nLon, precision)
+			if !seen[cell] {
+				seen[cell] = true
+				out = append(out, cell)
+			}
+		}
+	}
+	return out
+}
+
+// CellsInBox returns the cells at precision that overlap b, row by row from
+// the south-west corner. ok is false, with no cells, when there would be
+// more than limit of them.
+func CellsInBox(b Box, precision, limit int) (cells []string, ok bool) {
+	h, w := cellSize(precision)
+	sw, _ := Decode(Encode(b.MinLat, b.MinLon, precision))
+	rows := int(math.Floor((b.MaxLat-sw.MinLat)/h)) + 1
+	cols := int(math.Floor((b.MaxLon-sw.MinLon)/w)) + 1
+	if rows*cols > limit {
+		return nil, false
+	}
+	cells = make([]string, 0, rows*cols)
+	for r := 0; r < rows; r++ {
+		lat := sw.MinLat + (float64(r)+0.5)*h
+		if lat > 90 {
+			break
+		}
+		for c := 0; c < cols; c++ {
+			lon := sw.MinLon + (float64(c)+0.5)*w
+			if lon > 180 {
+				break
+			}
+			cells = append(cells, Encode(lat, lon, precision))
+		}
+	}
+	return cells, true
+}
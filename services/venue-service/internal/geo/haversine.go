@@ -0,0 +1,24 @@
+// Package geo has the spatial primitives behind discovery: great-circle
+// distance, geohash encoding and a geohash-prefix index over venues.
+package geo
+
+import "math"
+
+// EarthRadiusMeters is the mean Earth radius used by Distance.
+const EarthRadiusMeters = 6371008.8
+
+func radians(deg float64) float64 { return deg * math.Pi / 180 }
+
+// Distance returns the haversine great-circle distance in meters.
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	dLat := radians(lat2 - lat1)
+	dLon := radians(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(radians(lat1))*math.Cos(radians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// ValidCoords reports whether lat/lon are inside the WGS84 range.
+func ValidCoords(lat, lon float64) bool {
+	return lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180
+}
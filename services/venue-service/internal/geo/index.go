@@ -0,0 +1,118 @@
+package geo
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Hit is an indexed point with its distance from the query centre.
+type Hit struct {
+	ID       string
+	Distance float64 // meters
+}
+
+type entry struct {
+	hash     string
+	id       string
+	lat, lon float64
+}
+
+// Index keeps points sorted by full-precision geohash so a prefix cell is a
+// contiguous range found by binary search. Safe for concurrent use.
+type Index struct {
+	mu      sync.RWMutex
+	entries []entry          // sorted by (hash, id)
+	byID    map[string]entry // id -> entry, for updates and removal
+}
+
+func NewIndex() *Index {
+	return &Index{byID: map[string]entry{}}
+}
+
+func less(a, b entry) bool {
+	if a.hash != b.hash {
+		return a.hash < b.hash
+	}
+	return a.id < b.id
+}
+
+// Upsert adds or moves the point id.
+func (ix *Index) Upsert(id string, lat, lon float64) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+	e := entry{hash: Encode(lat, lon, MaxPrecision), id: id, lat: lat, lon: lon}
+	i := sort.Search(len(ix.entries), func(i int) bool { return !less(ix.entries[i], e) })
+	ix.entries = append(ix.entries, entry{})
+	copy(ix.entries[i+1:], ix.entries[i:])
+	ix.entries[i] = e
+	ix.byID[id] = e
+}
+
+func (ix *Index) Remove(id string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+}
+
+func (ix *Index) remove(id string) {
+	e, ok := ix.byID[id]
+	if !ok {
+		return
+	}
+	i := sort.Search(len(ix.entries), func(i int) bool { return !less(ix.entries[i], e) })
+	if i < len(ix.entries) && ix.entries[i].id == id {
+		ix.entries = append(ix.entries[:i], ix.entries[i+1:]...)
+	}
+	delete(ix.byID, id)
+}
+
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.entries)
+}
+
+// scanPrefix calls fn for every entry whose hash starts with prefix.
+func (ix *Index) scanPrefix(prefix string, fn func(entry)) {
+	i := sort.Search(len(ix.entries), func(i int) bool { return ix.entries[i].hash >= prefix })
+	for ; i < len(ix.entries) && strings.HasPrefix(ix.entries[i].hash, prefix); i++ {
+		fn(ix.entries[i])
+	}
+}
+
+// Within returns points no further than radius meters from lat/lon, nearest
+// first (ties broken by id so results are stable).
+func (ix *Index) Within(lat, lon, radius float64) []Hit {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	var hits []Hit
+	for _, cell := range Cover(lat, lon, PrecisionFor(radius, lat)) {
+		ix.scanPrefix(cell, func(e entry) {
+			if d := Distance(lat, lon, e.lat, e.lon); d <= radius {
+				hits = append(hits, Hit{ID: e.id, Distance: d})
+			}
+		})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Distance != hits[j].Distance {
+			return hits[i].Distance < hits[j].Distance
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	return hits
+}
+
+// InBox returns the ids of points inside b, in geohash order.
+func (ix *Index) InBox(b Box) []string {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	var ids []string
+	for _, e := range ix.entries {
+		if b.Contains(e.lat, e.lon) {
+			ids = append(ids, e.id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,90 @@
+// Package geocode turns venue addresses into coordinates.
+package geocode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"bytspot/services/venue-service/internal/db"
+)
+
+// ErrNoMatch means the geocoder answered but found nothing for the address.
+var ErrNoMatch = errors.New("geocode: no match")
+
+type Geocoder interface {
+	Geocode(ctx context.Context, a db.Address) (lat, lon float64, err error)
+}
+
+// FromEnv returns a Nominatim-compatible client for GEOCODER_URL, or nil
+// when it is unset (callers then require explicit coordinates).
+func FromEnv() Geocoder {
+	base := os.Getenv("GEOCODER_URL")
+	if base == "" {
+		return nil
+	}
+	return &Nominatim{BaseURL: strings.TrimRight(base, "/"), UserAgent: "bytspot-venue-service", Client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// Nominatim queries the /search endpoint of an OpenStreetMap Nominatim
+// server (or any service speaking the same format).
+type Nominatim struct {
+	BaseURL   string
+	UserAgent string
+	Client    *http.Client
+}
+
+// Query is the single-line form of a, as sent to the geocoder.
+func Query(a db.Address) string {
+	var parts []string
+	for _, p := range []string{a.Line1, a.City, a.Region, a.PostalCode, a.Country} {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (n *Nominatim) Geocode(ctx context.Context, a db.Address) (float64, float64, error) {
+	q := Query(a)
+	if q == "" {
+		return 0, 0, ErrNoMatch
+	}
+	u := n.BaseURL + "/search?" + url.Values{"q": {q}, "format": {"jsonv2"}, "limit": {"1"}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("User-Agent", n.UserAgent)
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Errorf("geocode: upstream status %d", resp.StatusCode)
+	}
+	var results []struct {
+		Lat string `json:"lat"`
+		Lon string `json:"lon"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return 0, 0, fmt.Errorf("geocode: %w", err)
+	}
+	if len(results) == 0 {
+		return 0, 0, ErrNoMatch
+	}
+	lat, err1 := strconv.ParseFloat(results[0].Lat, 64)
+	lon, err2 := strconv.ParseFloat(results[0].Lon, 64)
+	if err1 != nil || err2 != nil {
+		return 0, 0, fmt.Errorf("geocode: malformed coordinates %q,%q", results[0].Lat, results[0].Lon)
+	}
+	return lat, lon, nil
+}
@@ -0,0 +1,37 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"bytspot/services/venue-service/internal/db"
+)
+
+func TestNominatim_Geocode(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("q") {
+		case "55 2nd St, San Francisco, CA":
+			w.Write([]byte(`[{"lat":"37.7897","lon":"-122.4011"}]`))
+		case "":
+			t.Errorf("empty query sent")
+		default:
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer srv.Close()
+	g := &Nominatim{BaseURL: srv.URL, Client: srv.Client()}
+
+	lat, lon, err := g.Geocode(context.Background(), db.Address{Line1: "55 2nd St", City: "San Francisco", Region: "CA"})
+	if err != nil || lat != 37.7897 || lon != -122.4011 {
+		t.Fatalf("got %v,%v (%v)", lat, lon, err)
+	}
+	if _, _, err := g.Geocode(context.Background(), db.Address{Line1: "nowhere"}); !errors.Is(err, ErrNoMatch) {
+		t.Fatalf("expected ErrNoMatch, got %v", err)
+	}
+	if _, _, err := g.Geocode(context.Background(), db.Address{}); !errors.Is(err, ErrNoMatch) {
+		t.Fatalf("expected ErrNoMatch for empty address, got %v", err)
+	}
+}
@@ -0,0 +1,211 @@
+// Package heat turns live venue vibes and ingestion activity signals into
+// per-geohash-cell intensity for the map. Activity is kept per geohash7 cell
+// as time-decayed state and rolled up to coarser cells on request; computed
+// cells are cached for a short TTL.
+package heat
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// SignalPrecision is the geohash length ingestion signals are reported at.
+const SignalPrecision = 7
+
+const (
+	// vibeSaturation is the decayed report weight at which a cell's vibe
+	// counts for about two thirds of its score.
+	vibeSaturation = 2.0
+	// deviceSaturation is the device count at which activity counts for
+	// about two thirds of its score.
+	deviceSaturation = 15.0
+)
+
+// Signal is one activity aggregate for a geohash7 cell: devices seen and how
+// lively they were (0-1) over a window ending at At.
+type Signal struct {
+	Cell      string
+	Devices   int
+	Intensity float64
+	At        time.Time
+}
+
+// Level is activity at a point in time: the recent device count and their
+// device-weighted mean intensity.
+type Level struct {
+	Devices   float64
+	Intensity float64
+}
+
+// cellState holds exponentially decayed sums as of ref: w = Σ d,
+// devices = Σ n·d and lively = Σ n·i·d, with d = exp(-(ref - t)/τ).
+type cellState struct {
+	ref                time.Time
+	w, devices, lively float64
+}
+
+// Activity keeps decayed activity per geohash7 cell. Safe for concurrent use.
+type Activity struct {
+	mu    sync.RWMutex
+	tau   float64 // decay time constant in seconds
+	cells map[string]*cellState
+}
+
+// NewActivity decays signals with the given half-life.
+func NewActivity(halfLife time.Duration) *Activity {
+	return &Activity{tau: halfLife.Seconds() / math.Ln2, cells: map[string]*cellState{}}
+}
+
+func (a *Activity) decay(from, to time.Time) float64 {
+	return math.Exp(-to.Sub(from).Seconds() / a.tau)
+}
+
+func (a *Activity) add(cells map[string]*cellState, s Signal) {
+	st := cells[s.Cell]
+	if st == nil {
+		st = &cellState{ref: s.At}
+		cells[s.Cell] = st
+	}
+	// late signals are added with their decay relative to ref instead of
+	// moving ref backwards
+	f := 1.0
+	if s.At.After(st.ref) {
+		d := a.decay(st.ref, s.At)
+		st.ref, st.w, st.devices, st.lively = s.At, st.w*d, st.devices*d, st.lively*d
+	} else {
+		f = a.decay(s.At, st.ref)
+	}
+	n := float64(s.Devices)
+	st.w += f
+	st.devices += n * f
+	st.lively += n * s.Intensity * f
+}
+
+// Add folds one signal into its cell.
+func (a *Activity) Add(s Signal) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.add(a.cells, s)
+}
+
+// Rebuild replaces all state with the given signals.
+func (a *Activity) Rebuild(signals []Signal) {
+	cells := map[string]*cellState{}
+	for _, s := range signals {
+		a.add(cells, s)
+	}
+	a.mu.Lock()
+	a.cells = cells
+	a.mu.Unlock()
+}
+
+// level is the cell's activity at now. Devices is the decayed mean over the
+// reported windows, so a steady stream of reports doesn't pile up, while a
+// cell that stops reporting fades out.
+func (a *Activity) level(st *cellState, now time.Time) Level {
+	d := a.decay(st.ref, now)
+	w, devices := st.w*d, st.devices*d
+	var l Level
+	if devices > 0 {
+		l.Devices = devices / math.Max(1, w)
+		l.Intensity = st.lively / st.devices
+	}
+	return l
+}
+
+// Sum rolls the activity at now up into the given cells, which must all be
+// geohashes of length precision (at most SignalPrecision). Cells without
+// activity are left out.
+func (a *Activity) Sum(cells map[string]bool, precision int, now time.Time) map[string]Level {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	out := map[string]Level{}
+	for hash, st := range a.cells {
+		key := hash[:precision]
+		if !cells[key] {
+			continue
+		}
+		l := a.level(st, now)
+		if l.Devices == 0 {
+			continue
+		}
+		sum := out[key]
+		total := sum.Devices + l.Devices
+		sum.Intensity = (sum.Intensity*sum.Devices + l.Intensity*l.Devices) / total
+		sum.Devices = total
+		out[key] = sum
+	}
+	return out
+}
+
+// Intensity combines a cell's live vibe (0-10, with the decayed report
+// weight behind it) and its activity into a 0-1 heat value. Each part grows
+// with the evidence behind it, and either one alone can make a cell hot.
+func Intensity(vibe *float64, vibeWeight float64, act Level) float64 {
+	var v float64
+	if vibe != nil {
+		v = *vibe / 10 * (1 - math.Exp(-vibeWeight/vibeSaturation))
+	}
+	a := (1 - math.Exp(-act.Devices/deviceSaturation)) * (0.5 + 0.5*act.Intensity)
+	return 1 - (1-v)*(1-a)
+}
+
+// Cell is the heat of one geohash cell.
+type Cell struct {
+	Geohash string     `json:"geohash"`
+	Lat     float64    `json:"lat"` // cell centre
+	Lon     float64    `json:"lon"`
+	Bounds  [4]float64 `json:"bounds"` // minLon, minLat, maxLon, maxLat
+	// Intensity is the combined heat (0-1).
+	Intensity float64 `json:"intensity"`
+	// Vibe is the weighted live score of the cell's venues; nil when none
+	// has enough recent reports.
+	Vibe    *float64 `json:"vibe"`
+	Venues  int      `json:"venues"`
+	Devices float64  `json:"devices"`
+}
+
+type cacheEntry struct {
+	cell    Cell
+	expires time.Time
+}
+
+// Cache keeps computed cells for a fixed TTL. Safe for concurrent use.
+type Cache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]cacheEntry
+	next    time.Time // next sweep
+}
+
+func NewCache(ttl time.Duration) *Cache {
+	return &Cache{ttl: ttl, entries: map[string]cacheEntry{}}
+}
+
+// Get returns the cached cell if it hasn't expired at now.
+func (c *Cache) Get(hash string, now time.Time) (Cell, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[hash]
+	if !ok || !now.Before(e.expires) {
+		return Cell{}, false
+	}
+	return e.cell, true
+}
+
+// Put caches cell until now + TTL, sweeping expired entries at most once per
+// TTL.
+func (c *Cache) Put(cell Cell, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now.After(c.next) {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		c.next = now.Add(c.ttl)
+	}
+	c.entries[cell.Geohash] = cacheEntry{cell: cell, expires: now.Add(c.ttl)}
+}
@@ -0,0 +1,76 @@
+package heat
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var t0 = time.Date(2026, 1, 9, 22, 0, 0, 0, time.UTC)
+
+func TestActivity_SteadyReportsDontPileUpAndFade(t *testing.T) {
+	a := NewActivity(20 * time.Minute)
+	for i := 0; i < 12; i++ { // one window every 5 minutes for an hour
+		a.Add(Signal{Cell: "9q8yyk8", Devices: 10, Intensity: 0.6, At: t0.Add(time.Duration(i) * 5 * time.Minute)})
+	}
+	a.Add(Signal{Cell: "9q8yykb", Devices: 30, Intensity: 0.2, At: t0.Add(55 * time.Minute)})
+	now := t0.Add(time.Hour)
+	got := a.Sum(map[string]bool{"9q8yyk8": true}, 7, now)["9q8yyk8"]
+	if math.Abs(got.Devices-10) > 1 || math.Abs(got.Intensity-0.6) > 1e-9 {
+		t.Fatalf("steady cell = %+v, want about 10 devices at 0.6", got)
+	}
+	// both cells roll up into their parent, intensity weighted by devices
+	up := a.Sum(map[string]bool{"9q8yyk": true}, 6, now)["9q8yyk"]
+	if up.Devices < 35 || up.Intensity >= 0.6 || up.Intensity <= 0.2 {
+		t.Fatalf("rolled up = %+v", up)
+	}
+	// hours after reports stop the cell has faded out
+	if l := a.Sum(map[string]bool{"9q8yyk": true}, 6, now.Add(4*time.Hour))["9q8yyk"]; l.Devices > 0.5 {
+		t.Fatalf("expected activity to fade, got %+v", l)
+	}
+	// out-of-order signals rebuild to the same state
+	b := NewActivity(20 * time.Minute)
+	b.Rebuild([]Signal{
+		{Cell: "9q8yyk8", Devices: 10, Intensity: 0.6, At: t0.Add(10 * time.Minute)},
+		{Cell: "9q8yyk8", Devices: 10, Intensity: 0.6, At: t0},
+	})
+	c := NewActivity(20 * time.Minute)
+	c.Add(Signal{Cell: "9q8yyk8", Devices: 10, Intensity: 0.6, At: t0})
+	c.Add(Signal{Cell: "9q8yyk8", Devices: 10, Intensity: 0.6, At: t0.Add(10 * time.Minute)})
+	cells := map[string]bool{"9q8yyk8": true}
+	if l1, l2 := b.Sum(cells, 7, now)["9q8yyk8"], c.Sum(cells, 7, now)["9q8yyk8"]; math.Abs(l1.Devices-l2.Devices) > 1e-9 {
+		t.Fatalf("rebuilt %+v != incremental %+v", l1, l2)
+	}
+}
+
+func TestIntensity(t *testing.T) {
+	if Intensity(nil, 0, Level{}) != 0 {
+		t.Fatal("expected an empty cell to be cold")
+	}
+	hi, lo := 9.0, 3.0
+	if Intensity(&hi, 3, Level{}) <= Intensity(&lo, 3, Level{}) {
+		t.Fatal("a higher vibe should be hotter")
+	}
+	if Intensity(&hi, 0.5, Level{}) >= Intensity(&hi, 5, Level{}) {
+		t.Fatal("more reports behind the same vibe should be hotter")
+	}
+	both := Intensity(&hi, 5, Level{Devices: 40, Intensity: 1})
+	if both <= Intensity(&hi, 5, Level{}) || both > 1 {
+		t.Fatalf("activity should add heat without exceeding 1, got %v", both)
+	}
+}
+
+func TestCache_ExpiresAfterTTL(t *testing.T) {
+	c := NewCache(30 * time.Second)
+	c.Put(Cell{Geohash: "9q8yyk", Intensity: 0.5}, t0)
+	if got, ok := c.Get("9q8yyk", t0.Add(29*time.Second)); !ok || got.Intensity != 0.5 {
+		t.Fatalf("expected a cache hit, got %+v %v", got, ok)
+	}
+	if _, ok := c.Get("9q8yyk", t0.Add(30*time.Second)); ok {
+		t.Fatal("expected the entry to expire")
+	}
+	c.Put(Cell{Geohash: "9q8yym"}, t0.Add(time.Minute))
+	if len(c.entries) != 1 {
+		t.Fatalf("expected expired entries to be swept, have %d", len(c.entries))
+	}
+}
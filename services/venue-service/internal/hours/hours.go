@@ -0,0 +1,168 @@
+// Package hours evaluates venue opening hours in the venue's own timezone.
+package hours
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"bytspot/services/venue-service/internal/db"
+)
+
+// DateLayout is the format of exception dates.
+const DateLayout = "2006-01-02"
+
+// horizon is how far ahead opens_next looks; a venue closed longer than
+// this (e.g. a seasonal closure) has no next opening.
+const horizon = 14 * 24 * time.Hour
+
+// ParseClock parses "HH:MM" (00:00-24:00) into minutes after midnight.
+func ParseClock(s string) (int, error) {
+	var h, m int
+	if _, err := fmt.Sscanf(s, "%d:%d", &h, &m); err != nil || len(s) != 5 {
+		return 0, fmt.Errorf("invalid time %q, want HH:MM", s)
+	}
+	if h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time %q, want HH:MM", s)
+	}
+	return h*60 + m, nil
+}
+
+// Location resolves the venue timezone, falling back to UTC.
+func Location(h db.OpeningHours) *time.Location {
+	if h.Timezone != "" {
+		if loc, err := time.LoadLocation(h.Timezone); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// span is an opening range in minutes from the start of the range's day.
+// A range whose close is at or before open runs past midnight.
+func span(openAt, closeAt string) (open, close int, err error) {
+	if open, err = ParseClock(openAt); err != nil {
+		return
+	}
+	if close, err = ParseClock(closeAt); err != nil {
+		return
+	}
+	if close <= open {
+		close += 24 * 60
+	}
+	return
+}
+
+// Known reports whether the venue publishes any hours at all.
+func Known(h db.OpeningHours) bool {
+	return len(h.Weekly) > 0 || len(h.Exceptions) > 0
+}
+
+// Status is a venue's opening state at one instant. Times are in the
+// venue's timezone.
+type Status struct {
+	Open bool
+	// ClosesAt is when the current opening ends; nil while closed or when
+	// the venue is open around the clock.
+	ClosesAt *time.Time
+	// OpensNext is the next opening while closed; nil while open or when
+	// nothing opens within two weeks.
+	OpensNext *time.Time
+}
+
+type interval struct{ start, end time.Time }
+
+// ranges lists the [open, close) minute spans for one local date: the
+// exception for that date if there is one, else the weekly ranges.
+func ranges(h db.OpeningHours, exceptions map[string]db.HoursException, date time.Time) [][2]int {
+	var out [][2]int
+	if ex, ok := exceptions[date.Format(DateLayout)]; ok {
+		if ex.Closed {
+			return nil
+		}
+		for _, r := range ex.Ranges {
+			if open, close, err := span(r.Open, r.Close); err == nil {
+				out = append(out, [2]int{open, close})
+			}
+		}
+		return out
+	}
+	day := int(date.Weekday())
+	for _, r := range h.Weekly {
+		if r.Day != day {
+			continue
+		}
+		if open, close, err := span(r.Open, r.Close); err == nil {
+			out = append(out, [2]int{open, close})
+		}
+	}
+	return out
+}
+
+// intervals returns the merged opening intervals that overlap [from, to),
+// starting a day early so last night's ranges spilling past midnight count.
+func intervals(h db.OpeningHours, loc *time.Location, from, to time.Time) []interval {
+	exceptions := make(map[string]db.HoursException, len(h.Exceptions))
+	for _, ex := range h.Exceptions {
+		exceptions[ex.Date] = ex
+	}
+	local := from.In(loc)
+	var list []interval
+	for i := -1; ; i++ {
+		date := time.Date(local.Year(), local.Month(), local.Day()+i, 0, 0, 0, 0, loc)
+		if !date.Before(to) {
+			break
+		}
+		for _, r := range ranges(h, exceptions, date) {
+			start := time.Date(date.Year(), date.Month(), date.Day(), 0, r[0], 0, 0, loc)
+			end := time.Date(date.Year(), date.Month(), date.Day(), 0, r[1], 0, 0, loc)
+			if end.After(from) {
+				list = append(list, interval{start, end})
+			}
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].start.Before(list[j].start) })
+	var merged []interval
+	for _, iv := range list {
+		if n := len(merged); n > 0 && !iv.start.After(merged[n-1].end) {
+			if iv.end.After(merged[n-1].end) {
+				merged[n-1].end = iv.end
+			}
+			continue
+		}
+		merged = append(merged, iv)
+	}
+	return merged
+}
+
+// At computes the opening status at t, applying exceptions and ranges that
+// cross midnight. ok is false when the venue has no hours.
+func At(h db.OpeningHours, t time.Time) (st Status, ok bool) {
+	if !Known(h) {
+		return Status{}, false
+	}
+	loc := Location(h)
+	end := t.Add(horizon)
+	for _, iv := range intervals(h, loc, t, end) {
+		if !iv.start.After(t) && t.Before(iv.end) {
+			st.Open = true
+			if iv.end.Before(end) {
+				closes := iv.end
+				st.ClosesAt = &closes
+			}
+			return st, true
+		}
+		if iv.start.After(t) {
+			opens := iv.start
+			st.OpensNext = &opens
+			return st, true
+		}
+	}
+	return st, true
+}
+
+// IsOpen reports whether the venue is open at t.
+func IsOpen(h db.OpeningHours, t time.Time) bool {
+	st, _ := At(h, t)
+	return st.Open
+}
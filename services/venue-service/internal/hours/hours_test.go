@@ -0,0 +1,91 @@
+package hours
+
+import (
+	"testing"
+	"time"
+
+	"bytspot/services/venue-service/internal/db"
+)
+
+func TestIsOpen_CrossesMidnightInVenueTimezone(t *testing.T) {
+	h := db.OpeningHours{Timezone: "America/Los_Angeles", Weekly: []db.DayRange{{Day: 5, Open: "20:00", Close: "02:00"}}}
+	la, _ := time.LoadLocation("America/Los_Angeles")
+	cases := []struct {
+		at   time.Time
+		open bool
+	}{
+		{time.Date(2026, 1, 9, 19, 59, 0, 0, la), false},     // Friday before opening
+		{time.Date(2026, 1, 9, 23, 0, 0, 0, la), true},       // Friday night
+		{time.Date(2026, 1, 10, 1, 30, 0, 0, la), true},      // Saturday early hours
+		{time.Date(2026, 1, 10, 2, 0, 0, 0, la), false},      // closed at 02:00
+		{time.Date(2026, 1, 10, 7, 0, 0, 0, time.UTC), true}, // 23:00 Friday in LA
+	}
+	for _, c := range cases {
+		if got := IsOpen(h, c.at); got != c.open {
+			t.Errorf("IsOpen(%v) = %v, want %v", c.at, got, c.open)
+		}
+	}
+}
+
+func TestAt_ClosesAtOpensNextAndExceptions(t *testing.T) {
+	la, _ := time.LoadLocation("America/Los_Angeles")
+	h := db.OpeningHours{
+		Timezone: "America/Los_Angeles",
+		Weekly: []db.DayRange{
+			{Day: 5, Open: "18:00", Close: "24:00"}, // Friday, continued by Saturday's early range
+			{Day: 6, Open: "00:00", Close: "02:00"},
+			{Day: 6, Open: "18:00", Close: "02:00"},
+		},
+		Exceptions: []db.HoursException{
+			{Date: "2026-01-17", Closed: true, Note: "private event"},                     // Saturday
+			{Date: "2026-01-18", Ranges: []db.TimeRange{{Open: "12:00", Close: "16:00"}}}, // Sunday brunch
+		},
+	}
+
+	// Friday 23:00: adjacent ranges merge, so it closes at 02:00 Saturday
+	st, ok := At(h, time.Date(2026, 1, 9, 23, 0, 0, 0, la))
+	if !ok || !st.Open || st.ClosesAt == nil || !st.ClosesAt.Equal(time.Date(2026, 1, 10, 2, 0, 0, 0, la)) {
+		t.Fatalf("friday night = %+v", st)
+	}
+	if st.ClosesAt.Location().String() != "America/Los_Angeles" {
+		t.Fatalf("closesAt should be in the venue timezone, got %v", st.ClosesAt.Location())
+	}
+
+	// Saturday 10:00: closed, opens at 18:00
+	st, _ = At(h, time.Date(2026, 1, 10, 10, 0, 0, 0, la))
+	if st.Open || st.OpensNext == nil || !st.OpensNext.Equal(time.Date(2026, 1, 10, 18, 0, 0, 0, la)) {
+		t.Fatalf("saturday morning = %+v", st)
+	}
+
+	// the closed Saturday drops its own early range and skips to the
+	// Sunday exception
+	if IsOpen(h, time.Date(2026, 1, 17, 1, 0, 0, 0, la)) {
+		t.Fatalf("expected the closure to drop Saturday's 00:00-02:00 range")
+	}
+	st, _ = At(h, time.Date(2026, 1, 17, 20, 0, 0, 0, la))
+	if st.Open || st.OpensNext == nil || !st.OpensNext.Equal(time.Date(2026, 1, 18, 12, 0, 0, 0, la)) {
+		t.Fatalf("closed saturday = %+v", st)
+	}
+
+	if _, ok := At(db.OpeningHours{}, time.Now()); ok {
+		t.Fatalf("expected unknown status without hours")
+	}
+	always := db.OpeningHours{}
+	for d := 0; d < 7; d++ {
+		always.Weekly = append(always.Weekly, db.DayRange{Day: d, Open: "00:00", Close: "24:00"})
+	}
+	if st, _ := At(always, time.Now()); !st.Open || st.ClosesAt != nil {
+		t.Fatalf("24/7 venue = %+v", st)
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	if m, err := ParseClock("24:00"); err != nil || m != 1440 {
+		t.Fatalf("24:00: got %d, %v", m, err)
+	}
+	for _, bad := range []string{"7:00", "25:00", "12:60", "noon"} {
+		if _, err := ParseClock(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package importer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"bytspot/services/venue-service/internal/db"
+)
+
+const launchCSV = `Name,Category,Tags,Price,Latitude,Longitude,Address,City,Status
+The Energetic Bar!,bar,dj;late night,$$$,37.7898,-122.4012,55 2nd St,San Francisco,
+Harbor Oyster Co,restaurant,seafood,2,37.8060,-122.4100,Pier 39,San Francisco,draft
+Harbor Oyster Co.,restaurant,,2,37.8061,-122.4101,,,
+Nowhere,bar,,2,137.0,-122.4,,,
+Mystery,speakeasy,,2,37.78,-122.40,,,
+Lonely,bar,,2,,,,,
+`
+
+func seeded(t *testing.T) *db.MemStore {
+	t.Helper()
+	repo := db.NewMemStore()
+	if err := db.Seed(context.Background(), repo); err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func actions(rep *Report) string {
+	var out []string
+	for _, r := range rep.Rows {
+		out = append(out, r.Action)
+	}
+	return strings.Join(out, ",")
+}
+
+func TestNormalizeName(t *testing.T) {
+	for _, tc := range [][2]string{
+		{"The Energetic Bar!", "energetic bar"},
+		{"Salt & Straw", "salt and straw"},
+		{"  Fog  City Roasters ", "fog city roasters"},
+		{"The", "the"},
+	} {
+		if got := NormalizeName(tc[0]); got != tc[1] {
+			t.Errorf("NormalizeName(%q) = %q, want %q", tc[0], got, tc[1])
+		}
+	}
+}
+
+func TestRun_CSVDedupesAndReports(t *testing.T) {
+	ctx := context.Background()
+	repo := seeded(t)
+	rows, err := Parse(strings.NewReader(launchCSV), CSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dry, err := Run(ctx, repo, rows, Options{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := actions(dry); got != "updated,created,skipped,error,error,error" {
+		t.Fatalf("dry run actions = %s\n%+v", got, dry.Rows)
+	}
+	if r := dry.Rows[0]; r.ID != "v1" || r.Changes["priceTier"].To == nil || r.Changes["name"].To == nil {
+		t.Fatalf("expected v1 update with name and price changes, got %+v", r)
+	}
+	if r := dry.Rows[2]; r.Reason != "duplicate of line 3" {
+		t.Fatalf("in-file duplicate reason = %q", r.Reason)
+	}
+	if v, _ := repo.GetVenue(ctx, "v1"); v.PriceTier != 2 {
+		t.Fatalf("dry run wrote to the store")
+	}
+
+	var audited []string
+	rep, err := Run(ctx, repo, rows, Options{Audit: func(_ context.Context, _ map[string]db.FieldChange, id string) { audited = append(audited, id) }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Created != 1 || rep.Updated != 1 || rep.Skipped != 1 || rep.Errors != 3 || len(audited) != 2 {
+		t.Fatalf("report %+v, audited %v", rep, audited)
+	}
+	created, _ := repo.GetVenue(ctx, rep.Rows[1].ID)
+	if created == nil || created.Status != db.VenueDraft || created.Address.Line1 != "Pier 39" {
+		t.Fatalf("created venue = %+v", created)
+	}
+	if v, _ := repo.GetVenue(ctx, "v1"); v.PriceTier != 3 || len(v.Tags) != 2 || v.Category != "bar" {
+		t.Fatalf("v1 after import = %+v", v)
+	}
+
+	// re-running the same file changes nothing
+	again, _ := Run(ctx, repo, rows, Options{})
+	if again.Created != 0 || again.Updated != 0 || again.Skipped != 3 {
+		t.Fatalf("re-run = %s", actions(again))
+	}
+}
+
+func TestRun_UnknownIDFallsBackToNameMatch(t *testing.T) {
+	ctx := context.Background()
+	repo := seeded(t)
+	rows, err := Parse(strings.NewReader("id,name,category,price,lat,lng\nsf-999,Energetic Bar,bar,$,37.7897,-122.4011\n"), CSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rep, err := Run(ctx, repo, rows, Options{})
+	if err != nil || rep.Created != 0 || rep.Updated != 1 || rep.Rows[0].ID != "v1" {
+		t.Fatalf("expected v1 updated, got %+v (%v)", rep, err)
+	}
+	if v, _ := repo.GetVenue(ctx, "sf-999"); v != nil {
+		t.Fatalf("created a duplicate of v1: %+v", v)
+	}
+}
+
+func TestParse_GeoJSON(t *testing.T) {
+	const fc = `{"type":"FeatureCollection","features":[
+		{"type":"Feature","id":"sf-101","geometry":{"type":"Point","coordinates":[-122.42,37.77]},
+		 "properties":{"name":"Zuni","category":"restaurant","priceTier":3,"tags":["Oysters","Roast chicken"],
+		  "address":{"line1":"1658 Market St","city":"San Francisco"},
+		  "hours":{"timezone":"America/Los_Angeles","weekly":[{"day":2,"open":"11:30","close":"21:00"}]}}},
+		{"type":"Feature","geometry":{"type":"LineString","coordinates":[[0,0],[1,1]]},"properties":{"name":"Road"}}
+	]}`
+	rows, err := Parse(strings.NewReader(fc), GeoJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[1].Err == nil {
+		t.Fatalf("rows = %+v", rows)
+	}
+	r := rows[0]
+	if r.Err != nil || r.ID != "sf-101" || *r.Input.Lat != 37.77 || *r.Input.Lon != -122.42 || *r.Input.PriceTier != 3 {
+		t.Fatalf("row = %+v (%v)", r, r.Err)
+	}
+	if r.Input.Address.Line1 != "1658 Market St" || len(r.Input.Hours.Weekly) != 1 || len(*r.Input.Tags) != 2 {
+		t.Fatalf("nested fields not parsed: %+v", r.Input)
+	}
+
+	rep, err := Run(context.Background(), seeded(t), rows, Options{})
+	if err != nil || rep.Created != 1 || rep.Errors != 1 || rep.Rows[0].ID != "sf-101" {
+		t.Fatalf("report %+v (%v)", rep, err)
+	}
+	if _, err := Parse(strings.NewReader(`{"type":"Feature"}`), GeoJSON); err == nil {
+		t.Fatalf("expected a non-collection to be rejected")
+	}
+}
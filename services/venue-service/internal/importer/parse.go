@@ -0,0 +1,284 @@
+// Package importer loads venues in bulk from CSV or GeoJSON. Rows are
+// matched to existing venues by id, or by normalized name within a small
+// radius, so re-running an import updates venues instead of duplicating them.
+package importer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"bytspot/services/venue-service/internal/db"
+	"bytspot/services/venue-service/internal/venues"
+)
+
+type Format string
+
+const (
+	CSV     Format = "csv"
+	GeoJSON Format = "geojson"
+
+	// MaxRows bounds one import; split larger files.
+	MaxRows = 5000
+)
+
+// Row is one parsed record. Line is the CSV line (header is line 1) or the
+// 1-based GeoJSON feature index. Rows with Err set are reported, not written.
+type Row struct {
+	Line  int
+	ID    string // optional explicit venue id
+	Input venues.Input
+	Err   error
+}
+
+// Parse reads every row of a CSV file (with a header) or a GeoJSON
+// FeatureCollection of Points. It fails only when the file itself is
+// unreadable; problems in a single row are returned on that row.
+func Parse(r io.Reader, f Format) ([]Row, error) {
+	switch f {
+	case CSV:
+		return parseCSV(r)
+	case GeoJSON:
+		return parseGeoJSON(r)
+	}
+	return nil, fmt.Errorf("unknown format %q (want csv or geojson)", f)
+}
+
+// FormatFor guesses the format from a file name or content type.
+func FormatFor(nameOrType string) (Format, bool) {
+	s := strings.ToLower(nameOrType)
+	switch {
+	case strings.HasSuffix(s, ".csv"), strings.Contains(s, "text/csv"):
+		return CSV, true
+	case strings.HasSuffix(s, ".geojson"), strings.HasSuffix(s, ".json"), strings.Contains(s, "geo+json"), strings.Contains(s, "application/json"):
+		return GeoJSON, true
+	}
+	return "", false
+}
+
+func parseCSV(r io.Reader) ([]Row, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("csv header: %w", err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff") // Excel BOM
+	}
+	var rows []Row
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if len(rows) == MaxRows {
+			return nil, fmt.Errorf("more than %d rows", MaxRows)
+		}
+		if err != nil {
+			var perr *csv.ParseError
+			if !errors.As(err, &perr) {
+				return nil, err
+			}
+			rows = append(rows, Row{Line: perr.StartLine, Err: perr.Err})
+			continue
+		}
+		line, _ := cr.FieldPos(0)
+		b := builder{}
+		for i, val := range rec {
+			if i < len(header) {
+				b.set(header[i], val)
+			}
+		}
+		rows = append(rows, b.row(line))
+	}
+	return rows, nil
+}
+
+type feature struct {
+	Type     string `json:"type"`
+	ID       any    `json:"id"`
+	Geometry *struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	} `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+func parseGeoJSON(r io.Reader) ([]Row, error) {
+	var fc struct {
+		Type     string            `json:"type"`
+		Features []json.RawMessage `json:"features"`
+	}
+	if err := json.NewDecoder(r).Decode(&fc); err != nil {
+		return nil, fmt.Errorf("geojson: %w", err)
+	}
+	if fc.Type != "FeatureCollection" {
+		return nil, errors.New("geojson: want a FeatureCollection")
+	}
+	if len(fc.Features) > MaxRows {
+		return nil, fmt.Errorf("more than %d features", MaxRows)
+	}
+	rows := make([]Row, 0, len(fc.Features))
+	for i, raw := range fc.Features {
+		line := i + 1
+		var f feature
+		if err := json.Unmarshal(raw, &f); err != nil || f.Type != "Feature" {
+			rows = append(rows, Row{Line: line, Err: errors.New("not a GeoJSON Feature")})
+			continue
+		}
+		b := builder{}
+		for k, v := range f.Properties {
+			b.setAny(k, v)
+		}
+		if f.ID != nil && b.id == "" {
+			b.setAny("id", f.ID)
+		}
+		if f.Geometry == nil || f.Geometry.Type != "Point" {
+			b.fail(errors.New("geometry must be a Point"))
+		} else {
+			var c []float64
+			if err := json.Unmarshal(f.Geometry.Coordinates, &c); err != nil || len(c) < 2 {
+				b.fail(errors.New("point coordinates must be [lng, lat]"))
+			} else {
+				b.in.Lon, b.in.Lat = &c[0], &c[1]
+			}
+		}
+		rows = append(rows, b.row(line))
+	}
+	return rows, nil
+}
+
+// builder turns loosely named columns/properties into a venues.Input.
+// Empty values count as "not provided".
+type builder struct {
+	in      venues.Input
+	id      string
+	address db.Address
+	hasAddr bool
+	err     error
+}
+
+func (b *builder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+func (b *builder) row(line int) Row {
+	if b.hasAddr {
+		b.in.Address = &b.address
+	}
+	return Row{Line: line, ID: b.id, Input: b.in, Err: b.err}
+}
+
+// splitList splits "a;b|c" style list cells.
+func splitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return r == ';' || r == '|' })
+}
+
+// setAny flattens a GeoJSON property onto the CSV column names.
+func (b *builder) setAny(key string, v any) {
+	switch t := v.(type) {
+	case nil:
+	case string:
+		b.set(key, t)
+	case float64:
+		b.set(key, strconv.FormatFloat(t, 'f', -1, 64))
+	case bool:
+		b.set(key, strconv.FormatBool(t))
+	case []any:
+		parts := make([]string, 0, len(t))
+		for _, x := range t {
+			if s, ok := x.(string); ok {
+				parts = append(parts, s)
+			}
+		}
+		b.set(key, strings.Join(parts, ";"))
+	case map[string]any:
+		if canonicalKey(key) == "hours" {
+			raw, _ := json.Marshal(t)
+			b.set(key, string(raw))
+			return
+		}
+		for k, x := range t { // nested address
+			b.setAny(key+"."+k, x)
+		}
+	}
+}
+
+// canonicalKey folds case, separators and a leading "address." so that
+// "Postal Code", "postal_code" and "address.postalCode" are one column.
+func canonicalKey(key string) string {
+	k := strings.ToLower(strings.TrimSpace(key))
+	k = strings.TrimPrefix(k, "address.")
+	return strings.NewReplacer("_", "", "-", "", " ", "").Replace(k)
+}
+
+func (b *builder) set(key, val string) {
+	val = strings.TrimSpace(val)
+	if val == "" {
+		return
+	}
+	num := func(name string) *float64 {
+		f, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			b.fail(fmt.Errorf("%s %q is not a number", name, val))
+			return nil
+		}
+		return &f
+	}
+	switch canonicalKey(key) {
+	case "id":
+		b.id = val
+	case "name", "title":
+		b.in.Name = &val
+	case "subtitle", "neighborhood", "neighbourhood":
+		b.in.Subtitle = &val
+	case "category":
+		b.in.Category = &val
+	case "tags":
+		tags := splitList(val)
+		b.in.Tags = &tags
+	case "price", "pricetier":
+		if strings.HasPrefix(val, "$") {
+			b.in.Price = &val
+		} else if n, err := strconv.Atoi(val); err == nil {
+			b.in.PriceTier = &n
+		} else {
+			b.fail(fmt.Errorf("price %q must be 1-4 or $-$$$$", val))
+		}
+	case "lat", "latitude":
+		b.in.Lat = num("lat")
+	case "lng", "lon", "long", "longitude":
+		b.in.Lon = num("lng")
+	case "address", "line1", "addressline1", "street":
+		b.address.Line1, b.hasAddr = val, true
+	case "city":
+		b.address.City, b.hasAddr = val, true
+	case "region", "state":
+		b.address.Region, b.hasAddr = val, true
+	case "postalcode", "zip", "postcode":
+		b.address.PostalCode, b.hasAddr = val, true
+	case "country":
+		b.address.Country, b.hasAddr = val, true
+	case "hours":
+		var h db.OpeningHours
+		if err := json.Unmarshal([]byte(val), &h); err != nil {
+			b.fail(errors.New(`hours must be JSON like {"timezone":"...","weekly":[{"day":5,"open":"18:00","close":"02:00"}]}`))
+			return
+		}
+		b.in.Hours = &h
+	case "photos":
+		photos := splitList(val)
+		b.in.Photos = &photos
+	case "status":
+		st := db.VenueStatus(strings.ToLower(val))
+		b.in.Status = &st
+	}
+}
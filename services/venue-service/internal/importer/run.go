@@ -0,0 +1,205 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"bytspot/services/venue-service/internal/db"
+	"bytspot/services/venue-service/internal/geo"
+	"bytspot/services/venue-service/internal/venues"
+)
+
+// DefaultMatchRadius is how close (meters) a row must be to an existing
+// venue with the same normalized name to count as the same place.
+const DefaultMatchRadius = 75.0
+
+// Row outcomes.
+const (
+	Created = "created"
+	Updated = "updated"
+	Skipped = "skipped"
+	Failed  = "error"
+)
+
+type Options struct {
+	// DryRun plans the import and reports the diff without writing.
+	DryRun      bool
+	MatchRadius float64
+	// Audit is called after each write with the venue's changed fields.
+	Audit func(ctx context.Context, changes map[string]db.FieldChange, venueID string)
+}
+
+// RowResult is one line of the import report.
+type RowResult struct {
+	Line    int                       `json:"line"`
+	Action  string                    `json:"action"`
+	ID      string                    `json:"id,omitempty"`
+	Name    string                    `json:"name,omitempty"`
+	Reason  string                    `json:"reason,omitempty"`
+	Changes map[string]db.FieldChange `json:"changes,omitempty"`
+}
+
+type Report struct {
+	DryRun  bool        `json:"dryRun"`
+	Created int         `json:"created"`
+	Updated int         `json:"updated"`
+	Skipped int         `json:"skipped"`
+	Errors  int         `json:"errors"`
+	Rows    []RowResult `json:"rows"`
+}
+
+func (r *Report) add(res RowResult) {
+	switch res.Action {
+	case Created:
+		r.Created++
+	case Updated:
+		r.Updated++
+	case Skipped:
+		r.Skipped++
+	case Failed:
+		r.Errors++
+	}
+	r.Rows = append(r.Rows, res)
+}
+
+// NormalizeName folds a venue name for duplicate detection: case,
+// punctuation, "&" vs "and" and a leading "the" are ignored.
+func NormalizeName(name string) string {
+	name = strings.ToLower(strings.ReplaceAll(name, "&", " and "))
+	words := strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	if len(words) > 1 && words[0] == "the" {
+		words = words[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// matcher finds existing (or earlier imported) venues for a row.
+type matcher struct {
+	radius float64
+	index  *geo.Index
+	byID   map[string]*db.Venue
+	seenBy map[string]int // venue id -> line that already touched it
+}
+
+func (m *matcher) put(v *db.Venue) {
+	m.byID[v.ID] = v
+	m.index.Upsert(v.ID, v.Lat, v.Lon)
+}
+
+// find matches by id first; an id the catalog doesn't know (another
+// system's, or a typo) still matches by name and proximity.
+func (m *matcher) find(id, name string, lat, lon float64) *db.Venue {
+	if v := m.byID[id]; id != "" && v != nil {
+		return v
+	}
+	key := NormalizeName(name)
+	for _, hit := range m.index.Within(lat, lon, m.radius) {
+		if v := m.byID[hit.ID]; NormalizeName(v.Name) == key {
+			return v
+		}
+	}
+	return nil
+}
+
+// Run validates rows and creates or updates venues, matching each row to
+// an existing venue by id, else by normalized name within MatchRadius.
+// Fields a row leaves empty keep their current value. Every row appears in
+// the report; only a failure to read the catalog aborts the run.
+func Run(ctx context.Context, repo db.VenueRepo, rows []Row, opts Options) (*Report, error) {
+	if opts.MatchRadius <= 0 {
+		opts.MatchRadius = DefaultMatchRadius
+	}
+	existing, err := repo.ListAllVenues(ctx, db.VenueFilter{})
+	if err != nil {
+		return nil, err
+	}
+	m := &matcher{radius: opts.MatchRadius, index: geo.NewIndex(), byID: map[string]*db.Venue{}, seenBy: map[string]int{}}
+	for i := range existing {
+		m.put(&existing[i])
+	}
+	rep := &Report{DryRun: opts.DryRun, Rows: []RowResult{}}
+	for i, row := range rows {
+		res := RowResult{Line: row.Line, ID: row.ID}
+		if row.Input.Name != nil {
+			res.Name = strings.TrimSpace(*row.Input.Name)
+		}
+		if row.Err != nil {
+			res.Action, res.Reason = Failed, row.Err.Error()
+			rep.add(res)
+			continue
+		}
+		if row.Input.Lat == nil || row.Input.Lon == nil {
+			res.Action, res.Reason = Failed, "lat and lng are required"
+			rep.add(res)
+			continue
+		}
+		before := m.find(row.ID, res.Name, *row.Input.Lat, *row.Input.Lon)
+		if before != nil {
+			if line, ok := m.seenBy[before.ID]; ok {
+				res.Action, res.ID, res.Reason = Skipped, before.ID, fmt.Sprintf("duplicate of line %d", line)
+				rep.add(res)
+				continue
+			}
+		}
+		v := &db.Venue{ID: row.ID, Status: db.VenueActive}
+		if before != nil {
+			cp := *before
+			v = &cp
+		}
+		err := row.Input.Apply(v)
+		if err == nil {
+			err = venues.Validate(v)
+		}
+		if err != nil {
+			res.Action, res.Reason = Failed, err.Error()
+			rep.add(res)
+			continue
+		}
+		res.Name = v.Name
+		res.Changes = venues.Diff(before, v)
+		switch {
+		case before != nil && len(res.Changes) == 0:
+			res.Action, res.ID, res.Reason = Skipped, before.ID, "unchanged"
+		case before != nil:
+			res.Action, res.ID = Updated, before.ID
+			if !opts.DryRun {
+				err = repo.UpdateVenue(ctx, v)
+			}
+		default:
+			res.Action = Created
+			if !opts.DryRun {
+				err = repo.CreateVenue(ctx, v)
+			} else if v.ID == "" {
+				v.ID = fmt.Sprintf("new:%d", i+1) // placeholder so later rows can match it
+			}
+			res.ID = v.ID
+		}
+		if err != nil {
+			res.Action, res.Reason, res.Changes = Failed, writeError(err), nil
+			rep.add(res)
+			continue
+		}
+		m.seenBy[res.ID] = row.Line
+		if res.Action != Skipped {
+			m.put(v)
+			if !opts.DryRun && opts.Audit != nil {
+				opts.Audit(ctx, res.Changes, v.ID)
+			}
+		}
+		if opts.DryRun && strings.HasPrefix(res.ID, "new:") {
+			res.ID = ""
+		}
+		rep.add(res)
+	}
+	return rep, nil
+}
+
+func writeError(err error) string {
+	if errors.Is(err, db.ErrDuplicateID) {
+		return "id already exists"
+	}
+	return "write failed: " + err.Error()
+}
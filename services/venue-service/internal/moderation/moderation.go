@@ -0,0 +1,214 @@
+// Package moderation is the automatic first pass over user-written venue
+// content. It matches text against keyword lists after normalising the usual
+// evasions (case, leetspeak, punctuation, stretched letters) and decides
+// whether content is blocked outright, held for a moderator, or allowed.
+package moderation
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Action is what the filter wants done with a piece of content.
+type Action int
+
+const (
+	Allow Action = iota
+	Hold         // publish only after a moderator approves it
+	Block        // reject without review
+)
+
+func (a Action) String() string {
+	switch a {
+	case Hold:
+		return "hold"
+	case Block:
+		return "block"
+	}
+	return "allow"
+}
+
+// Result is the filter's decision and the terms that triggered it, in the
+// order they were found. Links and contact details are reported as "link",
+// "email" and "phone".
+type Result struct {
+	Action  Action
+	Matches []string
+}
+
+// DefaultBlocked are threats and harassment that are never published.
+var DefaultBlocked = []string{
+	"kill yourself", "kys", "i will kill you", "go die",
+}
+
+// DefaultHeld are profanity and spam phrases a moderator should look at.
+var DefaultHeld = []string{
+	"fuck", "fucking", "shit", "bitch", "asshole", "cunt", "dick",
+	"promo code", "discount code", "dm me", "whatsapp", "telegram", "bitcoin", "crypto", "onlyfans",
+	"click here", "free money",
+}
+
+var (
+	linkRe  = regexp.MustCompile(`(?i)\bhttps?://|\bwww\.|\b[a-z0-9-]+\.(com|net|org|io|co|me|ly|xyz|info)\b`)
+	emailRe = regexp.MustCompile(`(?i)\b[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}\b`)
+	phoneRe = regexp.MustCompile(`\+?\d[\d\s().-]{7,}\d`)
+)
+
+// minPhoneDigits keeps dates ("2024-05-01") and hours ("10 - 12 (2024)")
+// from reading as phone numbers; a national number has at least 10 digits.
+const minPhoneDigits = 10
+
+func hasPhone(text string) bool {
+	for _, m := range phoneRe.FindAllString(text, -1) {
+		n := 0
+		for _, r := range m {
+			if r >= '0' && r <= '9' {
+				n++
+			}
+		}
+		if n >= minPhoneDigits {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter matches content against blocked and held terms.
+type Filter struct {
+	blocked []term
+	held    []term
+}
+
+type term struct{ raw, norm string }
+
+// New builds a filter from term lists; terms are matched as whole words.
+func New(blocked, held []string) *Filter {
+	return &Filter{blocked: terms(blocked), held: terms(held)}
+}
+
+// FromEnv is the default lists plus MODERATION_BLOCKED_TERMS and
+// MODERATION_HELD_TERMS (comma-separated).
+func FromEnv() *Filter {
+	return New(append(DefaultBlocked, split(os.Getenv("MODERATION_BLOCKED_TERMS"))...),
+		append(DefaultHeld, split(os.Getenv("MODERATION_HELD_TERMS"))...))
+}
+
+func split(s string) []string {
+	var out []string
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func terms(raw []string) []term {
+	seen := map[string]bool{}
+	var out []term
+	for _, r := range raw {
+		n := normalize(r)
+		if n == "" || seen[n] {
+			continue
+		}
+		seen[n] = true
+		out = append(out, term{raw: strings.ToLower(strings.TrimSpace(r)), norm: n})
+	}
+	return out
+}
+
+// Check runs every text (e.g. a body and a display name) through the filter.
+func (f *Filter) Check(texts ...string) Result {
+	var res Result
+	seen := map[string]bool{}
+	add := func(a Action, match string) {
+		if a > res.Action {
+			res.Action = a
+		}
+		if !seen[match] {
+			seen[match] = true
+			res.Matches = append(res.Matches, match)
+		}
+	}
+	for _, text := range texts {
+		padded := " " + normalize(text) + " "
+		for _, t := range f.blocked {
+			if strings.Contains(padded, " "+t.norm+" ") {
+				add(Block, t.raw)
+			}
+		}
+		for _, t := range f.held {
+			if strings.Contains(padded, " "+t.norm+" ") {
+				add(Hold, t.raw)
+			}
+		}
+		switch {
+		case emailRe.MatchString(text):
+			add(Hold, "email")
+		case linkRe.MatchString(text):
+			add(Hold, "link")
+		}
+		if hasPhone(text) {
+			add(Hold, "phone")
+		}
+	}
+	return res
+}
+
+var leet = map[rune]rune{'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't', '@': 'a', '$': 's'}
+
+// normalize lowercases s, undoes leetspeak inside words, turns everything
+// that isn't a letter into a single space and squeezes repeated letters.
+// Terms go through it too, so "fuuuck", "F.U.C.K" and "sh1t" all meet
+// their list entry.
+func normalize(s string) string {
+	rs := []rune(strings.ToLower(s))
+	var b strings.Builder
+	var last rune
+	for i, r := range rs {
+		if m, ok := leet[r]; ok && inWord(rs, i) {
+			r = m
+		}
+		if !unicode.IsLetter(r) {
+			r = ' '
+		}
+		if r == last {
+			continue
+		}
+		b.WriteRune(r)
+		last = r
+	}
+	return joinSingles(strings.TrimSpace(b.String()))
+}
+
+// inWord reports whether rs[i] has a letter or digit next to it, so a
+// standalone "1" or "$" is left alone but "sh1t" is rewritten.
+func inWord(rs []rune, i int) bool {
+	letter := func(j int) bool {
+		return j >= 0 && j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]))
+	}
+	return letter(i-1) || letter(i+1)
+}
+
+// joinSingles merges runs of three or more one-letter words ("f u c k")
+// into one word.
+func joinSingles(s string) string {
+	words := strings.Fields(s)
+	var out []string
+	for i := 0; i < len(words); {
+		j := i
+		for j < len(words) && len([]rune(words[j])) == 1 {
+			j++
+		}
+		if j-i >= 3 {
+			out = append(out, strings.Join(words[i:j], ""))
+			i = j
+			continue
+		}
+		out = append(out, words[i])
+		i++
+	}
+	return strings.Join(out, " ")
+}
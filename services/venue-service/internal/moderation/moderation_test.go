@@ -0,0 +1,47 @@
+package moderation
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	f := New(DefaultBlocked, DefaultHeld)
+	cases := []struct {
+		text    string
+		action  Action
+		matches []string
+	}{
+		{"Great cocktails, ask for the off-menu mezcal one", Allow, nil},
+		{"Bartender said 'kill it tonight' and we did", Allow, nil},
+		{"Classic dickens-themed pub quiz on Tuesdays", Allow, nil},
+		{"The DJ was FUUUCKING amazing", Hold, []string{"fucking"}},
+		{"total sh1t service", Hold, []string{"shit"}},
+		{"F.U.C.K this place", Hold, []string{"fuck"}},
+		{"Use promo code NIGHT50 at checkout", Hold, []string{"promo code"}},
+		{"better deals at www.example.com", Hold, []string{"link"}},
+		{"book tables: host@example.com", Hold, []string{"email"}},
+		{"call +1 (415) 555-0100 for bottle service", Hold, []string{"phone"}},
+		{"text 415.555.0100", Hold, []string{"phone"}},
+		{"visited 2024-05-01, open 10 - 12 (2024)", Allow, nil},
+		{"doors at 10, 2 for 1 until 11", Allow, nil},
+		{"bouncer told me to kys", Block, []string{"kys"}},
+		{"Kill yourself, shit bar", Block, []string{"kill yourself", "shit"}},
+	}
+	for _, c := range cases {
+		got := f.Check(c.text)
+		if got.Action != c.action || !reflect.DeepEqual(got.Matches, c.matches) {
+			t.Errorf("%q: got %v %v, want %v %v", c.text, got.Action, got.Matches, c.action, c.matches)
+		}
+	}
+}
+
+func TestCheck_AllTextsAndCustomTerms(t *testing.T) {
+	f := New([]string{"Rival Bar"}, []string{"cheap"})
+	if got := f.Check("lovely", "go to rival   bar instead"); got.Action != Block {
+		t.Fatalf("phrase in second text: %+v", got)
+	}
+	if got := f.Check("ch3aaap drinks"); got.Action != Hold || got.Matches[0] != "cheap" {
+		t.Fatalf("custom held term: %+v", got)
+	}
+}
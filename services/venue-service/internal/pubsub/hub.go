@@ -0,0 +1,124 @@
+// Package pubsub fans venue events out to in-process subscribers (SSE
+// clients). Publishing never blocks: a subscriber whose buffer is full is
+// dropped and can reconnect, resuming from the hub's recent history.
+package pubsub
+
+import "sync"
+
+// Event is one venue update. ID increases monotonically per hub.
+type Event struct {
+	ID      uint64
+	VenueID string
+	Lat     float64
+	Lon     float64
+	Data    []byte // JSON payload sent to clients
+}
+
+// Subscription receives matching events on C. C is closed when the
+// subscriber is dropped for falling behind or after Close.
+type Subscription struct {
+	C       <-chan Event
+	ch      chan Event
+	match   func(Event) bool
+	hub     *Hub
+	dropped bool
+}
+
+// Dropped reports whether C was closed because the subscriber fell behind.
+func (s *Subscription) Dropped() bool {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	return s.dropped
+}
+
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+type Hub struct {
+	mu      sync.Mutex
+	lastID  uint64
+	history []Event // ring of the most recent events
+	start   int     // index of the oldest event in history
+	size    int
+	subs    map[*Subscription]struct{}
+	buffer  int
+}
+
+// NewHub keeps the last historySize events for resume and gives every
+// subscriber a buffer of buffer events.
+func NewHub(historySize, buffer int) *Hub {
+	return &Hub{history: make([]Event, historySize), subs: map[*Subscription]struct{}{}, buffer: buffer}
+}
+
+// LastID is the id of the most recent event (0 before the first).
+func (h *Hub) LastID() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.lastID
+}
+
+// Publish assigns the next id to e and delivers it without blocking.
+func (h *Hub) Publish(e Event) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastID++
+	e.ID = h.lastID
+	if len(h.history) > 0 {
+		if h.size < len(h.history) {
+			h.history[(h.start+h.size)%len(h.history)] = e
+			h.size++
+		} else {
+			h.history[h.start] = e
+			h.start = (h.start + 1) % len(h.history)
+		}
+	}
+	for s := range h.subs {
+		if !s.match(e) {
+			continue
+		}
+		select {
+		case s.ch <- e:
+		default:
+			s.dropped = true
+			h.remove(s)
+		}
+	}
+	return e.ID
+}
+
+func (h *Hub) remove(s *Subscription) {
+	if _, ok := h.subs[s]; ok {
+		delete(h.subs, s)
+		close(s.ch)
+	}
+}
+
+// Subscribe registers a subscriber for events accepted by match. With
+// after > 0 it also returns the matching history newer than after; resumed
+// is false when events after that id have already left the history, so the
+// caller should send a fresh snapshot instead.
+func (h *Hub) Subscribe(after uint64, match func(Event) bool) (sub *Subscription, backlog []Event, resumed bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	resumed = true
+	if after > 0 {
+		oldest := h.lastID - uint64(h.size) + 1
+		if after+1 < oldest || after > h.lastID {
+			resumed = false
+		} else {
+			for i := 0; i < h.size; i++ {
+				e := h.history[(h.start+i)%len(h.history)]
+				if e.ID > after && match(e) {
+					backlog = append(backlog, e)
+				}
+			}
+		}
+	}
+	ch := make(chan Event, h.buffer)
+	sub = &Subscription{C: ch, ch: ch, match: match, hub: h}
+	h.subs[sub] = struct{}{}
+	return sub, backlog, resumed
+}
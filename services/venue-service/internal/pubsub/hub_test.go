@@ -0,0 +1,51 @@
+package pubsub
+
+import "testing"
+
+func all(Event) bool { return true }
+
+func TestPublish_DropsSlowSubscriberWithoutBlocking(t *testing.T) {
+	h := NewHub(8, 2)
+	slow, _, _ := h.Subscribe(0, all)
+	fast, _, _ := h.Subscribe(0, func(e Event) bool { return e.VenueID == "v1" })
+	for i := 0; i < 3; i++ {
+		h.Publish(Event{VenueID: "v2"}) // never blocks even though slow isn't reading
+	}
+	if !slow.Dropped() {
+		t.Fatalf("expected slow subscriber to be dropped")
+	}
+	n := 0
+	for range slow.C {
+		n++
+	}
+	if n != 2 {
+		t.Fatalf("slow subscriber got %d buffered events before close, want 2", n)
+	}
+	h.Publish(Event{VenueID: "v1"})
+	if e := <-fast.C; e.ID != 4 || fast.Dropped() {
+		t.Fatalf("filtered subscriber: got %+v, dropped=%v", e, fast.Dropped())
+	}
+}
+
+func TestSubscribe_ResumesFromHistory(t *testing.T) {
+	h := NewHub(3, 4)
+	for _, id := range []string{"a", "b", "a", "b", "a"} { // ids 1..5; history keeps 3..5
+		h.Publish(Event{VenueID: id})
+	}
+	onlyA := func(e Event) bool { return e.VenueID == "a" }
+
+	sub, backlog, resumed := h.Subscribe(3, onlyA)
+	defer sub.Close()
+	if !resumed || len(backlog) != 1 || backlog[0].ID != 5 {
+		t.Fatalf("resume after 3: resumed=%v backlog=%+v", resumed, backlog)
+	}
+	if _, _, resumed := h.Subscribe(1, onlyA); resumed {
+		t.Fatalf("resume after 1: events 2.. are gone, expected resumed=false")
+	}
+	if _, backlog, resumed := h.Subscribe(5, onlyA); !resumed || len(backlog) != 0 {
+		t.Fatalf("resume at head: resumed=%v backlog=%v", resumed, backlog)
+	}
+	if _, _, resumed := h.Subscribe(99, onlyA); resumed {
+		t.Fatalf("id from a previous process must not resume")
+	}
+}
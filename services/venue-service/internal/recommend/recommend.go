@@ -0,0 +1,359 @@
+// Package recommend ranks venues for one user. Each candidate gets a blend
+// of content similarity to what the user liked and skipped, popularity, the
+// live vibe, distance and how well the venue suits the time of day. The
+// ranked list is then re-ordered for diversity, and every item carries a
+// short reason.
+package recommend
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"bytspot/services/venue-service/internal/db"
+	"bytspot/services/venue-service/internal/hours"
+)
+
+// Candidate is a venue with the request-time signals the ranker needs.
+type Candidate struct {
+	Venue      db.Venue
+	Distance   *float64 // meters; nil without a location
+	Vibe       *float64 // live score 0-10; nil when unknown
+	Status     hours.Status
+	HoursKnown bool
+}
+
+// Item is a recommended venue.
+type Item struct {
+	Candidate
+	Score  float64
+	Reason string
+}
+
+const (
+	// fullProfileLikes is how many likes it takes before personal taste
+	// gets its full weight; below that popularity fills in.
+	fullProfileLikes = 5
+	skipWeight       = 0.5 // a skip counts against a feature half as much as a like counts for it
+	proximityHalf    = 1000.0
+	unknownVibe      = 0.4
+	openingSoon      = 90 * time.Minute
+	// diversityLambda trades score (1) against novelty (0) when re-ranking.
+	diversityLambda = 0.75
+	maxRerank       = 100
+	// minSimilarReason is the similarity to a liked venue needed to name it.
+	minSimilarReason = 0.3
+)
+
+// Profile is the user's taste, built from their likes and skips.
+type Profile struct {
+	categories map[string]float64
+	tags       map[string]float64
+	prices     map[int]float64
+	liked      []db.Venue
+	// VibeTarget is the preferred vibe score (0-10), if known.
+	VibeTarget *float64
+}
+
+// NewProfile weighs every category, tag and price tier by how often it
+// appears among liked venues, minus half as often among skipped ones,
+// relative to the number of interactions.
+func NewProfile(liked, skipped []db.Venue, vibeTarget *float64) *Profile {
+	p := &Profile{categories: map[string]float64{}, tags: map[string]float64{}, prices: map[int]float64{}, liked: liked, VibeTarget: vibeTarget}
+	n := float64(len(liked) + len(skipped))
+	if n == 0 {
+		return p
+	}
+	add := func(v db.Venue, w float64) {
+		p.categories[v.Category] += w / n
+		p.prices[v.PriceTier] += w / n
+		for _, t := range v.Tags {
+			p.tags[t] += w / n
+		}
+	}
+	for _, v := range liked {
+		add(v, 1)
+	}
+	for _, v := range skipped {
+		add(v, -skipWeight)
+	}
+	return p
+}
+
+// strength is how much personal taste counts, 0 (cold start) to 1.
+func (p *Profile) strength() float64 {
+	return math.Min(1, float64(len(p.liked))/fullProfileLikes)
+}
+
+// content scores v against the profile; 1/3 is neutral.
+func (p *Profile) content(v db.Venue) float64 {
+	var tags float64
+	for _, t := range v.Tags {
+		tags += p.tags[t]
+	}
+	if len(v.Tags) > 0 {
+		tags /= float64(len(v.Tags))
+	}
+	raw := 0.5*p.categories[v.Category] + 0.3*tags + 0.2*p.prices[v.PriceTier]
+	return clamp((raw + skipWeight) / (1 + skipWeight))
+}
+
+// mostSimilarLiked returns the liked venue closest to v.
+func (p *Profile) mostSimilarLiked(v db.Venue) (db.Venue, float64) {
+	var best db.Venue
+	bestSim := -1.0
+	for _, l := range p.liked {
+		if s := similarity(v, l); s > bestSim {
+			best, bestSim = l, s
+		}
+	}
+	return best, bestSim
+}
+
+func clamp(x float64) float64 { return math.Max(0, math.Min(1, x)) }
+
+// similarity is how alike two venues are (0-1): half category, half tag
+// overlap. Used for diversity and for naming a liked venue in the reason.
+func similarity(a, b db.Venue) float64 {
+	s := 0.0
+	if a.Category == b.Category {
+		s += 0.5
+	}
+	if len(a.Tags) > 0 && len(b.Tags) > 0 {
+		set := make(map[string]bool, len(a.Tags))
+		for _, t := range a.Tags {
+			set[t] = true
+		}
+		inter := 0
+		for _, t := range b.Tags {
+			if set[t] {
+				inter++
+			}
+		}
+		s += 0.5 * float64(inter) / float64(len(a.Tags)+len(b.Tags)-inter)
+	}
+	return s
+}
+
+// Dayparts by local hour.
+type daypart int
+
+const (
+	morning daypart = iota
+	midday
+	afternoon
+	evening
+	lateNight
+)
+
+func daypartAt(t time.Time) daypart {
+	switch h := t.Hour(); {
+	case h >= 5 && h < 11:
+		return morning
+	case h >= 11 && h < 15:
+		return midday
+	case h >= 15 && h < 18:
+		return afternoon
+	case h >= 18 && h < 22:
+		return evening
+	}
+	return lateNight
+}
+
+var daypartLabels = [...]string{"Good for breakfast", "Good for lunch", "Good for an afternoon stop", "Good for tonight", "Good for late night"}
+
+// daypartFit is how well each category suits each daypart.
+var daypartFit = map[string][5]float64{
+	"cafe":       {1, 0.8, 0.8, 0.3, 0.05},
+	"restaurant": {0.3, 1, 0.4, 1, 0.3},
+	"bar":        {0, 0.2, 0.5, 0.9, 1},
+	"lounge":     {0.05, 0.2, 0.5, 0.9, 0.9},
+	"club":       {0, 0, 0.1, 0.5, 1},
+	"live_music": {0, 0.2, 0.4, 1, 0.9},
+}
+
+// timeFit is how well the venue's category suits the local daypart.
+func timeFit(c Candidate, at time.Time) float64 {
+	if f, ok := daypartFit[c.Venue.Category]; ok {
+		return f[daypartAt(at.In(hours.Location(c.Venue.Hours)))]
+	}
+	return 0.5
+}
+
+// availability scales the whole score: closed venues stay in the list but
+// rarely beat open ones.
+func availability(c Candidate, at time.Time) float64 {
+	switch {
+	case !c.HoursKnown:
+		return 0.85
+	case c.Status.Open:
+		return 1
+	case c.Status.OpensNext != nil && c.Status.OpensNext.Sub(at) <= openingSoon:
+		return 0.8
+	}
+	return 0.5
+}
+
+// signals are the per-candidate inputs, each in [0, 1].
+type signals struct {
+	content, popularity, vibe, proximity, time float64
+}
+
+type weights signals
+
+func (p *Profile) weights(hasLocation bool) weights {
+	s := p.strength()
+	w := weights{content: 0.35 * s, popularity: 0.2 + 0.15*(1-s), vibe: 0.15, proximity: 0.15, time: 0.15}
+	if !hasLocation {
+		w.proximity = 0
+	}
+	return w
+}
+
+func (w weights) total() float64 {
+	return w.content + w.popularity + w.vibe + w.proximity + w.time
+}
+
+func (p *Profile) signals(c Candidate, at time.Time, maxLikes int) signals {
+	var sig signals
+	sig.content = p.content(c.Venue)
+	sig.popularity = 0.5 * c.Venue.Rating / 5
+	if maxLikes > 0 {
+		sig.popularity += 0.5 * math.Log1p(float64(c.Venue.LikeCount)) / math.Log1p(float64(maxLikes))
+	}
+	sig.vibe = unknownVibe
+	if c.Vibe != nil {
+		if p.VibeTarget != nil {
+			sig.vibe = 1 - math.Abs(*c.Vibe-*p.VibeTarget)/10
+		} else {
+			sig.vibe = *c.Vibe / 10
+		}
+	}
+	if c.Distance != nil {
+		sig.proximity = proximityHalf / (proximityHalf + *c.Distance)
+	}
+	sig.time = timeFit(c, at)
+	return sig
+}
+
+// Recommend scores every candidate at time at, re-ranks the best for
+// diversity and returns up to limit items with reasons.
+func Recommend(p *Profile, cands []Candidate, at time.Time, limit int) []Item {
+	if len(cands) == 0 || limit <= 0 {
+		return []Item{}
+	}
+	maxLikes := 0
+	hasLocation := false
+	for _, c := range cands {
+		maxLikes = max(maxLikes, c.Venue.LikeCount)
+		hasLocation = hasLocation || c.Distance != nil
+	}
+	w := p.weights(hasLocation)
+	type scored struct {
+		Item
+		sig signals
+	}
+	all := make([]scored, len(cands))
+	var mean signals
+	for i, c := range cands {
+		sig := p.signals(c, at, maxLikes)
+		mean.content += sig.content / float64(len(cands))
+		mean.popularity += sig.popularity / float64(len(cands))
+		mean.vibe += sig.vibe / float64(len(cands))
+		mean.proximity += sig.proximity / float64(len(cands))
+		mean.time += sig.time / float64(len(cands))
+		score := (w.content*sig.content + w.popularity*sig.popularity + w.vibe*sig.vibe +
+			w.proximity*sig.proximity + w.time*sig.time) / w.total() * availability(c, at)
+		all[i] = scored{Item{Candidate: c, Score: score}, sig}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Score != all[j].Score {
+			return all[i].Score > all[j].Score
+		}
+		return all[i].Venue.ID < all[j].Venue.ID
+	})
+	pool := all[:min(len(all), maxRerank)]
+
+	// Maximal marginal relevance: repeatedly take the candidate with the best
+	// score after a penalty for resembling what is already picked.
+	out := make([]Item, 0, min(limit, len(pool)))
+	used := make([]bool, len(pool))
+	for len(out) < limit && len(out) < len(pool) {
+		best, bestVal := -1, math.Inf(-1)
+		for i, c := range pool {
+			if used[i] {
+				continue
+			}
+			maxSim := 0.0
+			for _, o := range out {
+				maxSim = math.Max(maxSim, similarity(c.Venue, o.Venue))
+			}
+			if v := diversityLambda*c.Score - (1-diversityLambda)*maxSim; v > bestVal {
+				best, bestVal = i, v
+			}
+		}
+		used[best] = true
+		it := pool[best].Item
+		it.Reason = p.reason(pool[best].Candidate, pool[best].sig, mean, w, at)
+		out = append(out, it)
+	}
+	return out
+}
+
+// reason explains an item by the signal that lifts it most above the
+// average candidate, falling back to the next one when that signal has no
+// presentable explanation.
+func (p *Profile) reason(c Candidate, sig, mean signals, w weights, at time.Time) string {
+	type part struct {
+		name string
+		v    float64
+	}
+	parts := []part{
+		{"content", w.content * (sig.content - mean.content)},
+		{"popularity", w.popularity * (sig.popularity - mean.popularity)},
+		{"vibe", w.vibe * (sig.vibe - mean.vibe)},
+		{"proximity", w.proximity * (sig.proximity - mean.proximity)},
+		{"time", w.time * (sig.time - mean.time)},
+	}
+	sort.SliceStable(parts, func(i, j int) bool { return parts[i].v > parts[j].v })
+	for _, top := range parts {
+		switch top.name {
+		case "content":
+			if l, sim := p.mostSimilarLiked(c.Venue); sim >= minSimilarReason {
+				return fmt.Sprintf("Similar to %s, which you liked", l.Name)
+			}
+		case "vibe":
+			if c.Vibe != nil && p.VibeTarget != nil {
+				return "The vibe right now matches what you like"
+			}
+			if c.Vibe != nil && *c.Vibe >= 7 {
+				return "Buzzing right now"
+			}
+		case "proximity":
+			if c.Distance != nil {
+				if c.HoursKnown && c.Status.Open {
+					return fmt.Sprintf("Open now, %s away", formatDistance(*c.Distance))
+				}
+				return formatDistance(*c.Distance) + " away"
+			}
+		case "time":
+			if !c.HoursKnown || c.Status.Open {
+				return daypartLabels[daypartAt(at.In(hours.Location(c.Venue.Hours)))]
+			}
+		case "popularity":
+			if c.Venue.Rating >= 4.5 {
+				return fmt.Sprintf("Highly rated (%.1f)", c.Venue.Rating)
+			}
+			return "Popular on Bytspot"
+		}
+	}
+	return "Popular on Bytspot"
+}
+
+func formatDistance(m float64) string {
+	if m < 1000 {
+		return fmt.Sprintf("%d m", int(math.Round(m/10)*10))
+	}
+	return fmt.Sprintf("%.1f km", m/1000)
+}
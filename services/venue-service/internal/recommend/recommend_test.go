@@ -0,0 +1,105 @@
+package recommend
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"bytspot/services/venue-service/internal/db"
+	"bytspot/services/venue-service/internal/hours"
+)
+
+var tz, _ = time.LoadLocation("America/Los_Angeles")
+
+func venue(id, category string, tags ...string) db.Venue {
+	return db.Venue{ID: id, Name: strings.ToUpper(id), Category: category, Tags: tags, PriceTier: 2, Rating: 4.2, LikeCount: 10,
+		Hours: db.OpeningHours{Timezone: "America/Los_Angeles"}}
+}
+
+func cand(v db.Venue, open bool) Candidate {
+	return Candidate{Venue: v, Status: hours.Status{Open: open}, HoursKnown: true}
+}
+
+func ids(items []Item) string {
+	var out []string
+	for _, it := range items {
+		out = append(out, it.Venue.ID)
+	}
+	return strings.Join(out, ",")
+}
+
+func TestRecommend_PersonalTasteAndReason(t *testing.T) {
+	liked := []db.Venue{venue("jazz1", "live_music", "jazz", "cocktails"), venue("jazz2", "live_music", "jazz")}
+	skipped := []db.Venue{venue("club1", "club", "techno")}
+	p := NewProfile(liked, skipped, nil)
+	cands := []Candidate{
+		cand(venue("club2", "club", "techno"), true),
+		cand(venue("jazz3", "live_music", "jazz", "wine"), true),
+		cand(venue("lounge", "lounge", "patio"), true),
+	}
+	at := time.Date(2026, 1, 9, 20, 0, 0, 0, tz)
+	items := Recommend(p, cands, at, 3)
+	if ids(items) != "jazz3,lounge,club2" {
+		t.Fatalf("unexpected order %s", ids(items))
+	}
+	if items[0].Reason != "Similar to JAZZ2, which you liked" {
+		t.Fatalf("unexpected reason %q", items[0].Reason)
+	}
+	for _, it := range items {
+		if it.Score <= 0 || it.Score > 1 || it.Reason == "" {
+			t.Fatalf("bad item %+v", it)
+		}
+	}
+}
+
+func TestRecommend_TimeOfDayAndOpenStatus(t *testing.T) {
+	p := NewProfile(nil, nil, nil)
+	cafe, club := venue("cafe", "cafe", "coffee"), venue("club", "club", "techno")
+	morning := time.Date(2026, 1, 9, 9, 0, 0, 0, tz)
+	if got := ids(Recommend(p, []Candidate{cand(club, true), cand(cafe, true)}, morning, 2)); got != "cafe,club" {
+		t.Fatalf("morning: %s", got)
+	}
+	late := time.Date(2026, 1, 9, 23, 30, 0, 0, tz)
+	if got := ids(Recommend(p, []Candidate{cand(club, true), cand(cafe, true)}, late, 2)); got != "club,cafe" {
+		t.Fatalf("late night: %s", got)
+	}
+	// a closed club loses to an open cafe even late at night
+	if got := ids(Recommend(p, []Candidate{cand(club, false), cand(cafe, true)}, late, 2)); got != "cafe,club" {
+		t.Fatalf("closed venue ranked first: %s", got)
+	}
+}
+
+func TestRecommend_DiversityReranking(t *testing.T) {
+	p := NewProfile(nil, nil, nil)
+	at := time.Date(2026, 1, 9, 21, 0, 0, 0, tz)
+	var cands []Candidate
+	for _, id := range []string{"bar1", "bar2", "bar3"} {
+		v := venue(id, "bar", "cocktails", "dj")
+		v.LikeCount, v.Rating = 50, 4.6
+		cands = append(cands, cand(v, true))
+	}
+	cands = append(cands, cand(venue("music", "live_music", "jazz"), true))
+	items := Recommend(p, cands, at, 2)
+	if ids(items) != "bar1,music" {
+		t.Fatalf("expected a different venue in second place, got %s", ids(items))
+	}
+}
+
+func TestRecommend_VibeTargetAndDistance(t *testing.T) {
+	target := 3.0
+	p := NewProfile(nil, nil, &target)
+	at := time.Date(2026, 1, 9, 21, 0, 0, 0, tz)
+	calm, loud := 3.0, 9.5
+	a, b := cand(venue("a", "bar"), true), cand(venue("b", "bar"), true)
+	a.Vibe, b.Vibe = &loud, &calm
+	if got := ids(Recommend(p, []Candidate{a, b}, at, 1)); got != "b" {
+		t.Fatalf("expected the calmer venue, got %s", got)
+	}
+	near, far := 150.0, 8000.0
+	a.Vibe, b.Vibe = nil, nil
+	a.Distance, b.Distance = &far, &near
+	items := Recommend(NewProfile(nil, nil, nil), []Candidate{a, b}, at, 2)
+	if ids(items) != "b,a" || items[0].Reason != "Open now, 150 m away" {
+		t.Fatalf("unexpected %s %q", ids(items), items[0].Reason)
+	}
+}
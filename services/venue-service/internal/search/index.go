@@ -0,0 +1,300 @@
+// Package search is an in-process full-text index over venue names, tags,
+// neighborhoods and categories. Terms are matched exactly, by prefix, or
+// fuzzily through shared trigrams, so "cocktial" still finds cocktail bars.
+// Documents are upserted and removed one at a time; nothing is rebuilt.
+package search
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Doc is the searchable part of a venue.
+type Doc struct {
+	ID           string
+	Name         string
+	Tags         []string
+	Neighborhood string
+	Category     string
+}
+
+func (d Doc) equal(o Doc) bool {
+	return d.ID == o.ID && d.Name == o.Name && d.Neighborhood == o.Neighborhood &&
+		d.Category == o.Category && slices.Equal(d.Tags, o.Tags)
+}
+
+// Field weights: a hit in the name counts more than one in a tag.
+const (
+	weightName         = 1.0
+	weightTag          = 0.8
+	weightNeighborhood = 0.7
+	weightCategory     = 0.6
+)
+
+// Match thresholds.
+const (
+	minFuzzyLen   = 3    // shorter query terms only match exactly or by prefix
+	minSimilarity = 0.35 // trigram Jaccard similarity for a fuzzy match
+	maxQueryTerms = 8
+)
+
+// Hit is a matching document; Score is text relevance in [0, 1].
+type Hit struct {
+	ID    string
+	Score float64
+}
+
+type term struct {
+	grams int                // distinct trigrams, for similarity
+	docs  map[string]float64 // doc id -> best field weight
+}
+
+// Index is safe for concurrent use.
+type Index struct {
+	mu    sync.RWMutex
+	docs  map[string]Doc
+	terms map[string]*term
+	grams map[string]map[string]bool // trigram -> terms containing it
+	vocab []string                   // sorted terms, for prefix scans
+	names map[string]string          // doc id -> normalized name
+
+	completions []completion // sorted by key, for Suggest
+}
+
+func NewIndex() *Index {
+	return &Index{
+		docs:  map[string]Doc{},
+		terms: map[string]*term{},
+		grams: map[string]map[string]bool{},
+		names: map[string]string{},
+	}
+}
+
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.docs)
+}
+
+var folder = strings.NewReplacer(
+	"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a",
+	"ç", "c", "è", "e", "é", "e", "ê", "e", "ë", "e",
+	"ì", "i", "í", "i", "î", "i", "ï", "i", "ñ", "n",
+	"ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o", "ø", "o",
+	"ù", "u", "ú", "u", "û", "u", "ü", "u", "ý", "y", "ÿ", "y",
+	"&", " and ", "'", "", "’", "",
+)
+
+// Tokens lowercases s, folds common accents and splits it into words, so
+// "Café Rosé" and "cafe rose" index the same.
+func Tokens(s string) []string {
+	s = folder.Replace(strings.ToLower(s))
+	return strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+}
+
+// Normalize is s as a single space-separated run of tokens.
+func Normalize(s string) string { return strings.Join(Tokens(s), " ") }
+
+// trigrams returns the distinct trigrams of a word padded like pg_trgm
+// ("  w", " wo", ..., "rd "), so word starts weigh more than the middle.
+func trigrams(word string) []string {
+	r := []rune("  " + word + " ")
+	seen := make(map[string]bool, len(r))
+	out := make([]string, 0, len(r))
+	for i := 0; i+3 <= len(r); i++ {
+		g := string(r[i : i+3])
+		if !seen[g] {
+			seen[g] = true
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+// fields lists the weighted terms of a document.
+func (d Doc) fields() map[string]float64 {
+	out := map[string]float64{}
+	add := func(s string, w float64) {
+		for _, t := range Tokens(s) {
+			if w > out[t] {
+				out[t] = w
+			}
+		}
+	}
+	add(d.Name, weightName)
+	for _, tag := range d.Tags {
+		add(tag, weightTag)
+	}
+	add(d.Neighborhood, weightNeighborhood)
+	add(d.Category, weightCategory)
+	return out
+}
+
+// Upsert indexes d, replacing any previous version. Unchanged documents are
+// left alone, so re-upserting a whole catalog only touches what changed.
+func (ix *Index) Upsert(d Doc) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if old, ok := ix.docs[d.ID]; ok {
+		if old.equal(d) {
+			return
+		}
+		ix.remove(old)
+	}
+	d.Tags = slices.Clone(d.Tags)
+	ix.docs[d.ID] = d
+	ix.names[d.ID] = Normalize(d.Name)
+	for t, w := range d.fields() {
+		tm, ok := ix.terms[t]
+		if !ok {
+			tm = &term{docs: map[string]float64{}}
+			for _, g := range trigrams(t) {
+				if ix.grams[g] == nil {
+					ix.grams[g] = map[string]bool{}
+				}
+				ix.grams[g][t] = true
+				tm.grams++
+			}
+			ix.terms[t] = tm
+			i, _ := slices.BinarySearch(ix.vocab, t)
+			ix.vocab = slices.Insert(ix.vocab, i, t)
+		}
+		tm.docs[d.ID] = w
+	}
+	ix.addCompletions(d)
+}
+
+func (ix *Index) Remove(id string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if d, ok := ix.docs[id]; ok {
+		ix.remove(d)
+	}
+}
+
+func (ix *Index) remove(d Doc) {
+	for t := range d.fields() {
+		tm := ix.terms[t]
+		if tm == nil {
+			continue
+		}
+		delete(tm.docs, d.ID)
+		if len(tm.docs) > 0 {
+			continue
+		}
+		delete(ix.terms, t)
+		for _, g := range trigrams(t) {
+			delete(ix.grams[g], t)
+			if len(ix.grams[g]) == 0 {
+				delete(ix.grams, g)
+			}
+		}
+		if i, ok := slices.BinarySearch(ix.vocab, t); ok {
+			ix.vocab = slices.Delete(ix.vocab, i, i+1)
+		}
+	}
+	ix.removeCompletions(d)
+	delete(ix.docs, d.ID)
+	delete(ix.names, d.ID)
+}
+
+// expand maps a query word to the indexed terms it matches and how well:
+// 1 for the exact term, less for a term it is a prefix of, and the trigram
+// similarity (scaled below a prefix hit) for near-misses.
+func (ix *Index) expand(q string) map[string]float64 {
+	out := map[string]float64{}
+	if _, ok := ix.terms[q]; ok {
+		out[q] = 1
+	}
+	n := len([]rune(q))
+	i, _ := slices.BinarySearch(ix.vocab, q)
+	for ; i < len(ix.vocab) && strings.HasPrefix(ix.vocab[i], q); i++ {
+		if t := ix.vocab[i]; t != q {
+			out[t] = 0.6 + 0.3*float64(n)/float64(len([]rune(t)))
+		}
+	}
+	if n < minFuzzyLen {
+		return out
+	}
+	qg := trigrams(q)
+	shared := map[string]int{}
+	for _, g := range qg {
+		for t := range ix.grams[g] {
+			shared[t]++
+		}
+	}
+	for t, c := range shared {
+		sim := float64(c) / float64(len(qg)+ix.terms[t].grams-c)
+		if sim >= minSimilarity && 0.85*sim > out[t] {
+			out[t] = 0.85 * sim
+		}
+	}
+	return out
+}
+
+// Search returns documents matching every word of q, best first (ties by
+// id). Each word scores its best field match; the score is their mean, with
+// a bonus when the name is or starts with the whole query.
+func (ix *Index) Search(q string, limit int) []Hit {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.search(q, limit)
+}
+
+func (ix *Index) search(q string, limit int) []Hit {
+	words := Tokens(q)
+	if len(words) == 0 {
+		return nil
+	}
+	words = words[:min(len(words), maxQueryTerms)]
+	var total map[string]float64
+	for _, w := range words {
+		best := map[string]float64{}
+		for t, sim := range ix.expand(w) {
+			for id, weight := range ix.terms[t].docs {
+				if total != nil {
+					if _, ok := total[id]; !ok {
+						continue // already missed an earlier word
+					}
+				}
+				if s := sim * weight; s > best[id] {
+					best[id] = s
+				}
+			}
+		}
+		if total == nil {
+			total = best
+			continue
+		}
+		next := make(map[string]float64, len(best))
+		for id, s := range best {
+			next[id] = total[id] + s
+		}
+		total = next
+	}
+	phrase := strings.Join(words, " ")
+	hits := make([]Hit, 0, len(total))
+	for id, sum := range total {
+		score := sum / float64(len(words))
+		switch name := ix.names[id]; {
+		case name == phrase:
+			score += 0.2
+		case strings.HasPrefix(name, phrase):
+			score += 0.1
+		}
+		hits = append(hits, Hit{ID: id, Score: score / 1.2})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
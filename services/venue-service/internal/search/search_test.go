@@ -0,0 +1,114 @@
+package search
+
+import (
+	"fmt"
+	"testing"
+)
+
+func testIndex() *Index {
+	ix := NewIndex()
+	for _, d := range []Doc{
+		{ID: "v1", Name: "The Blue Note", Tags: []string{"jazz", "live_music"}, Neighborhood: "Mission", Category: "live_music"},
+		{ID: "v2", Name: "Cocktail Club", Tags: []string{"cocktails", "rooftop"}, Neighborhood: "SoMa", Category: "bar"},
+		{ID: "v3", Name: "Café Rosé", Tags: []string{"coffee", "wine"}, Neighborhood: "Mission Bay", Category: "cafe"},
+		{ID: "v4", Name: "Jazz Kitchen", Tags: []string{"jazz", "dinner"}, Neighborhood: "Hayes Valley", Category: "restaurant"},
+	} {
+		ix.Upsert(d)
+	}
+	return ix
+}
+
+func ids(hits []Hit) []string {
+	out := make([]string, len(hits))
+	for i, h := range hits {
+		out[i] = h.ID
+	}
+	return out
+}
+
+func TestSearch_ExactPrefixAndFuzzy(t *testing.T) {
+	ix := testIndex()
+	cases := []struct {
+		q    string
+		want []string
+	}{
+		{"blue note", []string{"v1"}},
+		{"jazz", []string{"v4", "v1"}},    // name hit beats tag hit
+		{"cocktial", []string{"v2"}},      // transposed letters
+		{"cafe rose", []string{"v3"}},     // accents folded
+		{"mission", []string{"v1", "v3"}}, // neighborhood
+		{"coc", []string{"v2"}},           // prefix
+		{"jazz mission", []string{"v1"}},  // every word must match
+		{"sushi", []string{}},
+	}
+	for _, c := range cases {
+		got := ids(ix.Search(c.q, 10))
+		if fmt.Sprint(got) != fmt.Sprint(c.want) {
+			t.Errorf("Search(%q) = %v, want %v", c.q, got, c.want)
+		}
+	}
+	if hits := ix.Search("blue note", 1); hits[0].Score <= 0 || hits[0].Score > 1 {
+		t.Fatalf("score out of range: %v", hits[0].Score)
+	}
+}
+
+func TestUpsertRemove_Incremental(t *testing.T) {
+	ix := testIndex()
+	ix.Upsert(Doc{ID: "v2", Name: "Sunset Lounge", Tags: []string{"rooftop"}, Neighborhood: "SoMa", Category: "lounge"})
+	if got := ids(ix.Search("cocktail", 10)); len(got) != 0 {
+		t.Fatalf("old name still indexed: %v", got)
+	}
+	if got := ids(ix.Search("sunset", 10)); fmt.Sprint(got) != "[v2]" {
+		t.Fatalf("renamed venue not found: %v", got)
+	}
+	ix.Remove("v2")
+	if got := ids(ix.Search("rooftop", 10)); len(got) != 0 {
+		t.Fatalf("removed venue still found: %v", got)
+	}
+	if got := ix.Suggest("soma", 5); len(got) != 0 {
+		t.Fatalf("removed neighborhood still suggested: %v", got)
+	}
+	if _, ok := ix.terms["cocktails"]; ok || ix.Len() != 3 {
+		t.Fatalf("terms or docs leaked after removal (len %d)", ix.Len())
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	ix := testIndex()
+	got := ix.Suggest("jaz", 5)
+	want := []Suggestion{{Kind: KindVenue, Text: "Jazz Kitchen", ID: "v4"}, {Kind: KindTag, Text: "jazz", Count: 2}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("Suggest(jaz) = %v, want %v", got, want)
+	}
+	// word starts match too, after whole-phrase matches
+	got = ix.Suggest("note", 5)
+	if len(got) != 1 || got[0].ID != "v1" {
+		t.Fatalf("Suggest(note) = %v", got)
+	}
+	got = ix.Suggest("mission", 5)
+	if len(got) != 2 || got[0].Text != "Mission" || got[1].Text != "Mission Bay" {
+		t.Fatalf("Suggest(mission) = %v", got)
+	}
+	// no prefix match falls back to fuzzy venue search
+	got = ix.Suggest("cocktial", 5)
+	if len(got) != 1 || got[0].ID != "v2" {
+		t.Fatalf("Suggest(cocktial) = %v", got)
+	}
+}
+
+func BenchmarkSuggest(b *testing.B) {
+	ix := NewIndex()
+	words := []string{"blue", "note", "club", "jazz", "rooftop", "garden", "tavern", "social", "house", "kitchen"}
+	for i := 0; i < 5000; i++ {
+		ix.Upsert(Doc{
+			ID:           fmt.Sprintf("v%d", i),
+			Name:         fmt.Sprintf("%s %s %d", words[i%10], words[(i/10)%10], i),
+			Tags:         []string{words[(i/7)%10]},
+			Neighborhood: words[(i/3)%10] + " district",
+		})
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ix.Suggest("ro", 8)
+	}
+}
@@ -0,0 +1,181 @@
+package search
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Suggestion kinds, in the order they are offered.
+const (
+	KindVenue        = "venue"
+	KindNeighborhood = "neighborhood"
+	KindTag          = "tag"
+)
+
+var kindRank = map[string]int{KindVenue: 0, KindNeighborhood: 1, KindTag: 2}
+
+// maxSuggestScan bounds how many completion keys one keystroke looks at, so
+// a one-letter prefix costs the same as a long one.
+const maxSuggestScan = 512
+
+// Suggestion is one autocomplete entry. ID is set for venues; Count is how
+// many venues share a tag or neighborhood.
+type Suggestion struct {
+	Kind  string `json:"kind"`
+	Text  string `json:"text"`
+	ID    string `json:"id,omitempty"`
+	Count int    `json:"count,omitempty"`
+}
+
+// completion is one word-start suffix of a name, tag or neighborhood:
+// "blue note" is reachable as "blue note" and as "note".
+type completion struct {
+	key   string // normalized suffix
+	start bool   // key is the whole phrase, not a later word
+	kind  string
+	value string // venue id, or the normalized tag/neighborhood
+	text  string // display text
+	refs  int    // venues contributing a shared tag/neighborhood
+}
+
+func (c completion) less(o completion) bool {
+	if c.key != o.key {
+		return c.key < o.key
+	}
+	if c.kind != o.kind {
+		return c.kind < o.kind
+	}
+	return c.value < o.value
+}
+
+func (d Doc) completions() []completion {
+	var out []completion
+	add := func(kind, value, text string) {
+		words := Tokens(text)
+		if value == "" {
+			value = strings.Join(words, " ")
+		}
+		for i := range words {
+			out = append(out, completion{key: strings.Join(words[i:], " "), start: i == 0, kind: kind, value: value, text: text})
+		}
+	}
+	add(KindVenue, d.ID, d.Name)
+	seen := map[string]bool{}
+	for _, tag := range d.Tags {
+		if n := Normalize(tag); n != "" && !seen[n] {
+			seen[n] = true
+			add(KindTag, "", tag)
+		}
+	}
+	if Normalize(d.Neighborhood) != "" {
+		add(KindNeighborhood, "", d.Neighborhood)
+	}
+	return out
+}
+
+func (ix *Index) find(c completion) (int, bool) {
+	i := sort.Search(len(ix.completions), func(i int) bool { return !ix.completions[i].less(c) })
+	return i, i < len(ix.completions) && !c.less(ix.completions[i])
+}
+
+func (ix *Index) addCompletions(d Doc) {
+	for _, c := range d.completions() {
+		i, ok := ix.find(c)
+		if ok {
+			ix.completions[i].refs++
+			continue
+		}
+		c.refs = 1
+		ix.completions = append(ix.completions, completion{})
+		copy(ix.completions[i+1:], ix.completions[i:])
+		ix.completions[i] = c
+	}
+}
+
+func (ix *Index) removeCompletions(d Doc) {
+	for _, c := range d.completions() {
+		i, ok := ix.find(c)
+		if !ok {
+			continue
+		}
+		if ix.completions[i].refs--; ix.completions[i].refs == 0 {
+			ix.completions = append(ix.completions[:i], ix.completions[i+1:]...)
+		}
+	}
+}
+
+// Suggest completes prefix against venue names, neighborhoods and tags,
+// matching at the start of any word. Matches at the start of the phrase
+// come first, then venues before neighborhoods before tags, then the most
+// shared. When nothing matches it falls back to fuzzy venue search, so a
+// typo still offers something.
+func (ix *Index) Suggest(prefix string, limit int) []Suggestion {
+	key := Normalize(prefix)
+	if key == "" || limit <= 0 {
+		return nil
+	}
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	// top keeps the best limit completion positions seen so far, one per
+	// suggestion; a name matching at several words keeps its best entry.
+	top := make([]int, 0, limit+1)
+	i := sort.Search(len(ix.completions), func(i int) bool { return ix.completions[i].key >= key })
+	for n := 0; i < len(ix.completions) && n < maxSuggestScan && strings.HasPrefix(ix.completions[i].key, key); i, n = i+1, n+1 {
+		c := &ix.completions[i]
+		if j := slices.IndexFunc(top, func(k int) bool { o := &ix.completions[k]; return o.kind == c.kind && o.value == c.value }); j >= 0 {
+			if !ix.better(i, top[j]) {
+				continue
+			}
+			top = slices.Delete(top, j, j+1)
+		}
+		pos := sort.Search(len(top), func(k int) bool { return ix.better(i, top[k]) })
+		if pos < limit {
+			top = slices.Insert(top, pos, i)
+			top = top[:min(len(top), limit)]
+		}
+	}
+	if len(top) == 0 {
+		return ix.fuzzySuggest(prefix, limit)
+	}
+	res := make([]Suggestion, 0, len(top))
+	for _, i := range top {
+		m := ix.completions[i]
+		s := Suggestion{Kind: m.kind, Text: m.text}
+		if m.kind == KindVenue {
+			s.ID = m.value
+		} else {
+			s.Count = m.refs
+		}
+		res = append(res, s)
+	}
+	return res
+}
+
+// better orders completions: phrase starts first, then by kind, then the
+// most shared, then alphabetically.
+func (ix *Index) better(i, j int) bool {
+	a, b := &ix.completions[i], &ix.completions[j]
+	if a.start != b.start {
+		return a.start
+	}
+	if a.kind != b.kind {
+		return kindRank[a.kind] < kindRank[b.kind]
+	}
+	if a.refs != b.refs {
+		return a.refs > b.refs
+	}
+	if a.text != b.text {
+		return a.text < b.text
+	}
+	return a.value < b.value
+}
+
+// fuzzySuggest offers the best fuzzy venue matches; the caller holds mu.
+func (ix *Index) fuzzySuggest(q string, limit int) []Suggestion {
+	var out []Suggestion
+	for _, h := range ix.search(q, limit) {
+		out = append(out, Suggestion{Kind: KindVenue, Text: ix.docs[h.ID].Name, ID: h.ID})
+	}
+	return out
+}
@@ -0,0 +1,343 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"bytspot/services/venue-service/internal/achieve"
+	"bytspot/services/venue-service/internal/db"
+	"bytspot/shared/middleware"
+)
+
+const (
+	maxAchievementBatch   = 100
+	maxAchievementIDLen   = 128
+	maxAchievementAttrs   = 20
+	maxAchievementAttrLen = 200
+)
+
+// externalAchievementEvents are the event types other services report
+// through POST /achievements/events; the rest are recorded here as they
+// happen.
+var externalAchievementEvents = map[string]bool{achieve.EventValetUse: true, achieve.EventPlanCreated: true}
+
+// achievementCatalogFromEnv loads ACHIEVEMENTS_FILE (a JSON array of
+// definitions, see internal/achieve/definitions.json) or the built-in
+// catalog.
+func achievementCatalogFromEnv() *achieve.Catalog {
+	if path := os.Getenv("ACHIEVEMENTS_FILE"); path != "" {
+		f, err := os.Open(path)
+		if err == nil {
+			defs, lerr := achieve.Load(f)
+			f.Close()
+			if lerr == nil {
+				return achieve.NewCatalog(defs)
+			}
+			err = lerr
+		}
+		log.Printf("invalid ACHIEVEMENTS_FILE %q: %v; using the built-in achievements", path, err)
+	}
+	return achieve.NewCatalog(achieve.Defaults())
+}
+
+// achievementZoneFromEnv is the timezone for events without one of their
+// own (ACHIEVEMENTS_TZ, default UTC); it also decides when a streak day
+// ends.
+func achievementZoneFromEnv() *time.Location {
+	if v := os.Getenv("ACHIEVEMENTS_TZ"); v != "" {
+		loc, err := time.LoadLocation(v)
+		if err == nil {
+			return loc
+		}
+		log.Printf("invalid ACHIEVEMENTS_TZ %q; using UTC", v)
+	}
+	return time.UTC
+}
+
+// trackAchievement records an event from a write that has already
+// happened, so a failure is logged rather than returned.
+func (s *serverImpl) trackAchievement(ctx context.Context, e db.AchievementEvent) {
+	if s.achievements == nil {
+		return
+	}
+	if _, _, err := s.recordAchievement(ctx, e); err != nil {
+		log.Printf("achievement event write failed for %s: %v", e.UserID, err)
+	}
+}
+
+// recordAchievement stores a domain event and re-evaluates the achievements
+// it can advance; created is false for a re-delivered event (same user and
+// event id), which changes nothing. Only storing the event can fail: once it
+// is stored, evaluation errors are logged and the next event of the user
+// catches up. earned lists the achievements the event granted.
+func (s *serverImpl) recordAchievement(ctx context.Context, e db.AchievementEvent) (created bool, earned []string, err error) {
+	created, err = s.achievements.InsertAchievementEvent(ctx, &e)
+	if err != nil || !created {
+		return created, nil, err
+	}
+	defs := s.achieveCatalog.For(e.Type)
+	if len(defs) == 0 {
+		return true, nil, nil
+	}
+	types := map[string]bool{}
+	for _, d := range defs {
+		for _, t := range d.Rule.Events {
+			types[t] = true
+		}
+	}
+	list := make([]string, 0, len(types))
+	for t := range types {
+		list = append(list, t)
+	}
+	stored, err := s.achievements.ListAchievementEvents(ctx, e.UserID, list)
+	if err != nil {
+		log.Printf("achievement history read failed for %s: %v", e.UserID, err)
+		return true, nil, nil
+	}
+	zones := map[string]*time.Location{}
+	history := make([]achieve.Event, len(stored))
+	for i, ev := range stored {
+		history[i] = achieve.Event{Type: ev.Type, At: ev.OccurredAt.In(s.achievementZone(zones, ev.Zone)), Attrs: ev.Attrs}
+	}
+
+	now := s.now()
+	progress := make([]db.AchievementProgress, len(defs))
+	for i, d := range defs {
+		p := achieve.Evaluate(d, history, now.In(s.achieveZone))
+		progress[i] = db.AchievementProgress{AchievementID: d.ID, Progress: p.Progress, Target: p.Target}
+		if p.Met {
+			progress[i].EarnedAt = &now
+		}
+	}
+	earned, err = s.achievements.SaveAchievementProgress(ctx, e.UserID, progress)
+	if err != nil {
+		log.Printf("achievement progress write failed for %s: %v", e.UserID, err)
+		return true, nil, nil
+	}
+	for _, id := range earned {
+		if d, ok := s.achieveCatalog.Get(id); ok {
+			s.notifier.AchievementEarned(e.UserID, d)
+		}
+	}
+	return true, earned, nil
+}
+
+// achievementZone resolves an event's stored timezone through cache,
+// falling back to the service default.
+func (s *serverImpl) achievementZone(cache map[string]*time.Location, zone string) *time.Location {
+	if zone == "" {
+		return s.achieveZone
+	}
+	loc, ok := cache[zone]
+	if !ok {
+		var err error
+		if loc, err = time.LoadLocation(zone); err != nil {
+			loc = s.achieveZone
+		}
+		cache[zone] = loc
+	}
+	return loc
+}
+
+// venueAchievementEvent is a venue-scoped event judged in the venue's
+// timezone.
+func venueAchievementEvent(userID, eventID, typ string, v *db.Venue, at time.Time) db.AchievementEvent {
+	return db.AchievementEvent{
+		UserID:     userID,
+		EventID:    eventID,
+		Type:       typ,
+		Attrs:      map[string]string{"venueId": v.ID, "category": v.Category},
+		Zone:       v.Hours.Timezone,
+		OccurredAt: at,
+	}
+}
+
+// achievementView is a catalog entry without its rule; Target is the
+// threshold the rule counts toward.
+type achievementView struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	Rarity      string `json:"rarity"`
+	Target      int    `json:"target"`
+}
+
+func toAchievementView(d achieve.Definition) achievementView {
+	return achievementView{ID: d.ID, Title: d.Title, Description: d.Description, Type: d.Type, Rarity: d.Rarity, Target: d.Rule.Threshold}
+}
+
+// userAchievementView is an achievement with the caller's standing on it.
+type userAchievementView struct {
+	achievementView
+	Progress int        `json:"progress"`
+	EarnedAt *time.Time `json:"earnedAt,omitempty"`
+}
+
+// GET /achievements
+func (s *serverImpl) GetAchievements(w http.ResponseWriter, r *http.Request) {
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	defs := s.achieveCatalog.All()
+	items := make([]achievementView, len(defs))
+	for i, d := range defs {
+		items[i] = toAchievementView(d)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"items": items})
+}
+
+// GET /users/me/achievements
+// Earned achievements (newest first) and everything else in catalog order
+// with the caller's progress, zero where nothing counted yet.
+func (s *serverImpl) GetUsersMeAchievements(w http.ResponseWriter, r *http.Request) {
+	claims, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	rows, err := s.achievements.ListAchievementProgress(r.Context(), claims.Sub)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	byID := make(map[string]db.AchievementProgress, len(rows))
+	for _, p := range rows {
+		byID[p.AchievementID] = p
+	}
+	earned, inProgress := []userAchievementView{}, []userAchievementView{}
+	for _, d := range s.achieveCatalog.All() {
+		view := userAchievementView{achievementView: toAchievementView(d)}
+		p := byID[d.ID]
+		// the catalog may have changed since progress was saved
+		view.Progress = min(p.Progress, view.Target)
+		if p.EarnedAt != nil {
+			view.Progress, view.EarnedAt = view.Target, p.EarnedAt
+			earned = append(earned, view)
+			continue
+		}
+		inProgress = append(inProgress, view)
+	}
+	sort.SliceStable(earned, func(i, j int) bool { return earned[i].EarnedAt.After(*earned[j].EarnedAt) })
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"earned": earned, "inProgress": inProgress})
+}
+
+// POST /achievements/events
+// Achievement events owned by other services (valet uses, plans created),
+// authenticated by their service token. Event ids are unique per user and
+// type, so producers can retry a batch.
+func (s *serverImpl) PostAchievementsEvents(w http.ResponseWriter, r *http.Request) {
+	if !s.achieveTokens.Check(w, r) {
+		return
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	var req struct {
+		Events []struct {
+			ID         string            `json:"id"`
+			UserID     string            `json:"userId"`
+			Type       string            `json:"type"`
+			OccurredAt string            `json:"occurredAt"`
+			Timezone   string            `json:"timezone"`
+			Attrs      map[string]string `json:"attrs"`
+		} `json:"events"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "invalid json", "INVALID_JSON")
+		return
+	}
+	if len(req.Events) == 0 || len(req.Events) > maxAchievementBatch {
+		middleware.ErrorHandler(w, http.StatusBadRequest, fmt.Sprintf("events must have 1 to %d entries", maxAchievementBatch), "VALIDATION_ERROR")
+		return
+	}
+	now := s.now()
+	events := make([]db.AchievementEvent, len(req.Events))
+	for i, in := range req.Events {
+		invalid := func(msg string) {
+			middleware.ErrorHandler(w, http.StatusBadRequest, fmt.Sprintf("events[%d]: %s", i, msg), "VALIDATION_ERROR")
+		}
+		if in.ID == "" || len(in.ID) > maxAchievementIDLen || in.UserID == "" || len(in.UserID) > maxAchievementIDLen {
+			invalid(fmt.Sprintf("id and userId are required (at most %d characters)", maxAchievementIDLen))
+			return
+		}
+		if !externalAchievementEvents[in.Type] {
+			invalid("type must be valet_use or plan_created")
+			return
+		}
+		if in.Timezone != "" {
+			if _, err := time.LoadLocation(in.Timezone); err != nil {
+				invalid("timezone must be an IANA zone name")
+				return
+			}
+		}
+		if len(in.Attrs) > maxAchievementAttrs {
+			invalid(fmt.Sprintf("attrs may have at most %d entries", maxAchievementAttrs))
+			return
+		}
+		for k, v := range in.Attrs {
+			if k == "" || len(k) > maxAchievementAttrLen || len(v) > maxAchievementAttrLen {
+				invalid(fmt.Sprintf("attrs keys and values must be at most %d characters", maxAchievementAttrLen))
+				return
+			}
+		}
+		occurredAt := now
+		if in.OccurredAt != "" {
+			t, err := time.Parse(time.RFC3339, in.OccurredAt)
+			if err != nil {
+				invalid("occurredAt must be RFC3339")
+				return
+			}
+			if t.Before(now.Add(maxVibeClockSkew)) {
+				occurredAt = t
+			}
+		}
+		events[i] = db.AchievementEvent{
+			UserID:     in.UserID,
+			EventID:    in.Type + ":" + in.ID,
+			Type:       in.Type,
+			Attrs:      in.Attrs,
+			Zone:       in.Timezone,
+			OccurredAt: occurredAt,
+		}
+	}
+
+	type grant struct {
+		UserID        string `json:"userId"`
+		AchievementID string `json:"achievementId"`
+	}
+	accepted, duplicates, granted := 0, 0, []grant{}
+	for _, e := range events {
+		created, earned, err := s.recordAchievement(r.Context(), e)
+		if err != nil {
+			// events before this one are stored; a retry counts them as
+			// duplicates
+			middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+			return
+		}
+		if !created {
+			duplicates++
+			continue
+		}
+		accepted++
+		for _, id := range earned {
+			granted = append(granted, grant{e.UserID, id})
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]any{"accepted": accepted, "duplicates": duplicates, "earned": granted})
+}
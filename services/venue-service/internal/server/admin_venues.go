@@ -0,0 +1,447 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"bytspot/services/venue-service/internal/db"
+	"bytspot/services/venue-service/internal/geocode"
+	"bytspot/services/venue-service/internal/importer"
+	"bytspot/services/venue-service/internal/venues"
+	"bytspot/shared/jwtauth"
+	"bytspot/shared/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	maxBulkStatusIDs  = 100
+	defaultAuditLimit = 50
+	maxAuditLimit     = 200
+)
+
+// locate fills v's coordinates from its address via the geocoder.
+func (s *serverImpl) locate(ctx context.Context, v *db.Venue) error {
+	if s.geocoder == nil {
+		return errNoGeocoder
+	}
+	lat, lon, err := s.geocoder.Geocode(ctx, v.Address)
+	if err != nil {
+		return err
+	}
+	v.Lat, v.Lon = lat, lon
+	return nil
+}
+
+var errNoGeocoder = errors.New("no geocoder configured")
+
+// writeGeocodeError maps a locate failure to a response.
+func writeGeocodeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errNoGeocoder):
+		middleware.ErrorHandler(w, http.StatusBadRequest, "lat and lng are required (address geocoding is not configured)", "VALIDATION_ERROR")
+	case errors.Is(err, geocode.ErrNoMatch):
+		middleware.ErrorHandler(w, http.StatusBadRequest, "address could not be geocoded", "VALIDATION_ERROR")
+	default:
+		log.Printf("geocode failed: %v", err)
+		middleware.ErrorHandler(w, http.StatusBadGateway, "geocoder unavailable", "UPSTREAM_ERROR")
+	}
+}
+
+// venueEditor authenticates an admin or venue host with a full-access token,
+// writing 401/403 otherwise.
+func (s *serverImpl) venueEditor(w http.ResponseWriter, r *http.Request) (*jwtauth.Claims, bool) {
+	claims, ok := s.requireFullAccess(w, r)
+	if !ok {
+		return nil, false
+	}
+	if !hasRole(claims, "admin") && !hasRole(claims, "host") {
+		middleware.ErrorHandler(w, http.StatusForbidden, "admin or host role required", "FORBIDDEN")
+		return nil, false
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return nil, false
+	}
+	return claims, true
+}
+
+// canEditVenue: admins edit any venue, hosts only the ones they own.
+func canEditVenue(claims *jwtauth.Claims, v *db.Venue) bool {
+	return hasRole(claims, "admin") || (v.OwnerID != nil && *v.OwnerID == claims.Sub)
+}
+
+func actorRole(claims *jwtauth.Claims) string {
+	if hasRole(claims, "admin") {
+		return "admin"
+	}
+	return "host"
+}
+
+// editableVenue loads the venue at {id} and checks the caller may edit it.
+func (s *serverImpl) editableVenue(w http.ResponseWriter, r *http.Request, claims *jwtauth.Claims) (*db.Venue, bool) {
+	v, err := s.venues.GetVenue(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return nil, false
+	}
+	if v == nil {
+		middleware.ErrorHandler(w, http.StatusNotFound, "venue not found", "NOT_FOUND")
+		return nil, false
+	}
+	if !canEditVenue(claims, v) {
+		middleware.ErrorHandler(w, http.StatusForbidden, "not an owner of this venue", "FORBIDDEN")
+		return nil, false
+	}
+	return v, true
+}
+
+// recordAudit stores the history entry for a venue write. The write has
+// already happened, so a failure here is logged rather than returned.
+func (s *serverImpl) recordAudit(ctx context.Context, claims *jwtauth.Claims, action string, changes map[string]db.FieldChange, venueID string) {
+	a := &db.VenueAudit{VenueID: venueID, ActorID: claims.Sub, ActorRole: actorRole(claims), Action: action, Changes: changes}
+	if err := s.venueAudit.InsertVenueAudit(ctx, a); err != nil {
+		log.Printf("venue audit write failed for %s: %v", venueID, err)
+	}
+}
+
+func writeVenue(w http.ResponseWriter, status int, v *db.Venue) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(toView(*v))
+}
+
+// GET /admin/venues?status=&ownerId=
+func (s *serverImpl) listAdminVenues(w http.ResponseWriter, r *http.Request) {
+	claims, ok := s.venueEditor(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	f := db.VenueFilter{Status: db.VenueStatus(q.Get("status")), OwnerID: q.Get("ownerId")}
+	if f.Status != "" && !venues.Statuses[f.Status] {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "status must be draft, active or archived", "VALIDATION_ERROR")
+		return
+	}
+	if !hasRole(claims, "admin") {
+		f.OwnerID = claims.Sub
+	}
+	list, err := s.venues.ListAllVenues(r.Context(), f)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	items := make([]venueView, 0, len(list))
+	for _, v := range list {
+		items = append(items, toView(v))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"items": items})
+}
+
+// POST /admin/venues
+// Hosts own the venues they create, which start as drafts; admins' venues
+// are active unless a status is given. Only admins set the status: a host's
+// venue goes public when an admin publishes it.
+func (s *serverImpl) createAdminVenue(w http.ResponseWriter, r *http.Request) {
+	claims, ok := s.venueEditor(w, r)
+	if !ok {
+		return
+	}
+	var in venues.Input
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "invalid json", "INVALID_JSON")
+		return
+	}
+	v := &db.Venue{Status: db.VenueActive}
+	if !hasRole(claims, "admin") {
+		if in.OwnerID != nil && *in.OwnerID != claims.Sub {
+			middleware.ErrorHandler(w, http.StatusForbidden, "only admins can set the owner", "FORBIDDEN")
+			return
+		}
+		if in.Status != nil {
+			middleware.ErrorHandler(w, http.StatusForbidden, "only admins can set the status", "FORBIDDEN")
+			return
+		}
+		owner := claims.Sub
+		v.Status, in.OwnerID = db.VenueDraft, &owner
+	}
+	if err := in.Apply(v); err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+		return
+	}
+	if in.Lat == nil {
+		if err := s.locate(r.Context(), v); err != nil {
+			writeGeocodeError(w, err)
+			return
+		}
+	}
+	if err := venues.Validate(v); err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+		return
+	}
+	if err := s.venues.CreateVenue(r.Context(), v); err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	s.catalog.invalidate()
+	s.recordAudit(r.Context(), claims, "create", venues.Diff(nil, v), v.ID)
+	writeVenue(w, http.StatusCreated, v)
+}
+
+// GET /admin/venues/{id}
+func (s *serverImpl) getAdminVenue(w http.ResponseWriter, r *http.Request) {
+	claims, ok := s.venueEditor(w, r)
+	if !ok {
+		return
+	}
+	v, ok := s.editableVenue(w, r, claims)
+	if !ok {
+		return
+	}
+	writeVenue(w, http.StatusOK, v)
+}
+
+// PATCH /admin/venues/{id}
+// Changing the address without sending coordinates re-geocodes the venue.
+func (s *serverImpl) patchAdminVenue(w http.ResponseWriter, r *http.Request) {
+	claims, ok := s.venueEditor(w, r)
+	if !ok {
+		return
+	}
+	before, ok := s.editableVenue(w, r, claims)
+	if !ok {
+		return
+	}
+	var in venues.Input
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "invalid json", "INVALID_JSON")
+		return
+	}
+	if in.OwnerID != nil && !hasRole(claims, "admin") {
+		middleware.ErrorHandler(w, http.StatusForbidden, "only admins can change the owner", "FORBIDDEN")
+		return
+	}
+	if in.Status != nil && !hasRole(claims, "admin") {
+		middleware.ErrorHandler(w, http.StatusForbidden, "only admins can set the status", "FORBIDDEN")
+		return
+	}
+	v := *before
+	if err := in.Apply(&v); err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+		return
+	}
+	if in.Lat == nil && v.Address != before.Address {
+		if err := s.locate(r.Context(), &v); err != nil {
+			writeGeocodeError(w, err)
+			return
+		}
+	}
+	if err := venues.Validate(&v); err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+		return
+	}
+	s.saveVenue(w, r, claims, "update", before, &v)
+}
+
+// saveVenue writes after over before and records the diff; an edit that
+// changes nothing is answered without a write or audit entry.
+func (s *serverImpl) saveVenue(w http.ResponseWriter, r *http.Request, claims *jwtauth.Claims, action string, before, after *db.Venue) {
+	changes := venues.Diff(before, after)
+	if len(changes) == 0 {
+		writeVenue(w, http.StatusOK, before)
+		return
+	}
+	if err := s.venues.UpdateVenue(r.Context(), after); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			middleware.ErrorHandler(w, http.StatusNotFound, "venue not found", "NOT_FOUND")
+			return
+		}
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	s.catalog.invalidate()
+	s.recordAudit(r.Context(), claims, action, changes, after.ID)
+	writeVenue(w, http.StatusOK, after)
+}
+
+// POST /admin/venues/{id}/archive
+func (s *serverImpl) archiveAdminVenue(w http.ResponseWriter, r *http.Request) {
+	s.setVenueStatus(w, r, "archive", db.VenueArchived)
+}
+
+// POST /admin/venues/{id}/restore
+// Admins restore the venue to active; a host's restore brings it back as a
+// draft, since hosts can't publish.
+func (s *serverImpl) restoreAdminVenue(w http.ResponseWriter, r *http.Request) {
+	s.setVenueStatus(w, r, "restore", db.VenueActive)
+}
+
+func (s *serverImpl) setVenueStatus(w http.ResponseWriter, r *http.Request, action string, status db.VenueStatus) {
+	claims, ok := s.venueEditor(w, r)
+	if !ok {
+		return
+	}
+	before, ok := s.editableVenue(w, r, claims)
+	if !ok {
+		return
+	}
+	if status == db.VenueActive && !hasRole(claims, "admin") {
+		status = db.VenueDraft
+	}
+	v := *before
+	v.Status = status
+	s.saveVenue(w, r, claims, action, before, &v)
+}
+
+type bulkStatusResult struct {
+	ID     string `json:"id"`
+	Result string `json:"result"` // updated, unchanged, not_found, forbidden, error
+}
+
+// POST /admin/venues/bulk-status { ids, status }
+// Admin only. Each venue is updated and audited on its own; the response
+// reports the outcome per id.
+func (s *serverImpl) bulkVenueStatus(w http.ResponseWriter, r *http.Request) {
+	claims, ok := s.venueEditor(w, r)
+	if !ok {
+		return
+	}
+	if !hasRole(claims, "admin") {
+		middleware.ErrorHandler(w, http.StatusForbidden, "admin role required", "FORBIDDEN")
+		return
+	}
+	var req struct {
+		IDs    []string       `json:"ids"`
+		Status db.VenueStatus `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "invalid json", "INVALID_JSON")
+		return
+	}
+	if len(req.IDs) == 0 || len(req.IDs) > maxBulkStatusIDs {
+		middleware.ErrorHandler(w, http.StatusBadRequest, fmt.Sprintf("ids must list 1-%d venues", maxBulkStatusIDs), "VALIDATION_ERROR")
+		return
+	}
+	if !venues.Statuses[req.Status] {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "status must be draft, active or archived", "VALIDATION_ERROR")
+		return
+	}
+	results := make([]bulkStatusResult, 0, len(req.IDs))
+	changed := false
+	for _, id := range req.IDs {
+		res := bulkStatusResult{ID: id}
+		v, err := s.venues.GetVenue(r.Context(), id)
+		switch {
+		case err != nil:
+			res.Result = "error"
+		case v == nil:
+			res.Result = "not_found"
+		case !canEditVenue(claims, v):
+			res.Result = "forbidden"
+		case v.Status == req.Status:
+			res.Result = "unchanged"
+		default:
+			before := *v
+			v.Status = req.Status
+			if err := s.venues.UpdateVenue(r.Context(), v); err != nil {
+				res.Result = "error"
+				break
+			}
+			changed = true
+			res.Result = "updated"
+			s.recordAudit(r.Context(), claims, "status", venues.Diff(&before, v), id)
+		}
+		results = append(results, res)
+	}
+	if changed {
+		s.catalog.invalidate()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"status": req.Status, "results": results})
+}
+
+// GET /admin/venues/{id}/audit?limit=
+func (s *serverImpl) listVenueAudit(w http.ResponseWriter, r *http.Request) {
+	claims, ok := s.venueEditor(w, r)
+	if !ok {
+		return
+	}
+	v, ok := s.editableVenue(w, r, claims)
+	if !ok {
+		return
+	}
+	limit := defaultAuditLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			limit = min(n, maxAuditLimit)
+		}
+	}
+	items, err := s.venueAudit.ListVenueAudit(r.Context(), v.ID, limit)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	if items == nil {
+		items = []db.VenueAudit{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"items": items})
+}
+
+const maxImportBytes = 10 << 20
+
+// POST /admin/venues/import?format=csv|geojson&dryRun=true&radius=
+// The body is the raw file; without format the Content-Type decides. Admin only.
+func (s *serverImpl) importAdminVenues(w http.ResponseWriter, r *http.Request) {
+	claims, ok := s.venueEditor(w, r)
+	if !ok {
+		return
+	}
+	if !hasRole(claims, "admin") {
+		middleware.ErrorHandler(w, http.StatusForbidden, "admin role required", "FORBIDDEN")
+		return
+	}
+	q := r.URL.Query()
+	format, ok := importer.Format(q.Get("format")), q.Get("format") != ""
+	if !ok {
+		format, ok = importer.FormatFor(r.Header.Get("Content-Type"))
+	}
+	if !ok {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "format must be csv or geojson", "VALIDATION_ERROR")
+		return
+	}
+	dryRun, _ := strconv.ParseBool(q.Get("dryRun"))
+	opts := importer.Options{DryRun: dryRun}
+	if raw := q.Get("radius"); raw != "" {
+		radius, err := strconv.ParseFloat(raw, 64)
+		if err != nil || radius <= 0 || radius > 1000 {
+			middleware.ErrorHandler(w, http.StatusBadRequest, "radius must be between 0 and 1000 meters", "VALIDATION_ERROR")
+			return
+		}
+		opts.MatchRadius = radius
+	}
+	rows, err := importer.Parse(http.MaxBytesReader(w, r.Body, maxImportBytes), format)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+		return
+	}
+	opts.Audit = func(ctx context.Context, changes map[string]db.FieldChange, venueID string) {
+		s.recordAudit(ctx, claims, "import", changes, venueID)
+	}
+	report, err := importer.Run(r.Context(), s.venues, rows, opts)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	if report.Created+report.Updated > 0 && !dryRun {
+		s.catalog.invalidate()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,301 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"bytspot/services/venue-service/internal/analytics"
+	"bytspot/services/venue-service/internal/db"
+	"bytspot/services/venue-service/internal/hours"
+	"bytspot/shared/jwtauth"
+	"bytspot/shared/middleware"
+	"bytspot/shared/servicetoken"
+)
+
+const (
+	// analyticsInterval is how often impressions are flushed and recent days
+	// rolled up, which is how far behind the dashboard can be.
+	analyticsInterval    = 5 * time.Minute
+	rollupDays           = 2 // today and yesterday, local to each venue
+	rollupBackfillDays   = 30
+	defaultAnalyticsDays = 7
+	maxAnalyticsDays     = 366
+)
+
+// impressionCounter returns the func that records a venue shown in a result
+// list (discovery, search, trending, recommendations) to r's caller. Only
+// signed-in users count: anonymous, service and impersonation callers get a
+// no-op, so crawlers and internal reads don't inflate the dashboard.
+func (s *serverImpl) impressionCounter(r *http.Request) func(venueID string) {
+	if r.Header.Get(servicetoken.Header) != "" {
+		return func(string) {}
+	}
+	claims, err := jwtauth.Verify(jwtauth.BearerToken(r))
+	if err != nil || claims.Impersonating() {
+		return func(string) {}
+	}
+	return func(venueID string) { s.impressions.Add(s.now(), venueID) }
+}
+
+// venueZones resolves and caches each venue's timezone for one job run.
+type venueZones map[string]*time.Location
+
+func (z venueZones) get(v db.Venue) *time.Location {
+	loc, ok := z[v.ID]
+	if !ok {
+		loc = hours.Location(v.Hours)
+		z[v.ID] = loc
+	}
+	return loc
+}
+
+// flushImpressions adds the impressions counted since the last flush to the
+// rollups; on failure they are kept for the next run.
+func (s *serverImpl) flushImpressions(ctx context.Context) error {
+	counts := s.impressions.Drain()
+	if len(counts) == 0 {
+		return nil
+	}
+	zones := venueZones{}
+	byDay := map[db.VenueImpressions]int{}
+	for k, n := range counts {
+		loc := time.UTC
+		if v, ok := s.catalog.get(k.VenueID); ok {
+			loc = zones.get(v)
+		}
+		byDay[db.VenueImpressions{VenueID: k.VenueID, Day: analytics.Date(time.Unix(k.Hour, 0), loc)}] += n
+	}
+	out := make([]db.VenueImpressions, 0, len(byDay))
+	for k, n := range byDay {
+		k.Count = n
+		out = append(out, k)
+	}
+	if err := s.stats.AddVenueImpressions(ctx, out); err != nil {
+		s.impressions.Restore(counts)
+		return err
+	}
+	return nil
+}
+
+// rollupAnalytics recomputes the last days local days of every venue from
+// the like, check-in and vibe report stores. Older days are left alone:
+// their vibe reports may already be purged.
+func (s *serverImpl) rollupAnalytics(ctx context.Context, days int) error {
+	now := s.now()
+	// local midnight days-1 days ago is at most days*24h plus a DST hour back
+	since, until := now.Add(-time.Duration(days)*24*time.Hour-2*time.Hour), now.Add(time.Hour)
+	venues, err := s.venues.ListAllVenues(ctx, db.VenueFilter{})
+	if err != nil {
+		return err
+	}
+	byID := make(map[string]db.Venue, len(venues))
+	for _, v := range venues {
+		byID[v.ID] = v
+	}
+	likes, err := s.interactions.ListLikeEvents(ctx, since, until)
+	if err != nil {
+		return err
+	}
+	checkins, err := s.checkins.ListCheckinsBetween(ctx, since, until)
+	if err != nil {
+		return err
+	}
+	reports, err := s.vibes.ListVibeReports(ctx, since)
+	if err != nil {
+		return err
+	}
+	var acts []analytics.Activity
+	for _, l := range likes {
+		acts = append(acts, analytics.Activity{VenueID: l.VenueID, Kind: analytics.Like, At: l.CreatedAt})
+	}
+	for _, c := range checkins {
+		acts = append(acts, analytics.Activity{VenueID: c.VenueID, Kind: analytics.Checkin, At: c.CreatedAt})
+	}
+	for _, r := range reports {
+		acts = append(acts, analytics.Activity{VenueID: r.VenueID, Kind: analytics.VibeReport, At: r.ReportedAt, Score: r.Score})
+	}
+	zones := venueZones{}
+	var out []db.VenueDailyStats
+	for _, d := range analytics.Rollup(acts, func(id string) *time.Location { return zones.get(byID[id]) }) {
+		v, ok := byID[d.VenueID]
+		if !ok || d.Date.Before(analytics.Date(now, zones.get(v)).AddDate(0, 0, -(days-1))) {
+			continue
+		}
+		out = append(out, db.VenueDailyStats{VenueID: d.VenueID, Day: d.Date, Likes: d.Likes, Checkins: d.Checkins,
+			VibeReports: d.VibeReports, VibeScoreSum: d.VibeScoreSum, Hourly: d.Hourly[:]})
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return s.stats.SaveVenueDailyStats(ctx, out)
+}
+
+// backfillDays is how many days the first rollup after startup covers:
+// rollupBackfillDays, but only days whose vibe reports are all retained.
+func (s *serverImpl) backfillDays() int {
+	return max(rollupDays, min(rollupBackfillDays, int(s.vibeRetention/(24*time.Hour))-1))
+}
+
+// periodSpec is the requested dashboard range before it is resolved against
+// a venue's local today.
+type periodSpec struct {
+	days     int
+	from, to time.Time // explicit dates; zero for the last days days
+}
+
+// parsePeriod reads range=Nd (the last N days including today, default 7d)
+// or from and to (YYYY-MM-DD, inclusive).
+func parsePeriod(q url.Values) (periodSpec, error) {
+	rawRange, rawFrom, rawTo := q.Get("range"), q.Get("from"), q.Get("to")
+	if rawFrom != "" || rawTo != "" {
+		if rawRange != "" {
+			return periodSpec{}, errors.New("use either range or from and to")
+		}
+		from, err1 := time.Parse(analytics.DateLayout, rawFrom)
+		to, err2 := time.Parse(analytics.DateLayout, rawTo)
+		if err1 != nil || err2 != nil {
+			return periodSpec{}, errors.New("from and to must both be dates (YYYY-MM-DD)")
+		}
+		p := analytics.Period{From: from, To: to}
+		if to.Before(from) || p.Days() > maxAnalyticsDays {
+			return periodSpec{}, fmt.Errorf("to must be on or after from, at most %d days in all", maxAnalyticsDays)
+		}
+		return periodSpec{from: from, to: to}, nil
+	}
+	spec := periodSpec{days: defaultAnalyticsDays}
+	if rawRange != "" {
+		n, err := strconv.Atoi(strings.TrimSuffix(rawRange, "d"))
+		if err != nil || !strings.HasSuffix(rawRange, "d") || n < 1 || n > maxAnalyticsDays {
+			return periodSpec{}, fmt.Errorf("range must be 1d to %dd", maxAnalyticsDays)
+		}
+		spec.days = n
+	}
+	return spec, nil
+}
+
+func (p periodSpec) resolve(today time.Time) analytics.Period {
+	if !p.from.IsZero() {
+		return analytics.Period{From: p.from, To: p.to}
+	}
+	return analytics.Period{From: today.AddDate(0, 0, -(p.days - 1)), To: today}
+}
+
+// venueDays loads the rollups covering from..to for the venues.
+func (s *serverImpl) venueDays(ctx context.Context, venueIDs []string, from, to time.Time) (map[string][]analytics.Day, error) {
+	stats, err := s.stats.ListVenueDailyStats(ctx, venueIDs, from, to)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string][]analytics.Day{}
+	for _, st := range stats {
+		d := analytics.Day{VenueID: st.VenueID, Date: st.Day, Impressions: st.Impressions, Likes: st.Likes,
+			Checkins: st.Checkins, VibeReports: st.VibeReports, VibeScoreSum: st.VibeScoreSum}
+		copy(d.Hourly[:], st.Hourly)
+		out[st.VenueID] = append(out[st.VenueID], d)
+	}
+	return out, nil
+}
+
+// GET /admin/venues/{id}/analytics?range=7d | from&to
+// The venue's dashboard: totals against the previous period of the same
+// length, a daily series (the vibe trend is avgVibe per day) and peak hours,
+// all in the venue's timezone.
+func (s *serverImpl) getVenueAnalytics(w http.ResponseWriter, r *http.Request) {
+	claims, ok := s.venueEditor(w, r)
+	if !ok {
+		return
+	}
+	v, ok := s.editableVenue(w, r, claims)
+	if !ok {
+		return
+	}
+	spec, err := parsePeriod(r.URL.Query())
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+		return
+	}
+	loc := hours.Location(v.Hours)
+	p := spec.resolve(analytics.Date(s.now(), loc))
+	days, err := s.venueDays(r.Context(), []string{v.ID}, p.Previous().From, p.To)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		VenueID  string `json:"venueId"`
+		Timezone string `json:"timezone"`
+		analytics.Report
+	}{v.ID, loc.String(), analytics.Build(days[v.ID], p)})
+}
+
+// venueAnalyticsSummary is one row of the host's overview.
+type venueAnalyticsSummary struct {
+	VenueID  string           `json:"venueId"`
+	Name     string           `json:"name"`
+	Status   db.VenueStatus   `json:"status"`
+	Timezone string           `json:"timezone"`
+	Period   analytics.Period `json:"period"`
+	Previous analytics.Period `json:"previousPeriod"`
+	Totals   analytics.Totals `json:"totals"`
+}
+
+// GET /admin/venues/analytics?range=7d | from&to [&ownerId]
+// Totals for every venue the caller owns (admins: every venue, or ownerId's),
+// each over the period in its own timezone.
+func (s *serverImpl) listVenueAnalytics(w http.ResponseWriter, r *http.Request) {
+	claims, ok := s.venueEditor(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	spec, err := parsePeriod(q)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+		return
+	}
+	f := db.VenueFilter{OwnerID: q.Get("ownerId")}
+	if !hasRole(claims, "admin") {
+		f.OwnerID = claims.Sub
+	}
+	venues, err := s.venues.ListAllVenues(r.Context(), f)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	now := s.now()
+	items := make([]venueAnalyticsSummary, 0, len(venues))
+	ids := make([]string, 0, len(venues))
+	var from, to time.Time
+	for _, v := range venues {
+		loc := hours.Location(v.Hours)
+		p := spec.resolve(analytics.Date(now, loc))
+		items = append(items, venueAnalyticsSummary{VenueID: v.ID, Name: v.Name, Status: v.Status, Timezone: loc.String(), Period: p, Previous: p.Previous()})
+		ids = append(ids, v.ID)
+		if prev := p.Previous().From; from.IsZero() || prev.Before(from) {
+			from = prev
+		}
+		if p.To.After(to) {
+			to = p.To
+		}
+	}
+	if len(ids) > 0 {
+		days, err := s.venueDays(r.Context(), ids, from, to)
+		if err != nil {
+			middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+			return
+		}
+		for i := range items {
+			items[i].Totals = analytics.Summarize(days[items[i].VenueID], items[i].Period)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"items": items})
+}
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"bytspot/services/venue-service/internal/db"
+	"bytspot/services/venue-service/internal/geo"
+	"bytspot/services/venue-service/internal/search"
+)
+
+// catalogTTL bounds how stale the in-process copy may get when another
+// instance writes to the shared database.
+const catalogTTL = time.Minute
+
+// catalog is an in-process snapshot of the active venues plus a geohash
+// index and a text index over them, so discovery and search never scan the
+// table per request.
+type catalog struct {
+	repo db.VenueRepo
+	now  func() time.Time
+
+	mu         sync.RWMutex
+	loaded     bool
+	stale      bool
+	refreshing bool
+	loadedAt   time.Time
+	venues     map[string]db.Venue
+	index      *geo.Index
+	text       *search.Index
+}
+
+func newCatalog(repo db.VenueRepo, now func() time.Time) *catalog {
+	return &catalog{repo: repo, now: now, venues: map[string]db.Venue{}, index: geo.NewIndex(), text: search.NewIndex()}
+}
+
+func (c *catalog) fresh() bool {
+	return c.loaded && !c.stale && c.now().Sub(c.loadedAt) < catalogTTL
+}
+
+// load refreshes the snapshot when it is older than catalogTTL or was
+// invalidated.
+func (c *catalog) load(ctx context.Context) error {
+	c.mu.RLock()
+	fresh := c.fresh()
+	c.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+	return c.refresh(ctx)
+}
+
+// loadStale is load for latency-sensitive callers: once the catalog has
+// loaded it answers from the current snapshot and refreshes in the
+// background.
+func (c *catalog) loadStale(ctx context.Context) error {
+	c.mu.Lock()
+	if !c.loaded {
+		c.mu.Unlock()
+		return c.refresh(ctx)
+	}
+	start := !c.fresh() && !c.refreshing
+	if start {
+		c.refreshing = true
+	}
+	c.mu.Unlock()
+	if start {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			c.refresh(ctx)
+			c.mu.Lock()
+			c.refreshing = false
+			c.mu.Unlock()
+		}()
+	}
+	return nil
+}
+
+// refresh re-reads the active venues and applies the difference to the
+// indexes, so only venues that were added, moved, edited or removed are
+// re-indexed.
+func (c *catalog) refresh(ctx context.Context) error {
+	list, err := c.repo.ListVenues(ctx)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	seen := make(map[string]bool, len(list))
+	for _, v := range list {
+		seen[v.ID] = true
+		old, ok := c.venues[v.ID]
+		c.venues[v.ID] = v
+		if !ok || old.Lat != v.Lat || old.Lon != v.Lon {
+			c.index.Upsert(v.ID, v.Lat, v.Lon)
+		}
+		c.text.Upsert(searchDoc(v)) // no-op when the text is unchanged
+	}
+	for id := range c.venues {
+		if !seen[id] {
+			delete(c.venues, id)
+			c.index.Remove(id)
+			c.text.Remove(id)
+		}
+	}
+	c.loaded, c.stale, c.loadedAt = true, false, c.now()
+	return nil
+}
+
+func searchDoc(v db.Venue) search.Doc {
+	return search.Doc{ID: v.ID, Name: v.Name, Tags: v.Tags, Neighborhood: v.Subtitle, Category: v.Category}
+}
+
+// invalidate forces the next load to re-read the repository.
+func (c *catalog) invalidate() {
+	c.mu.Lock()
+	c.stale = true
+	c.mu.Unlock()
+}
+
+// nearby returns active venues within radius meters, nearest first.
+func (c *catalog) nearby(ctx context.Context, lat, lon, radius float64) ([]db.Venue, []float64, error) {
+	if err := c.load(ctx); err != nil {
+		return nil, nil, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	hits := c.index.Within(lat, lon, radius)
+	venues := make([]db.Venue, 0, len(hits))
+	dists := make([]float64, 0, len(hits))
+	for _, h := range hits {
+		venues = append(venues, c.venues[h.ID])
+		dists = append(dists, h.Distance)
+	}
+	return venues, dists, nil
+}
+
+// all returns every active venue ordered by id.
+func (c *catalog) all(ctx context.Context) ([]db.Venue, error) {
+	if err := c.load(ctx); err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	out := make([]db.Venue, 0, len(c.venues))
+	for _, v := range c.venues {
+		out = append(out, v)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+// inBox returns the ids of active venues inside b.
+func (c *catalog) inBox(ctx context.Context, b geo.Box) ([]string, error) {
+	if err := c.load(ctx); err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.index.InBox(b), nil
+}
+
+// get returns an active venue from the snapshot.
+func (c *catalog) get(id string) (db.Venue, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	v, ok := c.venues[id]
+	return v, ok
+}
@@ -0,0 +1,205 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"bytspot/services/venue-service/internal/achieve"
+	"bytspot/services/venue-service/internal/db"
+	"bytspot/services/venue-service/internal/geo"
+	"bytspot/shared/middleware"
+)
+
+const (
+	// checkinRadiusMeters is the geofence around a venue's coordinates.
+	checkinRadiusMeters = 150.0
+	// maxAccuracyAllowance caps how much of the reported GPS accuracy widens
+	// the geofence, so a vague fix can't check in from down the street.
+	maxAccuracyAllowance = 50.0
+	checkinCooldown      = 4 * time.Hour
+	maxShareRecipients   = 50
+	defaultCheckinsLimit = 50
+	maxCheckinsLimit     = 200
+)
+
+// checkinView is a check-in with the venue it was at.
+type checkinView struct {
+	db.Checkin
+	Venue *venueView `json:"venue,omitempty"` // absent once the venue is deleted
+}
+
+// POST /venues/{id}/checkin
+// Records that the caller is at an active venue: lat/lon must be within the
+// geofence, and a user checks in at the same venue at most once per
+// cooldown. share.friendIds that are the caller's friends in auth-service are
+// notified through notification-service, under the caller's profile name.
+func (s *serverImpl) PostVenuesIdCheckin(w http.ResponseWriter, r *http.Request, id string) {
+	claims, ok := s.requireFullAccess(w, r)
+	if !ok {
+		return
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	var req struct {
+		Lat      *float64 `json:"lat"`
+		Lon      *float64 `json:"lon"`
+		Accuracy *float64 `json:"accuracy"` // meters
+		Share    *struct {
+			FriendIDs []string `json:"friendIds"`
+		} `json:"share"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "invalid json", "INVALID_JSON")
+		return
+	}
+	if req.Lat == nil || req.Lon == nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "lat and lon are required", "VALIDATION_ERROR")
+		return
+	}
+	if !geo.ValidCoords(*req.Lat, *req.Lon) {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "lat/lon out of range", "VALIDATION_ERROR")
+		return
+	}
+	if req.Accuracy != nil && *req.Accuracy < 0 {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "accuracy must be a non-negative number of meters", "VALIDATION_ERROR")
+		return
+	}
+	var shareWith []string
+	if req.Share != nil {
+		seen := map[string]bool{claims.Sub: true}
+		for _, f := range req.Share.FriendIDs {
+			if f = strings.TrimSpace(f); f != "" && !seen[f] {
+				seen[f] = true
+				shareWith = append(shareWith, f)
+			}
+		}
+		if len(shareWith) > maxShareRecipients {
+			middleware.ErrorHandler(w, http.StatusBadRequest, fmt.Sprintf("share.friendIds may list at most %d friends", maxShareRecipients), "VALIDATION_ERROR")
+			return
+		}
+	}
+	v, err := s.venues.GetVenue(r.Context(), id)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	if v == nil || v.Status != db.VenueActive {
+		middleware.ErrorHandler(w, http.StatusNotFound, "venue not found", "NOT_FOUND")
+		return
+	}
+
+	dist := geo.Distance(*req.Lat, *req.Lon, v.Lat, v.Lon)
+	allowance := 0.0
+	if req.Accuracy != nil {
+		allowance = math.Min(*req.Accuracy, maxAccuracyAllowance)
+	}
+	if dist > checkinRadiusMeters+allowance {
+		middleware.ErrorHandler(w, http.StatusUnprocessableEntity,
+			fmt.Sprintf("you are %.0f m from the venue; check in within %.0f m", dist, checkinRadiusMeters), "OUTSIDE_GEOFENCE")
+		return
+	}
+
+	// Only actual friends hear about it; if the graph can't be asked, nobody
+	// does and the check-in itself still counts.
+	friendName := "A friend"
+	if len(shareWith) > 0 {
+		name, friends, err := s.friends.ShareTargets(r.Context(), claims.Sub, shareWith)
+		if err != nil {
+			log.Printf("checkin share: %v", err)
+		}
+		shareWith = friends
+		if name != "" {
+			friendName = name
+		}
+	}
+
+	now := s.now()
+	c := &db.Checkin{
+		UserID:     claims.Sub,
+		VenueID:    id,
+		Lat:        *req.Lat,
+		Lon:        *req.Lon,
+		Accuracy:   req.Accuracy,
+		Distance:   math.Round(dist),
+		SharedWith: shareWith,
+		CreatedAt:  now,
+	}
+	prev, err := s.checkins.InsertCheckin(r.Context(), c, now.Add(-checkinCooldown))
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	if prev != nil {
+		wait := prev.CreatedAt.Add(checkinCooldown).Sub(now)
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		middleware.ErrorHandler(w, http.StatusTooManyRequests,
+			fmt.Sprintf("already checked in here; try again in %s", wait.Round(time.Minute)), "CHECKIN_COOLDOWN")
+		return
+	}
+	if len(shareWith) > 0 {
+		s.notifier.CheckedIn(*c, v.Name, friendName)
+	}
+	s.trackAchievement(r.Context(), venueAchievementEvent(claims.Sub, "checkin:"+strconv.FormatInt(c.ID, 10), achieve.EventCheckin, v, now))
+	if c.SharedWith == nil {
+		c.SharedWith = []string{}
+	}
+	view := publicView(*v, now)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(checkinView{Checkin: *c, Venue: &view})
+}
+
+// GET /users/me/checkins?limit=
+func (s *serverImpl) GetUsersMeCheckins(w http.ResponseWriter, r *http.Request) {
+	claims, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	limit := defaultCheckinsLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if n, e := strconv.Atoi(v); e == nil && n > 0 {
+			limit = min(n, maxCheckinsLimit)
+		}
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	checkins, err := s.checkins.ListCheckins(r.Context(), claims.Sub, limit)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	now := s.now()
+	venues := map[string]*venueView{}
+	items := make([]checkinView, 0, len(checkins))
+	for _, c := range checkins {
+		view, ok := venues[c.VenueID]
+		if !ok {
+			v, err := s.venues.GetVenue(r.Context(), c.VenueID)
+			if err != nil {
+				middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+				return
+			}
+			if v != nil {
+				pv := publicView(*v, now)
+				view = &pv
+			}
+			venues[c.VenueID] = view
+		}
+		if c.SharedWith == nil {
+			c.SharedWith = []string{}
+		}
+		items = append(items, checkinView{Checkin: c, Venue: view})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"items": items})
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+
+	"bytspot/shared/jwtauth"
+)
+
+// discoverCursor is the keyset position after the last item of a page.
+// The query origin is pinned in the cursor so that paging stays stable while
+// the user moves: later pages keep measuring from where the feed started,
+// and boosting the venues whose events were running when it started.
+type discoverCursor struct {
+	Lat    *float64 `json:"lat,omitempty"`
+	Lon    *float64 `json:"lon,omitempty"`
+	Radius float64  `json:"r,omitempty"`
+	Rank   float64  `json:"d,omitempty"`  // last item's ranking key, see candidate.rank
+	ID     string   `json:"id"`           // last item's id
+	Filter string   `json:"f"`            // fingerprint of the filters the feed was started with
+	At     int64    `json:"at,omitempty"` // unix time live events were looked up at
+}
+
+var errBadCursor = errors.New("invalid cursor")
+
+func cursorSecret() []byte {
+	if s := os.Getenv("CURSOR_SECRET"); s != "" {
+		return []byte(s)
+	}
+	return jwtauth.Secret()
+}
+
+func cursorMAC(payload []byte) []byte {
+	m := hmac.New(sha256.New, cursorSecret())
+	m.Write(payload)
+	return m.Sum(nil)
+}
+
+// encode returns base64url(json).base64url(hmac); clients treat it as opaque.
+func (c discoverCursor) encode() string {
+	payload, _ := json.Marshal(c)
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(cursorMAC(payload))
+}
+
+func decodeCursor(s string) (discoverCursor, error) {
+	var c discoverCursor
+	body, sig, ok := strings.Cut(s, ".")
+	if !ok {
+		return c, errBadCursor
+	}
+	enc := base64.RawURLEncoding
+	payload, err1 := enc.DecodeString(body)
+	mac, err2 := enc.DecodeString(sig)
+	if err1 != nil || err2 != nil || !hmac.Equal(mac, cursorMAC(payload)) {
+		return c, errBadCursor
+	}
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return c, errBadCursor
+	}
+	return c, nil
+}
@@ -0,0 +1,400 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"bytspot/services/venue-service/internal/api"
+	"bytspot/services/venue-service/internal/db"
+	"bytspot/services/venue-service/internal/geo"
+	"bytspot/services/venue-service/internal/hours"
+	"bytspot/shared/middleware"
+)
+
+const (
+	defaultRadiusMeters = 1000
+	maxRadiusMeters     = 50000
+	defaultPageSize     = 20
+	maxPageSize         = 50
+	maxOpenAtAhead      = 90 * 24 * time.Hour
+	// liveEventBoost scales the ranking distance of a venue with an event
+	// running, so it ranks level with one half as far away without.
+	liveEventBoost = 0.5
+)
+
+// Vibe bands bucket the venue's live vibe score (0-10) for filter chips.
+const (
+	vibeUnknown = "unknown"
+	vibeLow     = "low"
+	vibeMedium  = "medium"
+	vibeHigh    = "high"
+)
+
+var vibeBands = map[string]bool{vibeUnknown: true, vibeLow: true, vibeMedium: true, vibeHigh: true}
+
+func vibeBand(score float64, known bool) string {
+	switch {
+	case !known:
+		return vibeUnknown
+	case score >= 7:
+		return vibeHigh
+	case score >= 4:
+		return vibeMedium
+	default:
+		return vibeLow
+	}
+}
+
+// discoverFilter holds the non-geo filters; empty sets match everything.
+type discoverFilter struct {
+	categories map[string]bool
+	prices     map[int]bool
+	vibes      map[string]bool
+	openNow    bool
+	openAt     time.Time // open_at; zero means "now"
+}
+
+// fingerprint identifies the filter set so a cursor can't be replayed
+// against a different query.
+func (f discoverFilter) fingerprint(radius float64) string {
+	keys := func(m map[string]bool) string {
+		var out []string
+		for k := range m {
+			out = append(out, k)
+		}
+		sort.Strings(out)
+		return strings.Join(out, ",")
+	}
+	var prices []string
+	for p := range f.prices {
+		prices = append(prices, strconv.Itoa(p))
+	}
+	sort.Strings(prices)
+	raw := fmt.Sprintf("c=%s|p=%s|v=%s|o=%t|r=%g", keys(f.categories), strings.Join(prices, ","), keys(f.vibes), f.openNow, radius)
+	if !f.openAt.IsZero() {
+		raw += fmt.Sprintf("|at=%d", f.openAt.Unix())
+	}
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:8])
+}
+
+// parsePrices accepts tiers ("1,2") or dollar signs ("$,$$").
+func parsePrices(s string) (map[int]bool, bool) {
+	out := map[int]bool{}
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		tier, err := strconv.Atoi(p)
+		if err != nil && strings.Trim(p, "$") == "" {
+			tier, err = len(p), nil
+		}
+		if err != nil || tier < 1 || tier > 4 {
+			return nil, false
+		}
+		out[tier] = true
+	}
+	return out, true
+}
+
+func splitSet(s string) map[string]bool {
+	out := map[string]bool{}
+	for _, c := range strings.Split(s, ",") {
+		if c = strings.ToLower(strings.TrimSpace(c)); c != "" {
+			out[c] = true
+		}
+	}
+	return out
+}
+
+// candidate is a venue with everything the filters and facets look at.
+type candidate struct {
+	venue  db.Venue
+	dist   *float64
+	band   string
+	status hours.Status
+	known  bool // venue publishes hours
+	live   *happeningView
+	// rank orders the feed, then id: the distance (boosted for live events)
+	// in geo feeds, 0 for live venues and 1 for the rest otherwise. Rounded
+	// to a millimeter so it survives the cursor.
+	rank float64
+}
+
+// matches reports which filter dimensions the candidate passes.
+func (f discoverFilter) matches(c candidate) (category, price, vibe, open bool) {
+	category = len(f.categories) == 0 || f.categories[c.venue.Category]
+	price = len(f.prices) == 0 || f.prices[c.venue.PriceTier]
+	vibe = len(f.vibes) == 0 || f.vibes[c.band]
+	open = !f.openNow || c.status.Open
+	return
+}
+
+type facets struct {
+	Category map[string]int `json:"category"`
+	Price    map[string]int `json:"price"`
+	Vibe     map[string]int `json:"vibe"`
+}
+
+// countFacets counts each dimension with every other filter applied, so a
+// chip's count is what the user gets by toggling that chip.
+func countFacets(f discoverFilter, cands []candidate) facets {
+	out := facets{Category: map[string]int{}, Price: map[string]int{}, Vibe: map[string]int{}}
+	for _, c := range cands {
+		cat, price, vibe, open := f.matches(c)
+		if !open {
+			continue
+		}
+		if price && vibe {
+			out.Category[c.venue.Category]++
+		}
+		if cat && vibe {
+			out.Price[priceLabels[c.venue.PriceTier]]++
+		}
+		if cat && price {
+			out.Vibe[c.band]++
+		}
+	}
+	return out
+}
+
+// after reports whether c sorts after the cursor position.
+func after(c candidate, cur *discoverCursor) bool {
+	if cur == nil {
+		return true
+	}
+	if c.rank != cur.Rank {
+		return c.rank > cur.Rank
+	}
+	return c.venue.ID > cur.ID
+}
+
+// GET /venues/discover?lat&lon&radius&category&price&vibe&open_now&open_at&cursor&limit
+// With lat/lon the result is limited to radius meters (default 1000) and
+// sorted nearest first with distance in meters; without them the active
+// catalog is paged by id.
+// Venues with an event running (at open_at, if given) rank as if half as
+// far away, or first in the by-id order, and report it as happeningNow.
+// Signed-in users don't see venues they already liked or skipped.
+// open_at (RFC3339) keeps venues open at that time and reports openNow,
+// closesAt and opensNext as of then, for planning ahead.
+func (s *serverImpl) GetVenuesDiscover(w http.ResponseWriter, r *http.Request, params api.GetVenuesDiscoverParams) {
+	claims, ok := s.optionalAuth(w, r)
+	if !ok {
+		return
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	if (params.Lat == nil) != (params.Lon == nil) {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "lat and lon must be given together", "VALIDATION_ERROR")
+		return
+	}
+	var f discoverFilter
+	if params.Category != nil {
+		f.categories = splitSet(*params.Category)
+	}
+	if params.Price != nil {
+		prices, ok := parsePrices(*params.Price)
+		if !ok {
+			middleware.ErrorHandler(w, http.StatusBadRequest, "price must be tiers 1-4 or $-$$$$", "VALIDATION_ERROR")
+			return
+		}
+		f.prices = prices
+	}
+	if params.Vibe != nil {
+		f.vibes = splitSet(*params.Vibe)
+		for b := range f.vibes {
+			if !vibeBands[b] {
+				middleware.ErrorHandler(w, http.StatusBadRequest, "vibe must be low, medium, high or unknown", "VALIDATION_ERROR")
+				return
+			}
+		}
+	}
+	f.openNow = params.OpenNow != nil && *params.OpenNow
+	now := s.now()
+	at := now
+	if params.OpenAt != nil {
+		if f.openNow {
+			middleware.ErrorHandler(w, http.StatusBadRequest, "use either open_now or open_at", "VALIDATION_ERROR")
+			return
+		}
+		if params.OpenAt.After(now.Add(maxOpenAtAhead)) {
+			middleware.ErrorHandler(w, http.StatusBadRequest, "open_at may be at most 90 days ahead", "VALIDATION_ERROR")
+			return
+		}
+		at = *params.OpenAt
+		f.openNow, f.openAt = true, at
+	}
+	limit := defaultPageSize
+	if params.Limit != nil {
+		if *params.Limit < 1 {
+			middleware.ErrorHandler(w, http.StatusBadRequest, "limit must be positive", "VALIDATION_ERROR")
+			return
+		}
+		limit = min(*params.Limit, maxPageSize)
+	}
+	radius := float64(defaultRadiusMeters)
+	if params.Radius != nil {
+		radius = *params.Radius
+	}
+	if radius <= 0 {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "radius must be positive", "VALIDATION_ERROR")
+		return
+	}
+	radius = math.Min(radius, maxRadiusMeters)
+	lat, lon := params.Lat, params.Lon
+
+	var cur *discoverCursor
+	if params.Cursor != nil {
+		c, err := decodeCursor(*params.Cursor)
+		if err == nil {
+			// later pages measure from the pinned origin, not the caller's position
+			lat, lon, radius = c.Lat, c.Lon, c.Radius
+		}
+		if err != nil || c.Filter != f.fingerprint(radius) {
+			middleware.ErrorHandler(w, http.StatusBadRequest, "invalid cursor", "VALIDATION_ERROR")
+			return
+		}
+		cur = &c
+	}
+
+	var (
+		venues []db.Venue
+		dists  []float64
+		err    error
+	)
+	if lat != nil {
+		if !geo.ValidCoords(*lat, *lon) {
+			middleware.ErrorHandler(w, http.StatusBadRequest, "lat/lon out of range", "VALIDATION_ERROR")
+			return
+		}
+		venues, dists, err = s.catalog.nearby(r.Context(), *lat, *lon, radius)
+	} else {
+		venues, err = s.catalog.all(r.Context())
+	}
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	excluded := map[string]bool{}
+	if claims != nil {
+		ids, err := s.interactions.InteractedVenueIDs(r.Context(), claims.Sub)
+		if err != nil {
+			middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+			return
+		}
+		for _, id := range ids {
+			excluded[id] = true
+		}
+	}
+
+	liveAt := at
+	if cur != nil && cur.At != 0 {
+		liveAt = time.Unix(cur.At, 0)
+	}
+	live, err := s.liveEvents(r.Context(), liveAt)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+
+	cands := make([]candidate, 0, len(venues))
+	for i, v := range venues {
+		if excluded[v.ID] {
+			continue
+		}
+		score, _, known := s.vibeAgg.Live(v.ID, now)
+		c := candidate{venue: v, band: vibeBand(score, known), live: live[v.ID]}
+		c.status, c.known = hours.At(v.Hours, at)
+		switch {
+		case dists != nil:
+			c.dist = &dists[i]
+			c.rank = dists[i]
+			if c.live != nil {
+				c.rank *= liveEventBoost
+			}
+		case c.live == nil:
+			c.rank = 1
+		}
+		c.rank = math.Round(c.rank*1000) / 1000
+		cands = append(cands, c)
+	}
+	sort.SliceStable(cands, func(i, j int) bool {
+		if cands[i].rank != cands[j].rank {
+			return cands[i].rank < cands[j].rank
+		}
+		return cands[i].venue.ID < cands[j].venue.ID
+	})
+
+	countImpression := s.impressionCounter(r)
+	items := make([]venueView, 0, limit)
+	var (
+		next *string
+		last candidate
+	)
+	for _, c := range cands {
+		if cat, price, vibe, open := f.matches(c); !(cat && price && vibe && open) || !after(c, cur) {
+			continue
+		}
+		if len(items) == limit {
+			nc := discoverCursor{Lat: lat, Lon: lon, Radius: radius, Rank: last.rank, ID: last.venue.ID, Filter: f.fingerprint(radius), At: liveAt.Unix()}
+			token := nc.encode()
+			next = &token
+			break
+		}
+		view := toView(c.venue).withStatus(c.status, c.known)
+		if c.dist != nil {
+			d := math.Round(*c.dist)
+			view.Distance = &d
+		}
+		view.HappeningNow = c.live
+		items = append(items, view)
+		countImpression(c.venue.ID)
+		last = c
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"items": items, "nextCursor": next, "facets": countFacets(f, cands)})
+}
+
+type venueCoords struct {
+	ID    string  `json:"id"`
+	Title string  `json:"title"`
+	Lat   float64 `json:"lat"`
+	Lng   float64 `json:"lng"`
+}
+
+// GET /internal/venues/coords
+// Every active venue's position in one unpaged list, for services that
+// place venues on a map (the BFF's live vibe feed). Nothing is counted as an
+// impression.
+func (s *serverImpl) listVenueCoords(w http.ResponseWriter, r *http.Request) {
+	if !s.catalogTokens.Check(w, r) {
+		return
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	venues, err := s.catalog.all(r.Context())
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	items := make([]venueCoords, len(venues))
+	for i, v := range venues {
+		items[i] = venueCoords{ID: v.ID, Title: toView(v).Title, Lat: v.Lat, Lng: v.Lon}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"items": items})
+}
@@ -0,0 +1,576 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"bytspot/services/venue-service/internal/api"
+	"bytspot/services/venue-service/internal/db"
+	"bytspot/services/venue-service/internal/events"
+	"bytspot/services/venue-service/internal/venues"
+	"bytspot/shared/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	maxEventTitleLen       = 120
+	maxEventDescriptionLen = 2000
+	maxEventTags           = 10
+	maxEventTagLen         = 40
+	maxEventDuration       = 7 * 24 * time.Hour
+	// GET /events looks a day ahead by default; a venue's own list a month.
+	defaultEventsWindow      = 24 * time.Hour
+	maxEventsWindow          = 31 * 24 * time.Hour
+	defaultVenueEventsWindow = 30 * 24 * time.Hour
+	maxVenueEventsWindow     = 92 * 24 * time.Hour
+	defaultEventsLimit       = 50
+	maxEventsLimit           = 200
+	// calendarHistory keeps recently finished series in the iCalendar feed so
+	// subscribed calendars don't drop last week's events.
+	calendarHistory = 30 * 24 * time.Hour
+)
+
+var eventKinds = map[db.EventKind]bool{db.EventKindEvent: true, db.EventKindSpecial: true}
+
+var eventStatuses = map[db.EventStatus]bool{db.EventScheduled: true, db.EventCancelled: true}
+
+// eventInput is a partial event as sent to the admin API; nil fields are
+// left as they are.
+type eventInput struct {
+	Kind           *db.EventKind   `json:"kind"`
+	Title          *string         `json:"title"`
+	Description    *string         `json:"description"`
+	StartsAt       *time.Time      `json:"startsAt"`
+	EndsAt         *time.Time      `json:"endsAt"`
+	Recurrence     *string         `json:"recurrence"`
+	TicketURL      *string         `json:"ticketUrl"`
+	ReservationURL *string         `json:"reservationUrl"`
+	Tags           *[]string       `json:"tags"`
+	Status         *db.EventStatus `json:"status"`
+}
+
+func (in eventInput) apply(e *db.VenueEvent) {
+	if in.Kind != nil {
+		e.Kind = *in.Kind
+	}
+	if in.Title != nil {
+		e.Title = strings.TrimSpace(*in.Title)
+	}
+	if in.Description != nil {
+		e.Description = strings.TrimSpace(*in.Description)
+	}
+	if in.StartsAt != nil {
+		e.StartsAt = *in.StartsAt
+	}
+	if in.EndsAt != nil {
+		e.EndsAt = *in.EndsAt
+	}
+	if in.Recurrence != nil {
+		e.Recurrence = strings.TrimSpace(*in.Recurrence)
+	}
+	if in.TicketURL != nil {
+		e.TicketURL = strings.TrimSpace(*in.TicketURL)
+	}
+	if in.ReservationURL != nil {
+		e.ReservationURL = strings.TrimSpace(*in.ReservationURL)
+	}
+	if in.Tags != nil {
+		e.Tags = venues.NormalizeTags(*in.Tags)
+	}
+	if in.Status != nil {
+		e.Status = *in.Status
+	}
+}
+
+// prepareEvent validates e for venue v. The schedule follows the venue's
+// timezone; the recurrence is stored in its canonical form along with when
+// the series ends.
+func prepareEvent(e *db.VenueEvent, v *db.Venue) error {
+	if !eventKinds[e.Kind] {
+		return errors.New("kind must be event or special")
+	}
+	if n := len([]rune(e.Title)); n == 0 || n > maxEventTitleLen {
+		return fmt.Errorf("title must be 1-%d characters", maxEventTitleLen)
+	}
+	if len([]rune(e.Description)) > maxEventDescriptionLen {
+		return fmt.Errorf("description may be at most %d characters", maxEventDescriptionLen)
+	}
+	if e.StartsAt.IsZero() || e.EndsAt.IsZero() {
+		return errors.New("startsAt and endsAt are required")
+	}
+	if d := e.EndsAt.Sub(e.StartsAt); d <= 0 || d > maxEventDuration {
+		return errors.New("endsAt must be after startsAt and within 7 days of it")
+	}
+	for name, u := range map[string]string{"ticketUrl": e.TicketURL, "reservationUrl": e.ReservationURL} {
+		if u == "" {
+			continue
+		}
+		if p, err := url.Parse(u); err != nil || (p.Scheme != "http" && p.Scheme != "https") || p.Host == "" {
+			return fmt.Errorf("%s must be an http(s) URL", name)
+		}
+	}
+	if len(e.Tags) > maxEventTags {
+		return fmt.Errorf("at most %d tags", maxEventTags)
+	}
+	for _, t := range e.Tags {
+		if len([]rune(t)) > maxEventTagLen {
+			return fmt.Errorf("tags may be at most %d characters", maxEventTagLen)
+		}
+	}
+	if !eventStatuses[e.Status] {
+		return errors.New("status must be scheduled or cancelled")
+	}
+
+	loc := time.UTC
+	if tz := v.Hours.Timezone; tz != "" {
+		if l, err := time.LoadLocation(tz); err == nil {
+			loc = l
+		}
+	}
+	e.Timezone = loc.String()
+	e.StartsAt, e.EndsAt = e.StartsAt.UTC(), e.EndsAt.UTC()
+	series := events.Series{Start: e.StartsAt.In(loc), Duration: e.EndsAt.Sub(e.StartsAt)}
+	if e.Recurrence != "" {
+		rule, err := events.ParseRule(e.Recurrence, loc)
+		if err != nil {
+			return fmt.Errorf("recurrence: %v", err)
+		}
+		series.Rule = rule
+		e.Recurrence = rule.String()
+	}
+	e.SeriesEndsAt = nil
+	if end, ok := series.End(); ok {
+		end = end.UTC()
+		e.SeriesEndsAt = &end
+	}
+	if e.Tags == nil {
+		e.Tags = []string{}
+	}
+	return nil
+}
+
+// eventSeries is the schedule of a stored event. Stored rules were checked
+// on write; one that no longer parses is treated as a one-off.
+func eventSeries(e db.VenueEvent) events.Series {
+	loc, err := time.LoadLocation(e.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	s := events.Series{Start: e.StartsAt.In(loc), Duration: e.EndsAt.Sub(e.StartsAt)}
+	if e.Recurrence != "" {
+		if rule, err := events.ParseRule(e.Recurrence, loc); err == nil {
+			s.Rule = rule
+		} else {
+			log.Printf("event %d: stored recurrence %q: %v", e.ID, e.Recurrence, err)
+		}
+	}
+	return s
+}
+
+// occurrenceView is one occurrence of an event as the public API returns it.
+type occurrenceView struct {
+	EventID        int64        `json:"eventId"`
+	VenueID        string       `json:"venueId"`
+	Kind           db.EventKind `json:"kind"`
+	Title          string       `json:"title"`
+	Description    string       `json:"description"`
+	StartsAt       time.Time    `json:"startsAt"`
+	EndsAt         time.Time    `json:"endsAt"`
+	Timezone       string       `json:"timezone"`
+	Recurrence     string       `json:"recurrence,omitempty"`
+	TicketURL      string       `json:"ticketUrl,omitempty"`
+	ReservationURL string       `json:"reservationUrl,omitempty"`
+	Tags           []string     `json:"tags"`
+	Venue          *venueView   `json:"venue,omitempty"` // GET /events only
+}
+
+// occurrences expands the series into at most limit occurrences overlapping
+// [from, to), by start time and then event id.
+func occurrences(evs []db.VenueEvent, from, to time.Time, limit int) []occurrenceView {
+	out := []occurrenceView{}
+	for _, e := range evs {
+		for _, o := range eventSeries(e).Between(from, to, limit) {
+			out = append(out, occurrenceView{
+				EventID: e.ID, VenueID: e.VenueID, Kind: e.Kind, Title: e.Title, Description: e.Description,
+				StartsAt: o.Start, EndsAt: o.End, Timezone: e.Timezone, Recurrence: e.Recurrence,
+				TicketURL: e.TicketURL, ReservationURL: e.ReservationURL, Tags: nonNilTags(e.Tags),
+			})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if !out[i].StartsAt.Equal(out[j].StartsAt) {
+			return out[i].StartsAt.Before(out[j].StartsAt)
+		}
+		return out[i].EventID < out[j].EventID
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+func nonNilTags(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+// eventWindow resolves from/to/limit, writing 400 when they are invalid.
+func eventWindow(w http.ResponseWriter, now time.Time, from, to *time.Time, limit *int, def, max time.Duration) (time.Time, time.Time, int, bool) {
+	start := now
+	if from != nil {
+		start = *from
+	}
+	end := start.Add(def)
+	if to != nil {
+		end = *to
+	}
+	if !end.After(start) {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "to must be after from", "VALIDATION_ERROR")
+		return start, end, 0, false
+	}
+	if end.Sub(start) > max {
+		middleware.ErrorHandler(w, http.StatusBadRequest, fmt.Sprintf("from-to may span at most %d days", int(max.Hours()/24)), "VALIDATION_ERROR")
+		return start, end, 0, false
+	}
+	n := defaultEventsLimit
+	if limit != nil {
+		if *limit < 1 {
+			middleware.ErrorHandler(w, http.StatusBadRequest, "limit must be positive", "VALIDATION_ERROR")
+			return start, end, 0, false
+		}
+		n = min(*limit, maxEventsLimit)
+	}
+	return start, end, n, true
+}
+
+// GET /events?from&to&bbox&kind&tag&limit
+// Occurrences of scheduled events at active venues between from (default
+// now) and to (default a day later), optionally inside a bbox
+// (minLon,minLat,maxLon,maxLat), soonest first, each with its venue.
+func (s *serverImpl) GetEvents(w http.ResponseWriter, r *http.Request, params api.GetEventsParams) {
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	now := s.now()
+	from, to, limit, ok := eventWindow(w, now, params.From, params.To, params.Limit, defaultEventsWindow, maxEventsWindow)
+	if !ok {
+		return
+	}
+	var kind db.EventKind
+	if params.Kind != nil {
+		if kind = db.EventKind(*params.Kind); !eventKinds[kind] {
+			middleware.ErrorHandler(w, http.StatusBadRequest, "kind must be event or special", "VALIDATION_ERROR")
+			return
+		}
+	}
+	var tag string
+	if params.Tag != nil {
+		tag = strings.ToLower(strings.TrimSpace(*params.Tag))
+	}
+	f := db.EventFilter{From: from, To: to}
+	if params.Bbox != nil {
+		box, err := parseBBox(*params.Bbox)
+		if err != nil {
+			middleware.ErrorHandler(w, http.StatusBadRequest, "bbox must be minLon,minLat,maxLon,maxLat", "VALIDATION_ERROR")
+			return
+		}
+		ids, err := s.catalog.inBox(r.Context(), box)
+		if err != nil {
+			middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+			return
+		}
+		f.VenueIDs = append([]string{}, ids...) // empty, not nil: nothing is in the box
+	} else if err := s.catalog.load(r.Context()); err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	var evs []db.VenueEvent
+	if f.VenueIDs == nil || len(f.VenueIDs) > 0 {
+		all, err := s.events.ListVenueEvents(r.Context(), f)
+		if err != nil {
+			middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+			return
+		}
+		for _, e := range all {
+			if _, active := s.catalog.get(e.VenueID); !active || (kind != "" && e.Kind != kind) || (tag != "" && !hasTag(e.Tags, tag)) {
+				continue
+			}
+			evs = append(evs, e)
+		}
+	}
+	items := occurrences(evs, from, to, limit)
+	views := map[string]*venueView{}
+	for i, o := range items {
+		view, ok := views[o.VenueID]
+		if !ok {
+			v, _ := s.catalog.get(o.VenueID)
+			pv := publicView(v, now)
+			view = &pv
+			views[o.VenueID] = view
+		}
+		items[i].Venue = view
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"items": items})
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// activeVenue loads venue id, answering 404 unless it is active.
+func (s *serverImpl) activeVenue(w http.ResponseWriter, r *http.Request, id string) (*db.Venue, bool) {
+	v, err := s.venues.GetVenue(r.Context(), id)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return nil, false
+	}
+	if v == nil || v.Status != db.VenueActive {
+		middleware.ErrorHandler(w, http.StatusNotFound, "venue not found", "NOT_FOUND")
+		return nil, false
+	}
+	return v, true
+}
+
+// GET /venues/{id}/events?from&to&limit
+// The venue's scheduled occurrences, by default for the next 30 days.
+func (s *serverImpl) GetVenuesIdEvents(w http.ResponseWriter, r *http.Request, id string, params api.GetVenuesIdEventsParams) {
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	from, to, limit, ok := eventWindow(w, s.now(), params.From, params.To, params.Limit, defaultVenueEventsWindow, maxVenueEventsWindow)
+	if !ok {
+		return
+	}
+	if _, ok := s.activeVenue(w, r, id); !ok {
+		return
+	}
+	evs, err := s.events.ListVenueEvents(r.Context(), db.EventFilter{VenueIDs: []string{id}, From: from, To: to})
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"items": occurrences(evs, from, to, limit)})
+}
+
+// GET /venues/{id}/calendar.ics
+// An iCalendar feed of the venue's events for calendar subscriptions:
+// upcoming and recently finished series, with cancelled ones marked so
+// subscribers remove them.
+func (s *serverImpl) GetVenuesIdCalendarIcs(w http.ResponseWriter, r *http.Request, id string) {
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	v, ok := s.activeVenue(w, r, id)
+	if !ok {
+		return
+	}
+	now := s.now()
+	evs, err := s.events.ListVenueEvents(r.Context(), db.EventFilter{VenueIDs: []string{id}, From: now.Add(-calendarHistory), IncludeCancelled: true})
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	cal := make([]events.CalendarEvent, 0, len(evs))
+	for _, e := range evs {
+		link := e.TicketURL
+		if link == "" {
+			link = e.ReservationURL
+		}
+		cal = append(cal, events.CalendarEvent{
+			UID:         fmt.Sprintf("event-%d@bytspot", e.ID),
+			Summary:     e.Title,
+			Description: e.Description,
+			URL:         link,
+			Categories:  append([]string{string(e.Kind)}, e.Tags...),
+			Series:      eventSeries(e),
+			Cancelled:   e.Status == db.EventCancelled,
+			Updated:     e.UpdatedAt,
+		})
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	if err := events.WriteCalendar(w, v.Name, cal, now); err != nil {
+		log.Printf("calendar for %s: %v", id, err)
+	}
+}
+
+// liveEvents returns, by venue, the scheduled event running at t; a venue
+// with several gets the one that started last.
+func (s *serverImpl) liveEvents(ctx context.Context, t time.Time) (map[string]*happeningView, error) {
+	evs, err := s.events.ListVenueEvents(ctx, db.EventFilter{From: t, To: t.Add(time.Nanosecond)})
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]*happeningView{}
+	started := map[string]time.Time{}
+	for _, e := range evs {
+		o, ok := eventSeries(e).At(t)
+		if !ok {
+			continue
+		}
+		if prev, seen := started[e.VenueID]; seen && !o.Start.After(prev) {
+			continue
+		}
+		started[e.VenueID] = o.Start
+		out[e.VenueID] = &happeningView{EventID: e.ID, Kind: e.Kind, Title: e.Title, EndsAt: o.End}
+	}
+	return out, nil
+}
+
+// venueEvent loads the event at {eventId}, which must belong to v.
+func (s *serverImpl) venueEvent(w http.ResponseWriter, r *http.Request, v *db.Venue) (*db.VenueEvent, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "eventId"), 10, 64)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusNotFound, "event not found", "NOT_FOUND")
+		return nil, false
+	}
+	e, err := s.events.GetVenueEvent(r.Context(), id)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return nil, false
+	}
+	if e == nil || e.VenueID != v.ID {
+		middleware.ErrorHandler(w, http.StatusNotFound, "event not found", "NOT_FOUND")
+		return nil, false
+	}
+	return e, true
+}
+
+func writeEvent(w http.ResponseWriter, status int, e *db.VenueEvent) {
+	e.Tags = nonNilTags(e.Tags)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(e)
+}
+
+// GET /admin/venues/{id}/events
+// Every series of the venue, past and cancelled ones included.
+func (s *serverImpl) listAdminEvents(w http.ResponseWriter, r *http.Request) {
+	claims, ok := s.venueEditor(w, r)
+	if !ok {
+		return
+	}
+	v, ok := s.editableVenue(w, r, claims)
+	if !ok {
+		return
+	}
+	evs, err := s.events.ListVenueEvents(r.Context(), db.EventFilter{VenueIDs: []string{v.ID}, IncludeCancelled: true})
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	items := make([]db.VenueEvent, len(evs))
+	for i, e := range evs {
+		e.Tags = nonNilTags(e.Tags)
+		items[i] = e
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"items": items})
+}
+
+// POST /admin/venues/{id}/events
+func (s *serverImpl) createAdminEvent(w http.ResponseWriter, r *http.Request) {
+	claims, ok := s.venueEditor(w, r)
+	if !ok {
+		return
+	}
+	v, ok := s.editableVenue(w, r, claims)
+	if !ok {
+		return
+	}
+	var in eventInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "invalid json", "INVALID_JSON")
+		return
+	}
+	e := db.VenueEvent{VenueID: v.ID, Kind: db.EventKindEvent, Status: db.EventScheduled, CreatedBy: claims.Sub}
+	in.apply(&e)
+	if err := prepareEvent(&e, v); err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+		return
+	}
+	if err := s.events.CreateVenueEvent(r.Context(), &e); err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	writeEvent(w, http.StatusCreated, &e)
+}
+
+// PATCH /admin/venues/{id}/events/{eventId}
+// Setting status to cancelled keeps the event in the calendar feed, marked
+// as cancelled; DELETE removes it.
+func (s *serverImpl) patchAdminEvent(w http.ResponseWriter, r *http.Request) {
+	claims, ok := s.venueEditor(w, r)
+	if !ok {
+		return
+	}
+	v, ok := s.editableVenue(w, r, claims)
+	if !ok {
+		return
+	}
+	e, ok := s.venueEvent(w, r, v)
+	if !ok {
+		return
+	}
+	var in eventInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "invalid json", "INVALID_JSON")
+		return
+	}
+	in.apply(e)
+	if err := prepareEvent(e, v); err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+		return
+	}
+	if err := s.events.UpdateVenueEvent(r.Context(), e); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			middleware.ErrorHandler(w, http.StatusNotFound, "event not found", "NOT_FOUND")
+			return
+		}
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	writeEvent(w, http.StatusOK, e)
+}
+
+// DELETE /admin/venues/{id}/events/{eventId}
+func (s *serverImpl) deleteAdminEvent(w http.ResponseWriter, r *http.Request) {
+	claims, ok := s.venueEditor(w, r)
+	if !ok {
+		return
+	}
+	v, ok := s.editableVenue(w, r, claims)
+	if !ok {
+		return
+	}
+	e, ok := s.venueEvent(w, r, v)
+	if !ok {
+		return
+	}
+	if _, err := s.events.DeleteVenueEvent(r.Context(), e.ID); err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"bytspot/shared/servicetoken"
+)
+
+// friendGraph answers who may hear about a user's check-ins. auth-service
+// owns users and the friend graph.
+type friendGraph interface {
+	// ShareTargets returns the user's profile name (empty if unset) and the
+	// ids that are the user's friends.
+	ShareTargets(ctx context.Context, userID string, ids []string) (name string, friends []string, err error)
+}
+
+// noFriends shares with nobody: without auth-service no recipient can be
+// verified.
+type noFriends struct{}
+
+func (noFriends) ShareTargets(context.Context, string, []string) (string, []string, error) {
+	return "", nil, nil
+}
+
+// httpFriendGraph asks auth-service's /internal/users/{id}/friends/check.
+type httpFriendGraph struct {
+	base   string
+	token  string // X-Service-Token, one of auth-service's AUTH_SERVICE_TOKENS
+	client *http.Client
+}
+
+func friendGraphFromEnv() friendGraph {
+	base := os.Getenv("AUTH_SERVICE_URL")
+	if base == "" {
+		return noFriends{}
+	}
+	return &httpFriendGraph{base: strings.TrimRight(base, "/"), token: os.Getenv("AUTH_SERVICE_TOKEN"), client: &http.Client{Timeout: 3 * time.Second}}
+}
+
+func (g *httpFriendGraph) ShareTargets(ctx context.Context, userID string, ids []string) (string, []string, error) {
+	b, _ := json.Marshal(map[string]any{"ids": ids})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.base+"/internal/users/"+url.PathEscape(userID)+"/friends/check", bytes.NewReader(b))
+	if err != nil {
+		return "", nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	servicetoken.Set(req, g.token)
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("auth-service friends check: %s", resp.Status)
+	}
+	var out struct {
+		Name      *string  `json:"name"`
+		FriendIDs []string `json:"friendIds"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", nil, err
+	}
+	name := ""
+	if out.Name != nil {
+		name = strings.TrimSpace(*out.Name)
+	}
+	return name, out.FriendIDs, nil
+}
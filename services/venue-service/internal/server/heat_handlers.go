@@ -0,0 +1,248 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"bytspot/services/venue-service/internal/api"
+	"bytspot/services/venue-service/internal/db"
+	"bytspot/services/venue-service/internal/geo"
+	"bytspot/services/venue-service/internal/heat"
+	"bytspot/shared/middleware"
+)
+
+const (
+	// activityRetention is how long ingestion signals are kept; they have
+	// decayed to nothing long before.
+	activityRetention = 24 * time.Hour
+	activityHalfLife  = 20 * time.Minute
+	maxActivityBatch  = 500
+	// heatCellTTL is how long a computed heat cell is served from cache.
+	heatCellTTL          = 30 * time.Second
+	defaultHeatPrecision = 6
+	minHeatPrecision     = 4
+	maxHeatCells         = 1024
+)
+
+// activityCutoff is the oldest signal still inside the retention window.
+func (s *serverImpl) activityCutoff() time.Time { return s.now().Add(-activityRetention) }
+
+// rebuildActivity reloads the in-process activity state from the store so it
+// also reflects signals other instances accepted.
+func (s *serverImpl) rebuildActivity(ctx context.Context) error {
+	signals, err := s.activity.ListActivitySignals(ctx, s.activityCutoff())
+	if err != nil {
+		return err
+	}
+	out := make([]heat.Signal, len(signals))
+	for i, a := range signals {
+		out[i] = heat.Signal{Cell: a.Geohash, Devices: a.Devices, Intensity: a.Intensity, At: a.ObservedAt}
+	}
+	s.heatActivity.Rebuild(out)
+	return nil
+}
+
+// purgeActivity deletes signals that fell out of the retention window.
+func (s *serverImpl) purgeActivity(ctx context.Context) (int64, error) {
+	return s.activity.PurgeActivitySignals(ctx, s.activityCutoff())
+}
+
+// POST /signals/activity
+// Per-cell activity aggregates from the ingestion pipeline, authenticated
+// with its own service token; the vibe report keys ship in the app and don't
+// count.
+func (s *serverImpl) PostSignalsActivity(w http.ResponseWriter, r *http.Request) {
+	if !s.signalTokens.Check(w, r) {
+		return
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	var req struct {
+		Signals []struct {
+			Geohash    string   `json:"geohash7"`
+			Devices    *int     `json:"devices"`
+			Intensity  *float64 `json:"intensity"`
+			ObservedAt string   `json:"observedAt"`
+		} `json:"signals"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "invalid json", "INVALID_JSON")
+		return
+	}
+	if len(req.Signals) == 0 || len(req.Signals) > maxActivityBatch {
+		middleware.ErrorHandler(w, http.StatusBadRequest, fmt.Sprintf("signals must have 1 to %d entries", maxActivityBatch), "VALIDATION_ERROR")
+		return
+	}
+	now := s.now()
+	signals := make([]db.ActivitySignal, len(req.Signals))
+	for i, in := range req.Signals {
+		invalid := func(msg string) {
+			middleware.ErrorHandler(w, http.StatusBadRequest, fmt.Sprintf("signals[%d]: %s", i, msg), "VALIDATION_ERROR")
+		}
+		if _, ok := geo.Decode(in.Geohash); !ok || len(in.Geohash) != heat.SignalPrecision {
+			invalid("geohash7 must be a 7-character geohash")
+			return
+		}
+		if in.Devices == nil || *in.Devices < 0 {
+			invalid("devices must be a non-negative integer")
+			return
+		}
+		if in.Intensity == nil || *in.Intensity < 0 || *in.Intensity > 1 {
+			invalid("intensity must be between 0 and 1")
+			return
+		}
+		observedAt := now
+		if in.ObservedAt != "" {
+			t, err := time.Parse(time.RFC3339, in.ObservedAt)
+			if err != nil {
+				invalid("observedAt must be RFC3339")
+				return
+			}
+			if t.Before(s.activityCutoff()) {
+				invalid("observedAt is outside the retention window")
+				return
+			}
+			if t.Before(now.Add(maxVibeClockSkew)) {
+				observedAt = t
+			}
+		}
+		signals[i] = db.ActivitySignal{Geohash: in.Geohash, Devices: *in.Devices, Intensity: *in.Intensity, ObservedAt: observedAt}
+	}
+	if err := s.activity.InsertActivitySignals(r.Context(), signals); err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	for _, a := range signals {
+		s.heatActivity.Add(heat.Signal{Cell: a.Geohash, Devices: a.Devices, Intensity: a.Intensity, At: a.ObservedAt})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]any{"accepted": len(signals)})
+}
+
+// computeHeat builds the given cells (all at precision) from the live vibe of
+// the venues inside them and the ingestion activity.
+func (s *serverImpl) computeHeat(ctx context.Context, cells map[string]bool, precision int, now time.Time) (map[string]heat.Cell, error) {
+	out := make(map[string]heat.Cell, len(cells))
+	span := geo.Box{MinLat: 90, MinLon: 180, MaxLat: -90, MaxLon: -180}
+	for hash := range cells {
+		b, _ := geo.Decode(hash)
+		out[hash] = heat.Cell{
+			Geohash: hash,
+			Lat:     (b.MinLat + b.MaxLat) / 2,
+			Lon:     (b.MinLon + b.MaxLon) / 2,
+			Bounds:  [4]float64{b.MinLon, b.MinLat, b.MaxLon, b.MaxLat},
+		}
+		span.MinLat, span.MinLon = min(span.MinLat, b.MinLat), min(span.MinLon, b.MinLon)
+		span.MaxLat, span.MaxLon = max(span.MaxLat, b.MaxLat), max(span.MaxLon, b.MaxLon)
+	}
+	ids, err := s.catalog.inBox(ctx, span)
+	if err != nil {
+		return nil, err
+	}
+	type vibeSum struct{ ws, w float64 }
+	vibes := map[string]vibeSum{}
+	for _, id := range ids {
+		v, ok := s.catalog.get(id)
+		if !ok {
+			continue
+		}
+		hash := geo.Encode(v.Lat, v.Lon, precision)
+		c, ok := out[hash]
+		if !ok {
+			continue
+		}
+		c.Venues++
+		out[hash] = c
+		if score, weight, known := s.vibeAgg.Live(id, now); known {
+			vs := vibes[hash]
+			vs.ws += score * weight
+			vs.w += weight
+			vibes[hash] = vs
+		}
+	}
+	activity := s.heatActivity.Sum(cells, precision, now)
+	for hash, c := range out {
+		var weight float64
+		if vs, ok := vibes[hash]; ok {
+			avg := vs.ws / vs.w
+			c.Vibe, weight = &avg, vs.w
+		}
+		act := activity[hash]
+		c.Devices = act.Devices
+		c.Intensity = heat.Intensity(c.Vibe, weight, act)
+		out[hash] = c
+	}
+	return out, nil
+}
+
+// GET /vibe/heatmap?bbox=minLon,minLat,maxLon,maxLat&precision
+// Heat per geohash cell at the given precision (4-7) inside bbox, from the
+// live vibe of venues and ingestion activity signals. Each cell is cached
+// for heatCellTTL; only cells with some heat are returned.
+func (s *serverImpl) GetVibeHeatmap(w http.ResponseWriter, r *http.Request, params api.GetVibeHeatmapParams) {
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	if params.Bbox == nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "bbox is required", "VALIDATION_ERROR")
+		return
+	}
+	box, err := parseBBox(*params.Bbox)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "bbox must be minLon,minLat,maxLon,maxLat", "VALIDATION_ERROR")
+		return
+	}
+	precision := defaultHeatPrecision
+	if params.Precision != nil {
+		precision = *params.Precision
+		if precision < minHeatPrecision || precision > heat.SignalPrecision {
+			middleware.ErrorHandler(w, http.StatusBadRequest, fmt.Sprintf("precision must be between %d and %d", minHeatPrecision, heat.SignalPrecision), "VALIDATION_ERROR")
+			return
+		}
+	}
+	hashes, ok := geo.CellsInBox(box, precision, maxHeatCells)
+	if !ok {
+		middleware.ErrorHandler(w, http.StatusBadRequest, fmt.Sprintf("bbox covers more than %d cells at precision %d; zoom in or lower the precision", maxHeatCells, precision), "VALIDATION_ERROR")
+		return
+	}
+
+	now := s.now()
+	cells := make([]heat.Cell, 0, len(hashes))
+	missing := map[string]bool{}
+	for _, h := range hashes {
+		if c, ok := s.heatCache.Get(h, now); ok {
+			cells = append(cells, c)
+		} else {
+			missing[h] = true
+		}
+	}
+	if len(missing) > 0 {
+		computed, err := s.computeHeat(r.Context(), missing, precision, now)
+		if err != nil {
+			middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+			return
+		}
+		for _, c := range computed {
+			s.heatCache.Put(c, now)
+			cells = append(cells, c)
+		}
+	}
+	hot := cells[:0]
+	for _, c := range cells {
+		if c.Intensity > 0 {
+			hot = append(hot, c)
+		}
+	}
+	sort.Slice(hot, func(i, j int) bool { return hot[i].Geohash < hot[j].Geohash })
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(heatCellTTL.Seconds())))
+	json.NewEncoder(w).Encode(map[string]any{"precision": precision, "cells": hot})
+}
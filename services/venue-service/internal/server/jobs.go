@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"time"
+
+	"bytspot/shared/jwtauth"
+	"bytspot/shared/servicetoken"
+)
+
+const purgeInterval = time.Hour
+
+// idempotencyPrincipal scopes Idempotency-Keys to the calling service (by a
+// hash of its token) or the signed-in user, so a refreshed token still
+// replays. Anonymous callers get no namespace and so no idempotency; their
+// vibe reports dedupe on meta.idempotency_key instead.
+func idempotencyPrincipal(r *http.Request) string {
+	if tok := r.Header.Get(servicetoken.Header); tok != "" {
+		sum := sha256.Sum256([]byte(tok))
+		return "service:" + hex.EncodeToString(sum[:])
+	}
+	if claims, err := jwtauth.Verify(jwtauth.BearerToken(r)); err == nil {
+		return "user:" + claims.Sub
+	}
+	return ""
+}
+
+// runPurgeJobs enforces retention now and then every interval until ctx is
+// done: expired vibe reports, activity signals, idempotency records and
+// signature nonces are deleted and the vibe and activity state is resynced
+// from the store.
+func (s *serverImpl) runPurgeJobs(ctx context.Context, every time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		if n, err := s.purgeVibes(ctx); err != nil {
+			log.Printf("vibe purge failed: %v", err)
+		} else if n > 0 {
+			log.Printf("vibe purge removed %d reports older than %s", n, s.vibeRetention)
+		}
+		if err := s.rebuildVibes(ctx); err != nil {
+			log.Printf("vibe aggregate rebuild failed: %v", err)
+		}
+		if _, err := s.purgeActivity(ctx); err != nil {
+			log.Printf("activity purge failed: %v", err)
+		}
+		if err := s.rebuildActivity(ctx); err != nil {
+			log.Printf("activity rebuild failed: %v", err)
+		}
+		if _, err := s.idempotency.PurgeIdempotentRequests(ctx, s.now()); err != nil {
+			log.Printf("idempotency purge failed: %v", err)
+		}
+		if _, err := s.nonces.PurgeNonces(ctx, s.now()); err != nil {
+			log.Printf("nonce purge failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// runAnalyticsJobs keeps the dashboard rollups current until ctx is done:
+// counted impressions are flushed and the most recent days recomputed every
+// interval. The first run backfills what retention still allows.
+func (s *serverImpl) runAnalyticsJobs(ctx context.Context, every time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for days := s.backfillDays(); ; days = rollupDays {
+		if err := s.flushImpressions(ctx); err != nil {
+			log.Printf("impression flush failed: %v", err)
+		}
+		if err := s.rollupAnalytics(ctx, days); err != nil {
+			log.Printf("analytics rollup failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"net/http"
+
+	"bytspot/shared/jwtauth"
+	"bytspot/shared/middleware"
+)
+
+// authenticate requires a valid bearer token, writing 401 otherwise.
+func (s *serverImpl) authenticate(w http.ResponseWriter, r *http.Request) (*jwtauth.Claims, bool) {
+	raw := jwtauth.BearerToken(r)
+	if raw == "" {
+		middleware.ErrorHandler(w, http.StatusUnauthorized, "missing bearer token", "UNAUTHORIZED")
+		return nil, false
+	}
+	claims, err := jwtauth.Verify(raw)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusUnauthorized, "invalid token", "UNAUTHORIZED")
+		return nil, false
+	}
+	return claims, true
+}
+
+// requireFullAccess is authenticate for writes: impersonation tokens let
+// support staff see what the user sees, not act for them.
+func (s *serverImpl) requireFullAccess(w http.ResponseWriter, r *http.Request) (*jwtauth.Claims, bool) {
+	claims, ok := s.authenticate(w, r)
+	if !ok || !fullAccess(w, claims) {
+		return nil, false
+	}
+	return claims, true
+}
+
+// fullAccess writes 403 for impersonation tokens; nil claims (anonymous
+// callers) pass.
+func fullAccess(w http.ResponseWriter, claims *jwtauth.Claims) bool {
+	if claims != nil && claims.Impersonating() {
+		middleware.ErrorHandler(w, http.StatusForbidden, "not allowed while impersonating", "IMPERSONATION_FORBIDDEN")
+		return false
+	}
+	return true
+}
+
+// optionalAuth is authenticate for endpoints that also serve anonymous
+// callers: no header yields nil claims, a bad token is still a 401.
+func (s *serverImpl) optionalAuth(w http.ResponseWriter, r *http.Request) (*jwtauth.Claims, bool) {
+	if r.Header.Get("Authorization") == "" {
+		return nil, true
+	}
+	return s.authenticate(w, r)
+}
+
+func hasRole(claims *jwtauth.Claims, role string) bool {
+	for _, r := range claims.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
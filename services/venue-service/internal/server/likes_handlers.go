@@ -0,0 +1,154 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"bytspot/services/venue-service/internal/achieve"
+	"bytspot/services/venue-service/internal/db"
+	"bytspot/shared/middleware"
+)
+
+const (
+	defaultLikesLimit = 50
+	maxLikesLimit     = 200
+)
+
+// swipe records kind for the caller on an active venue. Repeating the same
+// swipe is a no-op, so clients can retry freely.
+func (s *serverImpl) swipe(w http.ResponseWriter, r *http.Request, id string, kind db.InteractionKind) {
+	claims, ok := s.requireFullAccess(w, r)
+	if !ok {
+		return
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	v, err := s.venues.GetVenue(r.Context(), id)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	if v == nil || v.Status != db.VenueActive {
+		middleware.ErrorHandler(w, http.StatusNotFound, "venue not found", "NOT_FOUND")
+		return
+	}
+	changed, err := s.interactions.UpsertInteraction(r.Context(), claims.Sub, id, kind)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	if changed && kind == db.InteractionLike {
+		// one event per venue, so unlike and like again doesn't count twice
+		s.trackAchievement(r.Context(), venueAchievementEvent(claims.Sub, "like:"+id, achieve.EventLike, v, s.now()))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /venues/{id}/like
+func (s *serverImpl) PostVenuesIdLike(w http.ResponseWriter, r *http.Request, id string) {
+	s.swipe(w, r, id, db.InteractionLike)
+}
+
+// POST /venues/{id}/skip
+func (s *serverImpl) PostVenuesIdSkip(w http.ResponseWriter, r *http.Request, id string) {
+	s.swipe(w, r, id, db.InteractionSkip)
+}
+
+// DELETE /venues/{id}/like (unlike; 204 even if there was no like)
+func (s *serverImpl) DeleteVenuesIdLike(w http.ResponseWriter, r *http.Request, id string) {
+	claims, ok := s.requireFullAccess(w, r)
+	if !ok {
+		return
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	if _, err := s.interactions.DeleteLike(r.Context(), claims.Sub, id); err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /users/me/likes?limit=
+func (s *serverImpl) GetUsersMeLikes(w http.ResponseWriter, r *http.Request) {
+	claims, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	limit := defaultLikesLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if n, e := strconv.Atoi(v); e == nil && n > 0 {
+			limit = min(n, maxLikesLimit)
+		}
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	likes, err := s.interactions.ListLikes(r.Context(), claims.Sub, limit)
+	if err == nil {
+		err = s.catalog.load(r.Context())
+	}
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	type likedVenue struct {
+		venueView
+		LikedAt string `json:"likedAt"`
+	}
+	items := make([]likedVenue, 0, len(likes))
+	now := s.now()
+	for _, l := range likes {
+		v, ok := s.catalog.get(l.VenueID)
+		if !ok { // no longer active: only these hit the database
+			got, err := s.venues.GetVenue(r.Context(), l.VenueID)
+			if err != nil {
+				middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+				return
+			}
+			if got == nil {
+				continue
+			}
+			v = *got
+		}
+		items = append(items, likedVenue{venueView: publicView(v, now), LikedAt: l.CreatedAt.UTC().Format(time.RFC3339)})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"items": items})
+}
+
+// GET /admin/analytics/summary (admin) -> { venues, likes }
+// The BFF merges this with its user count for the admin dashboard.
+func (s *serverImpl) GetAdminAnalyticsSummary(w http.ResponseWriter, r *http.Request) {
+	claims, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	if !hasRole(claims, "admin") {
+		middleware.ErrorHandler(w, http.StatusForbidden, "admin role required", "FORBIDDEN")
+		return
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	venues, err := s.venues.CountVenues(r.Context())
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	likes, err := s.interactions.TotalLikes(r.Context())
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"venues": venues, "likes": likes})
+}
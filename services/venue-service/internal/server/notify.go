@@ -0,0 +1,79 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"bytspot/services/venue-service/internal/achieve"
+	"bytspot/services/venue-service/internal/db"
+	"bytspot/shared/servicetoken"
+)
+
+// userNotifier tells friends a user checked in and users that they earned an
+// achievement.
+type userNotifier interface {
+	CheckedIn(c db.Checkin, venueName, friendName string)
+	AchievementEarned(userID string, d achieve.Definition)
+}
+
+type noopNotifier struct{}
+
+func (noopNotifier) CheckedIn(db.Checkin, string, string)         {}
+func (noopNotifier) AchievementEarned(string, achieve.Definition) {}
+
+// httpNotifier posts friend.presence and achievement.earned events to
+// notification-service.
+type httpNotifier struct {
+	url    string
+	token  string // X-Service-Token, one of notification-service's NOTIFY_SERVICE_TOKENS
+	client *http.Client
+}
+
+func notifierFromEnv() userNotifier {
+	base := os.Getenv("NOTIFY_SERVICE_URL")
+	if base == "" {
+		return noopNotifier{}
+	}
+	return &httpNotifier{url: strings.TrimRight(base, "/") + "/events", token: os.Getenv("NOTIFY_SERVICE_TOKEN"), client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// CheckedIn is fire-and-forget: a slow push backend must not hold up the
+// check-in.
+func (n *httpNotifier) CheckedIn(c db.Checkin, venueName, friendName string) {
+	b, _ := json.Marshal(map[string]any{
+		"type":       "friend.presence",
+		"recipients": c.SharedWith,
+		"data":       map[string]any{"friendName": friendName, "friendId": c.UserID, "venueName": venueName, "venueId": c.VenueID, "checkinId": c.ID},
+	})
+	go n.post(b, "friend presence")
+}
+
+func (n *httpNotifier) AchievementEarned(userID string, d achieve.Definition) {
+	b, _ := json.Marshal(map[string]any{
+		"type":   "achievement.earned",
+		"userId": userID,
+		"data":   map[string]any{"achievementId": d.ID, "title": d.Title, "description": d.Description, "rarity": d.Rarity},
+	})
+	go n.post(b, "achievement earned")
+}
+
+func (n *httpNotifier) post(body []byte, what string) {
+	req, err := http.NewRequest(http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("notify %s: %v", what, err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	servicetoken.Set(req, n.token)
+	resp, err := n.client.Do(req)
+	if err != nil {
+		log.Printf("notify %s: %v", what, err)
+		return
+	}
+	resp.Body.Close()
+}
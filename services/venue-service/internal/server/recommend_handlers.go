@@ -0,0 +1,174 @@
+package server
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"bytspot/services/venue-service/internal/api"
+	"bytspot/services/venue-service/internal/db"
+	"bytspot/services/venue-service/internal/geo"
+	"bytspot/services/venue-service/internal/hours"
+	"bytspot/services/venue-service/internal/recommend"
+	"bytspot/shared/middleware"
+)
+
+const (
+	recommendRadiusMeters  = 10000
+	maxProfileInteractions = 200
+	// vibeProfileWindow is how far back liked venues' vibe is averaged to
+	// guess the vibe a user likes.
+	vibeProfileWindow = 7 * 24 * time.Hour
+)
+
+// vibeTargets maps a requested vibe band to a target score.
+var vibeTargets = map[string]float64{vibeLow: 2, vibeMedium: 5.5, vibeHigh: 8.5}
+
+// recommendedVenue is a venue plus why it was picked.
+type recommendedVenue struct {
+	venueView
+	Reason string `json:"reason"`
+}
+
+// userProfile loads the user's likes and skips as a recommendation profile.
+// The preferred vibe is the requested band, or else the recent average vibe
+// of the venues they liked.
+func (s *serverImpl) userProfile(r *http.Request, userID string, vibeBand string) (*recommend.Profile, map[string]bool, error) {
+	ctx := r.Context()
+	interactions, err := s.interactions.ListInteractions(ctx, userID, maxProfileInteractions)
+	if err != nil {
+		return nil, nil, err
+	}
+	seen := make(map[string]bool, len(interactions))
+	var liked, skipped []db.Venue
+	for _, in := range interactions {
+		seen[in.VenueID] = true
+		v, ok := s.catalog.get(in.VenueID)
+		if !ok { // archived since; still says something about taste
+			got, err := s.venues.GetVenue(ctx, in.VenueID)
+			if err != nil {
+				return nil, nil, err
+			}
+			if got == nil {
+				continue
+			}
+			v = *got
+		}
+		if in.Kind == db.InteractionLike {
+			liked = append(liked, v)
+		} else {
+			skipped = append(skipped, v)
+		}
+	}
+	var target *float64
+	if t, ok := vibeTargets[vibeBand]; ok {
+		target = &t
+	} else {
+		now := s.now()
+		var sum float64
+		var n int
+		for _, v := range liked {
+			if st := s.vibeAgg.Summarize(v.ID, now, vibeProfileWindow, hours.Location(v.Hours)); st.Count > 0 {
+				sum += st.Avg
+				n++
+			}
+		}
+		if n > 0 {
+			avg := sum / float64(n)
+			target = &avg
+		}
+	}
+	return recommend.NewProfile(liked, skipped, target), seen, nil
+}
+
+// GET /users/recommendations?lat&lon&vibe&limit
+// Personalized picks from the active catalog (within 10 km of lat/lon when
+// given), leaving out venues the user already liked or skipped. vibe (low,
+// medium or high) overrides the vibe inferred from their likes.
+func (s *serverImpl) GetUsersRecommendations(w http.ResponseWriter, r *http.Request, params api.GetUsersRecommendationsParams) {
+	claims, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	if (params.Lat == nil) != (params.Lon == nil) {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "lat and lon must be given together", "VALIDATION_ERROR")
+		return
+	}
+	if params.Lat != nil && !geo.ValidCoords(*params.Lat, *params.Lon) {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "lat/lon out of range", "VALIDATION_ERROR")
+		return
+	}
+	var band string
+	if params.Vibe != nil {
+		band = strings.ToLower(strings.TrimSpace(*params.Vibe))
+		if _, ok := vibeTargets[band]; !ok {
+			middleware.ErrorHandler(w, http.StatusBadRequest, "vibe must be low, medium or high", "VALIDATION_ERROR")
+			return
+		}
+	}
+	limit := defaultPageSize
+	if params.Limit != nil {
+		if *params.Limit < 1 {
+			middleware.ErrorHandler(w, http.StatusBadRequest, "limit must be positive", "VALIDATION_ERROR")
+			return
+		}
+		limit = min(*params.Limit, maxPageSize)
+	}
+
+	var (
+		venues []db.Venue
+		dists  []float64
+		err    error
+	)
+	if params.Lat != nil {
+		venues, dists, err = s.catalog.nearby(r.Context(), *params.Lat, *params.Lon, recommendRadiusMeters)
+	} else {
+		venues, err = s.catalog.all(r.Context())
+	}
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	profile, seen, err := s.userProfile(r, claims.Sub, band)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+
+	now := s.now()
+	cands := make([]recommend.Candidate, 0, len(venues))
+	for i, v := range venues {
+		if seen[v.ID] {
+			continue
+		}
+		c := recommend.Candidate{Venue: v}
+		c.Status, c.HoursKnown = hours.At(v.Hours, now)
+		if score, _, known := s.vibeAgg.Live(v.ID, now); known {
+			c.Vibe = &score
+		}
+		if dists != nil {
+			c.Distance = &dists[i]
+		}
+		cands = append(cands, c)
+	}
+	picks := recommend.Recommend(profile, cands, now, limit)
+	countImpression := s.impressionCounter(r)
+	items := make([]recommendedVenue, 0, len(picks))
+	for _, p := range picks {
+		view := toView(p.Venue).withStatus(p.Status, p.HoursKnown)
+		if p.Distance != nil {
+			d := math.Round(*p.Distance)
+			view.Distance = &d
+		}
+		items = append(items, recommendedVenue{venueView: view, Reason: p.Reason})
+		countImpression(p.Venue.ID)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"items": items})
+}
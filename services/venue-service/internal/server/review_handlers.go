@@ -0,0 +1,567 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"bytspot/services/venue-service/internal/api"
+	"bytspot/services/venue-service/internal/db"
+	"bytspot/services/venue-service/internal/moderation"
+	"bytspot/shared/jwtauth"
+	"bytspot/shared/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	minReviewLen     = 10 // runes
+	maxReviewLen     = 2000
+	minTipLen        = 5
+	maxTipLen        = 280
+	maxAuthorNameLen = 60
+	maxReportDetails = 500
+	// reportHoldThreshold is how many abuse reports send published content
+	// back to the moderation queue.
+	reportHoldThreshold = 3
+	defaultQueueLimit   = 50
+	maxQueueLimit       = 200
+	defaultAuthorName   = "Bytspot user"
+)
+
+var reportReasons = map[string]bool{"spam": true, "offensive": true, "misleading": true, "off_topic": true, "other": true}
+
+// premoderationFromEnv: REVIEWS_PREMODERATION=true holds all new content for
+// a moderator, not just what the keyword filter flags.
+func premoderationFromEnv() bool {
+	if v := os.Getenv("REVIEWS_PREMODERATION"); v != "" {
+		on, err := strconv.ParseBool(v)
+		if err == nil {
+			return on
+		}
+		log.Printf("invalid REVIEWS_PREMODERATION %q; publishing unflagged content", v)
+	}
+	return false
+}
+
+// reviewView is the public shape of a review or tip. Status is only shown
+// to the author.
+type reviewView struct {
+	ID           int64           `json:"id"`
+	VenueID      string          `json:"venueId"`
+	Kind         db.ReviewKind   `json:"kind"`
+	Rating       *int            `json:"rating,omitempty"`
+	Body         string          `json:"body"`
+	AuthorName   string          `json:"authorName"`
+	HelpfulCount int             `json:"helpfulCount"`
+	Status       db.ReviewStatus `json:"status,omitempty"`
+	CreatedAt    time.Time       `json:"createdAt"`
+	UpdatedAt    time.Time       `json:"updatedAt"`
+}
+
+func toReviewView(r db.Review, own bool) reviewView {
+	v := reviewView{ID: r.ID, VenueID: r.VenueID, Kind: r.Kind, Rating: r.Rating, Body: r.Body, AuthorName: r.AuthorName,
+		HelpfulCount: r.HelpfulCount, CreatedAt: r.CreatedAt, UpdatedAt: r.UpdatedAt}
+	if own {
+		v.Status = r.Status
+	}
+	return v
+}
+
+// validateReview checks r's fields for its kind.
+func validateReview(r *db.Review) error {
+	n := utf8.RuneCountInString(r.Body)
+	switch r.Kind {
+	case db.ReviewKindReview:
+		if r.Rating == nil || *r.Rating < 1 || *r.Rating > 5 {
+			return errors.New("rating must be an integer from 1 to 5")
+		}
+		if n < minReviewLen || n > maxReviewLen {
+			return fmt.Errorf("body must be %d to %d characters", minReviewLen, maxReviewLen)
+		}
+	case db.ReviewKindTip:
+		if r.Rating != nil {
+			return errors.New("tips have no rating")
+		}
+		if n < minTipLen || n > maxTipLen {
+			return fmt.Errorf("body must be %d to %d characters", minTipLen, maxTipLen)
+		}
+	default:
+		return errors.New("kind must be review or tip")
+	}
+	if utf8.RuneCountInString(r.AuthorName) > maxAuthorNameLen {
+		return fmt.Errorf("authorName may be at most %d characters", maxAuthorNameLen)
+	}
+	return nil
+}
+
+// screen runs r through the keyword filter and sets its flags and status.
+// Held content, and all new content under premoderation, waits for a
+// moderator; an edit keeps published content public only if it is clean,
+// and sends anything else back to the queue. blocked reports that r must be
+// refused outright.
+func (s *serverImpl) screen(r *db.Review) (blocked bool) {
+	res := s.moderation.Check(r.Body, r.AuthorName)
+	if res.Action == moderation.Block {
+		return true
+	}
+	flags := res.Matches
+	if slices.Contains(r.Flags, db.FlagReported) {
+		flags = append(flags, db.FlagReported)
+	}
+	r.Flags = flags
+	switch {
+	case res.Action == moderation.Hold, r.Status == "" && s.premoderate:
+		r.Status = db.ReviewPending
+	case r.Status == "":
+		r.Status = db.ReviewPublished
+	case r.Status != db.ReviewPublished:
+		r.Status = db.ReviewPending
+	}
+	return false
+}
+
+func writeContentRejected(w http.ResponseWriter) {
+	middleware.ErrorHandler(w, http.StatusUnprocessableEntity, "content breaks the community guidelines", "CONTENT_REJECTED")
+}
+
+// review loads the review at id, writing 404 when it is missing or (for
+// anyone but its author) not published.
+func (s *serverImpl) review(w http.ResponseWriter, r *http.Request, id string, claims *jwtauth.Claims) (*db.Review, bool) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusNotFound, "review not found", "NOT_FOUND")
+		return nil, false
+	}
+	rv, err := s.reviews.GetReview(r.Context(), n)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return nil, false
+	}
+	if rv == nil || (rv.Status != db.ReviewPublished && rv.UserID != claims.Sub && !hasRole(claims, "admin")) {
+		middleware.ErrorHandler(w, http.StatusNotFound, "review not found", "NOT_FOUND")
+		return nil, false
+	}
+	return rv, true
+}
+
+// GET /venues/{id}/reviews?kind&sort&limit
+// Published reviews and tips for a venue, newest or most helpful first.
+func (s *serverImpl) GetVenuesIdReviews(w http.ResponseWriter, r *http.Request, id string, params api.GetVenuesIdReviewsParams) {
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	f := db.ReviewFilter{VenueID: id, Status: db.ReviewPublished, Sort: db.ReviewsRecent, Limit: defaultPageSize}
+	if params.Kind != nil {
+		f.Kind = db.ReviewKind(*params.Kind)
+		if f.Kind != db.ReviewKindReview && f.Kind != db.ReviewKindTip {
+			middleware.ErrorHandler(w, http.StatusBadRequest, "kind must be review or tip", "VALIDATION_ERROR")
+			return
+		}
+	}
+	if params.Sort != nil {
+		f.Sort = db.ReviewSort(*params.Sort)
+		if f.Sort != db.ReviewsRecent && f.Sort != db.ReviewsHelpful {
+			middleware.ErrorHandler(w, http.StatusBadRequest, "sort must be recent or helpful", "VALIDATION_ERROR")
+			return
+		}
+	}
+	if params.Limit != nil {
+		if *params.Limit < 1 {
+			middleware.ErrorHandler(w, http.StatusBadRequest, "limit must be positive", "VALIDATION_ERROR")
+			return
+		}
+		f.Limit = min(*params.Limit, maxPageSize)
+	}
+	v, err := s.venues.GetVenue(r.Context(), id)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	if v == nil || v.Status != db.VenueActive {
+		middleware.ErrorHandler(w, http.StatusNotFound, "venue not found", "NOT_FOUND")
+		return
+	}
+	list, err := s.reviews.ListReviews(r.Context(), f)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	items := make([]reviewView, 0, len(list))
+	for _, rv := range list {
+		items = append(items, toReviewView(rv, false))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"items":        items,
+		"reviewCount":  v.ReviewCount,
+		"reviewRating": v.ReviewRating,
+	})
+}
+
+// POST /venues/{id}/reviews
+// Writes a review (kind "review", with a 1-5 rating; one per user and
+// venue) or an insider tip. Content the keyword filter holds is saved as
+// pending for the moderation queue; blocked content is refused with 422.
+func (s *serverImpl) PostVenuesIdReviews(w http.ResponseWriter, r *http.Request, id string) {
+	claims, ok := s.requireFullAccess(w, r)
+	if !ok {
+		return
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	var req struct {
+		Kind       db.ReviewKind `json:"kind"`
+		Rating     *int          `json:"rating"`
+		Body       string        `json:"body"`
+		AuthorName string        `json:"authorName"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "invalid json", "INVALID_JSON")
+		return
+	}
+	rv := &db.Review{VenueID: id, UserID: claims.Sub, Kind: req.Kind, Rating: req.Rating,
+		Body: strings.TrimSpace(req.Body), AuthorName: strings.TrimSpace(req.AuthorName)}
+	if rv.AuthorName == "" {
+		rv.AuthorName = defaultAuthorName
+	}
+	if err := validateReview(rv); err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+		return
+	}
+	v, err := s.venues.GetVenue(r.Context(), id)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	if v == nil || v.Status != db.VenueActive {
+		middleware.ErrorHandler(w, http.StatusNotFound, "venue not found", "NOT_FOUND")
+		return
+	}
+	if s.screen(rv) {
+		writeContentRejected(w)
+		return
+	}
+	if err := s.reviews.CreateReview(r.Context(), rv); err != nil {
+		if errors.Is(err, db.ErrDuplicateReview) {
+			middleware.ErrorHandler(w, http.StatusConflict, "you already reviewed this venue; edit that review instead", "ALREADY_REVIEWED")
+			return
+		}
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(toReviewView(*rv, true))
+}
+
+// PATCH /reviews/{id}
+// The author edits rating, body or authorName. The edit is screened again:
+// held or previously unpublished content goes (back) to the queue.
+func (s *serverImpl) PatchReviewsId(w http.ResponseWriter, r *http.Request, id string) {
+	claims, ok := s.requireFullAccess(w, r)
+	if !ok {
+		return
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	var req struct {
+		Rating     *int    `json:"rating"`
+		Body       *string `json:"body"`
+		AuthorName *string `json:"authorName"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "invalid json", "INVALID_JSON")
+		return
+	}
+	rv, ok := s.review(w, r, id, claims)
+	if !ok {
+		return
+	}
+	if rv.UserID != claims.Sub {
+		middleware.ErrorHandler(w, http.StatusForbidden, "only the author can edit this", "FORBIDDEN")
+		return
+	}
+	if req.Rating != nil {
+		rv.Rating = req.Rating
+	}
+	if req.Body != nil {
+		rv.Body = strings.TrimSpace(*req.Body)
+	}
+	if req.AuthorName != nil {
+		if rv.AuthorName = strings.TrimSpace(*req.AuthorName); rv.AuthorName == "" {
+			rv.AuthorName = defaultAuthorName
+		}
+	}
+	if err := validateReview(rv); err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+		return
+	}
+	if s.screen(rv) {
+		writeContentRejected(w)
+		return
+	}
+	if err := s.reviews.UpdateReview(r.Context(), rv); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			middleware.ErrorHandler(w, http.StatusNotFound, "review not found", "NOT_FOUND")
+			return
+		}
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toReviewView(*rv, true))
+}
+
+// DELETE /reviews/{id} (author or admin)
+func (s *serverImpl) DeleteReviewsId(w http.ResponseWriter, r *http.Request, id string) {
+	claims, ok := s.requireFullAccess(w, r)
+	if !ok {
+		return
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	rv, ok := s.review(w, r, id, claims)
+	if !ok {
+		return
+	}
+	if rv.UserID != claims.Sub && !hasRole(claims, "admin") {
+		middleware.ErrorHandler(w, http.StatusForbidden, "only the author or an admin can delete this", "FORBIDDEN")
+		return
+	}
+	if _, err := s.reviews.DeleteReview(r.Context(), rv.ID); err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setHelpful adds or withdraws the caller's helpful vote on a published
+// review; repeats are no-ops.
+func (s *serverImpl) setHelpful(w http.ResponseWriter, r *http.Request, id string, helpful bool) {
+	claims, ok := s.requireFullAccess(w, r)
+	if !ok {
+		return
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	rv, ok := s.review(w, r, id, claims)
+	if !ok {
+		return
+	}
+	if rv.UserID == claims.Sub {
+		middleware.ErrorHandler(w, http.StatusForbidden, "you can't vote on your own content", "FORBIDDEN")
+		return
+	}
+	if _, err := s.reviews.SetHelpful(r.Context(), rv.ID, claims.Sub, helpful); err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /reviews/{id}/helpful
+func (s *serverImpl) PostReviewsIdHelpful(w http.ResponseWriter, r *http.Request, id string) {
+	s.setHelpful(w, r, id, true)
+}
+
+// DELETE /reviews/{id}/helpful
+func (s *serverImpl) DeleteReviewsIdHelpful(w http.ResponseWriter, r *http.Request, id string) {
+	s.setHelpful(w, r, id, false)
+}
+
+// POST /reviews/{id}/report
+// Reports abuse, once per user. reportHoldThreshold reports take published
+// content down until a moderator looks at it.
+func (s *serverImpl) PostReviewsIdReport(w http.ResponseWriter, r *http.Request, id string) {
+	claims, ok := s.requireFullAccess(w, r)
+	if !ok {
+		return
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	var req struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "invalid json", "INVALID_JSON")
+		return
+	}
+	if !reportReasons[req.Reason] {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "reason must be spam, offensive, misleading, off_topic or other", "VALIDATION_ERROR")
+		return
+	}
+	reason := req.Reason
+	if details := strings.TrimSpace(req.Details); details != "" {
+		if utf8.RuneCountInString(details) > maxReportDetails {
+			middleware.ErrorHandler(w, http.StatusBadRequest, fmt.Sprintf("details may be at most %d characters", maxReportDetails), "VALIDATION_ERROR")
+			return
+		}
+		reason += ": " + details
+	}
+	rv, ok := s.review(w, r, id, claims)
+	if !ok {
+		return
+	}
+	if rv.UserID == claims.Sub {
+		middleware.ErrorHandler(w, http.StatusForbidden, "you can't report your own content", "FORBIDDEN")
+		return
+	}
+	if _, err := s.reviews.ReportReview(r.Context(), rv.ID, claims.Sub, reason, reportHoldThreshold); err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /users/me/reviews?limit=
+// The caller's reviews and tips in any status, so pending and rejected
+// content is visible to its author.
+func (s *serverImpl) GetUsersMeReviews(w http.ResponseWriter, r *http.Request) {
+	claims, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	limit := defaultQueueLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if n, e := strconv.Atoi(v); e == nil && n > 0 {
+			limit = min(n, maxQueueLimit)
+		}
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	list, err := s.reviews.ListReviews(r.Context(), db.ReviewFilter{UserID: claims.Sub, Limit: limit})
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	items := make([]reviewView, 0, len(list))
+	for _, rv := range list {
+		items = append(items, toReviewView(rv, true))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"items": items})
+}
+
+// reviewModerator authenticates an admin with a full-access token, writing
+// 401/403 otherwise.
+func (s *serverImpl) reviewModerator(w http.ResponseWriter, r *http.Request) (*jwtauth.Claims, bool) {
+	claims, ok := s.requireFullAccess(w, r)
+	if !ok {
+		return nil, false
+	}
+	if !hasRole(claims, "admin") {
+		middleware.ErrorHandler(w, http.StatusForbidden, "admin role required", "FORBIDDEN")
+		return nil, false
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return nil, false
+	}
+	return claims, true
+}
+
+// GET /admin/reviews?status=pending&venueId=&limit=
+// The moderation queue: pending content, most reported first, then oldest.
+// Other statuses list newest first.
+func (s *serverImpl) listReviewQueue(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.reviewModerator(w, r); !ok {
+		return
+	}
+	q := r.URL.Query()
+	f := db.ReviewFilter{Status: db.ReviewPending, VenueID: q.Get("venueId"), Sort: db.ReviewsReported, Limit: defaultQueueLimit}
+	if st := q.Get("status"); st != "" {
+		f.Status = db.ReviewStatus(st)
+		switch f.Status {
+		case db.ReviewPending:
+		case db.ReviewPublished, db.ReviewRejected:
+			f.Sort = db.ReviewsRecent
+		default:
+			middleware.ErrorHandler(w, http.StatusBadRequest, "status must be pending, published or rejected", "VALIDATION_ERROR")
+			return
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			middleware.ErrorHandler(w, http.StatusBadRequest, "limit must be a positive integer", "VALIDATION_ERROR")
+			return
+		}
+		f.Limit = min(n, maxQueueLimit)
+	}
+	list, err := s.reviews.ListReviews(r.Context(), f)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	if list == nil {
+		list = []db.Review{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"items": list})
+}
+
+// moderateReview sets the status of the review at {id} with the admin's
+// optional note.
+func (s *serverImpl) moderateReview(w http.ResponseWriter, r *http.Request, status db.ReviewStatus) {
+	claims, ok := s.reviewModerator(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Note string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "invalid json", "INVALID_JSON")
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusNotFound, "review not found", "NOT_FOUND")
+		return
+	}
+	rv, err := s.reviews.ModerateReview(r.Context(), id, status, claims.Sub, strings.TrimSpace(req.Note))
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	if rv == nil {
+		middleware.ErrorHandler(w, http.StatusNotFound, "review not found", "NOT_FOUND")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rv)
+}
+
+// POST /admin/reviews/{id}/approve
+func (s *serverImpl) approveReview(w http.ResponseWriter, r *http.Request) {
+	s.moderateReview(w, r, db.ReviewPublished)
+}
+
+// POST /admin/reviews/{id}/reject
+func (s *serverImpl) rejectReview(w http.ResponseWriter, r *http.Request) {
+	s.moderateReview(w, r, db.ReviewRejected)
+}
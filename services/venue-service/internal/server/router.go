@@ -1,137 +1,261 @@
 package server
 
 import (
-	"bytes"
-	"crypto/sha256"
-	"encoding/hex"
+	"context"
 	"encoding/json"
-	"io"
+	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
+	"bytspot/services/venue-service/internal/achieve"
+	"bytspot/services/venue-service/internal/analytics"
 	"bytspot/services/venue-service/internal/api"
+	"bytspot/services/venue-service/internal/db"
+	"bytspot/services/venue-service/internal/geocode"
+	"bytspot/services/venue-service/internal/heat"
+	"bytspot/services/venue-service/internal/hours"
+	"bytspot/services/venue-service/internal/moderation"
+	"bytspot/services/venue-service/internal/pubsub"
+	"bytspot/services/venue-service/internal/vibe"
+	"bytspot/shared/idempotency"
+	"bytspot/shared/middleware"
+	"bytspot/shared/servicetoken"
 
 	"github.com/go-chi/chi/v5"
 )
 
-type serverImpl struct{}
+type serverImpl struct {
+	venues       db.VenueRepo
+	interactions db.InteractionRepo
+	vibes        db.VibeRepo
+	activity     db.ActivityRepo
+	checkins     db.CheckinRepo
+	reviews      db.ReviewRepo
+	achievements db.AchievementRepo
+	events       db.EventRepo
+	stats        db.StatsRepo
+	idempotency  db.IdempotencyRepo
+	venueAudit   db.VenueAuditRepo
+	geocoder     geocode.Geocoder // nil: admin writes need explicit coordinates
+	notifier     userNotifier
+	friends      friendGraph // who check-ins may be shared with
+	moderation   *moderation.Filter
+	vibeAgg      *vibe.Aggregator
+	vibeHub      *pubsub.Hub
+	heatActivity *heat.Activity
+	heatCache    *heat.Cache
+	likeCounts   likeCounts
+	catalog      *catalog
+	impressions  *analytics.Counter // flushed to stats by runAnalyticsJobs
+	now          func() time.Time
 
-// In-memory vibe store for beta
-var vibeStore = struct {
-	items map[string][]map[string]any
-}{items: map[string][]map[string]any{}}
+	achieveCatalog *achieve.Catalog
+	achieveZone    *time.Location // for events without a timezone and streak days
 
-func (s *serverImpl) GetHealthz(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("ok"))
+	// Service credentials (X-Service-Token) of the producers of
+	// /achievements/events; unset, the endpoint rejects everything.
+	achieveTokens *servicetoken.Verifier
+	// Service credentials of the ingestion pipeline for /signals/activity;
+	// unset, the endpoint rejects everything.
+	signalTokens *servicetoken.Verifier
+	// Service credentials of internal catalog readers (the BFF) for
+	// /internal/venues/coords; unset, the endpoint rejects everything.
+	catalogTokens *servicetoken.Verifier
+
+	vibeRetention time.Duration
+	premoderate   bool              // hold all new reviews and tips for a moderator
+	signingKeys   map[string][]byte // vibe report HMAC keys by key id
+	nonces        db.NonceRepo
+	sseHeartbeat  time.Duration
 }
 
-func (s *serverImpl) GetReadyz(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("ready"))
+// NewServerImpl uses Postgres when DATABASE_URL is set and an in-memory,
+// demo-seeded catalog otherwise (or when VENUE_STORE=memory).
+// VENUE_SEED=true also seeds Postgres with the demo catalog.
+func NewServerImpl(ctx context.Context) (*serverImpl, error) {
+	if os.Getenv("VENUE_STORE") == "memory" || os.Getenv("DATABASE_URL") == "" {
+		mem := db.NewMemStore()
+		if err := db.Seed(ctx, mem); err != nil {
+			return nil, err
+		}
+		return newServerImpl(mem), nil
+	}
+	store, err := db.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if seed, _ := strconv.ParseBool(os.Getenv("VENUE_SEED")); seed {
+		if err := db.Seed(ctx, store); err != nil {
+			log.Printf("venue seed failed: %v", err)
+		}
+	}
+	return newServerImpl(store), nil
 }
 
-func (s *serverImpl) GetVenuesDiscover(w http.ResponseWriter, r *http.Request, params api.GetVenuesDiscoverParams) {
-	// Mock response for beta
-	items := []map[string]any{
-		{"id": "v1", "title": "Energetic Bar", "subtitle": "Downtown", "rating": 4.5, "distance": "0.5km", "price": "$$"},
-		{"id": "v2", "title": "Chill Lounge", "subtitle": "Riverside", "rating": 4.2, "distance": "1.2km", "price": "$"},
+func newServerImpl(repo db.Repository) *serverImpl {
+	return &serverImpl{
+		venues:         repo,
+		interactions:   repo,
+		vibes:          repo,
+		activity:       repo,
+		checkins:       repo,
+		reviews:        repo,
+		achievements:   repo,
+		events:         repo,
+		stats:          repo,
+		idempotency:    repo,
+		venueAudit:     repo,
+		geocoder:       geocode.FromEnv(),
+		notifier:       notifierFromEnv(),
+		friends:        friendGraphFromEnv(),
+		moderation:     moderation.FromEnv(),
+		premoderate:    premoderationFromEnv(),
+		vibeAgg:        vibe.New(vibe.DefaultConfig),
+		vibeHub:        pubsub.NewHub(sseHistorySize, sseClientBuffer),
+		heatActivity:   heat.NewActivity(activityHalfLife),
+		heatCache:      heat.NewCache(heatCellTTL),
+		catalog:        newCatalog(repo, time.Now),
+		impressions:    analytics.NewCounter(),
+		achieveCatalog: achievementCatalogFromEnv(),
+		achieveZone:    achievementZoneFromEnv(),
+		achieveTokens:  servicetoken.FromEnv("ACHIEVEMENT_SERVICE_TOKENS"),
+		signalTokens:   servicetoken.FromEnv("SIGNAL_SERVICE_TOKENS"),
+		catalogTokens:  servicetoken.FromEnv("CATALOG_SERVICE_TOKENS"),
+		now:            time.Now,
+		vibeRetention:  vibeRetentionFromEnv(),
+		signingKeys:    signingKeys(),
+		nonces:         repo,
+		sseHeartbeat:   defaultSSEHeartbeat,
 	}
-	resp := map[string]any{"items": items}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
 }
 
-func (s *serverImpl) GetVenuesId(w http.ResponseWriter, r *http.Request, id string) {
-	// Mock response
-	venue := map[string]any{"id": id, "title": "Venue", "subtitle": "Location", "rating": 4.4, "distance": "0.8km", "price": "$$$"}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(venue)
+// ready reports whether repositories are wired; handlers answer 500 otherwise.
+func (s *serverImpl) ready() bool { return s.venues != nil }
+
+// venueView is the wire shape: the stored venue plus the legacy card fields
+// (title, price) existing clients render.
+type venueView struct {
+	db.Venue
+	Title    string   `json:"title"`
+	Price    string   `json:"price"`
+	Distance *float64 `json:"distance,omitempty"` // meters; only for geo queries
+
+	// Opening status at request time (or open_at); absent without hours.
+	OpenNow   *bool      `json:"openNow,omitempty"`
+	ClosesAt  *time.Time `json:"closesAt,omitempty"`
+	OpensNext *time.Time `json:"opensNext,omitempty"`
+
+	HappeningNow *happeningView `json:"happeningNow,omitempty"` // discovery only
 }
 
-func (s *serverImpl) PostVenuesIdLike(w http.ResponseWriter, r *http.Request, id string) {
-	// Mock like acceptance
-	w.WriteHeader(http.StatusNoContent)
+// happeningView is the event running at a venue when the feed was built.
+type happeningView struct {
+	EventID int64        `json:"eventId"`
+	Kind    db.EventKind `json:"kind"`
+	Title   string       `json:"title"`
+	EndsAt  time.Time    `json:"endsAt"`
 }
 
-func (s *serverImpl) PostVenuesIdVibe(w http.ResponseWriter, r *http.Request, id string) {
-	// Optional HMAC signature verification (beta): if env VIBE_HMAC_SECRET set, verify x-signature header
-	secret := os.Getenv("VIBE_HMAC_SECRET")
-	if secret != "" {
-		var bodyBuf bytes.Buffer
-		tee := io.TeeReader(r.Body, &bodyBuf)
-		var raw map[string]any
-		if err := json.NewDecoder(tee).Decode(&raw); err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		b, _ := json.Marshal(raw)
-		sum := sha256.Sum256(append([]byte(secret), b...))
-		expected := hex.EncodeToString(sum[:])
-		got := r.Header.Get("x-signature")
-		if got == "" || got != expected {
-			w.WriteHeader(http.StatusUnauthorized)
-			return
-		}
-		r.Body = io.NopCloser(&bodyBuf)
-	}
+var priceLabels = map[int]string{1: "$", 2: "$$", 3: "$$$", 4: "$$$$"}
+
+func toView(v db.Venue) venueView {
+	return venueView{Venue: v, Title: v.Name, Price: priceLabels[v.PriceTier]}
+}
 
-	var req struct {
-		VibeScore  float64        `json:"vibeScore"`
-		Confidence float64        `json:"confidence"`
-		Timestamp  string         `json:"timestamp"`
-		Features   map[string]any `json:"features"`
-		Meta       struct {
-			IdempotencyKey string `json:"idempotency_key"`
-		} `json:"meta"`
+// withStatus adds the opening status at t.
+func (v venueView) withStatus(st hours.Status, known bool) venueView {
+	if known {
+		v.OpenNow, v.ClosesAt, v.OpensNext = &st.Open, st.ClosesAt, st.OpensNext
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+	return v
+}
+
+// publicView is toView plus the opening status at t.
+func publicView(v db.Venue, t time.Time) venueView {
+	return toView(v).withStatus(hours.At(v.Hours, t))
+}
+
+func (s *serverImpl) GetHealthz(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+func (s *serverImpl) GetReadyz(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ready"))
+}
+
+func (s *serverImpl) GetVenuesId(w http.ResponseWriter, r *http.Request, id string) {
+	claims, ok := s.optionalAuth(w, r)
+	if !ok {
 		return
 	}
-	// Basic validation
-	if req.VibeScore < 0 || req.VibeScore > 10 {
-		w.WriteHeader(http.StatusBadRequest)
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
 		return
 	}
-	if vibeStore.items[id] == nil {
-		vibeStore.items[id] = []map[string]any{}
+	v, err := s.venues.GetVenue(r.Context(), id)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
 	}
-	// naive dedupe by idempotency key within this process
-	if req.Meta.IdempotencyKey != "" {
-		for _, v := range vibeStore.items[id] {
-			if v["idempotency_key"] == req.Meta.IdempotencyKey {
-				w.WriteHeader(http.StatusAccepted)
-				return
-			}
-		}
+	// drafts and archived venues are only visible to their editors
+	if v == nil || (v.Status != db.VenueActive && (claims == nil || !canEditVenue(claims, v))) {
+		middleware.ErrorHandler(w, http.StatusNotFound, "venue not found", "NOT_FOUND")
+		return
 	}
-	vibeStore.items[id] = append(vibeStore.items[id], map[string]any{
-		"vibeScore":       req.VibeScore,
-		"confidence":      req.Confidence,
-		"timestamp":       req.Timestamp,
-		"idempotency_key": req.Meta.IdempotencyKey,
-	})
-	w.WriteHeader(http.StatusAccepted)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(publicView(*v, s.now()))
 }
 
-func (s *serverImpl) GetVenuesIdVibeAggregate(w http.ResponseWriter, r *http.Request, id string) {
-	var avg float64
-	var count int
-	for _, v := range vibeStore.items[id] {
-		if f, ok := v["vibeScore"].(float64); ok {
-			avg += f
-			count++
-		}
-	}
-	if count > 0 {
-		avg = avg / float64(count)
+func NewRouter() http.Handler {
+	impl, err := NewServerImpl(context.Background())
+	if err != nil {
+		// Keep serving health checks; store-backed endpoints answer 500
+		log.Printf("venue store unavailable: %v", err)
+		impl = &serverImpl{}
+	} else {
+		go impl.runPurgeJobs(context.Background(), purgeInterval)
+		go impl.runAnalyticsJobs(context.Background(), analyticsInterval)
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]any{"id": id, "avg": avg, "count": count})
+	return newRouter(impl)
 }
 
-func NewRouter() http.Handler {
+func newRouter(impl *serverImpl) http.Handler {
 	r := chi.NewRouter()
-	return api.HandlerFromMux(&serverImpl{}, r)
+	if impl.idempotency != nil {
+		r.Use(idempotency.Middleware(impl.idempotency, idempotency.Options{Principal: idempotencyPrincipal, MaxBody: maxImportBytes}))
+	}
+	h := api.HandlerFromMux(impl, r)
+
+	// Non-spec admin routes (secured by admin role)
+	r.Get("/admin/analytics/summary", impl.GetAdminAnalyticsSummary)
+
+	// Venue management (admin, or host for owned venues)
+	r.Get("/admin/venues", impl.listAdminVenues)
+	r.Get("/admin/venues/analytics", impl.listVenueAnalytics)
+	r.Post("/admin/venues", impl.createAdminVenue)
+	r.Post("/admin/venues/bulk-status", impl.bulkVenueStatus)
+	r.Post("/admin/venues/import", impl.importAdminVenues)
+	r.Get("/admin/venues/{id}", impl.getAdminVenue)
+	r.Patch("/admin/venues/{id}", impl.patchAdminVenue)
+	r.Post("/admin/venues/{id}/archive", impl.archiveAdminVenue)
+	r.Post("/admin/venues/{id}/restore", impl.restoreAdminVenue)
+	r.Get("/admin/venues/{id}/audit", impl.listVenueAudit)
+	r.Get("/admin/venues/{id}/analytics", impl.getVenueAnalytics)
+	r.Get("/admin/venues/{id}/events", impl.listAdminEvents)
+	r.Post("/admin/venues/{id}/events", impl.createAdminEvent)
+	r.Patch("/admin/venues/{id}/events/{eventId}", impl.patchAdminEvent)
+	r.Delete("/admin/venues/{id}/events/{eventId}", impl.deleteAdminEvent)
+
+	// Internal catalog reads (service token)
+	r.Get("/internal/venues/coords", impl.listVenueCoords)
+
+	// Review and tip moderation queue (admin)
+	r.Get("/admin/reviews", impl.listReviewQueue)
+	r.Post("/admin/reviews/{id}/approve", impl.approveReview)
+	r.Post("/admin/reviews/{id}/reject", impl.rejectReview)
+	return h
 }
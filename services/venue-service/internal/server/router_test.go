@@ -0,0 +1,1849 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"bytspot/services/venue-service/internal/achieve"
+	"bytspot/services/venue-service/internal/analytics"
+	"bytspot/services/venue-service/internal/db"
+	"bytspot/services/venue-service/internal/geo"
+	"bytspot/services/venue-service/internal/geocode"
+	"bytspot/shared/jwtauth"
+	"bytspot/shared/servicetoken"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func testToken(t *testing.T, sub string, roles ...string) string {
+	t.Helper()
+	claims := jwtauth.Claims{Sub: sub, Roles: roles, RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))}}
+	tok, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtauth.Secret())
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return tok
+}
+
+type testEnv struct {
+	t    *testing.T
+	repo *db.MemStore
+	impl *serverImpl
+	h    http.Handler
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	repo := db.NewMemStore()
+	if err := db.Seed(context.Background(), repo); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	impl := newServerImpl(repo)
+	impl.achieveTokens = servicetoken.New(testServiceToken)
+	impl.signalTokens = servicetoken.New(testServiceToken)
+	impl.catalogTokens = servicetoken.New(testServiceToken)
+	return &testEnv{t: t, repo: repo, impl: impl, h: newRouter(impl)}
+}
+
+const testServiceToken = "svc-test-token"
+
+// doService is do as another service, with its X-Service-Token.
+func (e *testEnv) doService(method, path string, body any) *httptest.ResponseRecorder {
+	e.t.Helper()
+	b, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(servicetoken.Header, testServiceToken)
+	w := httptest.NewRecorder()
+	e.h.ServeHTTP(w, req)
+	return w
+}
+
+func (e *testEnv) do(method, path, token string, body any) *httptest.ResponseRecorder {
+	e.t.Helper()
+	var rdr *bytes.Reader
+	if body != nil {
+		b, _ := json.Marshal(body)
+		rdr = bytes.NewReader(b)
+	} else {
+		rdr = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, path, rdr)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	e.h.ServeHTTP(w, req)
+	return w
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	var out map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decode %q: %v", w.Body.String(), err)
+	}
+	return out
+}
+
+func TestGetVenue_FromCatalog(t *testing.T) {
+	e := newTestEnv(t)
+	w := e.do(http.MethodGet, "/venues/v3", "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	v := decode(t, w)
+	if v["name"] != "Neon Warehouse" || v["title"] != "Neon Warehouse" || v["price"] != "$$$" || v["category"] != "club" {
+		t.Fatalf("unexpected venue %v", v)
+	}
+	if hours, _ := v["hours"].(map[string]any); hours["timezone"] != "America/Los_Angeles" {
+		t.Fatalf("expected opening hours, got %v", v["hours"])
+	}
+}
+
+func TestGetVenue_UnknownIs404(t *testing.T) {
+	e := newTestEnv(t)
+	if w := e.do(http.MethodGet, "/venues/nope", "", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestDiscover_ListsActiveCatalog(t *testing.T) {
+	e := newTestEnv(t)
+	_ = e.repo.CreateVenue(context.Background(), &db.Venue{ID: "draft", Name: "Soon", Category: "bar", PriceTier: 1, Status: db.VenueDraft})
+	w := e.do(http.MethodGet, "/venues/discover", "", nil)
+	items, _ := decode(t, w)["items"].([]any)
+	if len(items) != len(db.DemoVenues()) {
+		t.Fatalf("expected %d active venues, got %d", len(db.DemoVenues()), len(items))
+	}
+}
+
+func TestVenueCoords_UnpagedForServices(t *testing.T) {
+	e := newTestEnv(t)
+	_ = e.repo.CreateVenue(context.Background(), &db.Venue{ID: "draft", Name: "Soon", Category: "bar", PriceTier: 1, Status: db.VenueDraft})
+	if w := e.do(http.MethodGet, "/internal/venues/coords", testToken(t, "u1"), nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a service token, got %d", w.Code)
+	}
+	ids := itemIDs(t, e.doService(http.MethodGet, "/internal/venues/coords", nil))
+	if len(ids) != len(db.DemoVenues()) || slices.Contains(ids, "draft") {
+		t.Fatalf("expected every active venue in one list, got %v", ids)
+	}
+}
+
+func TestImpressions_OnlySignedInUsersCount(t *testing.T) {
+	e := newTestEnv(t)
+	drained := func() int {
+		n := 0
+		for _, c := range e.impl.impressions.Drain() {
+			n += c
+		}
+		return n
+	}
+
+	// anonymous and service callers walking the whole catalog count nothing
+	walk := func(do func(path string) *httptest.ResponseRecorder) {
+		t.Helper()
+		path := "/venues/discover?limit=2"
+		for {
+			w := do(path)
+			if w.Code != http.StatusOK {
+				t.Fatalf("discover: %d", w.Code)
+			}
+			next, _ := decode(t, w)["nextCursor"].(string)
+			if next == "" {
+				return
+			}
+			path = "/venues/discover?limit=2&cursor=" + url.QueryEscape(next)
+		}
+	}
+	walk(func(path string) *httptest.ResponseRecorder { return e.do(http.MethodGet, path, "", nil) })
+	walk(func(path string) *httptest.ResponseRecorder { return e.doService(http.MethodGet, path, nil) })
+	e.doService(http.MethodGet, "/internal/venues/coords", nil)
+	e.do(http.MethodGet, "/venues/search?q=bar", "", nil)
+	e.do(http.MethodGet, "/venues/trending", "", nil)
+	if n := drained(); n != 0 {
+		t.Fatalf("expected no impressions from anonymous or service reads, got %d", n)
+	}
+
+	// a signed-in user's page counts each venue on it once
+	if ids := itemIDs(t, e.do(http.MethodGet, "/venues/discover?limit=2", testToken(t, "u9"), nil)); len(ids) != 2 {
+		t.Fatalf("discover page = %v", ids)
+	}
+	if n := drained(); n != 2 {
+		t.Fatalf("expected 2 impressions for the user's page, got %d", n)
+	}
+}
+
+func itemIDs(t *testing.T, w *httptest.ResponseRecorder) []string {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	items, _ := decode(t, w)["items"].([]any)
+	ids := make([]string, 0, len(items))
+	for _, it := range items {
+		ids = append(ids, it.(map[string]any)["id"].(string))
+	}
+	return ids
+}
+
+func TestDiscover_RadiusAndDistanceOrder(t *testing.T) {
+	e := newTestEnv(t)
+	// Union Square: Rooftop 22 (~110m), Energetic Bar (~550m), Neon Warehouse (~1.6km)
+	w := e.do(http.MethodGet, "/venues/discover?lat=37.7879&lon=-122.4074&radius=1000", "", nil)
+	ids := itemIDs(t, w)
+	if len(ids) != 2 || ids[0] != "v7" || ids[1] != "v1" {
+		t.Fatalf("expected [v7 v1], got %v", ids)
+	}
+	items, _ := decode(t, w)["items"].([]any)
+	d, ok := items[1].(map[string]any)["distance"].(float64)
+	if !ok || d < 400 || d > 700 {
+		t.Fatalf("expected numeric distance in meters, got %v", items[1].(map[string]any)["distance"])
+	}
+	if ids := itemIDs(t, e.do(http.MethodGet, "/venues/discover?lat=37.7879&lon=-122.4074&radius=2000", "", nil)); len(ids) < 3 {
+		t.Fatalf("expected wider radius to include more venues, got %v", ids)
+	}
+}
+
+func TestDiscover_Filters(t *testing.T) {
+	e := newTestEnv(t)
+	base := "/venues/discover?lat=37.78&lon=-122.41&radius=10000"
+	if ids := itemIDs(t, e.do(http.MethodGet, base+"&category=bar", "", nil)); len(ids) != 2 {
+		t.Fatalf("category=bar: expected 2, got %v", ids)
+	}
+	if ids := itemIDs(t, e.do(http.MethodGet, base+"&price=$,$$", "", nil)); len(ids) != 5 {
+		t.Fatalf("price=$,$$: expected 5, got %v", ids)
+	}
+	// Saturday 10:00 in San Francisco: only the daytime venues are open
+	la, _ := time.LoadLocation("America/Los_Angeles")
+	e.impl.now = func() time.Time { return time.Date(2026, 1, 10, 10, 0, 0, 0, la) }
+	ids := itemIDs(t, e.do(http.MethodGet, base+"&open_now=true", "", nil))
+	if len(ids) != 3 || !containsAll(ids, "v4", "v5", "v8") {
+		t.Fatalf("open_now: expected the daytime venues, got %v", ids)
+	}
+
+	// planning for 22:00 the same night: only the late-night venues, with
+	// their closing time in the venue timezone
+	w := e.do(http.MethodGet, base+"&open_at=2026-01-10T22:00:00-08:00", "", nil)
+	items, _ := decode(t, w)["items"].([]any)
+	if len(items) != 5 {
+		t.Fatalf("open_at: expected the 5 late-night venues, got %d", len(items))
+	}
+	first := items[0].(map[string]any)
+	if first["openNow"] != true || first["closesAt"] != "2026-01-11T02:00:00-08:00" {
+		t.Fatalf("open_at status = %v / %v", first["openNow"], first["closesAt"])
+	}
+	if w := e.do(http.MethodGet, base+"&open_now=true&open_at=2026-01-10T22:00:00Z", "", nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for open_now with open_at, got %d", w.Code)
+	}
+}
+
+func TestGetVenue_OpeningStatusAndExceptions(t *testing.T) {
+	e := newTestEnv(t)
+	la, _ := time.LoadLocation("America/Los_Angeles")
+	e.impl.now = func() time.Time { return time.Date(2026, 1, 10, 10, 0, 0, 0, la) }
+	v := decode(t, e.do(http.MethodGet, "/venues/v1", "", nil))
+	if v["openNow"] != false || v["opensNext"] != "2026-01-10T17:00:00-08:00" {
+		t.Fatalf("v1 status = %v / %v", v["openNow"], v["opensNext"])
+	}
+
+	admin := testToken(t, "admin-1", "admin")
+	hours := v["hours"].(map[string]any)
+	hours["exceptions"] = []map[string]any{{"date": "2026-01-10", "closed": true, "note": "private event"}}
+	if w := e.do(http.MethodPatch, "/admin/venues/v1", admin, map[string]any{"hours": hours}); w.Code != http.StatusOK {
+		t.Fatalf("patch hours: %d %s", w.Code, w.Body.String())
+	}
+	v = decode(t, e.do(http.MethodGet, "/venues/v1", "", nil))
+	if v["opensNext"] != "2026-01-11T17:00:00-08:00" {
+		t.Fatalf("closure not applied, opensNext = %v", v["opensNext"])
+	}
+
+	bad := []map[string]any{{"date": "2026-13-01", "closed": true}}
+	hours["exceptions"] = bad
+	if w := e.do(http.MethodPatch, "/admin/venues/v1", admin, map[string]any{"hours": hours}); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad exception date, got %d", w.Code)
+	}
+}
+
+func TestDiscover_InvalidParams(t *testing.T) {
+	e := newTestEnv(t)
+	for _, q := range []string{"lat=abc&lon=1", "lat=37.7", "lat=95&lon=0", "lat=1&lon=1&radius=-5", "price=5", "open_now=maybe", "open_at=tonight"} {
+		if w := e.do(http.MethodGet, "/venues/discover?"+q, "", nil); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", q, w.Code)
+		}
+	}
+}
+
+func containsAll(xs []string, want ...string) bool {
+	set := map[string]bool{}
+	for _, x := range xs {
+		set[x] = true
+	}
+	for _, w := range want {
+		if !set[w] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDiscover_CursorPagesAreStableWhileMoving(t *testing.T) {
+	e := newTestEnv(t)
+	w := e.do(http.MethodGet, "/venues/discover?lat=37.78&lon=-122.41&radius=20000&limit=3", "", nil)
+	first := itemIDs(t, w)
+	next, _ := decode(t, w)["nextCursor"].(string)
+	if len(first) != 3 || next == "" {
+		t.Fatalf("expected a full first page and a cursor, got %v %q", first, next)
+	}
+	// The user walked ~2km; the second page must continue the original order.
+	moved := e.do(http.MethodGet, "/venues/discover?lat=37.76&lon=-122.42&radius=20000&limit=3&cursor="+next, "", nil)
+	stayed := e.do(http.MethodGet, "/venues/discover?lat=37.78&lon=-122.41&radius=20000&limit=3&cursor="+next, "", nil)
+	a, b := itemIDs(t, moved), itemIDs(t, stayed)
+	if len(a) != 3 || a[0] != b[0] || a[2] != b[2] {
+		t.Fatalf("expected the same second page, got %v vs %v", a, b)
+	}
+
+	seen := map[string]bool{}
+	cursor := ""
+	for page := 0; page < 10; page++ {
+		w := e.do(http.MethodGet, "/venues/discover?lat=37.78&lon=-122.41&radius=20000&limit=3"+cursor, "", nil)
+		for _, id := range itemIDs(t, w) {
+			if seen[id] {
+				t.Fatalf("venue %s returned twice", id)
+			}
+			seen[id] = true
+		}
+		nc, _ := decode(t, w)["nextCursor"].(string)
+		if nc == "" {
+			break
+		}
+		cursor = "&cursor=" + nc
+	}
+	if len(seen) != len(db.DemoVenues()) {
+		t.Fatalf("expected to page through all %d venues, saw %d", len(db.DemoVenues()), len(seen))
+	}
+}
+
+func TestDiscover_RejectsTamperedOrMismatchedCursor(t *testing.T) {
+	e := newTestEnv(t)
+	w := e.do(http.MethodGet, "/venues/discover?limit=2", "", nil)
+	next, _ := decode(t, w)["nextCursor"].(string)
+	if w := e.do(http.MethodGet, "/venues/discover?limit=2&cursor=x"+next, "", nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("tampered cursor: expected 400, got %d", w.Code)
+	}
+	if w := e.do(http.MethodGet, "/venues/discover?limit=2&category=bar&cursor="+next, "", nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("cursor with different filters: expected 400, got %d", w.Code)
+	}
+}
+
+func TestDiscover_ExcludesLikedAndSkippedAndCountsFacets(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	_, _ = e.repo.UpsertInteraction(ctx, "u1", "v1", db.InteractionLike)
+	_, _ = e.repo.UpsertInteraction(ctx, "u1", "v3", db.InteractionSkip)
+
+	w := e.do(http.MethodGet, "/venues/discover?category=bar", testToken(t, "u1"), nil)
+	ids := itemIDs(t, w)
+	if len(ids) != 1 || ids[0] != "v7" {
+		t.Fatalf("expected only v7 after excluding liked v1, got %v", ids)
+	}
+	f := decode(t, w)["facets"].(map[string]any)
+	cats := f["category"].(map[string]any)
+	// category counts ignore the category filter itself; v1 and v3 are excluded
+	if cats["bar"].(float64) != 1 || cats["club"] != nil || cats["restaurant"].(float64) != 2 {
+		t.Fatalf("unexpected category facets %v", cats)
+	}
+	if f["price"].(map[string]any)["$$$$"].(float64) != 1 || f["vibe"].(map[string]any)["unknown"].(float64) != 1 {
+		t.Fatalf("unexpected price/vibe facets %v", f)
+	}
+	if anon := itemIDs(t, e.do(http.MethodGet, "/venues/discover?category=bar", "", nil)); len(anon) != 2 {
+		t.Fatalf("anonymous feed should not exclude, got %v", anon)
+	}
+	if w := e.do(http.MethodGet, "/venues/discover", "garbage", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("bad token: expected 401, got %d", w.Code)
+	}
+}
+
+func TestLikes_IdempotentAndCounted(t *testing.T) {
+	e := newTestEnv(t)
+	alice, bob := testToken(t, "alice"), testToken(t, "bob")
+	if w := e.do(http.MethodPost, "/venues/v1/like", "", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("anonymous like: expected 401, got %d", w.Code)
+	}
+	if w := e.do(http.MethodPost, "/venues/nope/like", alice, nil); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown venue: expected 404, got %d", w.Code)
+	}
+	for i := 0; i < 3; i++ {
+		if w := e.do(http.MethodPost, "/venues/v1/like", alice, nil); w.Code != http.StatusNoContent {
+			t.Fatalf("like: expected 204, got %d", w.Code)
+		}
+	}
+	e.do(http.MethodPost, "/venues/v1/like", bob, nil)
+	e.do(http.MethodPost, "/venues/v2/like", alice, nil)
+	if n := decode(t, e.do(http.MethodGet, "/venues/v1", "", nil))["likeCount"].(float64); n != 2 {
+		t.Fatalf("expected 2 likes on v1, got %v", n)
+	}
+
+	// skipping a liked venue replaces the like
+	e.do(http.MethodPost, "/venues/v1/skip", bob, nil)
+	if w := e.do(http.MethodDelete, "/venues/v2/like", alice, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("unlike: expected 204, got %d", w.Code)
+	}
+	e.do(http.MethodDelete, "/venues/v2/like", alice, nil)
+	if n := decode(t, e.do(http.MethodGet, "/venues/v1", "", nil))["likeCount"].(float64); n != 1 {
+		t.Fatalf("expected 1 like on v1 after bob skipped, got %v", n)
+	}
+
+	// likes resolve from the catalog; only venues that left it are read
+	venues := &countingVenues{VenueRepo: e.repo}
+	e.impl.venues = venues
+	if ids := itemIDs(t, e.do(http.MethodGet, "/users/me/likes", alice, nil)); len(ids) != 1 || ids[0] != "v1" {
+		t.Fatalf("alice likes: expected [v1], got %v", ids)
+	}
+	if venues.gets != 0 {
+		t.Fatalf("expected no venue reads for active likes, got %d", venues.gets)
+	}
+	if ids := itemIDs(t, e.do(http.MethodGet, "/users/me/likes", bob, nil)); len(ids) != 0 {
+		t.Fatalf("bob likes: expected none, got %v", ids)
+	}
+
+	if w := e.do(http.MethodGet, "/admin/analytics/summary", alice, nil); w.Code != http.StatusForbidden {
+		t.Fatalf("non-admin summary: expected 403, got %d", w.Code)
+	}
+	sum := decode(t, e.do(http.MethodGet, "/admin/analytics/summary", testToken(t, "root", "admin"), nil))
+	if sum["likes"].(float64) != 1 || sum["venues"].(float64) != float64(len(db.DemoVenues())) {
+		t.Fatalf("unexpected summary %v", sum)
+	}
+
+	// a liked venue that was archived is read from the store
+	v1, _ := e.repo.GetVenue(context.Background(), "v1")
+	v1.Status = db.VenueArchived
+	_ = e.repo.UpdateVenue(context.Background(), v1)
+	e.impl.catalog.invalidate()
+	if ids := itemIDs(t, e.do(http.MethodGet, "/users/me/likes", alice, nil)); len(ids) != 1 || ids[0] != "v1" || venues.gets != 1 {
+		t.Fatalf("archived like: got %v after %d reads", ids, venues.gets)
+	}
+}
+
+// countingVenues counts single-venue reads.
+type countingVenues struct {
+	db.VenueRepo
+	gets int
+}
+
+func (c *countingVenues) GetVenue(ctx context.Context, id string) (*db.Venue, error) {
+	c.gets++
+	return c.VenueRepo.GetVenue(ctx, id)
+}
+
+func TestImpersonationTokens_ReadOnly(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	v7, _ := e.repo.GetVenue(ctx, "v7")
+	owner := "host-1"
+	v7.OwnerID = &owner
+	_ = e.repo.UpdateVenue(ctx, v7)
+	claims := jwtauth.Claims{Sub: "host-1", Roles: []string{"host"}, Scope: "impersonation",
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))}}
+	claims.Act = &struct {
+		Sub string `json:"sub"`
+	}{Sub: "support-1"}
+	tok, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtauth.Secret())
+
+	writes := []struct {
+		method, path string
+		body         any
+	}{
+		{http.MethodPost, "/venues/v1/like", nil},
+		{http.MethodPost, "/venues/v1/skip", nil},
+		{http.MethodDelete, "/venues/v1/like", nil},
+		{http.MethodPost, "/venues/v1/vibe", map[string]any{"vibeScore": 7}},
+		{http.MethodPost, "/venues/v1/checkin", map[string]any{"lat": 37.7897, "lon": -122.4011}},
+		{http.MethodPost, "/venues/v1/reviews", map[string]any{"kind": "review", "rating": 5, "body": "Great drinks and a friendly crowd"}},
+		{http.MethodPatch, "/admin/venues/v7", map[string]any{"name": "Renamed"}},
+		{http.MethodPost, "/admin/venues/v7/events", map[string]any{"title": "Party"}},
+	}
+	for _, wr := range writes {
+		if w := e.do(wr.method, wr.path, tok, wr.body); w.Code != http.StatusForbidden {
+			t.Fatalf("%s %s: expected 403, got %d %s", wr.method, wr.path, w.Code, w.Body.String())
+		}
+	}
+	if w := e.do(http.MethodGet, "/users/me/likes", tok, nil); w.Code != http.StatusOK {
+		t.Fatalf("reads stay allowed: %d", w.Code)
+	}
+	if w := e.do(http.MethodPost, "/venues/v1/like", testToken(t, "host-1", "host"), nil); w.Code/100 != 2 {
+		t.Fatalf("full token: %d", w.Code)
+	}
+}
+
+func TestVibe_StoredDedupedAndPurged(t *testing.T) {
+	e := newTestEnv(t)
+	report := func(score float64, key string) map[string]any {
+		return map[string]any{"vibeScore": score, "confidence": 0.8, "timestamp": time.Now().UTC().Format(time.RFC3339), "meta": map[string]any{"idempotency_key": key}}
+	}
+	if w := e.do(http.MethodPost, "/venues/v1/vibe", "", map[string]any{"vibeScore": 11}); w.Code != http.StatusBadRequest {
+		t.Fatalf("out of range score: expected 400, got %d", w.Code)
+	}
+	if w := e.do(http.MethodPost, "/venues/nope/vibe", "", report(5, "")); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown venue: expected 404, got %d", w.Code)
+	}
+	for _, r := range []map[string]any{report(9, "a"), report(9, "a"), report(7, "b")} {
+		if w := e.do(http.MethodPost, "/venues/v1/vibe", "", r); w.Code != http.StatusAccepted {
+			t.Fatalf("vibe: expected 202, got %d", w.Code)
+		}
+	}
+	agg := decode(t, e.do(http.MethodGet, "/venues/v1/vibe-aggregate", "", nil))
+	if agg["count"].(float64) != 2 || agg["avg"].(float64) != 8 {
+		t.Fatalf("expected 2 reports averaging 8, got %v", agg)
+	}
+	if ids := itemIDs(t, e.do(http.MethodGet, "/venues/discover?vibe=high", "", nil)); len(ids) != 1 || ids[0] != "v1" {
+		t.Fatalf("vibe=high: expected [v1], got %v", ids)
+	}
+
+	// past the retention window the reports are purged
+	e.impl.now = func() time.Time { return time.Now().Add(e.impl.vibeRetention + time.Hour) }
+	if n, err := e.impl.purgeVibes(context.Background()); err != nil || n != 2 {
+		t.Fatalf("purge: expected 2 removed, got %d (%v)", n, err)
+	}
+	e.impl.now = time.Now
+	if agg := decode(t, e.do(http.MethodGet, "/venues/v1/vibe-aggregate", "", nil)); agg["count"].(float64) != 0 {
+		t.Fatalf("expected no reports after purge, got %v", agg)
+	}
+}
+
+func TestVibe_SignedRequests(t *testing.T) {
+	e := newTestEnv(t)
+	e.impl.signingKeys = map[string][]byte{"old": []byte("old-secret"), "new": []byte("new-secret")}
+	body := []byte(`{"vibeScore": 6, "confidence": 0.9}`)
+	h, signedPath := e.h, "/venues/v1/vibe"
+	send := func(keyID, secret, nonce string, ts time.Time, payload []byte) int {
+		t.Helper()
+		stamp := strconv.FormatInt(ts.Unix(), 10)
+		req := httptest.NewRequest(http.MethodPost, "/venues/v1/vibe", bytes.NewReader(payload))
+		req.Header.Set(headerKeyID, keyID)
+		req.Header.Set(headerTimestamp, stamp)
+		req.Header.Set(headerNonce, nonce)
+		req.Header.Set(headerSignature, hex.EncodeToString(signatureMAC([]byte(secret), http.MethodPost, signedPath, stamp, nonce, body)))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+	now := time.Now()
+	if w := e.do(http.MethodPost, "/venues/v1/vibe", "", map[string]any{"vibeScore": 6}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("unsigned: expected 401, got %d", w.Code)
+	}
+	// both keys verify during a rotation
+	if code := send("old", "old-secret", "nonce-0000000001", now, body); code != http.StatusAccepted {
+		t.Fatalf("old key: expected 202, got %d", code)
+	}
+	if code := send("new", "new-secret", "nonce-0000000002", now.Add(-4*time.Minute), body); code != http.StatusAccepted {
+		t.Fatalf("new key within skew: expected 202, got %d", code)
+	}
+	cases := []struct {
+		name, keyID, secret, nonce string
+		ts                         time.Time
+		payload                    []byte
+	}{
+		{"replayed nonce", "old", "old-secret", "nonce-0000000001", now, body},
+		{"unknown key id", "gone", "old-secret", "nonce-0000000003", now, body},
+		{"wrong secret", "new", "old-secret", "nonce-0000000004", now, body},
+		{"stale timestamp", "new", "new-secret", "nonce-0000000005", now.Add(-6 * time.Minute), body},
+		{"short nonce", "new", "new-secret", "n1", now, body},
+		// same JSON with keys reordered is a different raw body
+		{"tampered body", "new", "new-secret", "nonce-0000000006", now, []byte(`{"confidence": 0.9, "vibeScore": 6}`)},
+	}
+	for _, c := range cases {
+		if code := send(c.keyID, c.secret, c.nonce, c.ts, c.payload); code != http.StatusUnauthorized {
+			t.Errorf("%s: expected 401, got %d", c.name, code)
+		}
+	}
+	// a report signed for another venue can't be posted to this one
+	signedPath = "/venues/v2/vibe"
+	if code := send("new", "new-secret", "nonce-0000000007", now, body); code != http.StatusUnauthorized {
+		t.Errorf("other path: expected 401, got %d", code)
+	}
+
+	// nonces are shared through the store, so another instance rejects a replay
+	signedPath = "/venues/v1/vibe"
+	other := newTestEnv(t)
+	other.impl.signingKeys = e.impl.signingKeys
+	other.impl.nonces = e.repo
+	h = other.h
+	if code := send("old", "old-secret", "nonce-0000000001", now, body); code != http.StatusUnauthorized {
+		t.Errorf("replay on another instance: expected 401, got %d", code)
+	}
+}
+
+func TestIdempotencyKey_ReplaysAndRejectsReuse(t *testing.T) {
+	e := newTestEnv(t)
+	post := func(path, token, key string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		b, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(b))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", key)
+		switch token {
+		case "":
+		case testServiceToken:
+			req.Header.Set(servicetoken.Header, token)
+		default:
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		e.h.ServeHTTP(w, req)
+		return w
+	}
+	bob := testToken(t, "bob")
+	report := map[string]any{"vibeScore": 7, "confidence": 1}
+	first := post("/venues/v1/vibe", bob, "k-1", report)
+	again := post("/venues/v1/vibe", bob, "k-1", report)
+	if first.Code != http.StatusAccepted || again.Code != http.StatusAccepted || again.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("replay: got %d then %d (replayed=%q)", first.Code, again.Code, again.Header().Get("Idempotent-Replayed"))
+	}
+	if n := decode(t, e.do(http.MethodGet, "/venues/v1/vibe-aggregate", "", nil))["count"].(float64); n != 1 {
+		t.Fatalf("expected the replay not to store a second report, got %v", n)
+	}
+	if w := post("/venues/v1/vibe", bob, "k-1", map[string]any{"vibeScore": 2}); w.Code != http.StatusConflict {
+		t.Fatalf("different payload: expected 409, got %d", w.Code)
+	}
+	if w := post("/venues/v2/like", testToken(t, "alice"), "k-1", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("same key from another caller: expected 204, got %d", w.Code)
+	}
+
+	// client errors are stored and replayed too
+	if w := post("/venues/nope/vibe", bob, "k-2", report); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown venue: expected 404, got %d", w.Code)
+	}
+	if w := post("/venues/nope/vibe", bob, "k-2", report); w.Code != http.StatusNotFound || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("replayed 404: got %d", w.Code)
+	}
+
+	// a refused request isn't stored: fixing its credentials and retrying
+	// with the same key runs it
+	e.impl.signingKeys = map[string][]byte{"k": []byte("secret")}
+	if w := post("/venues/v2/vibe", bob, "k-3", report); w.Code != http.StatusUnauthorized {
+		t.Fatalf("unsigned: expected 401, got %d", w.Code)
+	}
+	e.impl.signingKeys = nil
+	if w := post("/venues/v2/vibe", bob, "k-3", report); w.Code != http.StatusAccepted || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("retry after 401: got %d (replayed=%q)", w.Code, w.Header().Get("Idempotent-Replayed"))
+	}
+
+	// anonymous callers share nothing: their keys aren't recorded
+	if w := post("/venues/v3/vibe", "", "anon-1", report); w.Code != http.StatusAccepted {
+		t.Fatalf("anonymous: %d", w.Code)
+	}
+	if w := post("/venues/v3/vibe", "", "anon-1", map[string]any{"vibeScore": 2}); w.Code != http.StatusAccepted {
+		t.Fatalf("anonymous reuse of a key: expected 202, got %d", w.Code)
+	}
+	// a user can't pre-claim a producer's key
+	events := map[string]any{"events": []map[string]any{{"id": "p1", "userId": "u1", "type": "valet_use"}}}
+	if w := post("/venues/v2/like", bob, "valet:p1", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("user like: %d", w.Code)
+	}
+	if w := post("/achievements/events", testServiceToken, "valet:p1", events); w.Code != http.StatusAccepted {
+		t.Fatalf("producer with the same key: expected 202, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+type sseEvent struct {
+	id   string
+	data map[string]any
+}
+
+// readSSE returns the next event on the stream, skipping comments and retry.
+func readSSE(t *testing.T, rd *bufio.Reader) sseEvent {
+	t.Helper()
+	var ev sseEvent
+	for {
+		line, err := rd.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read stream: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "" && ev.data != nil:
+			return ev
+		case strings.HasPrefix(line, "id: "):
+			ev.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev.data); err != nil {
+				t.Fatalf("event data: %v", err)
+			}
+		}
+	}
+}
+
+func TestVibeStream_PushesUpdatesAndResumes(t *testing.T) {
+	e := newTestEnv(t)
+	e.impl.sseHeartbeat = 10 * time.Millisecond
+	srv := httptest.NewServer(e.h)
+	defer srv.Close()
+	open := func(path, lastID string) (*bufio.Reader, func()) {
+		t.Helper()
+		ctx, cancel := context.WithCancel(context.Background())
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+path, nil)
+		if lastID != "" {
+			req.Header.Set("Last-Event-ID", lastID)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil || res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != "text/event-stream" {
+			t.Fatalf("open %s: %v %v", path, err, res)
+		}
+		return bufio.NewReader(res.Body), func() { cancel(); res.Body.Close() }
+	}
+
+	venue, closeVenue := open("/venues/v1/vibe/stream", "")
+	defer closeVenue()
+	// v1 and v2 are in this box; v8 is not
+	area, closeArea := open("/vibe/stream?bbox=-122.43,37.77,-122.39,37.80", "")
+	defer closeArea()
+	if snap := readSSE(t, venue); snap.data["id"] != "v1" || snap.data["score"] != nil {
+		t.Fatalf("expected an empty v1 snapshot, got %+v", snap)
+	}
+
+	e.do(http.MethodPost, "/venues/v1/vibe", "", map[string]any{"vibeScore": 8, "confidence": 1})
+	ev := readSSE(t, venue)
+	if ev.data["id"] != "v1" || ev.data["score"].(float64) != 8 || ev.id == "" {
+		t.Fatalf("unexpected update %+v", ev)
+	}
+	// the box snapshot (no id yet) comes first, then the live update
+	var snapshot []string
+	for {
+		a := readSSE(t, area)
+		if a.id != "" {
+			if a.id != ev.id || a.data["id"] != "v1" {
+				t.Fatalf("area stream: unexpected update %+v", a)
+			}
+			break
+		}
+		snapshot = append(snapshot, a.data["id"].(string))
+	}
+	if !containsAll(snapshot, "v1", "v2") || containsAll(snapshot, "v8") {
+		t.Fatalf("area snapshot %v: want v1 and v2, not v8", snapshot)
+	}
+
+	// reconnecting with the last id replays what was missed, without a snapshot
+	e.do(http.MethodPost, "/venues/v1/vibe", "", map[string]any{"vibeScore": 6, "confidence": 1})
+	closeVenue()
+	resumed, closeResumed := open("/venues/v1/vibe/stream", ev.id)
+	defer closeResumed()
+	if next := readSSE(t, resumed); next.id == ev.id || next.data["score"].(float64) >= 8 {
+		t.Fatalf("expected the missed update after %s, got %+v", ev.id, next)
+	}
+
+	if w := e.do(http.MethodGet, "/vibe/stream?bbox=-123,37,-121,38", "", nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("oversized bbox: expected 400, got %d", w.Code)
+	}
+}
+
+type fakeGeocoder map[string][2]float64
+
+func (f fakeGeocoder) Geocode(_ context.Context, a db.Address) (float64, float64, error) {
+	if c, ok := f[a.Line1]; ok {
+		return c[0], c[1], nil
+	}
+	return 0, 0, geocode.ErrNoMatch
+}
+
+func TestAdminVenues_CreateUpdateArchiveAndAudit(t *testing.T) {
+	e := newTestEnv(t)
+	e.impl.geocoder = fakeGeocoder{"500 Howard St": {37.7888, -122.3965}}
+	admin := testToken(t, "admin-1", "admin")
+
+	if w := e.do(http.MethodPost, "/admin/venues", testToken(t, "u1"), map[string]any{"title": "X"}); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for plain user, got %d", w.Code)
+	}
+	if w := e.do(http.MethodPost, "/admin/venues", admin, map[string]any{"title": "Lantern", "category": "speakeasy", "lat": 37.78, "lng": -122.40}); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown category, got %d", w.Code)
+	}
+	if w := e.do(http.MethodPost, "/admin/venues", admin, map[string]any{"title": "Lantern", "category": "bar", "address": map[string]any{"line1": "nowhere"}}); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for ungeocodable address, got %d", w.Code)
+	}
+
+	// legacy VenueCreate fields plus a geocoded address
+	w := e.do(http.MethodPost, "/admin/venues", admin, map[string]any{
+		"title": " Lantern ", "subtitle": "SoMa", "price": "$$", "category": "Bar", "tags": []string{"Cocktails", "cocktails"},
+		"address": map[string]any{"line1": "500 Howard St", "city": "San Francisco"},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+	created := decode(t, w)
+	id := created["id"].(string)
+	if created["title"] != "Lantern" || created["priceTier"] != 2.0 || created["lat"] != 37.7888 || created["status"] != "active" {
+		t.Fatalf("unexpected venue %v", created)
+	}
+	if tags := created["tags"].([]any); len(tags) != 1 || tags[0] != "cocktails" {
+		t.Fatalf("tags not normalized: %v", tags)
+	}
+	if w := e.do(http.MethodGet, "/venues/"+id, "", nil); w.Code != http.StatusOK {
+		t.Fatalf("new venue not public: %d", w.Code)
+	}
+
+	if w := e.do(http.MethodPatch, "/admin/venues/"+id, admin, map[string]any{"hours": map[string]any{"weekly": []map[string]any{{"day": 7, "open": "18:00", "close": "02:00"}}}}); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for bad hours, got %d", w.Code)
+	}
+	w = e.do(http.MethodPatch, "/admin/venues/"+id, admin, map[string]any{"name": "Lantern Room", "lat": 37.79, "lng": -122.39})
+	if w.Code != http.StatusOK || decode(t, w)["name"] != "Lantern Room" {
+		t.Fatalf("patch: %d %s", w.Code, w.Body.String())
+	}
+	if w := e.do(http.MethodPost, "/admin/venues/"+id+"/archive", admin, nil); w.Code != http.StatusOK || decode(t, w)["status"] != "archived" {
+		t.Fatalf("archive: %d", w.Code)
+	}
+	// archiving twice is a no-op
+	if w := e.do(http.MethodPost, "/admin/venues/"+id+"/archive", admin, nil); w.Code != http.StatusOK {
+		t.Fatalf("re-archive: %d", w.Code)
+	}
+	if w := e.do(http.MethodGet, "/venues/discover", "", nil); containsAll(itemIDs(t, w), id) {
+		t.Fatalf("archived venue still discoverable")
+	}
+	if w := e.do(http.MethodPost, "/admin/venues/"+id+"/restore", admin, nil); w.Code != http.StatusOK || decode(t, w)["status"] != "active" {
+		t.Fatalf("restore: %d", w.Code)
+	}
+
+	w = e.do(http.MethodGet, "/admin/venues/"+id+"/audit", admin, nil)
+	items, _ := decode(t, w)["items"].([]any)
+	var actions []string
+	for _, it := range items {
+		actions = append(actions, it.(map[string]any)["action"].(string))
+	}
+	if strings.Join(actions, ",") != "restore,archive,update,create" {
+		t.Fatalf("audit actions = %v", actions)
+	}
+	update := items[2].(map[string]any)
+	changes := update["changes"].(map[string]any)
+	if update["actorId"] != "admin-1" || changes["name"] == nil || changes["category"] != nil {
+		t.Fatalf("unexpected update audit %v", update)
+	}
+}
+
+func TestAdminVenues_HostScopeAndBulkStatus(t *testing.T) {
+	e := newTestEnv(t)
+	host := testToken(t, "host-1", "host")
+	admin := testToken(t, "admin-1", "admin")
+
+	w := e.do(http.MethodPost, "/admin/venues", host, map[string]any{"name": "Host Bar", "category": "bar", "lat": 37.78, "lng": -122.41})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("host create: %d %s", w.Code, w.Body.String())
+	}
+	v := decode(t, w)
+	id := v["id"].(string)
+	if v["ownerId"] != "host-1" || v["status"] != "draft" {
+		t.Fatalf("host venue should be an owned draft: %v", v)
+	}
+	if ids := itemIDs(t, e.do(http.MethodGet, "/admin/venues", host, nil)); len(ids) != 1 || ids[0] != id {
+		t.Fatalf("host list = %v", ids)
+	}
+	if w := e.do(http.MethodPatch, "/admin/venues/v1", host, map[string]any{"name": "Mine now"}); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 editing another venue, got %d", w.Code)
+	}
+	if w := e.do(http.MethodPatch, "/admin/venues/"+id, host, map[string]any{"ownerId": "host-2"}); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 changing owner, got %d", w.Code)
+	}
+
+	// hosts can't publish: no status on create or PATCH, no bulk-status, and
+	// restore only brings a venue back as a draft
+	if w := e.do(http.MethodPost, "/admin/venues", host, map[string]any{"name": "Live Bar", "category": "bar", "lat": 37.78, "lng": -122.41, "status": "active"}); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for host create with status, got %d", w.Code)
+	}
+	if w := e.do(http.MethodPatch, "/admin/venues/"+id, host, map[string]any{"status": "active"}); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for host status patch, got %d", w.Code)
+	}
+	if w := e.do(http.MethodPost, "/admin/venues/bulk-status", host, map[string]any{"ids": []string{id}, "status": "active"}); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for host bulk-status, got %d", w.Code)
+	}
+	if w := e.do(http.MethodPost, "/admin/venues/"+id+"/archive", host, nil); w.Code != http.StatusOK || decode(t, w)["status"] != "archived" {
+		t.Fatalf("host archive: %d", w.Code)
+	}
+	if w := e.do(http.MethodPost, "/admin/venues/"+id+"/restore", host, nil); w.Code != http.StatusOK || decode(t, w)["status"] != "draft" {
+		t.Fatalf("host restore should leave a draft: %d", w.Code)
+	}
+
+	// drafts are hidden from the public until published
+	if w := e.do(http.MethodGet, "/venues/"+id, "", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a public draft, got %d", w.Code)
+	}
+	if w := e.do(http.MethodGet, "/venues/"+id, testToken(t, "u1"), nil); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for another user's draft, got %d", w.Code)
+	}
+	if w := e.do(http.MethodGet, "/venues/"+id, host, nil); w.Code != http.StatusOK {
+		t.Fatalf("owner should see the draft, got %d", w.Code)
+	}
+
+	w = e.do(http.MethodPost, "/admin/venues/bulk-status", admin, map[string]any{"ids": []string{id, "v1", "nope"}, "status": "active"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("bulk: %d %s", w.Code, w.Body.String())
+	}
+	got := map[string]string{}
+	for _, r := range decode(t, w)["results"].([]any) {
+		m := r.(map[string]any)
+		got[m["id"].(string)] = m["result"].(string)
+	}
+	if got[id] != "updated" || got["v1"] != "unchanged" || got["nope"] != "not_found" {
+		t.Fatalf("bulk results = %v", got)
+	}
+
+	w = e.do(http.MethodPost, "/admin/venues/bulk-status", admin, map[string]any{"ids": []string{"v1", "v2"}, "status": "archived"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("admin bulk: %d", w.Code)
+	}
+	if ids := itemIDs(t, e.do(http.MethodGet, "/admin/venues?status=archived", admin, nil)); !containsAll(ids, "v1", "v2") || len(ids) != 2 {
+		t.Fatalf("archived list = %v", ids)
+	}
+	if ids := itemIDs(t, e.do(http.MethodGet, "/venues/discover", "", nil)); containsAll(ids, "v1") || !containsAll(ids, id) {
+		t.Fatalf("discover after bulk = %v", ids)
+	}
+	if w := e.do(http.MethodGet, "/venues/v1", "", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an archived venue, got %d", w.Code)
+	}
+	if w := e.do(http.MethodGet, "/venues/v1", admin, nil); w.Code != http.StatusOK {
+		t.Fatalf("admin should see archived venues, got %d", w.Code)
+	}
+}
+
+func TestAdminVenues_Import(t *testing.T) {
+	e := newTestEnv(t)
+	csv := "name,category,price,lat,lng\nDogpatch Saloon,bar,$,37.7597,-122.3880\nEnergetic Bar,bar,$$,37.7897,-122.4011\nBroken,bar,$,x,y\n"
+	post := func(query, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/venues/import"+query, strings.NewReader(csv))
+		req.Header.Set("Content-Type", "text/csv")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		e.h.ServeHTTP(w, req)
+		return w
+	}
+	if w := post("", testToken(t, "host-1", "host")); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for host import, got %d", w.Code)
+	}
+	admin := testToken(t, "admin-1", "admin")
+
+	w := post("?dryRun=true", admin)
+	if w.Code != http.StatusOK {
+		t.Fatalf("dry run: %d %s", w.Code, w.Body.String())
+	}
+	if rep := decode(t, w); rep["dryRun"] != true || rep["created"] != 1.0 || rep["skipped"] != 1.0 || rep["errors"] != 1.0 {
+		t.Fatalf("dry run report %v", rep)
+	}
+	if ids := itemIDs(t, e.do(http.MethodGet, "/admin/venues", admin, nil)); len(ids) != 8 {
+		t.Fatalf("dry run created venues: %v", ids)
+	}
+
+	rep := decode(t, post("", admin))
+	id := rep["rows"].([]any)[0].(map[string]any)["id"].(string)
+	if rep["created"] != 1.0 || id == "" {
+		t.Fatalf("import report %v", rep)
+	}
+	if w := e.do(http.MethodGet, "/venues/"+id, "", nil); w.Code != http.StatusOK {
+		t.Fatalf("imported venue missing: %d", w.Code)
+	}
+	items, _ := decode(t, e.do(http.MethodGet, "/admin/venues/"+id+"/audit", admin, nil))["items"].([]any)
+	if len(items) != 1 || items[0].(map[string]any)["action"] != "import" {
+		t.Fatalf("audit = %v", items)
+	}
+
+	// files over the default 1 MiB idempotency buffer still import and replay
+	row := "Dogpatch Saloon,bar,$,37.7597,-122.3880," + strings.Repeat("x", 400) + "\n"
+	big := "name,category,price,lat,lng,notes\n" + strings.Repeat(row, 3000)
+	retry := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/venues/import?dryRun=true", strings.NewReader(big))
+		req.Header.Set("Content-Type", "text/csv")
+		req.Header.Set("Authorization", "Bearer "+admin)
+		req.Header.Set("Idempotency-Key", "import-1")
+		w := httptest.NewRecorder()
+		e.h.ServeHTTP(w, req)
+		return w
+	}
+	if w := retry(); w.Code != http.StatusOK {
+		t.Fatalf("large import: %d %.200s", w.Code, w.Body.String())
+	}
+	if w := retry(); w.Code != http.StatusOK || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("large import retry: %d (replayed=%q)", w.Code, w.Header().Get("Idempotent-Replayed"))
+	}
+}
+
+func TestSearch_FuzzyRankedAndReindexedOnEdit(t *testing.T) {
+	e := newTestEnv(t)
+	if ids := itemIDs(t, e.do(http.MethodGet, "/venues/search?q=blue+note", "", nil)); len(ids) != 1 || ids[0] != "v6" {
+		t.Fatalf("expected Blue Note Cellar, got %v", ids)
+	}
+	// typo in a tag, ranked nearest first around Union Square
+	if ids := itemIDs(t, e.do(http.MethodGet, "/venues/search?q=cocktials&lat=37.7880&lon=-122.4075", "", nil)); strings.Join(ids, ",") != "v7,v1,v6" {
+		t.Fatalf("expected cocktail bars nearest first, got %v", ids)
+	}
+	if ids := itemIDs(t, e.do(http.MethodGet, "/venues/search?q=cocktails&category=live_music", "", nil)); len(ids) != 1 || ids[0] != "v6" {
+		t.Fatalf("category filter: %v", ids)
+	}
+	if ids := itemIDs(t, e.do(http.MethodGet, "/venues/search?q=hayes", "", nil)); len(ids) != 1 || ids[0] != "v6" {
+		t.Fatalf("neighborhood search: %v", ids)
+	}
+
+	// edits reach the index without waiting for the catalog TTL
+	admin := testToken(t, "admin-1", "admin")
+	if w := e.do(http.MethodPatch, "/admin/venues/v6", admin, map[string]any{"name": "Green Room"}); w.Code != http.StatusOK {
+		t.Fatalf("patch: %d %s", w.Code, w.Body.String())
+	}
+	if ids := itemIDs(t, e.do(http.MethodGet, "/venues/search?q=blue+note", "", nil)); len(ids) != 0 {
+		t.Fatalf("old name still found: %v", ids)
+	}
+	if ids := itemIDs(t, e.do(http.MethodGet, "/venues/search?q=green+room", "", nil)); len(ids) != 1 || ids[0] != "v6" {
+		t.Fatalf("new name not found: %v", ids)
+	}
+	if w := e.do(http.MethodPost, "/admin/venues/v7/archive", admin, nil); w.Code != http.StatusOK {
+		t.Fatalf("archive: %d", w.Code)
+	}
+	if ids := itemIDs(t, e.do(http.MethodGet, "/venues/search?q=rooftop", "", nil)); len(ids) != 0 {
+		t.Fatalf("archived venue still found: %v", ids)
+	}
+
+	for _, q := range []string{"", "?q=+", "?q=bar&lat=37.7", "?q=" + strings.Repeat("x", 101)} {
+		if w := e.do(http.MethodGet, "/venues/search"+q, "", nil); w.Code != http.StatusBadRequest {
+			t.Fatalf("%q: expected 400, got %d", q, w.Code)
+		}
+	}
+}
+
+func TestAutocomplete_SuggestsVenuesNeighborhoodsAndTags(t *testing.T) {
+	e := newTestEnv(t)
+	w := e.do(http.MethodGet, "/venues/autocomplete?q=Blu", "", nil)
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Server-Timing"), "suggest;dur=") {
+		t.Fatalf("autocomplete: %d %v", w.Code, w.Header())
+	}
+	got, _ := decode(t, w)["suggestions"].([]any)
+	if len(got) != 1 || got[0].(map[string]any)["id"] != "v6" || got[0].(map[string]any)["text"] != "Blue Note Cellar" {
+		t.Fatalf("unexpected suggestions %v", got)
+	}
+	got, _ = decode(t, e.do(http.MethodGet, "/venues/autocomplete?q=coc", "", nil))["suggestions"].([]any)
+	if len(got) != 1 || got[0].(map[string]any)["kind"] != "tag" || got[0].(map[string]any)["count"] != 3.0 {
+		t.Fatalf("expected the shared cocktails tag, got %v", got)
+	}
+	got, _ = decode(t, e.do(http.MethodGet, "/venues/autocomplete?q=mission&limit=5", "", nil))["suggestions"].([]any)
+	if len(got) != 2 || got[0].(map[string]any)["kind"] != "venue" || got[1].(map[string]any)["kind"] != "neighborhood" {
+		t.Fatalf("expected venue then neighborhood, got %v", got)
+	}
+	got, _ = decode(t, e.do(http.MethodGet, "/venues/autocomplete?q=zzzz", "", nil))["suggestions"].([]any)
+	if got == nil || len(got) != 0 {
+		t.Fatalf("expected empty suggestions, got %v", got)
+	}
+	if w := e.do(http.MethodGet, "/venues/autocomplete?q=a&limit=0", "", nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for limit=0, got %d", w.Code)
+	}
+}
+
+func TestRecommendations_PersonalizedWithReasons(t *testing.T) {
+	e := newTestEnv(t)
+	la, _ := time.LoadLocation("America/Los_Angeles")
+	e.impl.now = func() time.Time { return time.Date(2026, 1, 9, 21, 0, 0, 0, la) } // Friday night
+	ctx := context.Background()
+	_, _ = e.repo.UpsertInteraction(ctx, "u1", "v1", db.InteractionLike)
+	_, _ = e.repo.UpsertInteraction(ctx, "u1", "v6", db.InteractionLike)
+	_, _ = e.repo.UpsertInteraction(ctx, "u1", "v3", db.InteractionSkip)
+
+	if w := e.do(http.MethodGet, "/users/recommendations", "", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", w.Code)
+	}
+	w := e.do(http.MethodGet, "/users/recommendations", testToken(t, "u1"), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("recommendations: %d %s", w.Code, w.Body.String())
+	}
+	items, _ := decode(t, w)["items"].([]any)
+	if len(items) != 5 {
+		t.Fatalf("expected the 5 venues not yet swiped, got %d", len(items))
+	}
+	first := items[0].(map[string]any)
+	if first["id"] != "v7" || first["reason"] != "Similar to Energetic Bar, which you liked" {
+		t.Fatalf("expected the open cocktail bar first, got %v %v", first["id"], first["reason"])
+	}
+	for _, it := range items {
+		m := it.(map[string]any)
+		if m["id"] == "v1" || m["id"] == "v3" || m["id"] == "v6" || m["reason"] == "" {
+			t.Fatalf("unexpected item %v", m)
+		}
+	}
+	// daytime venues are closed at 9pm and sink to the bottom
+	if last := items[4].(map[string]any); last["openNow"] != false {
+		t.Fatalf("expected a closed venue last, got %v", last)
+	}
+	// with a location, distance counts and is explained
+	items, _ = decode(t, e.do(http.MethodGet, "/users/recommendations?lat=37.7880&lon=-122.4075&limit=2", testToken(t, "u1"), nil))["items"].([]any)
+	if len(items) != 2 || items[0].(map[string]any)["reason"] != "Open now, 0 m away" {
+		t.Fatalf("unexpected nearby recommendations %v", items)
+	}
+
+	for _, q := range []string{"?vibe=loud", "?lat=37.7", "?limit=0"} {
+		if w := e.do(http.MethodGet, "/users/recommendations"+q, testToken(t, "u1"), nil); w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", q, w.Code)
+		}
+	}
+}
+
+func TestHeatmap_CellsFromVibesAndActivityCachedPerCell(t *testing.T) {
+	e := newTestEnv(t)
+	now := time.Now()
+	e.impl.now = func() time.Time { return now }
+	for i := 0; i < 3; i++ {
+		if w := e.do(http.MethodPost, "/venues/v7/vibe", "", map[string]any{"vibeScore": 9, "confidence": 1}); w.Code != http.StatusAccepted {
+			t.Fatalf("vibe: %d", w.Code)
+		}
+	}
+	quiet := geo.Encode(37.7700, -122.4300, 7) // no venues
+	signal := func(devices int) map[string]any {
+		return map[string]any{"signals": []map[string]any{{"geohash7": quiet, "devices": devices, "intensity": 0.8, "observedAt": now.UTC().Format(time.RFC3339)}}}
+	}
+	if w := e.do(http.MethodPost, "/signals/activity", testToken(t, "u1"), signal(20)); w.Code != http.StatusUnauthorized {
+		t.Fatalf("signals without a service token: %d", w.Code)
+	}
+	e.impl.signalTokens = servicetoken.New()
+	if w := e.doService(http.MethodPost, "/signals/activity", signal(20)); w.Code != http.StatusUnauthorized {
+		t.Fatalf("signals with no tokens configured: %d", w.Code)
+	}
+	e.impl.signalTokens = servicetoken.New(testServiceToken)
+	w := e.doService(http.MethodPost, "/signals/activity", signal(20))
+	if w.Code != http.StatusAccepted || decode(t, w)["accepted"].(float64) != 1 {
+		t.Fatalf("signals: %d %s", w.Code, w.Body.String())
+	}
+
+	const path = "/vibe/heatmap?bbox=-122.45,37.76,-122.39,37.80&precision=6"
+	cells := func() map[string]map[string]any {
+		t.Helper()
+		w := e.do(http.MethodGet, path, "", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("heatmap: %d %s", w.Code, w.Body.String())
+		}
+		if cc := w.Header().Get("Cache-Control"); cc != "public, max-age=30" {
+			t.Fatalf("unexpected Cache-Control %q", cc)
+		}
+		out := map[string]map[string]any{}
+		for _, c := range decode(t, w)["cells"].([]any) {
+			m := c.(map[string]any)
+			if in := m["intensity"].(float64); in <= 0 || in > 1 {
+				t.Fatalf("intensity out of range: %v", m)
+			}
+			out[m["geohash"].(string)] = m
+		}
+		return out
+	}
+	got := cells()
+	venue := got[geo.Encode(37.7880, -122.4075, 6)]
+	if venue == nil || venue["vibe"].(float64) != 9 || venue["venues"].(float64) < 1 {
+		t.Fatalf("expected v7's cell with its vibe, got %v", venue)
+	}
+	active := got[quiet[:6]]
+	if active == nil || math.Abs(active["devices"].(float64)-20) > 0.1 || active["vibe"] != nil {
+		t.Fatalf("expected the signal's cell with 20 devices, got %v", active)
+	}
+	if c := got[geo.Encode(37.7749, -122.4128, 6)]; c != nil {
+		t.Fatalf("v3 has no reports or activity, so its cell should be cold: %v", c)
+	}
+
+	// cells are served from cache until the TTL runs out
+	e.doService(http.MethodPost, "/signals/activity", signal(40))
+	if d := cells()[quiet[:6]]["devices"]; d != active["devices"] {
+		t.Fatalf("expected the cached cell, got %v devices", d)
+	}
+	now = now.Add(heatCellTTL + time.Second)
+	if d := cells()[quiet[:6]]["devices"].(float64); d <= 25 {
+		t.Fatalf("expected the cell to be recomputed after the TTL, got %v devices", d)
+	}
+
+	for _, q := range []string{"", "?bbox=-122.45,37.76,-122.39", "?bbox=-122.45,37.76,-122.39,37.80&precision=8",
+		"?bbox=-122.45,37.76,-122.39,37.80&precision=3", "?bbox=-123,37,-122,38&precision=7"} {
+		if w := e.do(http.MethodGet, "/vibe/heatmap"+q, "", nil); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", q, w.Code)
+		}
+	}
+	for _, body := range []map[string]any{
+		{"signals": []map[string]any{}},
+		{"signals": []map[string]any{{"geohash7": "9q8yy", "devices": 3, "intensity": 0.5}}},
+		{"signals": []map[string]any{{"geohash7": "9q8yyia", "devices": 3, "intensity": 0.5}}},
+		{"signals": []map[string]any{{"geohash7": quiet, "devices": -1, "intensity": 0.5}}},
+		{"signals": []map[string]any{{"geohash7": quiet, "devices": 3, "intensity": 1.5}}},
+		{"signals": []map[string]any{{"geohash7": quiet, "devices": 3, "intensity": 0.5, "observedAt": now.Add(-48 * time.Hour).Format(time.RFC3339)}}},
+	} {
+		if w := e.doService(http.MethodPost, "/signals/activity", body); w.Code != http.StatusBadRequest {
+			t.Errorf("%v: expected 400, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestTrending_RisingVibeAndLikesAgainstBaseline(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	now := time.Now()
+	report := func(id string, score float64, at time.Time) {
+		t.Helper()
+		body := map[string]any{"vibeScore": score, "confidence": 1, "timestamp": at.UTC().Format(time.RFC3339)}
+		if w := e.do(http.MethodPost, "/venues/"+id+"/vibe", "", body); w.Code != http.StatusAccepted {
+			t.Fatalf("vibe: %d", w.Code)
+		}
+	}
+	// v3 usually scores 3 and is at 9 tonight; v1 is as busy as ever
+	for d := 1; d <= 3; d++ {
+		report("v3", 3, now.Add(-time.Duration(d)*24*time.Hour))
+		report("v1", 7, now.Add(-time.Duration(d)*24*time.Hour))
+	}
+	for i := 0; i < 3; i++ {
+		report("v3", 9, now)
+		report("v1", 7, now)
+	}
+	// a burst of likes for v4, a single one for v2
+	for _, u := range []string{"u1", "u2", "u3", "u4"} {
+		_, _ = e.repo.UpsertInteraction(ctx, u, "v4", db.InteractionLike)
+	}
+	_, _ = e.repo.UpsertInteraction(ctx, "u1", "v2", db.InteractionLike)
+
+	w := e.do(http.MethodGet, "/venues/trending", "", nil)
+	if ids := itemIDs(t, w); strings.Join(ids, ",") != "v4,v3" {
+		t.Fatalf("expected [v4 v3], got %v", ids)
+	}
+	items := decode(t, w)["items"].([]any)
+	v4 := items[0].(map[string]any)["trend"].(map[string]any)
+	v3 := items[1].(map[string]any)["trend"].(map[string]any)
+	if v4["likesRecent"].(float64) != 4 || v4["score"].(float64) != 4 {
+		t.Fatalf("unexpected v4 trend %v", v4)
+	}
+	if v3["vibeBaseline"].(float64) != 3 || v3["vibeNow"].(float64) < 8.9 || v3["score"].(float64) < 2.9 {
+		t.Fatalf("unexpected v3 trend %v", v3)
+	}
+
+	// like counts are cached briefly
+	for _, u := range []string{"u5", "u6", "u7"} {
+		_, _ = e.repo.UpsertInteraction(ctx, u, "v2", db.InteractionLike)
+	}
+	if ids := itemIDs(t, e.do(http.MethodGet, "/venues/trending", "", nil)); len(ids) != 2 {
+		t.Fatalf("expected cached like counts, got %v", ids)
+	}
+	e.impl.now = func() time.Time { return time.Now().Add(likeCountsTTL) }
+	if ids := itemIDs(t, e.do(http.MethodGet, "/venues/trending", "", nil)); !containsAll(ids, "v2", "v3", "v4") {
+		t.Fatalf("expected v2 after the cache expired, got %v", ids)
+	}
+
+	// near v4 only venues within 10 km are considered, with distances
+	items = decode(t, e.do(http.MethodGet, "/venues/trending?lat=37.7599&lon=-122.4148&limit=1", "", nil))["items"].([]any)
+	if len(items) != 1 || items[0].(map[string]any)["distance"].(float64) != 0 {
+		t.Fatalf("unexpected nearby trending %v", items)
+	}
+	for _, q := range []string{"?lat=37.7", "?limit=0", "?lat=91&lon=0"} {
+		if w := e.do(http.MethodGet, "/venues/trending"+q, "", nil); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", q, w.Code)
+		}
+	}
+}
+
+type fakeNotifier struct {
+	checkins []db.Checkin
+	names    []string
+	earned   []string // userID/achievementID
+}
+
+func (f *fakeNotifier) CheckedIn(c db.Checkin, venueName, friendName string) {
+	f.checkins = append(f.checkins, c)
+	f.names = append(f.names, friendName+" @ "+venueName)
+}
+
+func (f *fakeNotifier) AchievementEarned(userID string, d achieve.Definition) {
+	f.earned = append(f.earned, userID+"/"+d.ID)
+}
+
+// fakeFriends is auth-service's friend graph: names and friends by user.
+type fakeFriends struct {
+	names   map[string]string
+	friends map[string][]string
+}
+
+func (f fakeFriends) ShareTargets(_ context.Context, userID string, ids []string) (string, []string, error) {
+	var out []string
+	for _, id := range ids {
+		if slices.Contains(f.friends[userID], id) {
+			out = append(out, id)
+		}
+	}
+	return f.names[userID], out, nil
+}
+
+func TestCheckin_GeofenceCooldownSharingAndHistory(t *testing.T) {
+	e := newTestEnv(t)
+	notifier := &fakeNotifier{}
+	e.impl.notifier = notifier
+	e.impl.friends = fakeFriends{names: map[string]string{"u1": "Sam"}, friends: map[string][]string{"u1": {"u2", "u3"}}}
+	now := time.Now()
+	e.impl.now = func() time.Time { return now }
+	tok := testToken(t, "u1")
+	// v7 Rooftop 22 is at 37.7880,-122.4075; ~100 m north is inside the fence
+	here := map[string]any{"lat": 37.7889, "lon": -122.4075, "accuracy": 10}
+
+	if w := e.do(http.MethodPost, "/venues/v7/checkin", "", here); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", w.Code)
+	}
+	far := map[string]any{"lat": 37.7910, "lon": -122.4075, "accuracy": 500} // ~330 m away
+	if w := e.do(http.MethodPost, "/venues/v7/checkin", tok, far); w.Code != http.StatusUnprocessableEntity || decode(t, w)["code"] != "OUTSIDE_GEOFENCE" {
+		t.Fatalf("expected 422 outside the geofence, got %d %s", w.Code, w.Body.String())
+	}
+	// accuracy widens the fence by at most 50 m
+	edge := map[string]any{"lat": 37.7897, "lon": -122.4075} // ~190 m away
+	if w := e.do(http.MethodPost, "/venues/v7/checkin", tok, edge); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 at 190 m without accuracy, got %d", w.Code)
+	}
+	edge["accuracy"] = 45.0
+	w := e.do(http.MethodPost, "/venues/v7/checkin", tok, edge)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("checkin: %d %s", w.Code, w.Body.String())
+	}
+	got := decode(t, w)
+	if got["venueId"] != "v7" || got["distance"].(float64) < 180 || got["venue"].(map[string]any)["name"] != "Rooftop 22" {
+		t.Fatalf("unexpected checkin %v", got)
+	}
+
+	// one check-in per venue per cooldown, but another venue is fine
+	w = e.do(http.MethodPost, "/venues/v7/checkin", tok, here)
+	if w.Code != http.StatusTooManyRequests || decode(t, w)["code"] != "CHECKIN_COOLDOWN" || w.Header().Get("Retry-After") != "14400" {
+		t.Fatalf("expected 429 with Retry-After, got %d %s %q", w.Code, w.Body.String(), w.Header().Get("Retry-After"))
+	}
+	now = now.Add(checkinCooldown + time.Minute)
+	// u9 is not a friend, and the name comes from the profile, not the request
+	share := map[string]any{"lat": 37.7889, "lon": -122.4075, "share": map[string]any{"friendIds": []string{"u2", "u9", "u3", "u2", "u1"}, "name": "Your bank"}}
+	w = e.do(http.MethodPost, "/venues/v7/checkin", tok, share)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("checkin after cooldown: %d %s", w.Code, w.Body.String())
+	}
+	if len(notifier.checkins) != 1 || strings.Join(notifier.checkins[0].SharedWith, ",") != "u2,u3" || notifier.names[0] != "Sam @ Rooftop 22" {
+		t.Fatalf("expected friends u2,u3 notified once, got %+v %v", notifier.checkins, notifier.names)
+	}
+	if w := e.do(http.MethodPost, "/venues/v1/checkin", tok, map[string]any{"lat": 37.7897, "lon": -122.4011}); w.Code != http.StatusCreated {
+		t.Fatalf("checkin at another venue: %d", w.Code)
+	}
+
+	items, _ := decode(t, e.do(http.MethodGet, "/users/me/checkins", tok, nil))["items"].([]any)
+	if len(items) != 3 {
+		t.Fatalf("expected 3 check-ins, got %d", len(items))
+	}
+	latest := items[0].(map[string]any)
+	if latest["venueId"] != "v1" || items[1].(map[string]any)["sharedWith"].([]any)[1] != "u3" {
+		t.Fatalf("unexpected history %v", items)
+	}
+	if other, _ := decode(t, e.do(http.MethodGet, "/users/me/checkins", testToken(t, "u2"), nil))["items"].([]any); len(other) != 0 {
+		t.Fatalf("check-ins leaked to another user: %v", other)
+	}
+
+	for _, body := range []map[string]any{{"lat": 37.7889}, {"lat": 91, "lon": 0}, {"lat": 37.7889, "lon": -122.4075, "accuracy": -1}} {
+		if w := e.do(http.MethodPost, "/venues/v7/checkin", tok, body); w.Code != http.StatusBadRequest {
+			t.Errorf("%v: expected 400, got %d", body, w.Code)
+		}
+	}
+	if w := e.do(http.MethodPost, "/venues/nope/checkin", tok, here); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown venue: expected 404, got %d", w.Code)
+	}
+}
+
+func TestReviews_ModerationVotesReportsAndAggregates(t *testing.T) {
+	e := newTestEnv(t)
+	alice, bob, admin := testToken(t, "alice"), testToken(t, "bob"), testToken(t, "root", "admin")
+	post := func(tok string, body map[string]any) *httptest.ResponseRecorder {
+		return e.do(http.MethodPost, "/venues/v1/reviews", tok, body)
+	}
+	venueStats := func() (float64, any) {
+		v := decode(t, e.do(http.MethodGet, "/venues/v1", "", nil))
+		return v["reviewCount"].(float64), v["reviewRating"]
+	}
+
+	if w := post("", map[string]any{"kind": "review", "rating": 5, "body": "Great rooftop views"}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", w.Code)
+	}
+	for _, bad := range []map[string]any{
+		{"kind": "review", "body": "No rating given here"},
+		{"kind": "review", "rating": 6, "body": "Rating out of range"},
+		{"kind": "tip", "rating": 4, "body": "Tips carry no rating"},
+		{"kind": "essay", "body": "Unknown kind of content"},
+		{"kind": "review", "rating": 3, "body": "short"},
+	} {
+		if w := post(alice, bad); w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %v, got %d", bad, w.Code)
+		}
+	}
+
+	// clean content publishes straight away and updates the aggregates
+	w := post(alice, map[string]any{"kind": "review", "rating": 5, "body": "Great rooftop views and quick bar", "authorName": "Alice"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create review: %d %s", w.Code, w.Body.String())
+	}
+	aliceReview := decode(t, w)
+	aliceID := fmt.Sprint(aliceReview["id"])
+	if aliceReview["status"] != "published" || aliceReview["authorName"] != "Alice" {
+		t.Fatalf("unexpected review %v", aliceReview)
+	}
+	if w := post(alice, map[string]any{"kind": "review", "rating": 4, "body": "Second review attempt"}); w.Code != http.StatusConflict || decode(t, w)["code"] != "ALREADY_REVIEWED" {
+		t.Fatalf("expected 409 for a second review, got %d", w.Code)
+	}
+	if w := post(bob, map[string]any{"kind": "review", "rating": 2, "body": "Way too loud for talking"}); w.Code != http.StatusCreated {
+		t.Fatalf("bob review: %d", w.Code)
+	}
+	if n, avg := venueStats(); n != 2 || avg != 3.5 {
+		t.Fatalf("expected 2 reviews averaging 3.5, got %v %v", n, avg)
+	}
+
+	// the keyword filter blocks threats and holds profanity for the queue
+	if w := post(bob, map[string]any{"kind": "tip", "body": "bartender should kys"}); w.Code != http.StatusUnprocessableEntity || decode(t, w)["code"] != "CONTENT_REJECTED" {
+		t.Fatalf("expected 422 for blocked content, got %d", w.Code)
+	}
+	w = post(bob, map[string]any{"kind": "tip", "body": "Sh1t coffee, order the negroni"})
+	held := decode(t, w)
+	if w.Code != http.StatusCreated || held["status"] != "pending" {
+		t.Fatalf("expected held tip, got %d %v", w.Code, held)
+	}
+	heldID := fmt.Sprint(held["id"])
+	// dates and hours aren't phone numbers
+	if w := post(alice, map[string]any{"kind": "tip", "body": "Ask for a table by the east window, 2024-05-01 open 10 - 12"}); w.Code != http.StatusCreated {
+		t.Fatalf("alice tip: %d", w.Code)
+	}
+	tips, _ := decode(t, e.do(http.MethodGet, "/venues/v1/reviews?kind=tip", "", nil))["items"].([]any)
+	if len(tips) != 1 || tips[0].(map[string]any)["status"] != nil {
+		t.Fatalf("expected only the published tip without status, got %v", tips)
+	}
+	if w := e.do(http.MethodPatch, "/reviews/"+heldID, alice, map[string]any{"body": "hijack"}); w.Code != http.StatusNotFound {
+		t.Fatalf("pending content must be hidden from others, got %d", w.Code)
+	}
+
+	// admin queue: approve the held tip
+	if w := e.do(http.MethodGet, "/admin/reviews", bob, nil); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for non-admin queue, got %d", w.Code)
+	}
+	queue, _ := decode(t, e.do(http.MethodGet, "/admin/reviews", admin, nil))["items"].([]any)
+	if len(queue) != 1 || fmt.Sprint(queue[0].(map[string]any)["id"]) != heldID || queue[0].(map[string]any)["flags"].([]any)[0] != "shit" {
+		t.Fatalf("unexpected queue %v", queue)
+	}
+	if w := e.do(http.MethodPost, "/admin/reviews/"+heldID+"/approve", admin, nil); w.Code != http.StatusOK || decode(t, w)["moderatedBy"] != "root" {
+		t.Fatalf("approve: %d", w.Code)
+	}
+	if tips, _ := decode(t, e.do(http.MethodGet, "/venues/v1/reviews?kind=tip", "", nil))["items"].([]any); len(tips) != 2 {
+		t.Fatalf("expected approved tip to be public, got %v", tips)
+	}
+
+	// helpful votes: idempotent, not on your own content, and sort the list
+	if w := e.do(http.MethodPost, "/reviews/"+aliceID+"/helpful", alice, nil); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 voting on own review, got %d", w.Code)
+	}
+	for i := 0; i < 2; i++ {
+		if w := e.do(http.MethodPost, "/reviews/"+aliceID+"/helpful", bob, nil); w.Code != http.StatusNoContent {
+			t.Fatalf("helpful: %d", w.Code)
+		}
+	}
+	reviews, _ := decode(t, e.do(http.MethodGet, "/venues/v1/reviews?kind=review&sort=helpful", "", nil))["items"].([]any)
+	if top := reviews[0].(map[string]any); fmt.Sprint(top["id"]) != aliceID || top["helpfulCount"] != 1.0 {
+		t.Fatalf("expected alice's review first with one vote, got %v", reviews)
+	}
+
+	// editing re-runs the filter; a held edit leaves the aggregates
+	if w := e.do(http.MethodPatch, "/reviews/"+aliceID, bob, map[string]any{"rating": 1}); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 editing someone else's review, got %d", w.Code)
+	}
+	w = e.do(http.MethodPatch, "/reviews/"+aliceID, alice, map[string]any{"rating": 4})
+	if w.Code != http.StatusOK || decode(t, w)["status"] != "published" {
+		t.Fatalf("clean edit: %d %s", w.Code, w.Body.String())
+	}
+	if n, avg := venueStats(); n != 2 || avg != 3.0 {
+		t.Fatalf("expected average 3 after edit, got %v %v", n, avg)
+	}
+	w = e.do(http.MethodPatch, "/reviews/"+aliceID, alice, map[string]any{"body": "Great views, promo code ROOF at the door"})
+	if w.Code != http.StatusOK || decode(t, w)["status"] != "pending" {
+		t.Fatalf("held edit: %d %s", w.Code, w.Body.String())
+	}
+	if n, avg := venueStats(); n != 1 || avg != 2.0 {
+		t.Fatalf("held review must leave the aggregates, got %v %v", n, avg)
+	}
+	e.do(http.MethodPost, "/admin/reviews/"+aliceID+"/approve", admin, nil)
+
+	// three reports take a review down until a moderator rejects or restores it
+	bobReview := fmt.Sprint(decode(t, e.do(http.MethodGet, "/users/me/reviews", bob, nil))["items"].([]any)[1].(map[string]any)["id"])
+	if w := e.do(http.MethodPost, "/reviews/"+bobReview+"/report", alice, map[string]any{"reason": "rude"}); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown reason, got %d", w.Code)
+	}
+	for _, who := range []string{"alice", "alice", "carol", "dave"} {
+		if w := e.do(http.MethodPost, "/reviews/"+bobReview+"/report", testToken(t, who), map[string]any{"reason": "spam"}); w.Code != http.StatusNoContent {
+			t.Fatalf("report by %s: %d", who, w.Code)
+		}
+	}
+	queue, _ = decode(t, e.do(http.MethodGet, "/admin/reviews", admin, nil))["items"].([]any)
+	if len(queue) != 1 || queue[0].(map[string]any)["reportCount"] != 3.0 || queue[0].(map[string]any)["flags"].([]any)[0] != "reported" {
+		t.Fatalf("expected reported review queued with 3 reports, got %v", queue)
+	}
+	if n, _ := venueStats(); n != 1 {
+		t.Fatalf("reported review must leave the aggregates, got %v", n)
+	}
+	if w := e.do(http.MethodPost, "/admin/reviews/"+bobReview+"/reject", admin, map[string]any{"note": "spam"}); w.Code != http.StatusOK {
+		t.Fatalf("reject: %d", w.Code)
+	}
+	mine, _ := decode(t, e.do(http.MethodGet, "/users/me/reviews", bob, nil))["items"].([]any)
+	if mine[1].(map[string]any)["status"] != "rejected" {
+		t.Fatalf("author should see the rejection, got %v", mine)
+	}
+
+	// delete by the author
+	if w := e.do(http.MethodDelete, "/reviews/"+aliceID, bob, nil); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 deleting someone else's review, got %d", w.Code)
+	}
+	if w := e.do(http.MethodDelete, "/reviews/"+aliceID, alice, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("delete: %d", w.Code)
+	}
+	if n, avg := venueStats(); n != 0 || avg != nil {
+		t.Fatalf("expected no reviews left, got %v %v", n, avg)
+	}
+}
+
+func TestAchievements_EventsProgressAndIdempotentGrants(t *testing.T) {
+	e := newTestEnv(t)
+	notifier := &fakeNotifier{}
+	e.impl.notifier = notifier
+	tok := testToken(t, "u1")
+
+	cat := decode(t, e.do(http.MethodGet, "/achievements", "", nil))
+	items := cat["items"].([]any)
+	if len(items) != len(achieve.Defaults()) {
+		t.Fatalf("catalog has %d items", len(items))
+	}
+	for _, it := range items {
+		if _, ok := it.(map[string]any)["rule"]; ok {
+			t.Fatal("catalog exposes rules")
+		}
+	}
+
+	if w := e.do(http.MethodPost, "/venues/v7/checkin", tok, map[string]any{"lat": 37.7889, "lon": -122.4075}); w.Code != http.StatusCreated {
+		t.Fatalf("checkin: %d %s", w.Code, w.Body.String())
+	}
+	for _, id := range []string{"v1", "v2", "v3"} {
+		e.do(http.MethodPost, "/venues/"+id+"/like", tok, nil)
+	}
+	// unlike and like again is the same like
+	e.do(http.MethodDelete, "/venues/v1/like", tok, nil)
+	e.do(http.MethodPost, "/venues/v1/like", tok, nil)
+	e.do(http.MethodPost, "/venues/v4/skip", tok, nil)
+
+	mine := func() (map[string]map[string]any, map[string]map[string]any) {
+		t.Helper()
+		w := e.do(http.MethodGet, "/users/me/achievements", tok, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("me: %d", w.Code)
+		}
+		out := decode(t, w)
+		index := func(key string) map[string]map[string]any {
+			m := map[string]map[string]any{}
+			for _, it := range out[key].([]any) {
+				a := it.(map[string]any)
+				m[a["id"].(string)] = a
+			}
+			return m
+		}
+		return index("earned"), index("inProgress")
+	}
+	earned, inProgress := mine()
+	if _, ok := earned["first_checkin"]; !ok || len(earned) != 1 {
+		t.Fatalf("earned: %v", earned)
+	}
+	if p := inProgress["tastemaker"]["progress"]; p != 3.0 {
+		t.Fatalf("tastemaker progress %v, want 3", p)
+	}
+	if p := inProgress["first_valet"]["progress"]; p != 0.0 {
+		t.Fatalf("first_valet progress %v, want 0", p)
+	}
+	if e.do(http.MethodGet, "/users/me/achievements", "", nil).Code != http.StatusUnauthorized {
+		t.Fatal("expected 401 without a token")
+	}
+
+	valet := map[string]any{"events": []map[string]any{{"id": "t1", "userId": "u1", "type": "valet_use", "timezone": "America/Los_Angeles"}}}
+	// Only producers with a service token may post events; user tokens and
+	// the client vibe keys don't count
+	if w := e.do(http.MethodPost, "/achievements/events", testToken(t, "u1"), valet); w.Code != http.StatusUnauthorized {
+		t.Fatalf("events without a service token: %d", w.Code)
+	}
+	e.impl.achieveTokens = servicetoken.New()
+	if w := e.doService(http.MethodPost, "/achievements/events", valet); w.Code != http.StatusUnauthorized {
+		t.Fatalf("events with no tokens configured: %d", w.Code)
+	}
+	e.impl.achieveTokens = servicetoken.New(testServiceToken)
+	w := e.doService(http.MethodPost, "/achievements/events", valet)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("events: %d %s", w.Code, w.Body.String())
+	}
+	if out := decode(t, w); out["accepted"] != 1.0 || len(out["earned"].([]any)) != 1 {
+		t.Fatalf("events: %v", out)
+	}
+	// a retried batch grants nothing twice
+	out := decode(t, e.doService(http.MethodPost, "/achievements/events", valet))
+	if out["accepted"] != 0.0 || out["duplicates"] != 1.0 || len(out["earned"].([]any)) != 0 {
+		t.Fatalf("retry: %v", out)
+	}
+	if earned, _ = mine(); earned["first_valet"] == nil || earned["first_valet"]["earnedAt"] == nil {
+		t.Fatalf("first_valet not earned: %v", earned)
+	}
+	if fmt.Sprint(notifier.earned) != "[u1/first_checkin u1/first_valet]" {
+		t.Fatalf("notifications: %v", notifier.earned)
+	}
+
+	for _, bad := range []map[string]any{
+		{"events": []map[string]any{}},
+		{"events": []map[string]any{{"id": "c1", "userId": "u1", "type": "checkin"}}},
+		{"events": []map[string]any{{"id": "t2", "type": "valet_use"}}},
+		{"events": []map[string]any{{"id": "t2", "userId": "u1", "type": "valet_use", "timezone": "Mars/Olympus"}}},
+		{"events": []map[string]any{{"id": "t2", "userId": "u1", "type": "valet_use", "occurredAt": "yesterday"}}},
+	} {
+		if w := e.doService(http.MethodPost, "/achievements/events", bad); w.Code != http.StatusBadRequest {
+			t.Errorf("%v: expected 400, got %d", bad, w.Code)
+		}
+	}
+}
+
+func TestEvents_CRUDRangeQueriesCalendarAndDiscoveryBoost(t *testing.T) {
+	e := newTestEnv(t)
+	la, _ := time.LoadLocation("America/Los_Angeles")
+	now := time.Date(2026, 10, 23, 19, 0, 0, 0, la) // a Friday evening
+	e.impl.now = func() time.Time { return now }
+	ctx := context.Background()
+	v7, _ := e.repo.GetVenue(ctx, "v7")
+	owner := "host-1"
+	v7.OwnerID = &owner
+	if err := e.repo.UpdateVenue(ctx, v7); err != nil {
+		t.Fatal(err)
+	}
+	host := testToken(t, "host-1", "host")
+	admin := testToken(t, "admin-1", "admin")
+
+	happy := map[string]any{"kind": "special", "title": " Happy hour ", "startsAt": "2026-10-16T17:00:00-07:00", "endsAt": "2026-10-16T20:00:00-07:00",
+		"recurrence": "rrule:freq=weekly;byday=fr", "tags": []string{"Drinks", "drinks"}}
+	w := e.do(http.MethodPost, "/admin/venues/v7/events", host, happy)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+	ev := decode(t, w)
+	hhID := strconv.Itoa(int(ev["id"].(float64)))
+	if ev["title"] != "Happy hour" || ev["timezone"] != "America/Los_Angeles" || ev["recurrence"] != "FREQ=WEEKLY;BYDAY=FR" || ev["seriesEndsAt"] != nil || len(ev["tags"].([]any)) != 1 {
+		t.Fatalf("unexpected event %v", ev)
+	}
+	if w := e.do(http.MethodPost, "/admin/venues/v1/events", host, happy); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for another venue, got %d", w.Code)
+	}
+	for _, bad := range []map[string]any{
+		{"title": "x", "startsAt": "2026-10-16T17:00:00Z", "endsAt": "2026-10-16T16:00:00Z"},
+		{"title": "x", "startsAt": "2026-10-16T17:00:00Z", "endsAt": "2026-10-16T18:00:00Z", "recurrence": "FREQ=YEARLY"},
+		{"title": "x", "startsAt": "2026-10-16T17:00:00Z", "endsAt": "2026-10-16T18:00:00Z", "ticketUrl": "ftp://tickets"},
+		{"title": "", "startsAt": "2026-10-16T17:00:00Z", "endsAt": "2026-10-16T18:00:00Z"},
+		{"kind": "party", "title": "x", "startsAt": "2026-10-16T17:00:00Z", "endsAt": "2026-10-16T18:00:00Z"},
+	} {
+		if w := e.do(http.MethodPost, "/admin/venues/v7/events", host, bad); w.Code != http.StatusBadRequest {
+			t.Errorf("%v: expected 400, got %d", bad, w.Code)
+		}
+	}
+	w = e.do(http.MethodPost, "/admin/venues/v3/events", admin, map[string]any{"title": "Techno night", "startsAt": "2026-10-23T22:00:00-07:00",
+		"endsAt": "2026-10-24T03:00:00-07:00", "ticketUrl": "https://tickets.example.com/techno", "tags": []string{"techno"}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("admin create: %d %s", w.Code, w.Body.String())
+	}
+	techno := decode(t, w)
+	technoID := strconv.Itoa(int(techno["id"].(float64)))
+	if techno["seriesEndsAt"] != "2026-10-24T10:00:00Z" || techno["kind"] != "event" {
+		t.Fatalf("one-off series should end with its only occurrence: %v", techno)
+	}
+
+	// the next 24 hours: the happy hour (running) and the techno night later on
+	w = e.do(http.MethodGet, "/events", "", nil)
+	items, _ := decode(t, w)["items"].([]any)
+	if len(items) != 2 {
+		t.Fatalf("expected 2 occurrences, got %s", w.Body.String())
+	}
+	first := items[0].(map[string]any)
+	if first["startsAt"] != "2026-10-23T17:00:00-07:00" || first["endsAt"] != "2026-10-23T20:00:00-07:00" || first["venue"].(map[string]any)["name"] != "Rooftop 22" {
+		t.Fatalf("unexpected first occurrence %v", first)
+	}
+	if items[1].(map[string]any)["title"] != "Techno night" {
+		t.Fatalf("unexpected second occurrence %v", items[1])
+	}
+	for query, want := range map[string]int{"kind=special": 1, "tag=TECHNO": 1, "bbox=-122.42,37.77,-122.41,37.78": 1, "bbox=-122.52,37.75,-122.50,37.76": 0} {
+		if items, _ := decode(t, e.do(http.MethodGet, "/events?"+query, "", nil))["items"].([]any); len(items) != want {
+			t.Errorf("%s: expected %d occurrences, got %d", query, want, len(items))
+		}
+	}
+	if items, _ := decode(t, e.do(http.MethodGet, "/venues/v7/events?from=2026-10-23T00:00:00Z&to=2026-11-07T00:00:00Z", "", nil))["items"].([]any); len(items) != 2 {
+		t.Fatalf("expected the 10-23 and 10-30 happy hours, got %v", items)
+	}
+	for _, path := range []string{"/events?from=2026-10-01T00:00:00Z&to=2026-12-01T00:00:00Z", "/events?kind=party", "/events?from=2026-10-02T00:00:00Z&to=2026-10-01T00:00:00Z"} {
+		if w := e.do(http.MethodGet, path, "", nil); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", path, w.Code)
+		}
+	}
+
+	w = e.do(http.MethodGet, "/venues/v7/calendar.ics", "", nil)
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/calendar") {
+		t.Fatalf("calendar: %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	for _, want := range []string{"X-WR-CALNAME:Rooftop 22\r\n", "UID:event-" + hhID + "@bytspot\r\n", "DTSTART;TZID=America/Los_Angeles:20261016T170000\r\n", "RRULE:FREQ=WEEKLY;BYDAY=FR\r\n"} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("calendar missing %q:\n%s", want, w.Body.String())
+		}
+	}
+
+	// v1 is ~290 m from here and v7 ~310 m, but v7's happy hour is on
+	w = e.do(http.MethodGet, "/venues/discover?lat=37.7885&lon=-122.4040&radius=5000&limit=1", "", nil)
+	page := decode(t, w)
+	top := page["items"].([]any)[0].(map[string]any)
+	if top["id"] != "v7" || top["happeningNow"].(map[string]any)["title"] != "Happy hour" {
+		t.Fatalf("expected v7 boosted to the top, got %v", top)
+	}
+	// later pages keep the ranking the feed started with
+	now = now.Add(2 * time.Hour)
+	rest := itemIDs(t, e.do(http.MethodGet, "/venues/discover?lat=37.7885&lon=-122.4040&radius=5000&limit=50&cursor="+page["nextCursor"].(string), "", nil))
+	if len(rest) == 0 || rest[0] != "v1" || strings.Contains(strings.Join(rest, ","), "v7") {
+		t.Fatalf("second page = %v", rest)
+	}
+	now = now.Add(-2 * time.Hour)
+	if ids := itemIDs(t, e.do(http.MethodGet, "/venues/discover", "", nil)); ids[0] != "v7" || ids[1] != "v1" {
+		t.Fatalf("live venues come first in the by-id feed, got %v", ids)
+	}
+
+	// cancelling keeps the event in the feed marked as such, and ends the boost
+	if w := e.do(http.MethodPatch, "/admin/venues/v7/events/"+hhID, host, map[string]any{"status": "cancelled"}); w.Code != http.StatusOK {
+		t.Fatalf("cancel: %d %s", w.Code, w.Body.String())
+	}
+	if items, _ := decode(t, e.do(http.MethodGet, "/events", "", nil))["items"].([]any); len(items) != 1 {
+		t.Fatalf("cancelled occurrences should be hidden, got %v", items)
+	}
+	if body := e.do(http.MethodGet, "/venues/v7/calendar.ics", "", nil).Body.String(); !strings.Contains(body, "STATUS:CANCELLED") {
+		t.Fatalf("calendar should mark the cancellation:\n%s", body)
+	}
+	if ids := itemIDs(t, e.do(http.MethodGet, "/venues/discover?lat=37.7885&lon=-122.4040&radius=5000", "", nil)); ids[0] != "v1" {
+		t.Fatalf("boost should end with the cancellation, got %v", ids)
+	}
+	if items, _ := decode(t, e.do(http.MethodGet, "/admin/venues/v7/events", host, nil))["items"].([]any); len(items) != 1 {
+		t.Fatalf("admin list should include cancelled events, got %v", items)
+	}
+
+	if w := e.do(http.MethodPatch, "/admin/venues/v7/events/"+technoID, admin, map[string]any{"title": "Moved"}); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for another venue's event, got %d", w.Code)
+	}
+	if w := e.do(http.MethodDelete, "/admin/venues/v3/events/"+technoID, host, nil); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 deleting on a venue the host doesn't own, got %d", w.Code)
+	}
+	if w := e.do(http.MethodDelete, "/admin/venues/v3/events/"+technoID, admin, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("delete: %d", w.Code)
+	}
+	if items, _ := decode(t, e.do(http.MethodGet, "/events", "", nil))["items"].([]any); len(items) != 0 {
+		t.Fatalf("expected no occurrences left, got %v", items)
+	}
+}
+
+func TestVenueAnalytics_RollupsComparisonAndHostScope(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+	la, _ := time.LoadLocation("America/Los_Angeles")
+	now := time.Now()
+	today := analytics.Date(now, la)
+	v7, _ := e.repo.GetVenue(ctx, "v7")
+	owner := "host-1"
+	v7.OwnerID = &owner
+	if err := e.repo.UpdateVenue(ctx, v7); err != nil {
+		t.Fatal(err)
+	}
+	host := testToken(t, "host-1", "host")
+	admin := testToken(t, "admin-1", "admin")
+
+	// This week: two likes, two check-ins, two vibe reports and two discovery impressions
+	_, _ = e.repo.UpsertInteraction(ctx, "u1", "v7", db.InteractionLike)
+	_, _ = e.repo.UpsertInteraction(ctx, "u2", "v7", db.InteractionLike)
+	_, _ = e.repo.UpsertInteraction(ctx, "u1", "v1", db.InteractionLike)
+	for _, u := range []string{"u1", "u2"} {
+		_, _ = e.repo.InsertCheckin(ctx, &db.Checkin{UserID: u, VenueID: "v7", CreatedAt: now}, now.Add(-time.Hour))
+	}
+	for _, score := range []float64{8, 9} {
+		_, _ = e.repo.InsertVibeReport(ctx, &db.VibeReport{VenueID: "v7", Score: score, ReportedAt: now})
+	}
+	for range 2 {
+		if w := e.do(http.MethodGet, "/venues/discover?lat=37.7879&lon=-122.4074&radius=1000", testToken(t, "u9"), nil); w.Code != http.StatusOK {
+			t.Fatalf("discover: %d", w.Code)
+		}
+	}
+	if err := e.impl.flushImpressions(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.impl.rollupAnalytics(ctx, rollupDays); err != nil {
+		t.Fatal(err)
+	}
+	// Rolling up again recomputes rather than adds
+	if err := e.impl.rollupAnalytics(ctx, rollupDays); err != nil {
+		t.Fatal(err)
+	}
+	// Last week, from an earlier rollup
+	lastWeek := today.AddDate(0, 0, -8)
+	_ = e.repo.SaveVenueDailyStats(ctx, []db.VenueDailyStats{{VenueID: "v7", Day: lastWeek, Likes: 2, Checkins: 4, VibeReports: 2, VibeScoreSum: 12, Hourly: make([]int, 24)}})
+	_ = e.repo.AddVenueImpressions(ctx, []db.VenueImpressions{{VenueID: "v7", Day: lastWeek, Count: 4}})
+
+	w := e.do(http.MethodGet, "/admin/venues/v7/analytics", host, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("analytics: %d %s", w.Code, w.Body.String())
+	}
+	rep := decode(t, w)
+	if rep["timezone"] != "America/Los_Angeles" || rep["period"].(map[string]any)["to"] != today.Format(analytics.DateLayout) ||
+		rep["previousPeriod"].(map[string]any)["to"] != today.AddDate(0, 0, -7).Format(analytics.DateLayout) {
+		t.Fatalf("periods: %v %v %v", rep["timezone"], rep["period"], rep["previousPeriod"])
+	}
+	totals := rep["totals"].(map[string]any)
+	want := map[string][3]float64{"impressions": {2, 4, -0.5}, "likes": {2, 2, 0}, "checkins": {2, 4, -0.5}, "vibeReports": {2, 2, 0}}
+	for k, v := range want {
+		c := totals[k].(map[string]any)
+		if c["value"] != v[0] || c["previous"] != v[1] || c["change"] != v[2] {
+			t.Fatalf("%s: %v, want %v", k, c, v)
+		}
+	}
+	if vibe := totals["avgVibe"].(map[string]any); vibe["value"] != 8.5 || vibe["previous"] != 6.0 || vibe["change"] != 2.5 {
+		t.Fatalf("avgVibe: %v", vibe)
+	}
+	daily := rep["daily"].([]any)
+	if len(daily) != 7 {
+		t.Fatalf("daily: %v", daily)
+	}
+	if last := daily[6].(map[string]any); last["date"] != today.Format(analytics.DateLayout) || last["checkins"] != 2.0 || last["avgVibe"] != 8.5 {
+		t.Fatalf("today: %v", last)
+	}
+	peaks := rep["peakHours"].([]any)
+	if len(peaks) != 1 || peaks[0].(map[string]any)["hour"] != float64(now.In(la).Hour()) || peaks[0].(map[string]any)["activity"] != 4.0 {
+		t.Fatalf("peakHours: %v", peaks)
+	}
+
+	// Explicit dates outside any activity
+	w = e.do(http.MethodGet, "/admin/venues/v7/analytics?from=2020-01-01&to=2020-01-31", host, nil)
+	if w.Code != http.StatusOK || len(decode(t, w)["daily"].([]any)) != 31 {
+		t.Fatalf("from/to: %d %s", w.Code, w.Body.String())
+	}
+	for _, q := range []string{"range=0d", "range=7", "range=400d", "from=2020-01-02&to=2020-01-01", "range=7d&from=2020-01-01&to=2020-01-02", "from=2020-01-01"} {
+		if w := e.do(http.MethodGet, "/admin/venues/v7/analytics?"+q, host, nil); w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", q, w.Code)
+		}
+	}
+
+	// Hosts only see their own venues
+	if w := e.do(http.MethodGet, "/admin/venues/v1/analytics", host, nil); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for another venue, got %d", w.Code)
+	}
+	if w := e.do(http.MethodGet, "/admin/venues/v7/analytics", testToken(t, "u1"), nil); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a plain user, got %d", w.Code)
+	}
+	items := decode(t, e.do(http.MethodGet, "/admin/venues/analytics?range=7d&ownerId=someone-else", host, nil))["items"].([]any)
+	if len(items) != 1 || items[0].(map[string]any)["venueId"] != "v7" {
+		t.Fatalf("host overview: %v", items)
+	}
+	if likes := items[0].(map[string]any)["totals"].(map[string]any)["likes"].(map[string]any); likes["value"] != 2.0 {
+		t.Fatalf("overview likes: %v", likes)
+	}
+	if items := decode(t, e.do(http.MethodGet, "/admin/venues/analytics", admin, nil))["items"].([]any); len(items) < 8 {
+		t.Fatalf("admin overview: %d items", len(items))
+	}
+}
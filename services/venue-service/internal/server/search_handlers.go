@@ -0,0 +1,168 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"bytspot/services/venue-service/internal/api"
+	"bytspot/services/venue-service/internal/geo"
+	"bytspot/services/venue-service/internal/search"
+	"bytspot/shared/middleware"
+)
+
+const (
+	maxSearchQueryLen   = 100 // runes
+	searchCandidates    = 200 // text hits re-ranked by distance and vibe
+	defaultSuggestLimit = 8
+	maxSuggestLimit     = 10
+	suggestCacheSeconds = 30
+)
+
+// Search ranking blends text relevance with proximity and the live vibe;
+// without a location the text and vibe weights are used alone.
+const (
+	rankText      = 0.6
+	rankProximity = 0.25
+	rankVibe      = 0.15
+	// proximityHalf is the distance (meters) at which proximity scores 0.5.
+	proximityHalf = 1000.0
+	// unknownVibe scores venues with no recent reports a little under a
+	// medium vibe, so quiet data doesn't bury them.
+	unknownVibe = 0.4
+)
+
+// searchScore combines the signals, each in [0, 1].
+func searchScore(text float64, dist *float64, vibe float64) float64 {
+	if dist == nil {
+		return (rankText*text + rankVibe*vibe) / (rankText + rankVibe)
+	}
+	proximity := proximityHalf / (proximityHalf + *dist)
+	return rankText*text + rankProximity*proximity + rankVibe*vibe
+}
+
+func searchQuery(w http.ResponseWriter, q *string) (string, bool) {
+	if q == nil || strings.TrimSpace(*q) == "" {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "q is required", "VALIDATION_ERROR")
+		return "", false
+	}
+	if utf8.RuneCountInString(*q) > maxSearchQueryLen {
+		middleware.ErrorHandler(w, http.StatusBadRequest, fmt.Sprintf("q may be at most %d characters", maxSearchQueryLen), "VALIDATION_ERROR")
+		return "", false
+	}
+	return *q, true
+}
+
+// GET /venues/search?q&lat&lon&category&limit
+// Typo-tolerant search over names, tags and neighborhoods. Results are
+// ranked by text relevance combined with distance from lat/lon (when
+// given) and the live vibe.
+func (s *serverImpl) GetVenuesSearch(w http.ResponseWriter, r *http.Request, params api.GetVenuesSearchParams) {
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	q, ok := searchQuery(w, params.Q)
+	if !ok {
+		return
+	}
+	if (params.Lat == nil) != (params.Lon == nil) {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "lat and lon must be given together", "VALIDATION_ERROR")
+		return
+	}
+	if params.Lat != nil && !geo.ValidCoords(*params.Lat, *params.Lon) {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "lat/lon out of range", "VALIDATION_ERROR")
+		return
+	}
+	limit := defaultPageSize
+	if params.Limit != nil {
+		if *params.Limit < 1 {
+			middleware.ErrorHandler(w, http.StatusBadRequest, "limit must be positive", "VALIDATION_ERROR")
+			return
+		}
+		limit = min(*params.Limit, maxPageSize)
+	}
+	var categories map[string]bool
+	if params.Category != nil {
+		categories = splitSet(*params.Category)
+	}
+	if err := s.catalog.load(r.Context()); err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+
+	type result struct {
+		view  venueView
+		score float64
+	}
+	now := s.now()
+	results := make([]result, 0, limit)
+	for _, h := range s.catalog.text.Search(q, searchCandidates) {
+		v, ok := s.catalog.get(h.ID)
+		if !ok || (len(categories) > 0 && !categories[v.Category]) {
+			continue
+		}
+		view := publicView(v, now)
+		if params.Lat != nil {
+			d := geo.Distance(*params.Lat, *params.Lon, v.Lat, v.Lon)
+			rounded := math.Round(d)
+			view.Distance = &rounded
+		}
+		vibe := unknownVibe
+		if score, _, known := s.vibeAgg.Live(v.ID, now); known {
+			vibe = score / 10
+		}
+		results = append(results, result{view, searchScore(h.Score, view.Distance, vibe)})
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].score > results[j].score })
+	countImpression := s.impressionCounter(r)
+	items := make([]venueView, 0, min(limit, len(results)))
+	for _, res := range results[:min(limit, len(results))] {
+		items = append(items, res.view)
+		countImpression(res.view.ID)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"items": items})
+}
+
+// GET /venues/autocomplete?q&limit
+// Completes venue names, neighborhoods and tags as the user types. It
+// answers from the in-memory index and never waits on the database once the
+// catalog has loaded; Server-Timing reports the lookup time.
+func (s *serverImpl) GetVenuesAutocomplete(w http.ResponseWriter, r *http.Request, params api.GetVenuesAutocompleteParams) {
+	start := time.Now()
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	q, ok := searchQuery(w, params.Q)
+	if !ok {
+		return
+	}
+	limit := defaultSuggestLimit
+	if params.Limit != nil {
+		if *params.Limit < 1 {
+			middleware.ErrorHandler(w, http.StatusBadRequest, "limit must be positive", "VALIDATION_ERROR")
+			return
+		}
+		limit = min(*params.Limit, maxSuggestLimit)
+	}
+	if err := s.catalog.loadStale(r.Context()); err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	suggestions := s.catalog.text.Suggest(q, limit)
+	if suggestions == nil {
+		suggestions = []search.Suggestion{}
+	}
+	h := w.Header()
+	h.Set("Content-Type", "application/json")
+	h.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", suggestCacheSeconds))
+	h.Set("Server-Timing", fmt.Sprintf("suggest;dur=%.2f", float64(time.Since(start).Microseconds())/1000))
+	json.NewEncoder(w).Encode(map[string]any{"suggestions": suggestions})
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"bytspot/shared/middleware"
+)
+
+// Signed vibe reports carry these headers. The signature is
+// hex(HMAC-SHA256(key, method + "\n" + path + "\n" + timestamp + "\n" + nonce +
+// "\n" + rawBody)), with path as venue-service serves it (the BFF strips its
+// /api prefix), so a signature is only good for the request it was made for.
+const (
+	headerKeyID     = "X-Vibe-Key-Id"
+	headerTimestamp = "X-Vibe-Timestamp" // unix seconds
+	headerNonce     = "X-Vibe-Nonce"
+	headerSignature = "X-Vibe-Signature"
+
+	maxSignatureSkew = 5 * time.Minute
+	maxSignedBody    = 64 << 10
+	minNonceLen      = 16
+	maxNonceLen      = 128
+)
+
+// signingKeys reads VIBE_HMAC_KEYS ("id:secret,id:secret"); during a
+// rotation both the old and new key are listed. A bare VIBE_HMAC_SECRET is
+// accepted as key id "default". No keys means signatures are not required.
+func signingKeys() map[string][]byte {
+	keys := map[string][]byte{}
+	for _, pair := range strings.Split(os.Getenv("VIBE_HMAC_KEYS"), ",") {
+		id, secret, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || id == "" || secret == "" {
+			if pair != "" {
+				log.Printf("ignoring malformed VIBE_HMAC_KEYS entry")
+			}
+			continue
+		}
+		keys[id] = []byte(secret)
+	}
+	if s := os.Getenv("VIBE_HMAC_SECRET"); s != "" {
+		if _, ok := keys["default"]; !ok {
+			keys["default"] = []byte(s)
+		}
+	}
+	return keys
+}
+
+func signatureMAC(key []byte, method, path, ts, nonce string, body []byte) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(method + "\n" + path + "\n" + ts + "\n" + nonce + "\n"))
+	m.Write(body)
+	return m.Sum(nil)
+}
+
+// verifySignature checks the signature headers against the raw body and
+// restores r.Body for the handler. It writes 401 and returns false when the
+// request is unsigned, stale, replayed or forged. With no keys configured
+// every request passes.
+func (s *serverImpl) verifySignature(w http.ResponseWriter, r *http.Request) bool {
+	if len(s.signingKeys) == 0 {
+		return true
+	}
+	keyID, ts, nonce, sig := r.Header.Get(headerKeyID), r.Header.Get(headerTimestamp), r.Header.Get(headerNonce), r.Header.Get(headerSignature)
+	if keyID == "" || ts == "" || nonce == "" || sig == "" {
+		middleware.ErrorHandler(w, http.StatusUnauthorized, "missing signature headers", "UNAUTHORIZED")
+		return false
+	}
+	key, ok := s.signingKeys[keyID]
+	if !ok {
+		middleware.ErrorHandler(w, http.StatusUnauthorized, "unknown key id", "UNAUTHORIZED")
+		return false
+	}
+	if len(nonce) < minNonceLen || len(nonce) > maxNonceLen {
+		middleware.ErrorHandler(w, http.StatusUnauthorized, "invalid nonce", "UNAUTHORIZED")
+		return false
+	}
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	now := s.now()
+	if err != nil || now.Sub(time.Unix(sec, 0)).Abs() > maxSignatureSkew {
+		middleware.ErrorHandler(w, http.StatusUnauthorized, "timestamp outside allowed skew", "UNAUTHORIZED")
+		return false
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSignedBody))
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "body too large", "VALIDATION_ERROR")
+		return false
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	got, err := hex.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, signatureMAC(key, r.Method, r.URL.Path, ts, nonce, body)) {
+		middleware.ErrorHandler(w, http.StatusUnauthorized, "invalid signature", "UNAUTHORIZED")
+		return false
+	}
+	// only claim the nonce once the signature is valid, so forged requests
+	// can't burn nonces; it is kept until the timestamp could no longer pass
+	fresh, err := s.nonces.ClaimNonce(r.Context(), keyID+"\n"+nonce, now, now.Add(2*maxSignatureSkew))
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return false
+	}
+	if !fresh {
+		middleware.ErrorHandler(w, http.StatusUnauthorized, "replayed nonce", "UNAUTHORIZED")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"bytspot/services/venue-service/internal/api"
+	"bytspot/services/venue-service/internal/db"
+	"bytspot/services/venue-service/internal/geo"
+	"bytspot/services/venue-service/internal/pubsub"
+	"bytspot/shared/middleware"
+)
+
+const (
+	defaultSSEHeartbeat = 15 * time.Second
+	sseHistorySize      = 1024 // events kept for Last-Event-ID resume
+	sseClientBuffer     = 32   // events a client may fall behind before it is dropped
+	sseRetryMillis      = 3000
+	maxStreamBoxSpan    = 1.0 // degrees of lat and lon
+)
+
+// vibeUpdate is the payload of a "vibe" SSE event.
+type vibeUpdate struct {
+	ID     string    `json:"id"`
+	Score  *float64  `json:"score"` // live score; null when unknown
+	Weight float64   `json:"weight"`
+	At     time.Time `json:"at"`
+}
+
+func (s *serverImpl) vibeUpdate(venueID string) vibeUpdate {
+	now := s.now()
+	u := vibeUpdate{ID: venueID, At: now.UTC()}
+	score, weight, ok := s.vibeAgg.Live(venueID, now)
+	if ok {
+		u.Score = &score
+	}
+	u.Weight = weight
+	return u
+}
+
+// publishVibe pushes the venue's current live score to stream subscribers.
+func (s *serverImpl) publishVibe(v *db.Venue) {
+	data, _ := json.Marshal(s.vibeUpdate(v.ID))
+	s.vibeHub.Publish(pubsub.Event{VenueID: v.ID, Lat: v.Lat, Lon: v.Lon, Data: data})
+}
+
+// GET /venues/{id}/vibe/stream
+func (s *serverImpl) GetVenuesIdVibeStream(w http.ResponseWriter, r *http.Request, id string) {
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	v, err := s.venues.GetVenue(r.Context(), id)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	if v == nil || v.Status != db.VenueActive {
+		middleware.ErrorHandler(w, http.StatusNotFound, "venue not found", "NOT_FOUND")
+		return
+	}
+	s.streamVibes(w, r, func(e pubsub.Event) bool { return e.VenueID == id }, []string{id})
+}
+
+// parseBBox reads "minLon,minLat,maxLon,maxLat" (GeoJSON order).
+func parseBBox(raw string) (geo.Box, error) {
+	parts := strings.Split(raw, ",")
+	if len(parts) != 4 {
+		return geo.Box{}, fmt.Errorf("bbox needs 4 numbers")
+	}
+	var f [4]float64
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return geo.Box{}, err
+		}
+		f[i] = v
+	}
+	b := geo.Box{MinLon: f[0], MinLat: f[1], MaxLon: f[2], MaxLat: f[3]}
+	if !geo.ValidCoords(b.MinLat, b.MinLon) || !geo.ValidCoords(b.MaxLat, b.MaxLon) || b.MinLat > b.MaxLat || b.MinLon > b.MaxLon {
+		return geo.Box{}, fmt.Errorf("bbox out of range")
+	}
+	return b, nil
+}
+
+// GET /vibe/stream?bbox=minLon,minLat,maxLon,maxLat
+func (s *serverImpl) GetVibeStream(w http.ResponseWriter, r *http.Request, params api.GetVibeStreamParams) {
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	if params.Bbox == nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "bbox is required", "VALIDATION_ERROR")
+		return
+	}
+	box, err := parseBBox(*params.Bbox)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "bbox must be minLon,minLat,maxLon,maxLat", "VALIDATION_ERROR")
+		return
+	}
+	if box.MaxLat-box.MinLat > maxStreamBoxSpan || box.MaxLon-box.MinLon > maxStreamBoxSpan {
+		middleware.ErrorHandler(w, http.StatusBadRequest, fmt.Sprintf("bbox may span at most %g degrees", maxStreamBoxSpan), "VALIDATION_ERROR")
+		return
+	}
+	ids, err := s.catalog.inBox(r.Context(), box)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	s.streamVibes(w, r, func(e pubsub.Event) bool { return box.Contains(e.Lat, e.Lon) }, ids)
+}
+
+// streamVibes serves an SSE stream of vibe events accepted by match. A
+// client resuming with Last-Event-ID gets the events it missed; otherwise
+// (or when they have aged out of the hub) it starts with a snapshot of
+// snapshotIDs. Comments are sent as heartbeats so proxies keep the
+// connection open. A client that falls behind is disconnected and resumes
+// on reconnect.
+func (s *serverImpl) streamVibes(w http.ResponseWriter, r *http.Request, match func(pubsub.Event) bool, snapshotIDs []string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "streaming unsupported", "INTERNAL_ERROR")
+		return
+	}
+	var after uint64
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		after, _ = strconv.ParseUint(v, 10, 64)
+	}
+	sub, backlog, resumed := s.vibeHub.Subscribe(after, match)
+	defer sub.Close()
+	snapshotID := s.vibeHub.LastID()
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetryMillis)
+
+	writeEvent := func(id uint64, data []byte) {
+		if id > 0 {
+			fmt.Fprintf(w, "id: %d\n", id)
+		}
+		fmt.Fprintf(w, "event: vibe\ndata: %s\n\n", data)
+	}
+	if after == 0 || !resumed {
+		for _, id := range snapshotIDs {
+			data, _ := json.Marshal(s.vibeUpdate(id))
+			writeEvent(snapshotID, data)
+		}
+	}
+	for _, e := range backlog {
+		writeEvent(e.ID, e.Data)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(s.sseHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-sub.C:
+			if !ok {
+				return // dropped for falling behind
+			}
+			writeEvent(e.ID, e.Data)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		}
+	}
+}
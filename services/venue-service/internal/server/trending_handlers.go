@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"bytspot/services/venue-service/internal/api"
+	"bytspot/services/venue-service/internal/db"
+	"bytspot/services/venue-service/internal/geo"
+	"bytspot/services/venue-service/internal/trend"
+	"bytspot/shared/middleware"
+)
+
+const (
+	trendingRadiusMeters = 10000
+	// Trending compares the recent window with the week before it.
+	trendRecentWindow   = 3 * time.Hour
+	trendBaselineWindow = 7 * 24 * time.Hour
+	// minVibeBaseline is how many reports make a usable vibe baseline.
+	minVibeBaseline = 3
+	// likeCountsTTL is how long like counts are reused between requests.
+	likeCountsTTL = time.Minute
+)
+
+// likeCounts caches per-venue like counts for the recent and baseline
+// windows, so the event log is scanned at most once per likeCountsTTL.
+type likeCounts struct {
+	mu               sync.Mutex
+	at               time.Time
+	recent, baseline map[string]int
+}
+
+func (s *serverImpl) trendLikes(ctx context.Context, now time.Time) (recent, baseline map[string]int, err error) {
+	c := &s.likeCounts
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.recent != nil && now.Sub(c.at) >= 0 && now.Sub(c.at) < likeCountsTTL {
+		return c.recent, c.baseline, nil
+	}
+	split := now.Add(-trendRecentWindow)
+	if recent, err = s.interactions.CountLikeEvents(ctx, split, now); err != nil {
+		return nil, nil, err
+	}
+	if baseline, err = s.interactions.CountLikeEvents(ctx, split.Add(-trendBaselineWindow), split); err != nil {
+		return nil, nil, err
+	}
+	c.at, c.recent, c.baseline = now, recent, baseline
+	return recent, baseline, nil
+}
+
+// trendingVenue is a venue plus how fast it is rising.
+type trendingVenue struct {
+	venueView
+	Trend trend.Rise `json:"trend"`
+}
+
+// GET /venues/trending?lat&lon&limit
+// Venues whose live vibe or likes are rising fastest against their own
+// baseline (the week before the last three hours), within 10 km of lat/lon
+// when given.
+func (s *serverImpl) GetVenuesTrending(w http.ResponseWriter, r *http.Request, params api.GetVenuesTrendingParams) {
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	if (params.Lat == nil) != (params.Lon == nil) {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "lat and lon must be given together", "VALIDATION_ERROR")
+		return
+	}
+	if params.Lat != nil && !geo.ValidCoords(*params.Lat, *params.Lon) {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "lat/lon out of range", "VALIDATION_ERROR")
+		return
+	}
+	limit := defaultPageSize
+	if params.Limit != nil {
+		if *params.Limit < 1 {
+			middleware.ErrorHandler(w, http.StatusBadRequest, "limit must be positive", "VALIDATION_ERROR")
+			return
+		}
+		limit = min(*params.Limit, maxPageSize)
+	}
+
+	var (
+		venues []db.Venue
+		dists  []float64
+		err    error
+	)
+	if params.Lat != nil {
+		venues, dists, err = s.catalog.nearby(r.Context(), *params.Lat, *params.Lon, trendingRadiusMeters)
+	} else {
+		venues, err = s.catalog.all(r.Context())
+	}
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	now := s.now()
+	recent, baseline, err := s.trendLikes(r.Context(), now)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+
+	share := trendRecentWindow.Seconds() / trendBaselineWindow.Seconds()
+	items := []trendingVenue{}
+	for i, v := range venues {
+		in := trend.Input{LikesRecent: recent[v.ID], LikesBaseline: baseline[v.ID], RecentShare: share}
+		if score, _, known := s.vibeAgg.Live(v.ID, now); known {
+			in.VibeNow = &score
+		}
+		if b := s.vibeAgg.Baseline(v.ID, now, trendBaselineWindow+trendRecentWindow, trendRecentWindow); b.Count >= minVibeBaseline {
+			in.VibeBaseline = &b.Avg
+		}
+		rise := trend.Score(in)
+		if !rise.Trending() {
+			continue
+		}
+		view := publicView(v, now)
+		if dists != nil {
+			d := math.Round(dists[i])
+			view.Distance = &d
+		}
+		items = append(items, trendingVenue{venueView: view, Trend: rise})
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Trend.Score > items[j].Trend.Score })
+	items = items[:min(limit, len(items))]
+	countImpression := s.impressionCounter(r)
+	for _, it := range items {
+		countImpression(it.ID)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"items": items})
+}
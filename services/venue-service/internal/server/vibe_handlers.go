@@ -0,0 +1,220 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"bytspot/services/venue-service/internal/achieve"
+	"bytspot/services/venue-service/internal/api"
+	"bytspot/services/venue-service/internal/db"
+	"bytspot/services/venue-service/internal/hours"
+	"bytspot/services/venue-service/internal/vibe"
+	"bytspot/shared/middleware"
+)
+
+const (
+	defaultVibeRetention = 30 * 24 * time.Hour
+	// maxVibeClockSkew is how far ahead of server time a device clock may be;
+	// later timestamps are clamped to now.
+	maxVibeClockSkew  = 5 * time.Minute
+	defaultVibeWindow = "7d"
+)
+
+// vibeRetentionFromEnv reads VIBE_RETENTION (a Go duration like "720h").
+func vibeRetentionFromEnv() time.Duration {
+	if v := os.Getenv("VIBE_RETENTION"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("invalid VIBE_RETENTION %q; using %s", v, defaultVibeRetention)
+	}
+	return defaultVibeRetention
+}
+
+// vibeCutoff is the oldest report still inside the retention window.
+func (s *serverImpl) vibeCutoff() time.Time { return s.now().Add(-s.vibeRetention) }
+
+// parseVibeWindow accepts days ("7d") or a Go duration ("90m", "24h").
+func parseVibeWindow(raw string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(raw, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid window %q", raw)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid window %q", raw)
+	}
+	return d, nil
+}
+
+// rebuildVibes reloads the in-process aggregate from the store so it also
+// reflects reports other instances accepted. Reports this instance accepts
+// while the store is read are replayed on top.
+func (s *serverImpl) rebuildVibes(ctx context.Context) error {
+	return s.vibeAgg.Reload(func() ([]vibe.Report, error) {
+		reports, err := s.vibes.ListVibeReports(ctx, s.vibeCutoff())
+		if err != nil {
+			return nil, err
+		}
+		rs := make([]vibe.Report, len(reports))
+		for i := range reports {
+			rs[i] = aggregateReport(&reports[i])
+		}
+		return rs, nil
+	})
+}
+
+// aggregateReport is r as the aggregate sees it, with the reporter as its
+// source.
+func aggregateReport(r *db.VibeReport) vibe.Report {
+	out := vibe.Report{ID: r.ID, VenueID: r.VenueID, Score: r.Score, Confidence: r.Confidence, At: r.ReportedAt}
+	if r.UserID != nil {
+		out.Source = *r.UserID
+	}
+	return out
+}
+
+// POST /venues/{id}/vibe
+func (s *serverImpl) PostVenuesIdVibe(w http.ResponseWriter, r *http.Request, id string) {
+	if !s.verifySignature(w, r) {
+		return
+	}
+	claims, ok := s.optionalAuth(w, r)
+	if !ok || !fullAccess(w, claims) {
+		return
+	}
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	var req struct {
+		VibeScore  *float64           `json:"vibeScore"`
+		Confidence *float64           `json:"confidence"`
+		Timestamp  string             `json:"timestamp"`
+		Features   map[string]float64 `json:"features"`
+		Meta       struct {
+			IdempotencyKey string `json:"idempotency_key"`
+		} `json:"meta"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "invalid json", "INVALID_JSON")
+		return
+	}
+	if req.VibeScore == nil || *req.VibeScore < 0 || *req.VibeScore > 10 {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "vibeScore must be between 0 and 10", "VALIDATION_ERROR")
+		return
+	}
+	confidence := 1.0 // reports from before confidence existed count fully
+	if req.Confidence != nil {
+		confidence = *req.Confidence
+	}
+	if confidence < 0 || confidence > 1 {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "confidence must be between 0 and 1", "VALIDATION_ERROR")
+		return
+	}
+	now := s.now()
+	reportedAt := now
+	if req.Timestamp != "" {
+		t, err := time.Parse(time.RFC3339, req.Timestamp)
+		if err != nil {
+			middleware.ErrorHandler(w, http.StatusBadRequest, "timestamp must be RFC3339", "VALIDATION_ERROR")
+			return
+		}
+		if t.Before(s.vibeCutoff()) {
+			middleware.ErrorHandler(w, http.StatusBadRequest, "timestamp is outside the retention window", "VALIDATION_ERROR")
+			return
+		}
+		if t.Before(now.Add(maxVibeClockSkew)) {
+			reportedAt = t
+		}
+	}
+	v, err := s.venues.GetVenue(r.Context(), id)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	if v == nil || v.Status != db.VenueActive {
+		middleware.ErrorHandler(w, http.StatusNotFound, "venue not found", "NOT_FOUND")
+		return
+	}
+
+	report := &db.VibeReport{
+		VenueID:    id,
+		Score:      *req.VibeScore,
+		Confidence: confidence,
+		Features:   req.Features,
+		ReportedAt: reportedAt,
+	}
+	if claims != nil {
+		report.UserID = &claims.Sub
+	}
+	if req.Meta.IdempotencyKey != "" {
+		report.IdempotencyKey = &req.Meta.IdempotencyKey
+	}
+	// a duplicate idempotency key is accepted without storing a second row
+	created, err := s.vibes.InsertVibeReport(r.Context(), report)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	if created {
+		if s.vibeAgg.Add(aggregateReport(report)) {
+			s.publishVibe(v)
+		}
+		if claims != nil {
+			s.trackAchievement(r.Context(), venueAchievementEvent(claims.Sub, "vibe:"+strconv.FormatInt(report.ID, 10), achieve.EventVibeReport, v, report.ReportedAt))
+		}
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// GET /venues/{id}/vibe-aggregate?window
+func (s *serverImpl) GetVenuesIdVibeAggregate(w http.ResponseWriter, r *http.Request, id string, params api.GetVenuesIdVibeAggregateParams) {
+	if !s.ready() {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "store not ready", "INTERNAL_ERROR")
+		return
+	}
+	label := defaultVibeWindow
+	if params.Window != nil {
+		label = *params.Window
+	}
+	window, err := parseVibeWindow(label)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusBadRequest, "window must be like 6h or 7d", "VALIDATION_ERROR")
+		return
+	}
+	window = min(window, s.vibeRetention)
+	v, err := s.venues.GetVenue(r.Context(), id)
+	if err != nil {
+		middleware.ErrorHandler(w, http.StatusInternalServerError, "db error", "INTERNAL_ERROR")
+		return
+	}
+	if v == nil {
+		middleware.ErrorHandler(w, http.StatusNotFound, "venue not found", "NOT_FOUND")
+		return
+	}
+	sum := s.vibeAgg.Summarize(id, s.now(), window, hours.Location(v.Hours))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		ID     string `json:"id"`
+		Window string `json:"window"`
+		vibe.Summary
+	}{id, label, sum})
+}
+
+// purgeVibes deletes reports that fell out of the retention window.
+func (s *serverImpl) purgeVibes(ctx context.Context) (int64, error) {
+	cutoff := s.vibeCutoff()
+	s.vibeAgg.Prune(cutoff)
+	return s.vibes.PurgeVibeReports(ctx, cutoff)
+}
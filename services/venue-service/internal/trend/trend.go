@@ -0,0 +1,55 @@
+// Package trend scores how fast a venue is heating up compared with its own
+// baseline: its live vibe against its usual vibe, and its recent likes
+// against the rate it normally gets them.
+package trend
+
+import "math"
+
+const (
+	// vibeScale is the rise in vibe points worth one unit of score.
+	vibeScale = 2.0
+	// minRecentLikes is how many recent likes it takes before likes count
+	// as a rise at all; one like is noise.
+	minRecentLikes = 2
+	// MinScore is the score at which a venue counts as trending.
+	MinScore = 1.0
+)
+
+// Input is one venue's recent activity and its baseline.
+type Input struct {
+	VibeNow      *float64 // live score; nil when unknown
+	VibeBaseline *float64 // usual score; nil without enough history
+	LikesRecent  int      // likes in the recent window
+	// LikesBaseline is the number of likes in the baseline window and
+	// RecentShare the recent window's length as a fraction of it.
+	LikesBaseline int
+	RecentShare   float64
+}
+
+// Rise is a venue's trend score and what it was computed from.
+type Rise struct {
+	Score         float64  `json:"score"`
+	VibeNow       *float64 `json:"vibeNow"`
+	VibeBaseline  *float64 `json:"vibeBaseline"`
+	LikesRecent   int      `json:"likesRecent"`
+	LikesExpected float64  `json:"likesExpected"`
+}
+
+// Trending reports whether the rise is strong enough to list.
+func (r Rise) Trending() bool { return r.Score >= MinScore }
+
+// Score adds two parts: the vibe's rise over its baseline in units of
+// vibeScale, and how far recent likes exceed the count the baseline rate
+// predicts, in Poisson standard deviations. A falling vibe counts against
+// the venue.
+func Score(in Input) Rise {
+	r := Rise{VibeNow: in.VibeNow, VibeBaseline: in.VibeBaseline, LikesRecent: in.LikesRecent}
+	if in.VibeNow != nil && in.VibeBaseline != nil {
+		r.Score += (*in.VibeNow - *in.VibeBaseline) / vibeScale
+	}
+	r.LikesExpected = float64(in.LikesBaseline) * in.RecentShare
+	if in.LikesRecent >= minRecentLikes {
+		r.Score += math.Max(0, float64(in.LikesRecent)-r.LikesExpected) / math.Sqrt(r.LikesExpected+1)
+	}
+	return r
+}
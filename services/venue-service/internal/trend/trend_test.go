@@ -0,0 +1,32 @@
+package trend
+
+import "testing"
+
+func f(x float64) *float64 { return &x }
+
+func TestScore(t *testing.T) {
+	share := 3.0 / (7 * 24) // 3h against a week
+	cases := []struct {
+		name     string
+		in       Input
+		trending bool
+	}{
+		{"vibe well above usual", Input{VibeNow: f(8), VibeBaseline: f(5)}, true},
+		{"vibe at usual level", Input{VibeNow: f(8), VibeBaseline: f(7.5)}, false},
+		{"no baseline vibe", Input{VibeNow: f(9)}, false},
+		{"burst of likes at a quiet venue", Input{LikesRecent: 4, LikesBaseline: 7, RecentShare: share}, true},
+		{"usual likes at a busy venue", Input{LikesRecent: 20, LikesBaseline: 1100, RecentShare: share}, false},
+		{"single like", Input{LikesRecent: 1, RecentShare: share}, false},
+		{"likes up but vibe falling", Input{VibeNow: f(3), VibeBaseline: f(8), LikesRecent: 3, RecentShare: share}, false},
+	}
+	for _, c := range cases {
+		if got := Score(c.in); got.Trending() != c.trending {
+			t.Errorf("%s: score %.2f, trending %v, want %v", c.name, got.Score, got.Trending(), c.trending)
+		}
+	}
+	a := Score(Input{LikesRecent: 6, LikesBaseline: 7, RecentShare: share})
+	b := Score(Input{LikesRecent: 3, LikesBaseline: 7, RecentShare: share})
+	if a.Score <= b.Score {
+		t.Fatalf("more recent likes should rise faster: %v <= %v", a.Score, b.Score)
+	}
+}
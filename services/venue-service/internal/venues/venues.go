@@ -0,0 +1,258 @@
+// Package venues holds the rules for writing venues, shared by the admin API
+// and the bulk importer: partial inputs, validation and change diffs.
+package venues
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"bytspot/services/venue-service/internal/db"
+	"bytspot/services/venue-service/internal/geo"
+	"bytspot/services/venue-service/internal/hours"
+)
+
+const (
+	maxVenueNameLen     = 120
+	maxVenueSubtitleLen = 200
+	maxVenueTags        = 20
+	maxVenueTagLen      = 40
+	maxVenuePhotos      = 20
+	maxVenueHourRanges  = 28
+	maxHoursExceptions  = 100
+	maxExceptionNoteLen = 200
+)
+
+// Categories are the venue categories clients know how to render.
+var Categories = map[string]bool{"bar": true, "cafe": true, "club": true, "live_music": true, "lounge": true, "restaurant": true}
+
+var Statuses = map[db.VenueStatus]bool{db.VenueDraft: true, db.VenueActive: true, db.VenueArchived: true}
+
+// PriceTiers maps the legacy "$".."$$$$" labels back to tiers.
+var PriceTiers = map[string]int{"$": 1, "$$": 2, "$$$": 3, "$$$$": 4}
+
+// Input is a partial venue as sent to the admin API or read by the importer.
+// Nil fields are left as they are. title and price are the VenueCreate names
+// for name and priceTier, still sent by older admin clients.
+type Input struct {
+	Name      *string          `json:"name"`
+	Title     *string          `json:"title"`
+	Subtitle  *string          `json:"subtitle"`
+	Category  *string          `json:"category"`
+	Tags      *[]string        `json:"tags"`
+	PriceTier *int             `json:"priceTier"`
+	Price     *string          `json:"price"`
+	Lat       *float64         `json:"lat"`
+	Lon       *float64         `json:"lng"`
+	Address   *db.Address      `json:"address"`
+	Hours     *db.OpeningHours `json:"hours"`
+	Photos    *[]string        `json:"photos"`
+	Status    *db.VenueStatus  `json:"status"`
+	OwnerID   *string          `json:"ownerId"`
+}
+
+// Apply copies the fields set in in onto v, normalizing whitespace and tags.
+func (in Input) Apply(v *db.Venue) error {
+	if in.Name == nil {
+		in.Name = in.Title
+	}
+	if in.Name != nil {
+		v.Name = strings.TrimSpace(*in.Name)
+	}
+	if in.Subtitle != nil {
+		v.Subtitle = strings.TrimSpace(*in.Subtitle)
+	}
+	if in.Category != nil {
+		v.Category = strings.ToLower(strings.TrimSpace(*in.Category))
+	}
+	if in.Tags != nil {
+		v.Tags = NormalizeTags(*in.Tags)
+	}
+	switch {
+	case in.PriceTier != nil:
+		v.PriceTier = *in.PriceTier
+	case in.Price != nil:
+		tier, ok := PriceTiers[*in.Price]
+		if !ok {
+			return errors.New("price must be one of $, $$, $$$, $$$$")
+		}
+		v.PriceTier = tier
+	}
+	if (in.Lat == nil) != (in.Lon == nil) {
+		return errors.New("lat and lng must be set together")
+	}
+	if in.Lat != nil {
+		v.Lat, v.Lon = *in.Lat, *in.Lon
+	}
+	if in.Address != nil {
+		v.Address = *in.Address
+	}
+	if in.Hours != nil {
+		v.Hours = *in.Hours
+	}
+	if in.Photos != nil {
+		v.Photos = append([]string{}, *in.Photos...)
+	}
+	if in.Status != nil {
+		v.Status = *in.Status
+	}
+	if in.OwnerID != nil {
+		if owner := strings.TrimSpace(*in.OwnerID); owner != "" {
+			v.OwnerID = &owner
+		} else {
+			v.OwnerID = nil
+		}
+	}
+	return nil
+}
+
+// NormalizeTags lowercases, trims and de-duplicates tags, keeping order.
+func NormalizeTags(tags []string) []string {
+	out := []string{}
+	seen := map[string]bool{}
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t != "" && !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// Validate checks a venue before it is written.
+func Validate(v *db.Venue) error {
+	if n := len([]rune(v.Name)); n == 0 || n > maxVenueNameLen {
+		return fmt.Errorf("name must be 1-%d characters", maxVenueNameLen)
+	}
+	if len([]rune(v.Subtitle)) > maxVenueSubtitleLen {
+		return fmt.Errorf("subtitle may be at most %d characters", maxVenueSubtitleLen)
+	}
+	if !Categories[v.Category] {
+		names := make([]string, 0, len(Categories))
+		for c := range Categories {
+			names = append(names, c)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("category must be one of %s", strings.Join(names, ", "))
+	}
+	if len(v.Tags) > maxVenueTags {
+		return fmt.Errorf("at most %d tags", maxVenueTags)
+	}
+	for _, t := range v.Tags {
+		if len([]rune(t)) > maxVenueTagLen {
+			return fmt.Errorf("tags may be at most %d characters", maxVenueTagLen)
+		}
+	}
+	if v.PriceTier < 0 || v.PriceTier > 4 {
+		return errors.New("priceTier must be between 1 and 4")
+	}
+	if !geo.ValidCoords(v.Lat, v.Lon) || (v.Lat == 0 && v.Lon == 0) {
+		return errors.New("lat/lng out of range")
+	}
+	if len(v.Photos) > maxVenuePhotos {
+		return fmt.Errorf("at most %d photos", maxVenuePhotos)
+	}
+	for _, p := range v.Photos {
+		u, err := url.Parse(p)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("photo %q must be an http(s) URL", p)
+		}
+	}
+	if tz := v.Hours.Timezone; tz != "" {
+		if _, err := time.LoadLocation(tz); err != nil {
+			return fmt.Errorf("unknown timezone %q", tz)
+		}
+	}
+	if len(v.Hours.Weekly) > maxVenueHourRanges {
+		return fmt.Errorf("at most %d opening ranges", maxVenueHourRanges)
+	}
+	for _, r := range v.Hours.Weekly {
+		if r.Day < 0 || r.Day > 6 {
+			return errors.New("hours day must be 0 (Sunday) to 6 (Saturday)")
+		}
+		if _, err := hours.ParseClock(r.Open); err != nil {
+			return err
+		}
+		if _, err := hours.ParseClock(r.Close); err != nil {
+			return err
+		}
+	}
+	if err := validateExceptions(v.Hours.Exceptions); err != nil {
+		return err
+	}
+	if !Statuses[v.Status] {
+		return errors.New("status must be draft, active or archived")
+	}
+	return nil
+}
+
+// auditFields is the JSON of every audited venue field.
+func auditFields(v *db.Venue) map[string]json.RawMessage {
+	out := map[string]json.RawMessage{}
+	if v == nil {
+		return out
+	}
+	b, _ := json.Marshal(v)
+	_ = json.Unmarshal(b, &out)
+	for _, k := range []string{"id", "rating", "likeCount", "createdAt", "updatedAt"} {
+		delete(out, k)
+	}
+	return out
+}
+
+// Diff lists the fields that differ between two versions of a venue, as
+// stored in the audit history; before is nil on create.
+func Diff(before, after *db.Venue) map[string]db.FieldChange {
+	from, to := auditFields(before), auditFields(after)
+	changes := map[string]db.FieldChange{}
+	for k, nv := range to {
+		if ov, ok := from[k]; !ok || string(ov) != string(nv) {
+			changes[k] = db.FieldChange{From: from[k], To: nv}
+		}
+	}
+	for k, ov := range from {
+		if _, ok := to[k]; !ok {
+			changes[k] = db.FieldChange{From: ov}
+		}
+	}
+	return changes
+}
+
+func validateExceptions(exceptions []db.HoursException) error {
+	if len(exceptions) > maxHoursExceptions {
+		return fmt.Errorf("at most %d hours exceptions", maxHoursExceptions)
+	}
+	seen := map[string]bool{}
+	for _, ex := range exceptions {
+		if _, err := time.Parse(hours.DateLayout, ex.Date); err != nil {
+			return fmt.Errorf("exception date %q must be YYYY-MM-DD", ex.Date)
+		}
+		if seen[ex.Date] {
+			return fmt.Errorf("more than one exception for %s", ex.Date)
+		}
+		seen[ex.Date] = true
+		if ex.Closed == (len(ex.Ranges) > 0) {
+			return fmt.Errorf("exception for %s needs either closed or ranges", ex.Date)
+		}
+		if len(ex.Ranges) > 4 {
+			return fmt.Errorf("at most 4 ranges on %s", ex.Date)
+		}
+		for _, r := range ex.Ranges {
+			if _, err := hours.ParseClock(r.Open); err != nil {
+				return err
+			}
+			if _, err := hours.ParseClock(r.Close); err != nil {
+				return err
+			}
+		}
+		if len([]rune(ex.Note)) > maxExceptionNoteLen {
+			return fmt.Errorf("exception notes may be at most %d characters", maxExceptionNoteLen)
+		}
+	}
+	return nil
+}
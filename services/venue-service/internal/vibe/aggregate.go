@@ -0,0 +1,380 @@
+// Package vibe turns raw vibe reports into a live, time-decayed venue score
+// and "usually busy at" histograms. State is updated per report; nothing
+// rescans history except an explicit Rebuild.
+package vibe
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Report is the part of a stored vibe report the aggregate needs.
+type Report struct {
+	ID         int64 // store id, used to tell replayed reports apart; 0 if none
+	VenueID    string
+	Score      float64 // 0-10
+	Confidence float64 // 0-1, used as the report's weight
+	At         time.Time
+	// Source identifies the reporter; anonymous reports ("") all count as one
+	// source when deciding whether a shift is real.
+	Source string
+}
+
+type Config struct {
+	// HalfLife is how long it takes a report's weight in the live score to halve.
+	HalfLife time.Duration
+	// Reports further than OutlierK standard deviations from the live mean are
+	// rejected once the venue has at least MinOutlierWeight of decayed weight.
+	OutlierK         float64
+	MinOutlierWeight float64
+	// MinStdDev floors the spread so a run of identical scores doesn't make
+	// every different report an outlier.
+	MinStdDev float64
+	// A real change (the venue filling up) looks like a run of outliers on
+	// the same side of the mean. Once ShiftReports sources within ShiftWindow
+	// agree, the held reports and the new one are accepted together.
+	ShiftReports int
+	ShiftWindow  time.Duration
+	// MinLiveWeight is the decayed weight below which the live score is unknown.
+	MinLiveWeight float64
+}
+
+var DefaultConfig = Config{
+	HalfLife:         45 * time.Minute,
+	OutlierK:         3,
+	MinOutlierWeight: 3,
+	MinStdDev:        1,
+	ShiftReports:     3,
+	ShiftWindow:      15 * time.Minute,
+	MinLiveWeight:    0.5,
+}
+
+// maxHeld caps the rejected reports kept per venue while waiting for a shift.
+const maxHeld = 32
+
+// decayed holds exponentially decayed moments as of ref: w = Σ c·d,
+// s = Σ c·d·x, q = Σ c·d·x², with d = exp(-(ref - t)/τ).
+type decayed struct {
+	ref     time.Time
+	w, s, q float64
+}
+
+// bucket is one UTC hour of accepted reports.
+type bucket struct {
+	w, s     float64 // confidence-weighted
+	n        int
+	rejected int
+}
+
+type venueState struct {
+	live    decayed
+	buckets map[int64]*bucket // key: unix hour
+	held    []Report          // recent outliers that may turn out to be a shift
+}
+
+// Aggregator keeps per-venue state; it is safe for concurrent use.
+type Aggregator struct {
+	mu     sync.RWMutex
+	cfg    Config
+	tau    float64 // decay time constant in seconds
+	venues map[string]*venueState
+	// added logs reports added while Reload is loading, for Rebuild to
+	// replay; nil otherwise.
+	added []Report
+}
+
+func New(cfg Config) *Aggregator {
+	return &Aggregator{cfg: cfg, tau: cfg.HalfLife.Seconds() / math.Ln2, venues: map[string]*venueState{}}
+}
+
+func hourKey(t time.Time) int64 { return t.Unix() / 3600 }
+
+// at returns the moments decayed to t (t may be before ref for late reports).
+func (a *Aggregator) at(d decayed, t time.Time) decayed {
+	f := math.Exp(-t.Sub(d.ref).Seconds() / a.tau)
+	return decayed{ref: t, w: d.w * f, s: d.s * f, q: d.q * f}
+}
+
+func (d decayed) meanStd() (mean, std float64) {
+	if d.w == 0 {
+		return 0, 0
+	}
+	mean = d.s / d.w
+	return mean, math.Sqrt(math.Max(d.q/d.w-mean*mean, 0))
+}
+
+// Add folds r into the venue's state. It returns false when r was rejected
+// as an outlier; rejected reports are counted but do not move any score.
+func (a *Aggregator) Add(r Report) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.added != nil {
+		a.added = append(a.added, r)
+	}
+	return a.add(a.venues, r)
+}
+
+func (a *Aggregator) add(venues map[string]*venueState, r Report) bool {
+	st := venues[r.VenueID]
+	if st == nil {
+		st = &venueState{live: decayed{ref: r.At}, buckets: map[int64]*bucket{}}
+		venues[r.VenueID] = st
+	}
+
+	// compare against the live state as of the report's own time
+	cur := a.at(st.live, r.At)
+	if cur.w >= a.cfg.MinOutlierWeight {
+		mean, std := cur.meanStd()
+		if math.Abs(r.Score-mean) > a.cfg.OutlierK*math.Max(std, a.cfg.MinStdDev) {
+			if !a.shift(st, r, mean) {
+				return false
+			}
+		}
+	}
+	a.fold(st, r)
+	return true
+}
+
+// shift decides whether outlier r is part of a change enough independent
+// sources agree on. If so it folds in the held reports on r's side of mean
+// and reports true; otherwise r is counted as rejected and held.
+func (a *Aggregator) shift(st *venueState, r Report, mean float64) bool {
+	up := r.Score > mean
+	kept := st.held[:0]
+	sources := map[string]bool{r.Source: true}
+	for _, h := range st.held {
+		if r.At.Sub(h.At).Abs() > a.cfg.ShiftWindow {
+			continue
+		}
+		kept = append(kept, h)
+		if (h.Score > mean) == up {
+			sources[h.Source] = true
+		}
+	}
+	st.held = kept
+	if len(sources) < a.cfg.ShiftReports {
+		bucketAt(st, r.At).rejected++
+		if len(st.held) == maxHeld {
+			st.held = st.held[1:]
+		}
+		st.held = append(st.held, r)
+		return false
+	}
+	st.held = st.held[:0]
+	for _, h := range kept {
+		if (h.Score > mean) != up {
+			st.held = append(st.held, h)
+			continue
+		}
+		if b := bucketAt(st, h.At); b.rejected > 0 {
+			b.rejected--
+		}
+		a.fold(st, h)
+	}
+	return true
+}
+
+func bucketAt(st *venueState, t time.Time) *bucket {
+	b := st.buckets[hourKey(t)]
+	if b == nil {
+		b = &bucket{}
+		st.buckets[hourKey(t)] = b
+	}
+	return b
+}
+
+// fold adds an accepted report to the live moments and its hourly bucket.
+func (a *Aggregator) fold(st *venueState, r Report) {
+	// late reports are added with their decay relative to ref instead of
+	// moving ref backwards
+	f := 1.0
+	if r.At.After(st.live.ref) {
+		st.live = a.at(st.live, r.At)
+	} else {
+		f = math.Exp(-st.live.ref.Sub(r.At).Seconds() / a.tau)
+	}
+	c := r.Confidence
+	st.live.w += c * f
+	st.live.s += c * f * r.Score
+	st.live.q += c * f * r.Score * r.Score
+
+	b := bucketAt(st, r.At)
+	b.w += c
+	b.s += c * r.Score
+	b.n++
+}
+
+// Live returns the decayed, confidence-weighted score at now and the decayed
+// weight behind it. ok is false when there is too little recent data.
+func (a *Aggregator) Live(venueID string, now time.Time) (score, weight float64, ok bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	st := a.venues[venueID]
+	if st == nil {
+		return 0, 0, false
+	}
+	cur := a.at(st.live, now)
+	score, _ = cur.meanStd()
+	return score, cur.w, cur.w >= a.cfg.MinLiveWeight
+}
+
+// Bucket is a confidence-weighted mean over a slice of time.
+type Bucket struct {
+	Avg   float64 `json:"avg"`
+	Count int     `json:"count"`
+}
+
+// Summary describes a venue's vibe over a window ending now.
+type Summary struct {
+	Score    *float64 `json:"score"` // live decayed score; nil when unknown
+	Weight   float64  `json:"weight"`
+	Avg      float64  `json:"avg"` // confidence-weighted mean over the window
+	Count    int      `json:"count"`
+	Rejected int      `json:"rejected"`
+	// Hourly and Weekday (0=Sunday) are in the venue's local time.
+	Hourly      [24]Bucket `json:"hourly"`
+	Weekday     [7]Bucket  `json:"weekday"`
+	BusiestHour *int       `json:"busiestHour"`
+}
+
+type acc struct {
+	w, s float64
+	n    int
+}
+
+func (x *acc) add(b *bucket) { x.w += b.w; x.s += b.s; x.n += b.n }
+
+func (x acc) bucket() Bucket {
+	if x.w == 0 {
+		return Bucket{Count: x.n}
+	}
+	return Bucket{Avg: x.s / x.w, Count: x.n}
+}
+
+// Summarize reports the live score plus window statistics. The window is
+// applied at hour granularity, and histograms bucket by the local hour the
+// UTC hour starts in (exact for whole-hour offsets).
+func (a *Aggregator) Summarize(venueID string, now time.Time, window time.Duration, loc *time.Location) Summary {
+	var sum Summary
+	if score, weight, ok := a.Live(venueID, now); ok {
+		sum.Score, sum.Weight = &score, weight
+	} else {
+		sum.Weight = weight
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	st := a.venues[venueID]
+	if st == nil {
+		return sum
+	}
+	from := hourKey(now.Add(-window))
+	var total acc
+	var hourly [24]acc
+	var weekday [7]acc
+	for k, b := range st.buckets {
+		if k < from || k > hourKey(now) {
+			continue
+		}
+		local := time.Unix(k*3600, 0).In(loc)
+		total.add(b)
+		hourly[local.Hour()].add(b)
+		weekday[local.Weekday()].add(b)
+		sum.Rejected += b.rejected
+	}
+	t := total.bucket()
+	sum.Avg, sum.Count = t.Avg, t.Count
+	for h := range hourly {
+		sum.Hourly[h] = hourly[h].bucket()
+		if sum.Hourly[h].Count > 0 && (sum.BusiestHour == nil || sum.Hourly[h].Avg > sum.Hourly[*sum.BusiestHour].Avg) {
+			hh := h
+			sum.BusiestHour = &hh
+		}
+	}
+	for d := range weekday {
+		sum.Weekday[d] = weekday[d].bucket()
+	}
+	return sum
+}
+
+// Baseline is the confidence-weighted mean of reports from window ago up to
+// exclude ago (hour granularity): the venue's usual vibe without the latest
+// reports, for spotting a rise.
+func (a *Aggregator) Baseline(venueID string, now time.Time, window, exclude time.Duration) Bucket {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	st := a.venues[venueID]
+	if st == nil {
+		return Bucket{}
+	}
+	from, to := hourKey(now.Add(-window)), hourKey(now.Add(-exclude))
+	var total acc
+	for k, b := range st.buckets {
+		if k >= from && k < to {
+			total.add(b)
+		}
+	}
+	return total.bucket()
+}
+
+// Prune drops hourly buckets that started before the cutoff. The live score
+// needs no pruning; old reports have decayed to nothing.
+func (a *Aggregator) Prune(before time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	cut := hourKey(before)
+	for id, st := range a.venues {
+		for k := range st.buckets {
+			if k < cut {
+				delete(st.buckets, k)
+			}
+		}
+		if len(st.buckets) == 0 {
+			delete(a.venues, id)
+		}
+	}
+}
+
+// Reload rebuilds the state from the reports load returns. Reports added
+// while load runs are replayed on top unless load returned them too (by
+// ID), so none are lost when the state is replaced. On error the state is
+// left as it was.
+func (a *Aggregator) Reload(load func() ([]Report, error)) error {
+	a.mu.Lock()
+	a.added = []Report{}
+	a.mu.Unlock()
+	reports, err := load()
+	if err != nil {
+		a.mu.Lock()
+		a.added = nil
+		a.mu.Unlock()
+		return err
+	}
+	a.Rebuild(reports)
+	return nil
+}
+
+// Rebuild replaces all state with the given reports, replayed in time order,
+// plus any added since Reload started loading them.
+func (a *Aggregator) Rebuild(reports []Report) {
+	sorted := append([]Report(nil), reports...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].At.Before(sorted[j].At) })
+	venues := map[string]*venueState{}
+	seen := map[int64]bool{}
+	for _, r := range sorted {
+		a.add(venues, r)
+		if r.ID != 0 {
+			seen[r.ID] = true
+		}
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, r := range a.added {
+		if r.ID == 0 || !seen[r.ID] {
+			a.add(venues, r)
+		}
+	}
+	a.venues, a.added = venues, nil
+}
@@ -0,0 +1,188 @@
+package vibe
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+var t0 = time.Date(2026, 1, 9, 20, 0, 0, 0, time.UTC) // a Friday
+
+func TestLive_DecaysAndWeightsByConfidence(t *testing.T) {
+	a := New(DefaultConfig)
+	a.Add(Report{VenueID: "v", Score: 2, Confidence: 1, At: t0})
+	a.Add(Report{VenueID: "v", Score: 8, Confidence: 1, At: t0.Add(DefaultConfig.HalfLife)})
+
+	// the older report has half the weight: (2·0.5 + 8·1) / 1.5 = 6
+	score, weight, ok := a.Live("v", t0.Add(DefaultConfig.HalfLife))
+	if !ok || math.Abs(score-6) > 1e-9 || math.Abs(weight-1.5) > 1e-9 {
+		t.Fatalf("live = %v (weight %v, ok %v), want 6 (1.5)", score, weight, ok)
+	}
+
+	// a low-confidence report barely moves the score
+	a.Add(Report{VenueID: "v", Score: 0, Confidence: 0.05, At: t0.Add(DefaultConfig.HalfLife)})
+	if s, _, _ := a.Live("v", t0.Add(DefaultConfig.HalfLife)); s < 5.7 {
+		t.Fatalf("low-confidence report moved score to %v", s)
+	}
+
+	// after many half-lives the score is unknown
+	if _, _, ok := a.Live("v", t0.Add(10*DefaultConfig.HalfLife)); ok {
+		t.Fatalf("expected stale score to be unknown")
+	}
+}
+
+func TestAdd_RejectsOutliers(t *testing.T) {
+	a := New(DefaultConfig)
+	for i := 0; i < 5; i++ {
+		if !a.Add(Report{VenueID: "v", Score: 6, Confidence: 1, At: t0.Add(time.Duration(i) * time.Minute)}) {
+			t.Fatalf("report %d rejected", i)
+		}
+	}
+	if a.Add(Report{VenueID: "v", Score: 10, Confidence: 1, At: t0.Add(5 * time.Minute)}) {
+		t.Fatalf("expected 10 to be rejected against a steady 6")
+	}
+	if !a.Add(Report{VenueID: "v", Score: 7, Confidence: 1, At: t0.Add(6 * time.Minute)}) {
+		t.Fatalf("expected 7 to be accepted")
+	}
+	sum := a.Summarize("v", t0.Add(time.Hour), 24*time.Hour, time.UTC)
+	if sum.Count != 6 || sum.Rejected != 1 {
+		t.Fatalf("count/rejected = %d/%d, want 6/1", sum.Count, sum.Rejected)
+	}
+}
+
+func TestSummarize_HistogramsInLocalTimeAndWindow(t *testing.T) {
+	la, _ := time.LoadLocation("America/Los_Angeles")
+	a := New(DefaultConfig)
+	// Friday 22:00 in LA, a week apart, and a quieter Saturday 14:00
+	a.Add(Report{VenueID: "v", Score: 9, Confidence: 1, At: time.Date(2026, 1, 2, 22, 10, 0, 0, la)})
+	a.Add(Report{VenueID: "v", Score: 8, Confidence: 1, At: time.Date(2026, 1, 9, 22, 10, 0, 0, la)})
+	a.Add(Report{VenueID: "v", Score: 3, Confidence: 1, At: time.Date(2026, 1, 10, 14, 0, 0, 0, la)})
+	now := time.Date(2026, 1, 10, 15, 0, 0, 0, la)
+
+	sum := a.Summarize("v", now, 14*24*time.Hour, la)
+	if sum.Count != 3 || sum.BusiestHour == nil || *sum.BusiestHour != 22 {
+		t.Fatalf("unexpected summary %+v", sum)
+	}
+	if h := sum.Hourly[22]; h.Count != 2 || h.Avg != 8.5 {
+		t.Fatalf("hour 22 = %+v, want 2 reports averaging 8.5", h)
+	}
+	if d := sum.Weekday[time.Saturday]; d.Count != 1 || d.Avg != 3 {
+		t.Fatalf("saturday = %+v", d)
+	}
+
+	// a 1-day window only sees the last Friday night and Saturday
+	if sum := a.Summarize("v", now, 24*time.Hour, la); sum.Count != 2 {
+		t.Fatalf("1d window count = %d, want 2", sum.Count)
+	}
+}
+
+func TestRebuild_MatchesIncremental(t *testing.T) {
+	reports := []Report{
+		{VenueID: "v", Score: 5, Confidence: 0.5, At: t0.Add(20 * time.Minute)},
+		{VenueID: "v", Score: 7, Confidence: 1, At: t0},
+		{VenueID: "w", Score: 3, Confidence: 0.9, At: t0.Add(10 * time.Minute)},
+	}
+	inc := New(DefaultConfig)
+	for _, r := range []int{1, 2, 0} { // time order
+		inc.Add(reports[r])
+	}
+	reb := New(DefaultConfig)
+	reb.Rebuild(reports)
+	for _, id := range []string{"v", "w"} {
+		s1, w1, _ := inc.Live(id, t0.Add(time.Hour))
+		s2, w2, _ := reb.Live(id, t0.Add(time.Hour))
+		if math.Abs(s1-s2) > 1e-9 || math.Abs(w1-w2) > 1e-9 {
+			t.Fatalf("%s: incremental %v/%v != rebuilt %v/%v", id, s1, w1, s2, w2)
+		}
+	}
+
+	reb.Prune(t0.Add(2 * time.Hour))
+	if sum := reb.Summarize("v", t0.Add(2*time.Hour), 24*time.Hour, time.UTC); sum.Count != 0 {
+		t.Fatalf("expected pruned buckets to be gone, got %+v", sum)
+	}
+}
+
+func TestBaseline_LeavesOutRecentHours(t *testing.T) {
+	a := New(DefaultConfig)
+	for d := 1; d <= 3; d++ {
+		a.Add(Report{VenueID: "v", Score: 4, Confidence: 1, At: t0.Add(-time.Duration(d) * 24 * time.Hour)})
+	}
+	a.Add(Report{VenueID: "v", Score: 6, Confidence: 1, At: t0.Add(-10 * time.Minute)})
+	b := a.Baseline("v", t0, 7*24*time.Hour, time.Hour)
+	if b.Count != 3 || b.Avg != 4 {
+		t.Fatalf("baseline = %+v, want 3 reports averaging 4", b)
+	}
+	if b := a.Baseline("v", t0, 7*24*time.Hour, 0); b.Count != 4 {
+		t.Fatalf("without exclusion expected 4 reports, got %+v", b)
+	}
+}
+
+func TestAdd_AcceptsStepChangeOnceSourcesAgree(t *testing.T) {
+	a := New(DefaultConfig)
+	for i := 0; i < 8; i++ {
+		a.Add(Report{VenueID: "v", Score: 3, Confidence: 1, At: t0.Add(time.Duration(i) * time.Minute), Source: "regular"})
+	}
+	at := t0.Add(10 * time.Minute)
+
+	// one reporter repeating itself is still an outlier
+	for i := 0; i < 4; i++ {
+		if a.Add(Report{VenueID: "v", Score: 9, Confidence: 1, At: at, Source: "u1"}) {
+			t.Fatalf("repeat %d from a single source accepted", i)
+		}
+	}
+	if a.Add(Report{VenueID: "v", Score: 9, Confidence: 1, At: at.Add(time.Minute), Source: "u2"}) {
+		t.Fatalf("two sources should not be enough")
+	}
+	// the third source confirms the jump: the held reports count too
+	if !a.Add(Report{VenueID: "v", Score: 9, Confidence: 1, At: at.Add(2 * time.Minute), Source: "u3"}) {
+		t.Fatalf("expected the shift to be accepted")
+	}
+	score, _, _ := a.Live("v", at.Add(2*time.Minute))
+	if score < 5 {
+		t.Fatalf("live score %v didn't follow the step", score)
+	}
+	if !a.Add(Report{VenueID: "v", Score: 9, Confidence: 1, At: at.Add(3 * time.Minute), Source: "u4"}) {
+		t.Fatalf("reports at the new level should be accepted")
+	}
+	sum := a.Summarize("v", t0.Add(time.Hour), 24*time.Hour, time.UTC)
+	if sum.Count != 15 || sum.Rejected != 0 {
+		t.Fatalf("count/rejected = %d/%d, want 15/0", sum.Count, sum.Rejected)
+	}
+
+	// outliers too far apart in time don't add up
+	b := New(DefaultConfig)
+	for i := 0; i < 8; i++ {
+		b.Add(Report{VenueID: "v", Score: 3, Confidence: 1, At: t0.Add(time.Duration(i) * time.Minute)})
+	}
+	b.Add(Report{VenueID: "v", Score: 9, Confidence: 1, At: t0.Add(8 * time.Minute), Source: "u1"})
+	b.Add(Report{VenueID: "v", Score: 9, Confidence: 1, At: t0.Add(9 * time.Minute), Source: "u2"})
+	if b.Add(Report{VenueID: "v", Score: 9, Confidence: 1, At: t0.Add(9*time.Minute + DefaultConfig.ShiftWindow + time.Minute), Source: "u3"}) {
+		t.Fatalf("expected stale outliers not to confirm a shift")
+	}
+}
+
+func TestReload_KeepsReportsAddedWhileLoading(t *testing.T) {
+	a := New(DefaultConfig)
+	stored := []Report{{ID: 1, VenueID: "v", Score: 4, Confidence: 1, At: t0}}
+	err := a.Reload(func() ([]Report, error) {
+		// one report lands after the load read it, one before
+		a.Add(Report{ID: 1, VenueID: "v", Score: 4, Confidence: 1, At: t0})
+		a.Add(Report{ID: 2, VenueID: "v", Score: 6, Confidence: 1, At: t0})
+		return stored, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum := a.Summarize("v", t0, time.Hour, time.UTC); sum.Count != 2 || math.Abs(sum.Avg-5) > 1e-9 {
+		t.Fatalf("count/avg = %d/%v, want 2/5", sum.Count, sum.Avg)
+	}
+
+	// a failed load leaves the state alone
+	if err := a.Reload(func() ([]Report, error) { return nil, errors.New("db down") }); err == nil {
+		t.Fatal("expected the load error")
+	}
+	if sum := a.Summarize("v", t0, time.Hour, time.UTC); sum.Count != 2 {
+		t.Fatalf("count = %d after failed reload, want 2", sum.Count)
+	}
+}
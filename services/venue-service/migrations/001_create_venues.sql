@@ -0,0 +1,29 @@
+-- +goose Up
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+-- Venue catalog. Ids are text so seeded/imported ids ("v1") and generated
+-- uuids can coexist.
+CREATE TABLE IF NOT EXISTS venues (
+    id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::text,
+    name TEXT NOT NULL,
+    subtitle TEXT NOT NULL DEFAULT '',
+    category TEXT NOT NULL,
+    tags TEXT[] NOT NULL DEFAULT ARRAY[]::TEXT[],
+    price_tier SMALLINT NOT NULL DEFAULT 2 CHECK (price_tier BETWEEN 1 AND 4),
+    lat DOUBLE PRECISION NOT NULL CHECK (lat BETWEEN -90 AND 90),
+    lon DOUBLE PRECISION NOT NULL CHECK (lon BETWEEN -180 AND 180),
+    address JSONB NOT NULL DEFAULT '{}'::jsonb,
+    hours JSONB NOT NULL DEFAULT '{}'::jsonb,
+    photos TEXT[] NOT NULL DEFAULT ARRAY[]::TEXT[],
+    status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('draft','active','archived')),
+    rating DOUBLE PRECISION NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_venues_status ON venues (status);
+CREATE INDEX IF NOT EXISTS idx_venues_category ON venues (category);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_venues_category;
+DROP INDEX IF EXISTS idx_venues_status;
+DROP TABLE IF EXISTS venues;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- A user's latest swipe on a venue: like or skip. Liking a skipped venue
+-- replaces the skip. user_id is the auth-service user id (JWT sub).
+CREATE TABLE IF NOT EXISTS venue_interactions (
+    user_id TEXT NOT NULL,
+    venue_id TEXT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('like','skip')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, venue_id)
+);
+CREATE INDEX IF NOT EXISTS idx_venue_interactions_venue_kind ON venue_interactions (venue_id, kind);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_venue_interactions_venue_kind;
+DROP TABLE IF EXISTS venue_interactions;
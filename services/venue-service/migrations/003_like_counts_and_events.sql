@@ -0,0 +1,20 @@
+-- +goose Up
+-- Denormalised like counter, maintained with venue_interactions in one tx.
+ALTER TABLE venues ADD COLUMN IF NOT EXISTS like_count INT NOT NULL DEFAULT 0;
+UPDATE venues v SET like_count = (SELECT COUNT(*) FROM venue_interactions i WHERE i.venue_id = v.id AND i.kind = 'like');
+
+-- Append-only swipe history (only state changes are logged, so retries are
+-- not double counted). Feeds analytics.
+CREATE TABLE IF NOT EXISTS venue_interaction_events (
+    id BIGSERIAL PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    venue_id TEXT NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('like','unlike','skip')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_venue_interaction_events_venue_time ON venue_interaction_events (venue_id, created_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_venue_interaction_events_venue_time;
+DROP TABLE IF EXISTS venue_interaction_events;
+ALTER TABLE venues DROP COLUMN IF EXISTS like_count;
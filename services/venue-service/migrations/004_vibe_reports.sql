@@ -0,0 +1,23 @@
+-- +goose Up
+-- Crowd-sourced vibe samples from devices at the venue. Rows older than the
+-- retention window (VIBE_RETENTION) are deleted by the purge job.
+CREATE TABLE IF NOT EXISTS vibe_reports (
+    id BIGSERIAL PRIMARY KEY,
+    venue_id TEXT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+    user_id TEXT,
+    score DOUBLE PRECISION NOT NULL CHECK (score >= 0 AND score <= 10),
+    confidence DOUBLE PRECISION NOT NULL CHECK (confidence >= 0 AND confidence <= 1),
+    features JSONB NOT NULL DEFAULT '{}'::jsonb,
+    idempotency_key TEXT,
+    reported_at TIMESTAMPTZ NOT NULL,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_vibe_reports_venue_time ON vibe_reports (venue_id, reported_at);
+CREATE INDEX IF NOT EXISTS idx_vibe_reports_received ON vibe_reports (received_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_vibe_reports_idem ON vibe_reports (venue_id, idempotency_key) WHERE idempotency_key IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_vibe_reports_idem;
+DROP INDEX IF EXISTS idx_vibe_reports_received;
+DROP INDEX IF EXISTS idx_vibe_reports_venue_time;
+DROP TABLE IF EXISTS vibe_reports;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- Idempotency-Key records for POST endpoints (bytspot/shared/idempotency).
+-- key is a hash of the caller and the client key; status is NULL while the
+-- first request is still running.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key TEXT PRIMARY KEY,
+    fingerprint TEXT NOT NULL,
+    status INT,
+    content_type TEXT NOT NULL DEFAULT '',
+    body BYTEA,
+    locked_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires ON idempotency_keys (expires_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_idempotency_keys_expires;
+DROP TABLE IF EXISTS idempotency_keys;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- owner_id is the venue host (auth-service user id) allowed to edit the venue.
+ALTER TABLE venues ADD COLUMN IF NOT EXISTS owner_id TEXT;
+CREATE INDEX IF NOT EXISTS idx_venues_owner ON venues (owner_id) WHERE owner_id IS NOT NULL;
+
+-- Every admin/host write to a venue, with the changed fields.
+CREATE TABLE IF NOT EXISTS venue_audit (
+    id BIGSERIAL PRIMARY KEY,
+    venue_id TEXT NOT NULL,
+    actor_id TEXT NOT NULL,
+    actor_role TEXT NOT NULL,
+    action TEXT NOT NULL,
+    changes JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_venue_audit_venue_time ON venue_audit (venue_id, created_at DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_venue_audit_venue_time;
+DROP TABLE IF EXISTS venue_audit;
+DROP INDEX IF EXISTS idx_venues_owner;
+ALTER TABLE venues DROP COLUMN IF EXISTS owner_id;
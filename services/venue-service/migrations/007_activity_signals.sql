@@ -0,0 +1,23 @@
+-- +goose Up
+-- Activity aggregates per geohash7 cell from the ingestion pipeline (device
+-- count and a 0-1 motion/loudness intensity over a short window). Rows older
+-- than a day are deleted by the purge job.
+CREATE TABLE IF NOT EXISTS activity_signals (
+    id BIGSERIAL PRIMARY KEY,
+    geohash TEXT NOT NULL CHECK (length(geohash) = 7),
+    devices INT NOT NULL CHECK (devices >= 0),
+    intensity DOUBLE PRECISION NOT NULL CHECK (intensity >= 0 AND intensity <= 1),
+    observed_at TIMESTAMPTZ NOT NULL,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_activity_signals_observed ON activity_signals (observed_at);
+CREATE INDEX IF NOT EXISTS idx_activity_signals_received ON activity_signals (received_at);
+
+-- Trending counts recent likes across all venues.
+CREATE INDEX IF NOT EXISTS idx_venue_interaction_events_likes_time ON venue_interaction_events (created_at) WHERE kind = 'like';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_venue_interaction_events_likes_time;
+DROP INDEX IF EXISTS idx_activity_signals_received;
+DROP INDEX IF EXISTS idx_activity_signals_observed;
+DROP TABLE IF EXISTS activity_signals;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- Append-only check-ins: the user was inside the venue's geofence at
+-- created_at. distance is meters from the venue; shared_with lists the
+-- friends who were notified.
+CREATE TABLE IF NOT EXISTS venue_checkins (
+    id BIGSERIAL PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    venue_id TEXT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+    lat DOUBLE PRECISION NOT NULL,
+    lon DOUBLE PRECISION NOT NULL,
+    accuracy DOUBLE PRECISION,
+    distance DOUBLE PRECISION NOT NULL,
+    shared_with TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_venue_checkins_user_time ON venue_checkins (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_venue_checkins_user_venue_time ON venue_checkins (user_id, venue_id, created_at DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_venue_checkins_user_venue_time;
+DROP INDEX IF EXISTS idx_venue_checkins_user_time;
+DROP TABLE IF EXISTS venue_checkins;
@@ -0,0 +1,59 @@
+-- +goose Up
+-- User-written reviews (with a 1-5 rating) and short insider tips. Content
+-- the keyword filter holds, or that collects enough abuse reports, waits in
+-- the admin queue as 'pending'; only 'published' rows are public. flags are
+-- the filter's matches plus 'reported'.
+CREATE TABLE IF NOT EXISTS venue_reviews (
+    id BIGSERIAL PRIMARY KEY,
+    venue_id TEXT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL,
+    author_name TEXT NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('review','tip')),
+    rating SMALLINT CHECK (rating BETWEEN 1 AND 5),
+    body TEXT NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('pending','published','rejected')),
+    flags TEXT[] NOT NULL DEFAULT '{}',
+    helpful_count INT NOT NULL DEFAULT 0,
+    report_count INT NOT NULL DEFAULT 0,
+    moderated_by TEXT,
+    moderation_note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK ((kind = 'review') = (rating IS NOT NULL))
+);
+-- One review per user and venue; tips are not limited.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_venue_reviews_one_per_user ON venue_reviews (venue_id, user_id) WHERE kind = 'review';
+CREATE INDEX IF NOT EXISTS idx_venue_reviews_venue_status ON venue_reviews (venue_id, status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_venue_reviews_user ON venue_reviews (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_venue_reviews_pending ON venue_reviews (report_count DESC, created_at) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS venue_review_votes (
+    review_id BIGINT NOT NULL REFERENCES venue_reviews(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (review_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS venue_review_reports (
+    review_id BIGINT NOT NULL REFERENCES venue_reviews(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (review_id, user_id)
+);
+
+-- Published review aggregates, maintained with venue_reviews in one tx. The
+-- editorial rating column is left as it is.
+ALTER TABLE venues ADD COLUMN IF NOT EXISTS review_count INT NOT NULL DEFAULT 0;
+ALTER TABLE venues ADD COLUMN IF NOT EXISTS review_rating DOUBLE PRECISION;
+
+-- +goose Down
+ALTER TABLE venues DROP COLUMN IF EXISTS review_rating;
+ALTER TABLE venues DROP COLUMN IF EXISTS review_count;
+DROP TABLE IF EXISTS venue_review_reports;
+DROP TABLE IF EXISTS venue_review_votes;
+DROP INDEX IF EXISTS idx_venue_reviews_pending;
+DROP INDEX IF EXISTS idx_venue_reviews_user;
+DROP INDEX IF EXISTS idx_venue_reviews_venue_status;
+DROP INDEX IF EXISTS idx_venue_reviews_one_per_user;
+DROP TABLE IF EXISTS venue_reviews;
@@ -0,0 +1,33 @@
+-- +goose Up
+-- Domain events that can advance achievements, one row per user and event
+-- id so re-delivered events are ignored. zone is the IANA timezone the
+-- event's local hour and day are judged in ('' = ACHIEVEMENTS_TZ).
+CREATE TABLE IF NOT EXISTS achievement_events (
+    user_id TEXT NOT NULL,
+    event_id TEXT NOT NULL,
+    type TEXT NOT NULL,
+    attrs JSONB NOT NULL DEFAULT '{}',
+    zone TEXT NOT NULL DEFAULT '',
+    occurred_at TIMESTAMPTZ NOT NULL,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, event_id)
+);
+CREATE INDEX IF NOT EXISTS idx_achievement_events_user_type ON achievement_events (user_id, type, occurred_at);
+
+-- Progress per user and achievement, recomputed from achievement_events.
+-- earned_at is set once, when the threshold is first reached, and never
+-- cleared.
+CREATE TABLE IF NOT EXISTS user_achievements (
+    user_id TEXT NOT NULL,
+    achievement_id TEXT NOT NULL,
+    progress INT NOT NULL,
+    target INT NOT NULL,
+    earned_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, achievement_id)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS user_achievements;
+DROP INDEX IF EXISTS idx_achievement_events_user_type;
+DROP TABLE IF EXISTS achievement_events;
@@ -0,0 +1,33 @@
+-- +goose Up
+-- Events and specials (DJ nights, happy hours) a venue promotes. starts_at and
+-- ends_at are the first occurrence; recurrence is an RRULE value expanded in
+-- timezone (the venue's at the time of writing). series_ends_at is the end of
+-- the last occurrence, NULL for open-ended series, so range queries can skip
+-- series that are over without expanding them.
+CREATE TABLE IF NOT EXISTS venue_events (
+    id BIGSERIAL PRIMARY KEY,
+    venue_id TEXT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('event','special')),
+    title TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    timezone TEXT NOT NULL,
+    recurrence TEXT NOT NULL DEFAULT '',
+    series_ends_at TIMESTAMPTZ,
+    ticket_url TEXT NOT NULL DEFAULT '',
+    reservation_url TEXT NOT NULL DEFAULT '',
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    status TEXT NOT NULL DEFAULT 'scheduled' CHECK (status IN ('scheduled','cancelled')),
+    created_by TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (ends_at > starts_at)
+);
+CREATE INDEX IF NOT EXISTS idx_venue_events_venue ON venue_events (venue_id, starts_at);
+CREATE INDEX IF NOT EXISTS idx_venue_events_span ON venue_events (starts_at, series_ends_at) WHERE status = 'scheduled';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_venue_events_span;
+DROP INDEX IF EXISTS idx_venue_events_venue;
+DROP TABLE IF EXISTS venue_events;
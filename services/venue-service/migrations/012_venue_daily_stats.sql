@@ -0,0 +1,28 @@
+-- +goose Up
+-- Daily rollups behind the host dashboard, one row per venue and local date
+-- (in the venue's timezone). likes, checkins, vibe_reports, vibe_score_sum
+-- and hourly (check-ins and vibe reports by local hour) are recomputed from
+-- their source tables while the day is recent; impressions exist only here
+-- and are added as instances flush them. Rows outlive the vibe report
+-- retention, so older days keep their totals.
+CREATE TABLE IF NOT EXISTS venue_daily_stats (
+    venue_id TEXT NOT NULL REFERENCES venues(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    impressions INT NOT NULL DEFAULT 0,
+    likes INT NOT NULL DEFAULT 0,
+    checkins INT NOT NULL DEFAULT 0,
+    vibe_reports INT NOT NULL DEFAULT 0,
+    vibe_score_sum DOUBLE PRECISION NOT NULL DEFAULT 0,
+    hourly INT[] NOT NULL DEFAULT array_fill(0, ARRAY[24]),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (venue_id, day)
+);
+
+-- The rollup job reads recent likes and check-ins across all venues.
+CREATE INDEX IF NOT EXISTS idx_venue_interaction_events_time ON venue_interaction_events (created_at);
+CREATE INDEX IF NOT EXISTS idx_venue_checkins_time ON venue_checkins (created_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_venue_checkins_time;
+DROP INDEX IF EXISTS idx_venue_interaction_events_time;
+DROP TABLE IF EXISTS venue_daily_stats;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- Nonces of signed vibe reports, shared by every instance so a captured
+-- request can't be replayed against another replica. key is the key id and
+-- nonce; rows outlive the timestamp skew window and are then purged.
+CREATE TABLE IF NOT EXISTS signature_nonces (
+    key TEXT PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_signature_nonces_expires ON signature_nonces (expires_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_signature_nonces_expires;
+DROP TABLE IF EXISTS signature_nonces;
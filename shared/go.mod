@@ -5,4 +5,5 @@ go 1.22
 require (
 	github.com/google/uuid v1.6.0
 	github.com/go-chi/chi/v5 v5.0.12
+	github.com/golang-jwt/jwt/v5 v5.2.1
 )
@@ -0,0 +1,271 @@
+// Package idempotency makes POST handlers safe to retry. A client sends an
+// Idempotency-Key header; the first request with a key runs normally and its
+// response is stored, later requests with the same key and payload get the
+// stored response back, and the same key with a different payload is a 409.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"bytspot/shared/middleware"
+)
+
+const (
+	HeaderKey      = "Idempotency-Key"
+	HeaderReplayed = "Idempotent-Replayed"
+
+	DefaultTTL = 24 * time.Hour
+	// LockTimeout is how long an unfinished request holds its key before a
+	// retry may take over (e.g. after the first instance crashed mid-request).
+	LockTimeout = time.Minute
+	// DefaultMaxBody is the largest body fingerprinted by default.
+	DefaultMaxBody = 1 << 20
+	maxKeyLen      = 255
+)
+
+// Record is what a store keeps per key. Status is 0 while the first request
+// is still running.
+type Record struct {
+	Fingerprint string
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// Store persists records. Implementations must make BeginIdempotentRequest
+// atomic: exactly one caller acquires a free (or expired) key.
+type Store interface {
+	// BeginIdempotentRequest claims key for fingerprint until ttl passes. It
+	// returns nil when the caller acquired the key, or the existing record.
+	BeginIdempotentRequest(ctx context.Context, key, fingerprint string, ttl time.Duration) (*Record, error)
+	CompleteIdempotentRequest(ctx context.Context, key string, status int, contentType string, body []byte) error
+	// ReleaseIdempotentRequest frees the key so the request can be retried.
+	ReleaseIdempotentRequest(ctx context.Context, key string) error
+}
+
+type Options struct {
+	TTL time.Duration
+	// Principal scopes keys per caller; the default is the Authorization
+	// header, so two users can't collide on (or read) each other's keys. An
+	// empty principal (a caller with nothing to scope by) skips idempotency:
+	// in a shared namespace anyone could pre-claim a guessable key.
+	Principal func(r *http.Request) string
+	// MaxBody caps the body buffered for the fingerprint (default
+	// DefaultMaxBody). Larger requests pass through without idempotency, so
+	// routes with their own, higher limit (imports) still work.
+	MaxBody int64
+}
+
+func hash(parts ...string) string {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recorder tees the response so it can be stored after the handler returns.
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Middleware applies idempotency to POST requests carrying Idempotency-Key;
+// everything else passes through. 5xx responses are not stored, so the
+// client can retry them with the same key, and neither are 401, 403 and 429:
+// the fingerprint doesn't cover credentials, so a retry with fixed ones (a
+// re-signed request, a fresh token) or after the rate limit must run again.
+func Middleware(store Store, opts Options) func(http.Handler) http.Handler {
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+	if opts.Principal == nil {
+		opts.Principal = func(r *http.Request) string { return r.Header.Get("Authorization") }
+	}
+	if opts.MaxBody <= 0 {
+		opts.MaxBody = DefaultMaxBody
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(HeaderKey)
+			if r.Method != http.MethodPost || key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxKeyLen {
+				middleware.ErrorHandler(w, http.StatusBadRequest, "Idempotency-Key too long", "VALIDATION_ERROR")
+				return
+			}
+			body, err := io.ReadAll(io.LimitReader(r.Body, opts.MaxBody+1))
+			if err != nil {
+				middleware.ErrorHandler(w, http.StatusBadRequest, "could not read body", "VALIDATION_ERROR")
+				return
+			}
+			principal := opts.Principal(r)
+			if int64(len(body)) > opts.MaxBody || principal == "" {
+				// too large to fingerprint, or nobody to scope the key to:
+				// hand the handler the untouched body and its own limits
+				r.Body = readCloser{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+				next.ServeHTTP(w, r)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			storeKey := hash(principal, key)
+			fingerprint := hash(r.Method, r.URL.Path, string(body))
+			rec, err := store.BeginIdempotentRequest(r.Context(), storeKey, fingerprint, opts.TTL)
+			if err != nil {
+				middleware.ErrorHandler(w, http.StatusInternalServerError, "idempotency store error", "INTERNAL_ERROR")
+				return
+			}
+			if rec != nil {
+				switch {
+				case rec.Fingerprint != fingerprint:
+					middleware.ErrorHandler(w, http.StatusConflict, "Idempotency-Key was used with a different request", "IDEMPOTENCY_KEY_REUSED")
+				case rec.Status == 0:
+					middleware.ErrorHandler(w, http.StatusConflict, "a request with this Idempotency-Key is in progress", "IDEMPOTENCY_IN_PROGRESS")
+				default:
+					if rec.ContentType != "" {
+						w.Header().Set("Content-Type", rec.ContentType)
+					}
+					w.Header().Set(HeaderReplayed, "true")
+					w.WriteHeader(rec.Status)
+					w.Write(rec.Body)
+				}
+				return
+			}
+
+			rw := &recorder{ResponseWriter: w}
+			done := false
+			defer func() {
+				// detached from the request so a client hang-up still records the outcome
+				ctx := context.WithoutCancel(r.Context())
+				status := rw.status
+				if status == 0 {
+					status = http.StatusOK
+				}
+				var err error
+				if !done || retryable(status) { // panicked, failed or refused: let the client retry
+					err = store.ReleaseIdempotentRequest(ctx, storeKey)
+				} else {
+					err = store.CompleteIdempotentRequest(ctx, storeKey, status, w.Header().Get("Content-Type"), rw.body.Bytes())
+				}
+				if err != nil {
+					log.Printf("idempotency: saving response failed: %v", err)
+				}
+			}()
+			next.ServeHTTP(rw, r)
+			done = true
+		})
+	}
+}
+
+// readCloser replays the bytes already read ahead of the rest of the body.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func retryable(status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests:
+		return true
+	}
+	return status >= 500
+}
+
+// MemoryStore is an in-process Store for tests and single-instance runs.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memEntry
+	nextSweep time.Time
+	now       func() time.Time
+}
+
+type memEntry struct {
+	rec       Record
+	lockedAt  time.Time
+	expiresAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: map[string]*memEntry{}, now: time.Now}
+}
+
+func (m *MemoryStore) BeginIdempotentRequest(_ context.Context, key, fingerprint string, ttl time.Duration) (*Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	if now.After(m.nextSweep) {
+		for k, e := range m.entries {
+			if now.After(e.expiresAt) {
+				delete(m.entries, k)
+			}
+		}
+		m.nextSweep = now.Add(time.Minute)
+	}
+	e, ok := m.entries[key]
+	abandoned := ok && e.rec.Status == 0 && now.Sub(e.lockedAt) > LockTimeout
+	if ok && !abandoned && !now.After(e.expiresAt) {
+		rec := e.rec
+		rec.Body = append([]byte(nil), e.rec.Body...)
+		return &rec, nil
+	}
+	m.entries[key] = &memEntry{rec: Record{Fingerprint: fingerprint}, lockedAt: now, expiresAt: now.Add(ttl)}
+	return nil, nil
+}
+
+func (m *MemoryStore) CompleteIdempotentRequest(_ context.Context, key string, status int, contentType string, body []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.entries[key]; ok {
+		e.rec.Status, e.rec.ContentType, e.rec.Body = status, contentType, append([]byte(nil), body...)
+	}
+	return nil
+}
+
+// PurgeIdempotentRequests drops records that expired before the cutoff.
+func (m *MemoryStore) PurgeIdempotentRequests(_ context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for k, e := range m.entries {
+		if e.expiresAt.Before(before) {
+			delete(m.entries, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MemoryStore) ReleaseIdempotentRequest(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+	return nil
+}
@@ -0,0 +1,67 @@
+// Package jwtauth verifies the HS256 access tokens auth-service issues, for
+// the services that accept them from users (directly or forwarded by the
+// BFF). Service-to-service calls use servicetoken instead.
+package jwtauth
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims mirrors the access tokens auth-service issues.
+type Claims struct {
+	Sub   string   `json:"sub"`
+	Roles []string `json:"roles,omitempty"`
+	Sid   string   `json:"sid,omitempty"`
+	// Act is set on admin impersonation tokens.
+	Act *struct {
+		Sub string `json:"sub"`
+	} `json:"act,omitempty"`
+	Scope string `json:"scope,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Impersonating reports whether the token was issued to support staff acting
+// as the subject.
+func (c *Claims) Impersonating() bool { return c.Act != nil }
+
+// Secret is the HMAC key shared with auth-service.
+func Secret() []byte {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		// Development fallback only; set JWT_SECRET in prod
+		secret = "dev_secret_change_me"
+	}
+	return []byte(secret)
+}
+
+// Verify parses a raw token and returns its claims if the signature, expiry
+// and subject check out.
+func Verify(tokenString string) (*Claims, error) {
+	tok, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return Secret(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if claims, ok := tok.Claims.(*Claims); ok && tok.Valid && claims.Sub != "" {
+		return claims, nil
+	}
+	return nil, errors.New("invalid token")
+}
+
+// BearerToken is the token in r's Authorization header, or "" without one.
+func BearerToken(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return ""
+	}
+	return strings.TrimPrefix(auth, "Bearer ")
+}
@@ -44,12 +44,22 @@ function useAuthz() {
 
 function VenuesPage() {
   const [title, setTitle] = useState('');
+  const [category, setCategory] = useState('bar');
+  const [address, setAddress] = useState('');
   const { data, isLoading, error } = useVenuesQuery(true);
   const createVenue = useCreateVenue();
   const items = data?.items || [];
   const create = async () => {
-    await createVenue.mutateAsync({ title });
+    // venue-service geocodes the address into coordinates
+    await createVenue.mutateAsync({ title, category, address: { line1: address } });
     setTitle('');
+    setAddress('');
+  };
+  const inputStyle = {
+    padding: 'var(--space-sm) var(--space-md)',
+    borderRadius: 'var(--radius-md)px',
+    border: '1px solid var(--color-border)',
+    color: 'var(--color-text)', background: 'var(--color-bg)'
   };
   return (
     <div style={{ padding: 'var(--space-lg)', color: 'var(--color-text)', background: 'var(--color-bg)' }}>
@@ -59,12 +69,16 @@ function VenuesPage() {
           value={title}
           onChange={(e: React.ChangeEvent<HTMLInputElement>) => setTitle(e.target.value)}
           placeholder="New venue title"
-          style={{
-            padding: 'var(--space-sm) var(--space-md)',
-            borderRadius: 'var(--radius-md)px',
-            border: '1px solid var(--color-border)',
-            color: 'var(--color-text)', background: 'var(--color-bg)'
-          }}
+          style={inputStyle}
+        />
+        <select value={category} onChange={(e: React.ChangeEvent<HTMLSelectElement>) => setCategory(e.target.value)} style={inputStyle}>
+          {['bar', 'cafe', 'club', 'live_music', 'lounge', 'restaurant'].map((c) => <option key={c} value={c}>{c}</option>)}
+        </select>
+        <input
+          value={address}
+          onChange={(e: React.ChangeEvent<HTMLInputElement>) => setAddress(e.target.value)}
+          placeholder="Street address, city"
+          style={inputStyle}
         />
         <button onClick={create} disabled={createVenue.isPending} style={{
           padding: 'var(--space-sm) var(--space-md)', borderRadius: 'var(--radius-md)px',
@@ -72,10 +86,11 @@ function VenuesPage() {
           border: '1px solid var(--button-primary-border, var(--color-accent))', cursor: 'pointer'
         }}>Create</button>
       </div>
+      {createVenue.error && <div style={{ color: 'var(--color-danger, #fda4af)' }}>{(createVenue.error as Error).message}</div>}
       {isLoading && <div>Loading...</div>}
       {error && <div style={{ color: 'var(--color-danger, #fda4af)' }}>Failed to load venues</div>}
       <ul>
-        {items.map((v: any) => (<li key={v.id}>{v.title} — {v.subtitle}</li>))}
+        {items.map((v: any) => (<li key={v.id}>{v.title} — {v.subtitle} ({v.status})</li>))}
       </ul>
     </div>
   );
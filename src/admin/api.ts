@@ -1,4 +1,4 @@
-import type { Session, AuditItem, HostOnboardingState, HostType, VenueItem, UserItem } from './types';
+import type { Session, AuditItem, HostOnboardingState, HostType, VenueItem, UserItem, VenueAnalytics, VenueAnalyticsSummary } from './types';
 
 const API_BASE = (import.meta as any).env.VITE_BFF_URL || 'http://localhost:3000';
 
@@ -51,9 +51,22 @@ export async function fetchVenues(): Promise<{ items: VenueItem[] }> {
   return r.json();
 }
 
-export async function createVenue(input: { title: string; subtitle?: string; rating?: number; distance?: string; price?: string }): Promise<VenueItem> {
+export type VenueCreateInput = {
+  title: string;
+  subtitle?: string;
+  category: string;
+  price?: string;
+  lat?: number;
+  lng?: number;
+  address?: { line1?: string; city?: string; region?: string; postalCode?: string; country?: string };
+};
+
+export async function createVenue(input: VenueCreateInput): Promise<VenueItem> {
   const r = await fetch(`${API_BASE}/api/admin/venues`, { method: 'POST', headers: { ...getAuthHeaders(), 'Content-Type': 'application/json' }, body: JSON.stringify(input) });
-  if (!r.ok) throw new Error('Failed to create venue');
+  if (!r.ok) {
+    const d = await r.json().catch(() => null);
+    throw new Error(d?.error || 'Failed to create venue');
+  }
   return r.json();
 }
 
@@ -69,3 +82,25 @@ export async function fetchAnalyticsSummary(): Promise<{ users: number; venues:
   return r.json();
 }
 
+
+// range is e.g. '7d' or '30d'; alternatively pass explicit local dates.
+export type AnalyticsQuery = { range?: string; from?: string; to?: string };
+
+function analyticsParams(q: AnalyticsQuery): string {
+  const p = new URLSearchParams();
+  for (const [k, v] of Object.entries(q)) if (v) p.set(k, v);
+  const s = p.toString();
+  return s ? `?${s}` : '';
+}
+
+export async function fetchVenueAnalyticsOverview(q: AnalyticsQuery = {}): Promise<{ items: VenueAnalyticsSummary[] }> {
+  const r = await fetch(`${API_BASE}/api/admin/venues/analytics${analyticsParams(q)}`, { headers: getAuthHeaders() });
+  if (!r.ok) throw new Error('Failed to fetch venue analytics');
+  return r.json();
+}
+
+export async function fetchVenueAnalytics(venueId: string, q: AnalyticsQuery = {}): Promise<VenueAnalytics> {
+  const r = await fetch(`${API_BASE}/api/admin/venues/${encodeURIComponent(venueId)}/analytics${analyticsParams(q)}`, { headers: getAuthHeaders() });
+  if (!r.ok) throw new Error('Failed to fetch venue analytics');
+  return r.json();
+}
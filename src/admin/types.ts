@@ -1,7 +1,20 @@
 export type Session = { sub: string; roles: string[] };
-export type VenueItem = { id: string; title: string; subtitle?: string; rating?: number; distance?: string; price?: string };
+export type VenueItem = { id: string; title: string; subtitle?: string; rating?: number; distance?: string; price?: string; category?: string; status?: 'draft' | 'active' | 'archived'; ownerId?: string };
 export type UserItem = { id: string; email: string; name?: string };
 export type AuditItem = { id: string; actor_id: string; target_email: string; action: 'promote'|'demote'; reason?: string; created_at: string };
 export type HostType = { key: 'venue'|'parking'|'valet'; label: string; description: string };
 export type HostOnboardingState = { userId?: string; serviceType?: HostType['key']; data?: Record<string, any>; progress: number };
 
+export type AnalyticsPeriod = { from: string; to: string };
+export type AnalyticsCount = { value: number; previous: number; change: number | null };
+export type AnalyticsTotals = {
+  impressions: AnalyticsCount; likes: AnalyticsCount; checkins: AnalyticsCount; vibeReports: AnalyticsCount;
+  avgVibe: { value: number | null; previous: number | null; change: number | null };
+};
+export type VenueAnalyticsSummary = { venueId: string; name: string; status: 'draft' | 'active' | 'archived'; timezone: string; period: AnalyticsPeriod; previousPeriod: AnalyticsPeriod; totals: AnalyticsTotals };
+export type VenueAnalytics = {
+  venueId: string; timezone: string; period: AnalyticsPeriod; previousPeriod: AnalyticsPeriod; totals: AnalyticsTotals;
+  daily: { date: string; impressions: number; likes: number; checkins: number; vibeReports: number; avgVibe: number | null }[];
+  hourly: number[];
+  peakHours: { hour: number; activity: number }[];
+};